@@ -18,6 +18,10 @@ package main
 
 import (
 	"context"
+	"log"
+	"os"
+	"strconv"
+	"strings"
 
 	"github.com/tektoncd/resolution/gitresolver/pkg/git"
 	"github.com/tektoncd/resolution/pkg/resolver/framework"
@@ -26,6 +30,46 @@ import (
 
 func main() {
 	sharedmain.Main("controller",
-		framework.NewController(context.Background(), &git.Resolver{}),
+		framework.NewController(context.Background(), &git.Resolver{}, modifiers()...),
 	)
 }
+
+// readinessAddr is the address that the readiness probe, which fails
+// while the git-resolver-config configmap is malformed, is served on.
+const readinessAddr = ":8080"
+
+// modifiers returns the modifiers that the controller is started with.
+func modifiers() []framework.ReconcilerModifier {
+	mods := append([]framework.ReconcilerModifier{framework.WithReadinessProbe(readinessAddr)}, metricLabels()...)
+	return append(mods, compressionThreshold()...)
+}
+
+// compressionThreshold returns a modifier that gzips resolved data
+// larger than the number of bytes in the COMPRESSION_THRESHOLD env
+// var, if it's set.
+func compressionThreshold() []framework.ReconcilerModifier {
+	value := strings.TrimSpace(os.Getenv("COMPRESSION_THRESHOLD"))
+	if value == "" {
+		return nil
+	}
+	size, err := strconv.Atoi(value)
+	if err != nil {
+		log.Fatalf("invalid COMPRESSION_THRESHOLD %q: %v", value, err)
+	}
+	return []framework.ReconcilerModifier{framework.WithCompressionThreshold(size)}
+}
+
+// metricLabels returns a modifier that tags resolution metrics with
+// the ResolutionRequest labels listed in the METRIC_LABELS env var.
+func metricLabels() []framework.ReconcilerModifier {
+	labels := []string{}
+	for _, label := range strings.Split(os.Getenv("METRIC_LABELS"), ",") {
+		if label = strings.TrimSpace(label); label != "" {
+			labels = append(labels, label)
+		}
+	}
+	if len(labels) == 0 {
+		return nil
+	}
+	return []framework.ReconcilerModifier{framework.WithMetricLabels(labels...)}
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// minCommitAbbrevLength is the shortest abbreviation of a commit hash
+// that's accepted, matching the shortest that git itself accepts.
+const minCommitAbbrevLength = 4
+
+// defaultShortHashLength is the shortest that a commit hash is
+// abbreviated to for the commit-short annotation, matching git's
+// default.
+const defaultShortHashLength = 7
+
+// maxAbbrevCandidates is the most commits that are listed in the error
+// for an ambiguous abbreviation.
+const maxAbbrevCandidates = 10
+
+// validateCommitHash returns an error unless s is a full commit hash or
+// an abbreviation of one: between 4 and 40 hex characters.
+func validateCommitHash(param, s string) error {
+	if len(s) < minCommitAbbrevLength || len(s) > 40 || strings.Trim(strings.ToLower(s), "0123456789abcdef") != "" {
+		return fmt.Errorf("invalid value for %q: %q is not a commit hash or an abbreviation of one of at least %d characters", param, s, minCommitAbbrevLength)
+	}
+	return nil
+}
+
+// expandCommitHash returns the full hash of the one commit in
+// repository whose hash starts with abbrev. It fails if there's no such
+// commit, or if several commits share the prefix, listing them.
+func expandCommitHash(repository *git.Repository, abbrev string) (string, error) {
+	prefix := strings.ToLower(abbrev)
+	if isFullCommitHash(prefix) {
+		return prefix, nil
+	}
+	iter, err := repository.CommitObjects()
+	if err != nil {
+		return "", fmt.Errorf("error listing commits: %v", err)
+	}
+	candidates := []string{}
+	err = iter.ForEach(func(c *object.Commit) error {
+		if hash := c.Hash.String(); strings.HasPrefix(hash, prefix) {
+			candidates = append(candidates, hash)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("error listing commits: %v", err)
+	}
+	switch len(candidates) {
+	case 0:
+		return "", fmt.Errorf("error finding commit %q: %w", abbrev, plumbing.ErrObjectNotFound)
+	case 1:
+		return candidates[0], nil
+	}
+	sort.Strings(candidates)
+	listed := candidates
+	if len(listed) > maxAbbrevCandidates {
+		listed = listed[:maxAbbrevCandidates]
+	}
+	more := ""
+	if len(candidates) > len(listed) {
+		more = fmt.Sprintf(" and %d more", len(candidates)-len(listed))
+	}
+	return "", fmt.Errorf("commit %q is ambiguous: it abbreviates %d commits: %s%s", abbrev, len(candidates), strings.Join(listed, ", "), more)
+}
+
+// shortCommitHash returns the shortest prefix of commit, of at least
+// defaultShortHashLength characters, that no other object in
+// repository starts with, much like git rev-parse --short.
+func shortCommitHash(repository *git.Repository, commit string) (string, error) {
+	iter, err := repository.Storer.IterEncodedObjects(plumbing.AnyObject)
+	if err != nil {
+		return "", fmt.Errorf("error listing objects: %v", err)
+	}
+	length := defaultShortHashLength
+	err = iter.ForEach(func(o plumbing.EncodedObject) error {
+		hash := o.Hash().String()
+		if hash == commit {
+			return nil
+		}
+		shared := 0
+		for shared < len(hash) && shared < len(commit) && hash[shared] == commit[shared] {
+			shared++
+		}
+		if shared >= length {
+			length = shared + 1
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("error listing objects: %v", err)
+	}
+	if length > len(commit) {
+		length = len(commit)
+	}
+	return commit[:length], nil
+}
+
+// shortHashOf abbreviates commit to defaultShortHashLength characters,
+// for when there's no repository to check it's unique in.
+func shortHashOf(commit string) string {
+	if len(commit) > defaultShortHashLength {
+		return commit[:defaultShortHashLength]
+	}
+	return commit
+}
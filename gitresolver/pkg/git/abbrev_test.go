@@ -0,0 +1,236 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/tektoncd/resolution/pkg/resolver/framework"
+)
+
+func TestResolveAbbreviatedCommit(t *testing.T) {
+	repoDir, commits := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "one"},
+	}, {
+		files: map[string]string{"task.yaml": "two"},
+	}})
+
+	for _, tc := range []struct {
+		name string
+		conf map[string]string
+	}{
+		{name: "clone", conf: map[string]string{}},
+		{name: "minimal fetch", conf: map[string]string{ConfigFieldMinimalFetch: "true"}},
+		{name: "cache", conf: map[string]string{ConfigFieldCacheSize: "10"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			resolver := Resolver{}
+			resource, err := resolver.Resolve(framework.InjectResolverConfigToContext(context.Background(), tc.conf), map[string]string{
+				URLParam:    repoDir,
+				PathParam:   "task.yaml",
+				CommitParam: strings.ToUpper(commits[0][:7]),
+			})
+			if err != nil {
+				t.Fatalf("unexpected error resolving abbreviated commit: %v", err)
+			}
+			if string(resource.Data()) != "one" {
+				t.Errorf("expected content of commit %s, received %q", commits[0], string(resource.Data()))
+			}
+			if resource.Annotations()[AnnotationKeyCommitHash] != commits[0] {
+				t.Errorf("expected the full commit %q to be recorded, received %q", commits[0], resource.Annotations()[AnnotationKeyCommitHash])
+			}
+		})
+	}
+}
+
+func TestResolveCommitShortHash(t *testing.T) {
+	repoDir, commits := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "one"},
+	}, {
+		files: map[string]string{"task.yaml": "two"},
+	}})
+
+	for _, tc := range []struct {
+		name string
+		conf map[string]string
+	}{
+		{name: "clone", conf: map[string]string{}},
+		{name: "minimal fetch", conf: map[string]string{ConfigFieldMinimalFetch: "true"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			resolver := Resolver{}
+			resource, err := resolver.Resolve(framework.InjectResolverConfigToContext(context.Background(), tc.conf), map[string]string{
+				URLParam:    repoDir,
+				PathParam:   "task.yaml",
+				CommitParam: commits[0],
+			})
+			if err != nil {
+				t.Fatalf("unexpected error resolving commit: %v", err)
+			}
+			annotations := resource.Annotations()
+			full, short := annotations[AnnotationKeyCommitHash], annotations[AnnotationKeyCommitShortHash]
+			if full != commits[0] {
+				t.Errorf("expected the full commit %q to be recorded, received %q", commits[0], full)
+			}
+			if len(short) < defaultShortHashLength || !strings.HasPrefix(full, short) {
+				t.Errorf("expected a short hash of at least %d characters prefixing %q, received %q", defaultShortHashLength, full, short)
+			}
+		})
+	}
+}
+
+func TestShortCommitHashIsUnique(t *testing.T) {
+	testCommits := []testCommit{}
+	for i := 0; i < 20; i++ {
+		testCommits = append(testCommits, testCommit{files: map[string]string{"task.yaml": fmt.Sprintf("%d", i)}})
+	}
+	repoDir, commits := createTestRepo(t, testCommits)
+	repository, err := git.PlainOpen(repoDir)
+	if err != nil {
+		t.Fatalf("error opening repo: %v", err)
+	}
+	iter, err := repository.Storer.IterEncodedObjects(plumbing.AnyObject)
+	if err != nil {
+		t.Fatalf("error listing objects: %v", err)
+	}
+	hashes := []string{}
+	if err := iter.ForEach(func(o plumbing.EncodedObject) error {
+		hashes = append(hashes, o.Hash().String())
+		return nil
+	}); err != nil {
+		t.Fatalf("error listing objects: %v", err)
+	}
+
+	for _, commit := range commits {
+		short, err := shortCommitHash(repository, commit)
+		if err != nil {
+			t.Fatalf("unexpected error abbreviating %s: %v", commit, err)
+		}
+		if len(short) < defaultShortHashLength || !strings.HasPrefix(commit, short) {
+			t.Errorf("expected a short hash of at least %d characters prefixing %q, received %q", defaultShortHashLength, commit, short)
+		}
+		for _, hash := range hashes {
+			if hash != commit && strings.HasPrefix(hash, short) {
+				t.Errorf("expected %q to abbreviate only %s, but %s starts with it too", short, commit, hash)
+			}
+		}
+	}
+}
+
+func TestCommitShortHashWithoutRepo(t *testing.T) {
+	commit := "aeb957601cf41c012be462827053a21a420befca"
+	resource := &ResolvedGitResource{Commit: commit, Path: "task.yaml"}
+	if short := resource.Annotations()[AnnotationKeyCommitShortHash]; short != commit[:defaultShortHashLength] {
+		t.Errorf("expected short hash %q, received %q", commit[:defaultShortHashLength], short)
+	}
+}
+
+func TestResolveAbbreviatedCommitErrors(t *testing.T) {
+	testCommits := []testCommit{}
+	for i := 0; i < 17; i++ {
+		testCommits = append(testCommits, testCommit{files: map[string]string{"task.yaml": fmt.Sprintf("%d", i)}})
+	}
+	repoDir, commits := createTestRepo(t, testCommits)
+	// With more commits than hex digits, at least two share a first
+	// character.
+	sharing := map[byte][]string{}
+	var ambiguous byte
+	for _, c := range commits {
+		sharing[c[0]] = append(sharing[c[0]], c)
+		if len(sharing[c[0]]) == 2 {
+			ambiguous = c[0]
+		}
+	}
+	missing := "0000000"
+	for _, c := range commits {
+		if strings.HasPrefix(c, missing) {
+			missing = "1111111"
+		}
+	}
+
+	for _, tc := range []struct {
+		name     string
+		commit   string
+		expected string
+	}{{
+		name:     "ambiguous",
+		commit:   string(ambiguous),
+		expected: fmt.Sprintf("commit %q is ambiguous: it abbreviates %d commits: ", string(ambiguous), len(sharing[ambiguous])),
+	}, {
+		name:     "missing",
+		commit:   missing,
+		expected: fmt.Sprintf("error finding commit %q: object not found", missing),
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			resolver := Resolver{}
+			_, err := resolver.Resolve(context.Background(), map[string]string{
+				URLParam:    repoDir,
+				PathParam:   "task.yaml",
+				CommitParam: tc.commit,
+			})
+			if err == nil || !strings.Contains(err.Error(), tc.expected) {
+				t.Fatalf("expected error containing %q, received %v", tc.expected, err)
+			}
+			if tc.name == "ambiguous" {
+				for _, c := range sharing[ambiguous] {
+					if !strings.Contains(err.Error(), c) {
+						t.Errorf("expected candidate %s to be listed in %q", c, err)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestResolveContainingRefsAbbreviatedCommit(t *testing.T) {
+	repoDir, commits := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "one"},
+	}})
+	setTestRef(t, repoDir, "refs/heads/feature", commits[0])
+
+	resolver := Resolver{}
+	resource, err := resolver.Resolve(context.Background(), map[string]string{
+		URLParam:            repoDir,
+		CommitParam:         commits[0][:8],
+		ContainingRefsParam: "true",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error resolving abbreviated commit: %v", err)
+	}
+	if !strings.Contains(string(resource.Data()), "feature") {
+		t.Errorf("expected the feature branch to contain the commit, received %s", resource.Data())
+	}
+}
+
+func TestValidateParamsCommitHash(t *testing.T) {
+	resolver := Resolver{}
+	for _, tc := range []struct {
+		commit    string
+		expectErr bool
+	}{
+		{commit: "abcd"},
+		{commit: "ABC1234"},
+		{commit: strings.Repeat("a", 40)},
+		{commit: "abc", expectErr: true},
+		{commit: strings.Repeat("a", 41), expectErr: true},
+		{commit: "main", expectErr: true},
+		{commit: "abc123z", expectErr: true},
+		{commit: "HEAD~1", expectErr: true},
+	} {
+		err := resolver.ValidateParams(context.Background(), map[string]string{
+			URLParam:    "foo",
+			PathParam:   "bar",
+			CommitParam: tc.commit,
+		})
+		if tc.expectErr {
+			if err == nil || !strings.Contains(err.Error(), `invalid value for "commit"`) {
+				t.Errorf("expected commit %q to be rejected, received %v", tc.commit, err)
+			}
+		} else if err != nil {
+			t.Errorf("unexpected error validating commit %q: %v", tc.commit, err)
+		}
+	}
+}
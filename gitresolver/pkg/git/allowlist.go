@@ -0,0 +1,72 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+// checkAllowedURL fails unless the repo at url is on one of the hosts
+// configured with the allowed-urls field in the git-resolver-config
+// configmap. Each entry is a host, or a glob pattern of hosts like
+// "*.example.com", that matches repos served over any remote protocol.
+// An entry may be prefixed with a scheme, like "https://github.com", to
+// only match that protocol, and "file://" matches local repos, which
+// are otherwise never allowed. Every repo is allowed if no entries are
+// configured.
+func checkAllowedURL(conf map[string]string, url string) error {
+	entries := []string{}
+	for _, entry := range strings.Split(conf[ConfigFieldAllowedURLs], ",") {
+		if entry = strings.ToLower(strings.TrimSpace(entry)); entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+
+	ep, err := transport.NewEndpoint(url)
+	if err != nil {
+		return fmt.Errorf("invalid repo url %q: %w", url, err)
+	}
+	host := strings.ToLower(ep.Host)
+	for _, entry := range entries {
+		scheme, pattern, ok := strings.Cut(entry, "://")
+		if !ok {
+			scheme, pattern = "", entry
+		}
+		matched, err := path.Match(pattern, host)
+		if err != nil {
+			return fmt.Errorf("invalid %s entry %q: %w", ConfigFieldAllowedURLs, entry, err)
+		}
+		switch {
+		case !matched:
+		case scheme == "" && ep.Protocol != "file" && host != "":
+			return nil
+		case scheme == ep.Protocol:
+			return nil
+		}
+	}
+	if ep.Protocol == "file" {
+		return fmt.Errorf("repo url %q is not allowed: local repos aren't in %s", url, ConfigFieldAllowedURLs)
+	}
+	return fmt.Errorf("repo url %q is not allowed: host %q isn't in %s", url, ep.Host, ConfigFieldAllowedURLs)
+}
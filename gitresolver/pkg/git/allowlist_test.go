@@ -0,0 +1,128 @@
+package git
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/tektoncd/resolution/pkg/resolver/framework"
+)
+
+func TestCheckAllowedURL(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		allowed string
+		url     string
+		message string
+	}{
+		{name: "unset", url: "https://example.com/org/repo"},
+		{name: "empty", allowed: " , ", url: "/tmp/repo"},
+		{name: "host", allowed: "github.com", url: "https://github.com/org/repo"},
+		{name: "host with port", allowed: "git.example.com", url: "https://git.example.com:8443/org/repo"},
+		{name: "host case", allowed: "GitHub.com", url: "https://github.COM/org/repo"},
+		{name: "host over ssh", allowed: "github.com", url: "git@github.com:org/repo.git"},
+		{name: "one of several", allowed: "gitlab.com, github.com", url: "https://github.com/org/repo"},
+		{name: "glob", allowed: "*.example.com", url: "https://git.example.com/org/repo"},
+		{name: "scheme", allowed: "https://github.com", url: "https://github.com/org/repo"},
+		{name: "local explicitly allowed", allowed: "github.com,file://", url: "/tmp/repo"},
+		{name: "file url explicitly allowed", allowed: "file://", url: "file:///tmp/repo"},
+		{name: "other host", allowed: "github.com", url: "https://evil.example.com/org/repo", message: `host "evil.example.com" isn't in allowed-urls`},
+		{name: "host suffix", allowed: "github.com", url: "https://github.com.evil.example.com/org/repo", message: "isn't in allowed-urls"},
+		{name: "glob doesn't match apex", allowed: "*.example.com", url: "https://example.com/org/repo", message: "isn't in allowed-urls"},
+		{name: "other scheme", allowed: "https://github.com", url: "git@github.com:org/repo.git", message: "isn't in allowed-urls"},
+		{name: "local by default", allowed: "github.com", url: "/tmp/repo", message: "local repos aren't in allowed-urls"},
+		{name: "local not matched by wildcard", allowed: "*", url: "file:///tmp/repo", message: "local repos aren't in allowed-urls"},
+		{name: "invalid pattern", allowed: "[", url: "https://github.com/org/repo", message: `invalid allowed-urls entry "["`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			conf := map[string]string{}
+			if tc.allowed != "" {
+				conf[ConfigFieldAllowedURLs] = tc.allowed
+			}
+			err := checkAllowedURL(conf, tc.url)
+			if tc.message == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.message) {
+				t.Fatalf("expected error containing %q, received %v", tc.message, err)
+			}
+		})
+	}
+}
+
+func TestValidateParamsAllowedURLs(t *testing.T) {
+	ctx := framework.InjectResolverConfigToContext(context.Background(), map[string]string{
+		ConfigFieldAllowedURLs: "github.com",
+	})
+	resolver := Resolver{}
+	for _, tc := range []struct {
+		name    string
+		params  map[string]string
+		message string
+	}{{
+		name:   "allowed",
+		params: map[string]string{URLParam: "https://github.com/org/repo", PathParam: "task.yaml"},
+	}, {
+		name:    "denied url",
+		params:  map[string]string{URLParam: "https://example.com/org/repo", PathParam: "task.yaml"},
+		message: `repo url "https://example.com/org/repo" is not allowed`,
+	}, {
+		name:    "denied locator",
+		params:  map[string]string{LocatorParam: "https://example.com/org/repo//task.yaml"},
+		message: `repo url "https://example.com/org/repo" is not allowed`,
+	}, {
+		name:    "denied overlay",
+		params:  map[string]string{URLParam: "https://github.com/org/repo", PathParam: "task.yaml", OverlayURLParam: "https://example.com/org/repo", OverlayPathParam: "overlay.yaml"},
+		message: `repo url "https://example.com/org/repo" is not allowed`,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := resolver.ValidateParams(ctx, tc.params)
+			if tc.message == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.message) {
+				t.Fatalf("expected error containing %q, received %v", tc.message, err)
+			}
+		})
+	}
+}
+
+func TestResolveAllowedURLs(t *testing.T) {
+	repoDir, _ := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "kind: Task\n"},
+	}})
+	params := map[string]string{URLParam: repoDir, PathParam: "task.yaml"}
+
+	for _, tc := range []struct {
+		name      string
+		allowed   string
+		expectErr bool
+	}{
+		{name: "no allowlist"},
+		{name: "local allowed", allowed: "github.com,file://"},
+		{name: "local denied", allowed: "github.com", expectErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := framework.InjectResolverConfigToContext(context.Background(), map[string]string{
+				ConfigFieldAllowedURLs: tc.allowed,
+			})
+			resolver := Resolver{}
+			_, err := resolver.Resolve(ctx, params)
+			if tc.expectErr {
+				if err == nil || !strings.Contains(err.Error(), "is not allowed") {
+					t.Fatalf("expected the repo to be denied, received %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error resolving: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"errors"
+	"fmt"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"github.com/go-git/go-git/v5/storage"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+// alternatesRefPrefix namespaces the alternates' refs when they're
+// listed alongside a fetched repository's own, so that they're sent to
+// the remote as haves without clashing with the fetched refs.
+const alternatesRefPrefix = "refs/alternates/"
+
+// alternateObjects is the subset of a storer that's used to read from
+// an alternates repository. Nothing that can write to it is included so
+// that the shared store is never modified by a resolution.
+type alternateObjects interface {
+	EncodedObject(plumbing.ObjectType, plumbing.Hash) (plumbing.EncodedObject, error)
+	HasEncodedObject(plumbing.Hash) error
+	EncodedObjectSize(plumbing.Hash) (int64, error)
+	IterReferences() (storer.ReferenceIter, error)
+}
+
+// alternatesStorage is an in-memory storer that falls back to reading
+// objects from an alternates repository. Objects the alternates already
+// hold are neither requested from the remote nor copied into memory.
+type alternatesStorage struct {
+	*memory.Storage
+	alternates alternateObjects
+}
+
+// newStorage returns the storer that a fetched repository is kept in:
+// an in-memory one, backed by the repository at alternatesDir if it's
+// set.
+func newStorage(alternatesDir string) (storage.Storer, error) {
+	if alternatesDir == "" {
+		return memory.NewStorage(), nil
+	}
+	alternates, err := git.PlainOpen(alternatesDir)
+	if err != nil {
+		return nil, fmt.Errorf("error opening alternates repository %q: %w", alternatesDir, err)
+	}
+	return &alternatesStorage{
+		Storage:    memory.NewStorage(),
+		alternates: alternates.Storer,
+	}, nil
+}
+
+// EncodedObject returns the object with the given hash, reading it from
+// the alternates if it hasn't been fetched.
+func (s *alternatesStorage) EncodedObject(t plumbing.ObjectType, h plumbing.Hash) (plumbing.EncodedObject, error) {
+	obj, err := s.Storage.EncodedObject(t, h)
+	if errors.Is(err, plumbing.ErrObjectNotFound) {
+		return s.alternates.EncodedObject(t, h)
+	}
+	return obj, err
+}
+
+// HasEncodedObject returns nil if either the fetched objects or the
+// alternates hold the given hash.
+func (s *alternatesStorage) HasEncodedObject(h plumbing.Hash) error {
+	err := s.Storage.HasEncodedObject(h)
+	if errors.Is(err, plumbing.ErrObjectNotFound) {
+		return s.alternates.HasEncodedObject(h)
+	}
+	return err
+}
+
+// EncodedObjectSize returns the size of the object with the given hash,
+// reading it from the alternates if it hasn't been fetched.
+func (s *alternatesStorage) EncodedObjectSize(h plumbing.Hash) (int64, error) {
+	size, err := s.Storage.EncodedObjectSize(h)
+	if errors.Is(err, plumbing.ErrObjectNotFound) {
+		return s.alternates.EncodedObjectSize(h)
+	}
+	return size, err
+}
+
+// IterReferences lists the repository's own refs followed by a ref
+// under alternatesRefPrefix for each commit the alternates' refs point
+// to. go-git negotiates fetches using the local refs, so this is what
+// stops the remote from sending objects that the alternates hold.
+func (s *alternatesStorage) IterReferences() (storer.ReferenceIter, error) {
+	var refs []*plumbing.Reference
+	local, err := s.Storage.IterReferences()
+	if err != nil {
+		return nil, err
+	}
+	if err := local.ForEach(func(ref *plumbing.Reference) error {
+		refs = append(refs, ref)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	alternates, err := s.alternates.IterReferences()
+	if err != nil {
+		return nil, fmt.Errorf("error listing alternates refs: %w", err)
+	}
+	seen := map[plumbing.Hash]bool{}
+	if err := alternates.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Type() != plumbing.HashReference || seen[ref.Hash()] {
+			return nil
+		}
+		seen[ref.Hash()] = true
+		name := plumbing.ReferenceName(alternatesRefPrefix + ref.Hash().String())
+		refs = append(refs, plumbing.NewHashReference(name, ref.Hash()))
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("error listing alternates refs: %w", err)
+	}
+	return storer.NewReferenceSliceIter(refs), nil
+}
@@ -0,0 +1,156 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/tektoncd/resolution/pkg/resolver/framework"
+)
+
+// createAlternatesRepo makes a bare clone of the feature branch of
+// repoDir for use as an alternates repository.
+func createAlternatesRepo(t *testing.T, repoDir string) string {
+	t.Helper()
+	dir := t.TempDir()
+	if _, err := git.PlainClone(dir, true, &git.CloneOptions{
+		URL:           repoDir,
+		ReferenceName: plumbing.NewBranchReferenceName("feature"),
+		SingleBranch:  true,
+	}); err != nil {
+		t.Fatalf("error cloning alternates repo: %v", err)
+	}
+	return dir
+}
+
+// listFiles returns every file under dir with its size.
+func listFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	var files []string
+	if err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		files = append(files, strings.TrimPrefix(path, dir)+":"+info.ModTime().String())
+		return nil
+	}); err != nil {
+		t.Fatalf("error listing %q: %v", dir, err)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// blobHash returns the hash of the file at path in commit.
+func blobHash(t *testing.T, repoDir, commit, path string) plumbing.Hash {
+	t.Helper()
+	repo, err := git.PlainOpen(repoDir)
+	if err != nil {
+		t.Fatalf("error opening repo: %v", err)
+	}
+	c, err := repo.CommitObject(plumbing.NewHash(commit))
+	if err != nil {
+		t.Fatalf("error reading commit: %v", err)
+	}
+	file, err := c.File(path)
+	if err != nil {
+		t.Fatalf("error reading %q: %v", path, err)
+	}
+	return file.Hash
+}
+
+func TestFetchRepositoryAlternates(t *testing.T) {
+	repoDir, commits := createTestRepo(t, []testCommit{{
+		files: map[string]string{"shared.yaml": "shared", "task.yaml": "one"},
+	}, {
+		files: map[string]string{"task.yaml": "two"},
+	}})
+	setTestRef(t, repoDir, "refs/heads/feature", commits[0])
+	alternatesDir := createAlternatesRepo(t, repoDir)
+	before := listFiles(t, alternatesDir)
+	repoURL, _ := newCountingGitHTTPServer(t, repoDir)
+
+	for _, minimal := range []string{"false", "true"} {
+		t.Run("minimal-fetch "+minimal, func(t *testing.T) {
+			ctx := framework.InjectResolverConfigToContext(context.Background(), map[string]string{
+				ConfigFieldAlternatesDir: alternatesDir,
+				ConfigFieldMinimalFetch:  minimal,
+			})
+			fetched, err := fetchRepository(ctx, repoURL, nil, []plumbing.ReferenceName{plumbing.NewBranchReferenceName("master")}, "")
+			if err != nil {
+				t.Fatalf("unexpected error fetching: %v", err)
+			}
+			defer fetched.cleanup()
+			if fetched.head.String() != commits[1] {
+				t.Errorf("expected head %q received %q", commits[1], fetched.head)
+			}
+
+			store, ok := fetched.repository.Storer.(*alternatesStorage)
+			if !ok {
+				t.Fatalf("expected alternates storage, received %T", fetched.repository.Storer)
+			}
+			shared := blobHash(t, repoDir, commits[1], "shared.yaml")
+			if err := store.Storage.HasEncodedObject(shared); !errors.Is(err, plumbing.ErrObjectNotFound) {
+				t.Errorf("expected object held by the alternates not to be fetched, received %v", err)
+			}
+			if err := store.Storage.HasEncodedObject(blobHash(t, repoDir, commits[1], "task.yaml")); err != nil {
+				t.Errorf("expected object missing from the alternates to be fetched: %v", err)
+			}
+			if _, err := fetched.repository.BlobObject(shared); err != nil {
+				t.Errorf("expected object to be readable from the alternates: %v", err)
+			}
+		})
+	}
+
+	if after := listFiles(t, alternatesDir); strings.Join(before, "\n") != strings.Join(after, "\n") {
+		t.Errorf("expected alternates repo to be unmodified, files before:\n%v\nafter:\n%v", before, after)
+	}
+}
+
+func TestResolveAlternatesSkipsFetch(t *testing.T) {
+	repoDir, commits := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "one"},
+	}, {
+		files: map[string]string{"task.yaml": "two"},
+	}})
+	setTestRef(t, repoDir, "refs/heads/feature", commits[0])
+	alternatesDir := createAlternatesRepo(t, repoDir)
+	repoURL, fetches := newCountingGitHTTPServer(t, repoDir)
+
+	ctx := framework.InjectResolverConfigToContext(context.Background(), map[string]string{
+		ConfigFieldAlternatesDir: alternatesDir,
+	})
+	resolver := Resolver{}
+	resource, err := resolver.Resolve(ctx, map[string]string{
+		URLParam:    repoURL,
+		BranchParam: "feature",
+		PathParam:   "task.yaml",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error resolving: %v", err)
+	}
+	if string(resource.Data()) != "one" {
+		t.Errorf("expected %q received %q", "one", resource.Data())
+	}
+	if n := atomic.LoadInt32(fetches); n != 0 {
+		t.Errorf("expected a commit held by the alternates not to be fetched, received %d fetches", n)
+	}
+}
+
+func TestFetchRepositoryAlternatesInvalid(t *testing.T) {
+	repoDir, _ := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "one"},
+	}})
+	ctx := framework.InjectResolverConfigToContext(context.Background(), map[string]string{
+		ConfigFieldAlternatesDir: t.TempDir(),
+	})
+	if _, err := fetchRepository(ctx, repoDir, nil, nil, ""); err == nil || !strings.Contains(err.Error(), "alternates repository") {
+		t.Errorf("expected error opening alternates repository, received %v", err)
+	}
+}
@@ -20,4 +20,104 @@ const (
 	// AnnotationKeyCommitHash is the commit hash that was fetched
 	// from git
 	AnnotationKeyCommitHash = "commit"
+
+	// AnnotationKeyCommitShortHash is the shortest abbreviation of the
+	// resolved commit's hash, of at least 7 characters, that's unique
+	// in the repo
+	AnnotationKeyCommitShortHash = "commit-short"
+
+	// AnnotationKeyURL is the url of the repo that the file was
+	// resolved from, without any credentials
+	AnnotationKeyURL = "url"
+
+	// AnnotationKeyRef is the branch, tag or other ref that the file
+	// was resolved from, as requested
+	AnnotationKeyRef = "ref"
+
+	// AnnotationKeyRefType is the kind of ref that the requested ref
+	// resolved to: "branch", "lightweight-tag" or "annotated-tag", or
+	// "commit" when a commit was requested
+	AnnotationKeyRefType = "ref-type"
+
+	// AnnotationKeyDefaultBranch is the name of the repo's default
+	// branch, when no ref or commit was requested and the file was
+	// resolved from it
+	AnnotationKeyDefaultBranch = "default-branch"
+
+	// AnnotationKeyOCIDigest is the sha256 digest of the resolved
+	// content as an OCI blob, e.g. "sha256:abc123..."
+	AnnotationKeyOCIDigest = "oci-digest"
+
+	// AnnotationKeyLicense is the SPDX identifier of the license found
+	// at the root of the repository, e.g. "Apache-2.0"
+	AnnotationKeyLicense = "license"
+
+	// AnnotationKeyCanonicalized is set to "true" when the resolved
+	// content was re-emitted in canonical yaml form
+	AnnotationKeyCanonicalized = "canonicalized"
+
+	// AnnotationKeyCommentsStripped is set to "true" when comments and
+	// blank lines were removed from the resolved content
+	AnnotationKeyCommentsStripped = "comments-stripped"
+
+	// AnnotationKeyBackendMismatch describes the digests of the
+	// differing content that go-git and the git binary resolved when
+	// cross-check-backends is "warn"
+	AnnotationKeyBackendMismatch = "backend-mismatch"
+
+	// AnnotationKeyAttestation is a json DSSE envelope of a signed
+	// in-toto statement of where the resolved content came from
+	AnnotationKeyAttestation = "attestation"
+
+	// AnnotationKeyPinned is set to "true" when the resolved commit is
+	// the one the requested ref was pinned to on its first resolution
+	AnnotationKeyPinned = "pinned"
+
+	// AnnotationKeyResolvedFrom is set to "default" when the requested
+	// file was missing and the onMissing param's default was returned
+	AnnotationKeyResolvedFrom = "resolved-from"
+
+	// AnnotationKeyOverlayURL is the url of the repo that an overlay
+	// file merged over the resolved file was fetched from
+	AnnotationKeyOverlayURL = "overlay-url"
+
+	// AnnotationKeyOverlayPath is the path of the overlay file merged
+	// over the resolved file
+	AnnotationKeyOverlayPath = "overlay-path"
+
+	// AnnotationKeyOverlayCommit is the commit hash that the overlay
+	// file was fetched from
+	AnnotationKeyOverlayCommit = "overlay-commit"
+
+	// AnnotationKeySignatureKey is the id of the trusted pgp key whose
+	// detached signature of the resolved file was verified
+	AnnotationKeySignatureKey = "signature-key"
+
+	// AnnotationKeyCommitSigner is the id of the trusted pgp key that
+	// the resolved commit was signed with
+	AnnotationKeyCommitSigner = "commit-signer"
+
+	// AnnotationKeyEncoding is the text encoding that the resolved file
+	// was detected as being committed in, e.g. "UTF-8" or "UTF-16LE"
+	AnnotationKeyEncoding = "encoding"
+
+	// AnnotationKeyDependencies is a comma separated list of the paths
+	// of the referenced files bundled with the resolved file
+	AnnotationKeyDependencies = "dependencies"
+
+	// AnnotationKeyCommitAuthorName is the name of the author of the
+	// resolved commit
+	AnnotationKeyCommitAuthorName = "commit-author-name"
+
+	// AnnotationKeyCommitAuthorEmail is the email address of the author
+	// of the resolved commit
+	AnnotationKeyCommitAuthorEmail = "commit-author-email"
+
+	// AnnotationKeyCommitDate is when the resolved commit was committed,
+	// in RFC 3339 format
+	AnnotationKeyCommitDate = "commit-date"
+
+	// AnnotationKeyCommitSubject is the first line of the resolved
+	// commit's message, truncated if it's long
+	AnnotationKeyCommitSubject = "commit-subject"
 )
@@ -0,0 +1,100 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+// defaultGitHubAPIURL is the api that github.com repos are looked up
+// in. Other hosts are assumed to be GitHub Enterprise servers, which
+// serve the same api under /api/v3.
+const defaultGitHubAPIURL = "https://api.github.com"
+
+// apiClient makes requests to the apis of git hosts. It is replaced
+// along with go-git's transports by installTransports.
+var apiClient = newAPIClient()
+
+// newAPIClient returns a client for the apis of git hosts that goes
+// through the same proxies, dialer and rate limit handling as fetches
+// do.
+func newAPIClient() *http.Client {
+	return &http.Client{
+		Transport:     &retryAfterTransport{base: newHTTPTransport()},
+		CheckRedirect: checkRedirect,
+	}
+}
+
+// errAPIRateLimited is returned for api requests that were turned away
+// because the caller ran out of its rate limit.
+var errAPIRateLimited = errors.New("rate limited")
+
+// errAPINotFound is returned for api requests for something that
+// doesn't exist, or that the caller isn't allowed to see.
+var errAPINotFound = errors.New("not found")
+
+// githubAPIURL returns the base url of the GitHub compatible api that
+// serves the repos on host.
+func githubAPIURL(host string) string {
+	if host == "github.com" {
+		return defaultGitHubAPIURL
+	}
+	return "https://" + host + "/api/v3"
+}
+
+// getAPI returns the body of a GET of url from a GitHub compatible api,
+// accepting the given media type and authenticating with the token or
+// basic auth credentials, if any, that the repo is fetched with.
+func getAPI(ctx context.Context, url, accept string, auth transport.AuthMethod) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", accept)
+	switch auth := auth.(type) {
+	case *githttp.TokenAuth:
+		req.Header.Set("Authorization", "Bearer "+auth.Token)
+	case *githttp.BasicAuth:
+		req.SetBasicAuth(auth.Username, auth.Password)
+	}
+	resp, err := apiClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusOK:
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, fmt.Errorf("unexpected status from %s: %s: %w", req.URL.Redacted(), resp.Status, errAPINotFound)
+	case (resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests) && resp.Header.Get("X-RateLimit-Remaining") == "0":
+		return nil, fmt.Errorf("unexpected status from %s: %s: %w", req.URL.Redacted(), resp.Status, errAPIRateLimited)
+	default:
+		return nil, fmt.Errorf("unexpected status from %s: %s", req.URL.Redacted(), resp.Status)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response from %s: %w", req.URL.Redacted(), err)
+	}
+	return body, nil
+}
@@ -0,0 +1,175 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/tektoncd/resolution/pkg/resolver/framework"
+)
+
+// TarContentType is the content type to use when returning a tar
+// archive.
+const TarContentType string = framework.ArchiveContentType
+
+// archiveOptions are the normalized metadata that every entry of an
+// archive is written with, so that archiving the same tree always
+// produces the same bytes.
+type archiveOptions struct {
+	uid, gid int
+
+	// fileMode is the permissions of every regular file. Zero keeps
+	// the 0644 or 0755 that git records.
+	fileMode int64
+}
+
+// getArchiveOptions returns the ownership and permissions of archive
+// entries, as configured with the archive-owner and archive-file-mode
+// fields in the git-resolver-config configmap. Entries are owned by
+// root by default.
+func getArchiveOptions(conf map[string]string) (archiveOptions, error) {
+	opts := archiveOptions{}
+	if owner := conf[ConfigFieldArchiveOwner]; owner != "" {
+		uidString, gidString, ok := strings.Cut(owner, ":")
+		uid, uidErr := strconv.Atoi(uidString)
+		gid, gidErr := strconv.Atoi(gidString)
+		if !ok || uidErr != nil || gidErr != nil || uid < 0 || gid < 0 {
+			return archiveOptions{}, fmt.Errorf("invalid %s %q: must be a numeric uid:gid", ConfigFieldArchiveOwner, owner)
+		}
+		opts.uid, opts.gid = uid, gid
+	}
+	if mode := conf[ConfigFieldArchiveFileMode]; mode != "" {
+		fileMode, err := strconv.ParseInt(mode, 8, 64)
+		if err != nil || fileMode <= 0 || fileMode > 0777 {
+			return archiveOptions{}, fmt.Errorf("invalid %s %q: must be octal permissions like 0644", ConfigFieldArchiveFileMode, mode)
+		}
+		opts.fileMode = fileMode
+	}
+	return opts, nil
+}
+
+// archivePath returns the files beneath the directory at path in the
+// given commit, or the file at path itself, as a tar archive. The
+// archive is written with framework.ArchiveFiles, so entries are sorted
+// by name and have zero mtimes, and with the normalized ownership and
+// permissions in opts, so the same tree always produces the same
+// archive. The files are kept alongside it for the framework to package
+// again. Directories with more than maxFiles files are rejected rather
+// than truncated.
+func archivePath(repository *git.Repository, commit, path string, maxFiles int, opts archiveOptions) (*ResolvedGitResource, error) {
+	commitObj, err := repository.CommitObject(plumbing.NewHash(commit))
+	if err != nil {
+		return nil, fmt.Errorf("error reading commit %q: %v", commit, err)
+	}
+	tree, err := commitObj.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("error reading tree of commit %q: %v", commit, err)
+	}
+
+	files := []framework.ResolvedFile{}
+	dir := strings.Trim(path, "/")
+	isDir := dir == ""
+	if !isDir {
+		entry, err := tree.FindEntry(dir)
+		if err != nil {
+			return nil, fmt.Errorf("error opening file %q: %w", path, err)
+		}
+		if isDir = entry.Mode == filemode.Dir; !isDir {
+			file, err := tree.TreeEntryFile(entry)
+			if err != nil {
+				return nil, fmt.Errorf("error opening file %q: %v", path, err)
+			}
+			archived, err := archiveFile(entry.Name, file, opts)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, archived)
+		} else if tree, err = tree.Tree(dir); err != nil {
+			return nil, fmt.Errorf("error reading directory %q: %v", path, err)
+		}
+	}
+	if isDir {
+		count := 0
+		err = tree.Files().ForEach(func(f *object.File) error {
+			if count++; count > maxFiles {
+				return nil
+			}
+			archived, err := archiveFile(f.Name, f, opts)
+			if err != nil {
+				return err
+			}
+			files = append(files, archived)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error listing directory %q: %v", path, err)
+		}
+		if err := checkFileCount(path, count, maxFiles); err != nil {
+			return nil, err
+		}
+	}
+
+	content, err := framework.ArchiveFiles(files)
+	if err != nil {
+		return nil, fmt.Errorf("error writing archive of %q: %w", path, err)
+	}
+	return &ResolvedGitResource{
+		Commit:      commit,
+		Content:     content,
+		ContentType: TarContentType,
+		Files:       files,
+	}, nil
+}
+
+// archiveFile returns file as an archive entry named name with
+// normalized metadata. Git only records whether a file is executable or
+// a symlink, so those are the only differences between entries besides
+// their content.
+func archiveFile(name string, file *object.File, opts archiveOptions) (framework.ResolvedFile, error) {
+	archived := framework.ResolvedFile{Name: name, UID: opts.uid, GID: opts.gid}
+	reader, err := file.Reader()
+	if err != nil {
+		return archived, fmt.Errorf("error reading file %q: %v", name, err)
+	}
+	defer reader.Close()
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return archived, fmt.Errorf("error reading file %q: %v", name, err)
+	}
+
+	switch file.Mode {
+	case filemode.Symlink:
+		archived.Linkname, archived.Mode = string(content), 0777
+		return archived, nil
+	case filemode.Executable:
+		archived.Mode = 0755
+	default:
+		archived.Mode = 0644
+	}
+	if opts.fileMode != 0 {
+		archived.Mode = opts.fileMode
+	}
+	archived.Data = content
+	return archived, nil
+}
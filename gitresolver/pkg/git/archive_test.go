@@ -0,0 +1,207 @@
+package git
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tektoncd/resolution/pkg/resolver/framework"
+)
+
+var archiveTestFiles = map[string]string{
+	"pipeline/b.yaml":        "kind: Task\nmetadata:\n  name: b\n",
+	"pipeline/a.yaml":        "kind: Task\nmetadata:\n  name: a\n",
+	"pipeline/nested/c.yaml": "kind: Task\nmetadata:\n  name: c\n",
+	"pipeline/README.md":     "# pipeline\n",
+	"other.yaml":             "kind: Task\n",
+}
+
+// readTestArchive returns the headers and contents of the entries of a
+// tar archive, in order.
+func readTestArchive(t *testing.T, content []byte) ([]*tar.Header, []string) {
+	t.Helper()
+	headers, contents := []*tar.Header{}, []string{}
+	tr := tar.NewReader(bytes.NewReader(content))
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return headers, contents
+		}
+		if err != nil {
+			t.Fatalf("error reading archive: %v", err)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatalf("error reading archive entry %q: %v", header.Name, err)
+		}
+		headers, contents = append(headers, header), append(contents, string(data))
+	}
+}
+
+func TestResolveTarArchive(t *testing.T) {
+	repoDir, _ := createTestRepo(t, []testCommit{{files: archiveTestFiles}})
+	resolver := Resolver{}
+	params := map[string]string{
+		URLParam:    repoDir,
+		PathParam:   "pipeline",
+		FormatParam: FormatTar,
+	}
+	if err := resolver.ValidateParams(context.Background(), params); err != nil {
+		t.Fatalf("unexpected error validating params: %v", err)
+	}
+	resource, err := resolver.Resolve(context.Background(), params)
+	if err != nil {
+		t.Fatalf("unexpected error resolving: %v", err)
+	}
+	if ct := resource.(*ResolvedGitResource).ContentType; ct != TarContentType {
+		t.Errorf("expected content type %q received %q", TarContentType, ct)
+	}
+
+	headers, contents := readTestArchive(t, resource.Data())
+	expectedNames := []string{"README.md", "a.yaml", "b.yaml", "nested/c.yaml"}
+	if len(headers) != len(expectedNames) {
+		t.Fatalf("expected %d entries, received %d", len(expectedNames), len(headers))
+	}
+	for i, header := range headers {
+		if header.Name != expectedNames[i] {
+			t.Errorf("expected entry %d to be %q, received %q", i, expectedNames[i], header.Name)
+		}
+		if contents[i] != archiveTestFiles["pipeline/"+expectedNames[i]] {
+			t.Errorf("expected entry %q to contain %q, received %q", header.Name, archiveTestFiles["pipeline/"+expectedNames[i]], contents[i])
+		}
+		if !header.ModTime.Equal(time.Unix(0, 0)) || header.Uid != 0 || header.Gid != 0 || header.Uname != "" || header.Gname != "" || header.Mode != 0644 {
+			t.Errorf("expected normalized metadata for %q, received %+v", header.Name, header)
+		}
+	}
+}
+
+func TestResolveTarArchiveReproducible(t *testing.T) {
+	// The same tree committed at different times, with different
+	// history, archives to the same bytes.
+	firstDir, _ := createTestRepo(t, []testCommit{{
+		files: archiveTestFiles,
+		when:  time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC),
+	}})
+	secondDir, _ := createTestRepo(t, []testCommit{{
+		files:  map[string]string{"pipeline/a.yaml": "draft"},
+		author: "someone",
+	}, {
+		files: archiveTestFiles,
+		when:  time.Date(2022, time.June, 1, 0, 0, 0, 0, time.UTC),
+	}})
+	ctx := framework.InjectResolverConfigToContext(context.Background(), map[string]string{
+		ConfigFieldComputeOCIDigest: "true",
+	})
+
+	resolver := Resolver{}
+	resolved := []*ResolvedGitResource{}
+	for _, repoDir := range []string{firstDir, firstDir, secondDir} {
+		resource, err := resolver.Resolve(ctx, map[string]string{
+			URLParam:    repoDir,
+			PathParam:   "pipeline",
+			FormatParam: FormatTar,
+		})
+		if err != nil {
+			t.Fatalf("unexpected error resolving: %v", err)
+		}
+		resolved = append(resolved, resource.(*ResolvedGitResource))
+	}
+	for _, r := range resolved[1:] {
+		if !bytes.Equal(r.Content, resolved[0].Content) {
+			t.Errorf("expected archives of the same tree to be identical")
+		}
+		if r.OCIDigest != resolved[0].OCIDigest || r.OCIDigest == "" {
+			t.Errorf("expected digest %q received %q", resolved[0].OCIDigest, r.OCIDigest)
+		}
+	}
+}
+
+func TestResolveTarArchiveFiles(t *testing.T) {
+	// The framework packages the resolved files into the same archive
+	// that's returned as the content, and the archive unpacks back to
+	// the files in the directory.
+	repoDir, _ := createTestRepo(t, []testCommit{{files: archiveTestFiles}})
+	resource, err := (&Resolver{}).Resolve(context.Background(), map[string]string{
+		URLParam:    repoDir,
+		PathParam:   "pipeline",
+		FormatParam: FormatTar,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error resolving: %v", err)
+	}
+	files := resource.(framework.ResolvedResourceWithFiles).ResolvedFiles()
+	archived, err := framework.ArchiveFiles(files)
+	if err != nil {
+		t.Fatalf("unexpected error archiving: %v", err)
+	}
+	if !bytes.Equal(archived, resource.Data()) {
+		t.Errorf("expected the archived files to match the resolved content")
+	}
+	headers, contents := readTestArchive(t, archived)
+	if len(headers) != 4 {
+		t.Fatalf("expected 4 entries, received %d", len(headers))
+	}
+	for i, header := range headers {
+		if expected := archiveTestFiles["pipeline/"+header.Name]; contents[i] != expected {
+			t.Errorf("expected entry %q to contain %q, received %q", header.Name, expected, contents[i])
+		}
+	}
+}
+
+func TestResolveTarArchiveWrappedHasNoFiles(t *testing.T) {
+	repoDir, _ := createTestRepo(t, []testCommit{{files: archiveTestFiles}})
+	ctx := framework.InjectResolverConfigToContext(context.Background(), map[string]string{
+		ConfigFieldWrapWithManifest: ManifestContentInline,
+	})
+	resource, err := (&Resolver{}).Resolve(ctx, map[string]string{
+		URLParam:    repoDir,
+		PathParam:   "pipeline",
+		FormatParam: FormatTar,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error resolving: %v", err)
+	}
+	if files := resource.(framework.ResolvedResourceWithFiles).ResolvedFiles(); files != nil {
+		t.Errorf("expected a manifest-wrapped archive to be written as is, received files %+v", files)
+	}
+}
+
+func TestResolveTarArchiveOptions(t *testing.T) {
+	repoDir, _ := createTestRepo(t, []testCommit{{files: archiveTestFiles}})
+	ctx := framework.InjectResolverConfigToContext(context.Background(), map[string]string{
+		ConfigFieldArchiveOwner:    "1000:2000",
+		ConfigFieldArchiveFileMode: "0400",
+	})
+	resolver := Resolver{}
+	resource, err := resolver.Resolve(ctx, map[string]string{
+		URLParam:    repoDir,
+		PathParam:   "other.yaml",
+		FormatParam: FormatTar,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error resolving: %v", err)
+	}
+	headers, contents := readTestArchive(t, resource.Data())
+	if len(headers) != 1 || headers[0].Name != "other.yaml" || contents[0] != "kind: Task\n" {
+		t.Fatalf("expected an archive of just the file, received %v", headers)
+	}
+	if header := headers[0]; header.Uid != 1000 || header.Gid != 2000 || header.Mode != 0400 {
+		t.Errorf("expected configured ownership and permissions, received %+v", header)
+	}
+}
+
+func TestGetArchiveOptionsInvalid(t *testing.T) {
+	for field, value := range map[string]string{
+		ConfigFieldArchiveOwner:    "root:root",
+		ConfigFieldArchiveFileMode: "rw-r--r--",
+	} {
+		if _, err := getArchiveOptions(map[string]string{field: value}); err == nil || !strings.Contains(err.Error(), field) {
+			t.Errorf("expected invalid %s error, received %v", field, err)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/system"
+)
+
+// attestationKeyKey is the key of the attestation key secret that holds
+// the PEM encoded PKCS #8 private key that attestations are signed with.
+const attestationKeyKey = "key.pem"
+
+// The types that identify the documents making up an attestation.
+const (
+	// DSSEPayloadTypeInToto is the DSSE payload type of an in-toto
+	// statement.
+	DSSEPayloadTypeInToto = "application/vnd.in-toto+json"
+
+	// InTotoStatementType is the type of the in-toto statement that
+	// attestations carry.
+	InTotoStatementType = "https://in-toto.io/Statement/v1"
+
+	// ResolutionPredicateType is the type of the predicate describing
+	// where an attested file was resolved from.
+	ResolutionPredicateType = "https://tekton.dev/resolution/git/v1"
+)
+
+// DSSEEnvelope is a Dead Simple Signing Envelope, which signs its
+// payload along with the payload's type.
+type DSSEEnvelope struct {
+	PayloadType string `json:"payloadType"`
+
+	// Payload is the signed document, base64 encoded in json.
+	Payload    []byte          `json:"payload"`
+	Signatures []DSSESignature `json:"signatures"`
+}
+
+// DSSESignature is one signature of a DSSEEnvelope's payload.
+type DSSESignature struct {
+	KeyID string `json:"keyid"`
+
+	// Sig is the signature, base64 encoded in json.
+	Sig []byte `json:"sig"`
+}
+
+// InTotoStatement is an in-toto statement that a resolved file,
+// identified by its digest, was resolved as the predicate describes.
+type InTotoStatement struct {
+	Type          string              `json:"_type"`
+	Subject       []InTotoSubject     `json:"subject"`
+	PredicateType string              `json:"predicateType"`
+	Predicate     ResolutionPredicate `json:"predicate"`
+}
+
+// InTotoSubject identifies an attested file.
+type InTotoSubject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+// ResolutionPredicate records where an attested file was resolved from
+// and when.
+type ResolutionPredicate struct {
+	Source     ManifestSource `json:"source"`
+	ResolvedAt time.Time      `json:"resolvedAt"`
+}
+
+// dssePAE returns the DSSE pre-authentication encoding of a payload
+// and its type, which is what an envelope's signatures are made over.
+func dssePAE(payloadType string, payload []byte) []byte {
+	return []byte(fmt.Sprintf("DSSEv1 %d %s %d %s", len(payloadType), payloadType, len(payload), payload))
+}
+
+// attestationSigner reads the private key in the secret named by
+// attestation-key-secret in the resolver's namespace. Only ECDSA and
+// Ed25519 keys are supported.
+func (r *Resolver) attestationSigner(ctx context.Context, name string) (crypto.Signer, error) {
+	namespace := system.Namespace()
+	secret, err := r.kubeClientSet.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error reading attestation key secret %s/%s: %w", namespace, name, err)
+	}
+	data, ok := secret.Data[attestationKeyKey]
+	if !ok {
+		return nil, fmt.Errorf("attestation key secret %s/%s has no %q key", namespace, name, attestationKeyKey)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("attestation key secret %s/%s has no PEM encoded key", namespace, name)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing attestation key in secret %s/%s: %w", namespace, name, err)
+	}
+	switch key := key.(type) {
+	case *ecdsa.PrivateKey:
+		return key, nil
+	case ed25519.PrivateKey:
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported attestation key type %T in secret %s/%s: must be ECDSA or Ed25519", key, namespace, name)
+	}
+}
+
+// attestationKeyID returns the id that signatures made with the key
+// are recorded under: the hex sha256 of its PKIX encoded public key.
+func attestationKeyID(public crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		return "", fmt.Errorf("error encoding attestation public key: %w", err)
+	}
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// signDSSE returns a DSSEEnvelope of payload signed with signer. ECDSA
+// keys sign the sha256 of the pre-authentication encoding and Ed25519
+// keys sign it whole.
+func signDSSE(signer crypto.Signer, payloadType string, payload []byte) (*DSSEEnvelope, error) {
+	keyID, err := attestationKeyID(signer.Public())
+	if err != nil {
+		return nil, err
+	}
+	message := dssePAE(payloadType, payload)
+	var opts crypto.SignerOpts = crypto.Hash(0)
+	if _, ok := signer.(*ecdsa.PrivateKey); ok {
+		sum := sha256.Sum256(message)
+		message, opts = sum[:], crypto.SHA256
+	}
+	sig, err := signer.Sign(rand.Reader, message, opts)
+	if err != nil {
+		return nil, fmt.Errorf("error signing attestation: %w", err)
+	}
+	return &DSSEEnvelope{
+		PayloadType: payloadType,
+		Payload:     payload,
+		Signatures:  []DSSESignature{{KeyID: keyID, Sig: sig}},
+	}, nil
+}
+
+// attest returns a copy of resolved with a signed in-toto attestation
+// of where its content was resolved from, made at the given time with
+// the key in the named secret.
+func (r *Resolver) attest(ctx context.Context, secretName string, resolved *ResolvedGitResource, resolvedAt time.Time) (*ResolvedGitResource, error) {
+	signer, err := r.attestationSigner(ctx, secretName)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(resolved.Content)
+	statement, err := json.Marshal(InTotoStatement{
+		Type: InTotoStatementType,
+		Subject: []InTotoSubject{{
+			Name:   resolved.Path,
+			Digest: map[string]string{"sha256": hex.EncodeToString(sum[:])},
+		}},
+		PredicateType: ResolutionPredicateType,
+		Predicate: ResolutionPredicate{
+			Source: ManifestSource{
+				URL:     sanitizeURL(resolved.URL),
+				Ref:     resolved.Ref,
+				RefType: resolved.RefType,
+				Commit:  resolved.Commit,
+				Path:    resolved.Path,
+			},
+			ResolvedAt: resolvedAt.UTC(),
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error serializing attestation for %q: %w", resolved.Path, err)
+	}
+	envelope, err := signDSSE(signer, DSSEPayloadTypeInToto, statement)
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := json.Marshal(envelope)
+	if err != nil {
+		return nil, fmt.Errorf("error serializing attestation for %q: %w", resolved.Path, err)
+	}
+	attested := *resolved
+	attested.Attestation = string(encoded)
+	return &attested, nil
+}
@@ -0,0 +1,275 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	resolutioncommon "github.com/tektoncd/resolution/pkg/common"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/system"
+)
+
+const (
+	// AuthModeNone uses no credentials beyond those go-git picks by
+	// default for a url.
+	AuthModeNone = "none"
+
+	// AuthModeWorkloadIdentity authenticates https fetches with short
+	// lived access tokens minted by the environment's metadata service.
+	AuthModeWorkloadIdentity = "workload-identity"
+)
+
+// metadataTokenURL is the metadata service endpoint that workload
+// identity tokens are requested from. It is a variable so that tests
+// can point it at a fake metadata server.
+var metadataTokenURL = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+
+// defaultTokenRefreshWindow is how long before its expiry a token is
+// replaced, so that it can't expire partway through a fetch, when
+// token-refresh-window isn't configured.
+const defaultTokenRefreshWindow = time.Minute
+
+// getAuthMode returns the auth mode configured with the auth-mode field
+// in the git-resolver-config configmap, defaulting to none.
+func getAuthMode(conf map[string]string) (string, error) {
+	switch mode := conf[ConfigFieldAuthMode]; mode {
+	case "", AuthModeNone:
+		return AuthModeNone, nil
+	case AuthModeWorkloadIdentity:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid %s %q: must be one of %q, %q", ConfigFieldAuthMode, mode, AuthModeNone, AuthModeWorkloadIdentity)
+	}
+}
+
+// httpAuth returns the auth to fetch the repo at url with according to
+// the configured auth mode, or nil to leave go-git to pick a default.
+func (r *Resolver) httpAuth(ctx context.Context, conf map[string]string, url string) (transport.AuthMethod, error) {
+	mode, err := getAuthMode(conf)
+	if err != nil {
+		return nil, err
+	}
+	if mode == AuthModeNone {
+		return nil, nil
+	}
+	ep, err := transport.NewEndpoint(url)
+	if err != nil || ep.Protocol != "https" && ep.Protocol != "http" {
+		return nil, nil
+	}
+	window, err := getTokenRefreshWindow(conf)
+	if err != nil {
+		return nil, err
+	}
+	token, err := r.tokens.get(ctx, r.now(), window)
+	if err != nil {
+		return nil, err
+	}
+	return &githttp.TokenAuth{Token: token}, nil
+}
+
+// getTokenRefreshWindow returns how long before their expiry minted
+// tokens are replaced, as configured with the token-refresh-window
+// field in the git-resolver-config configmap.
+func getTokenRefreshWindow(conf map[string]string) (time.Duration, error) {
+	window, err := getDuration(conf, ConfigFieldTokenRefreshWindow)
+	if err != nil {
+		return 0, err
+	}
+	if window <= 0 {
+		return defaultTokenRefreshWindow, nil
+	}
+	return window, nil
+}
+
+// withTokenRetry calls fn with auth. If auth is a minted token that the
+// server rejects, for instance because it expired partway through a
+// resolution, the token is discarded and fn is called once more with a
+// new one. The auth that fn last ran with is returned so that later
+// calls can reuse it.
+func (r *Resolver) withTokenRetry(ctx context.Context, conf map[string]string, url string, auth transport.AuthMethod, fn func(transport.AuthMethod) error) (transport.AuthMethod, error) {
+	err := fn(auth)
+	tokenAuth, ok := auth.(*githttp.TokenAuth)
+	if !ok || !isAuthRejected(err) {
+		return auth, err
+	}
+	r.tokens.invalidate(tokenAuth.Token)
+	refreshed, authErr := r.httpAuth(ctx, conf, url)
+	if authErr != nil {
+		return auth, fmt.Errorf("%w; refreshing token: %v", err, authErr)
+	}
+	return refreshed, fn(refreshed)
+}
+
+// isAuthRejected returns true if err indicates that a git server
+// refused the credentials it was sent.
+func isAuthRejected(err error) bool {
+	return errors.Is(err, transport.ErrAuthenticationRequired) || errors.Is(err, transport.ErrAuthorizationFailed)
+}
+
+// withProxyAuth returns a context whose http requests authenticate to
+// proxies with the basic auth username and password in the secret
+// named by proxy-auth-secret in the resolver's namespace. This is
+// separate from, and sent alongside, any auth for the repo itself.
+func (r *Resolver) withProxyAuth(ctx context.Context, conf map[string]string) (context.Context, error) {
+	name := conf[ConfigFieldProxyAuthSecret]
+	if name == "" {
+		return ctx, nil
+	}
+	namespace := system.Namespace()
+	secret, err := r.kubeClientSet.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error reading proxy auth secret %s/%s: %w", namespace, name, err)
+	}
+	username, ok := secret.Data[corev1.BasicAuthUsernameKey]
+	if !ok {
+		return nil, fmt.Errorf("proxy auth secret %s/%s has no %q key", namespace, name, corev1.BasicAuthUsernameKey)
+	}
+	password := secret.Data[corev1.BasicAuthPasswordKey]
+	credentials := base64.StdEncoding.EncodeToString([]byte(string(username) + ":" + string(password)))
+	return withProxyAuthorization(ctx, "Basic "+credentials), nil
+}
+
+// withClientCert returns a copy of ctx whose https requests present the
+// client certificate in the secret named by client-cert-secret, for git
+// servers that require mutual TLS. The secret is read from the
+// namespace of the request being resolved, so each namespace brings
+// its own certificate.
+func (r *Resolver) withClientCert(ctx context.Context, conf map[string]string) (context.Context, error) {
+	name := conf[ConfigFieldClientCertSecret]
+	if name == "" {
+		return ctx, nil
+	}
+	namespace := resolutioncommon.RequestNamespace(ctx)
+	secret, err := r.kubeClientSet.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error reading client certificate secret %s/%s: %w", namespace, name, err)
+	}
+	for _, key := range []string{corev1.TLSCertKey, corev1.TLSPrivateKeyKey} {
+		if _, ok := secret.Data[key]; !ok {
+			return nil, fmt.Errorf("client certificate secret %s/%s has no %q key", namespace, name, key)
+		}
+	}
+	cert, err := tls.X509KeyPair(secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey])
+	if err != nil {
+		return nil, fmt.Errorf("error parsing client certificate secret %s/%s: %w", namespace, name, err)
+	}
+	return withClientCertificate(ctx, &cert), nil
+}
+
+// defaultTokenSecretKey is the key a token is read from when
+// tokenSecretKey isn't given.
+const defaultTokenSecretKey = "token"
+
+// tokenSecretAuth returns http basic auth with the personal access
+// token from the secret named by the tokenSecret param, or nil if there
+// is none. The secret is read from the namespace of the request being
+// resolved. Errors name the secret but never include its content.
+func (r *Resolver) tokenSecretAuth(ctx context.Context, params map[string]string) (transport.AuthMethod, error) {
+	name := params[TokenSecretParam]
+	if name == "" {
+		return nil, nil
+	}
+	key := params[TokenSecretKeyParam]
+	if key == "" {
+		key = defaultTokenSecretKey
+	}
+	namespace := resolutioncommon.RequestNamespace(ctx)
+	secret, err := r.kubeClientSet.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error reading token secret %s/%s: %w", namespace, name, err)
+	}
+	token := strings.TrimSpace(string(secret.Data[key]))
+	if token == "" {
+		return nil, fmt.Errorf("token secret %s/%s has no %q key", namespace, name, key)
+	}
+	return &githttp.BasicAuth{Username: "token", Password: token}, nil
+}
+
+// tokenSource mints access tokens from the metadata service and reuses
+// each one until shortly before it expires. The zero value is ready to
+// use.
+type tokenSource struct {
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// metadataToken is the body returned by the metadata token endpoint.
+type metadataToken struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+	TokenType   string `json:"token_type"`
+}
+
+// get returns a token that is valid for at least window past now,
+// requesting a new one if needed.
+func (s *tokenSource) get(ctx context.Context, now time.Time, window time.Duration) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token != "" && now.Add(window).Before(s.expires) {
+		return s.token, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, metadataTokenURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("error requesting workload identity token: %w", err)
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error requesting workload identity token: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("error requesting workload identity token: metadata service returned %s", resp.Status)
+	}
+	var minted metadataToken
+	if err := json.NewDecoder(resp.Body).Decode(&minted); err != nil {
+		return "", fmt.Errorf("error decoding workload identity token: %w", err)
+	}
+	if minted.AccessToken == "" {
+		return "", fmt.Errorf("error requesting workload identity token: metadata service returned no token")
+	}
+	s.token = minted.AccessToken
+	s.expires = now.Add(time.Duration(minted.ExpiresIn) * time.Second)
+	return s.token, nil
+}
+
+// invalidate discards token so that the next call to get mints a new
+// one. It does nothing if token has already been replaced, so that
+// concurrent resolutions failing with the same token only refresh it
+// once.
+func (s *tokenSource) invalidate(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token == token {
+		s.token = ""
+	}
+}
@@ -0,0 +1,417 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	resolutioncommon "github.com/tektoncd/resolution/pkg/common"
+	"github.com/tektoncd/resolution/pkg/resolver/framework"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+// newMetadataServer starts a fake metadata service that mints a new
+// token, valid for an hour, on every request. The returned counter holds
+// the number of tokens minted.
+func newMetadataServer(t *testing.T) *int32 {
+	t.Helper()
+	var minted int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata-Flavor") != "Google" {
+			http.Error(w, "missing Metadata-Flavor header", http.StatusForbidden)
+			return
+		}
+		n := atomic.AddInt32(&minted, 1)
+		fmt.Fprintf(w, `{"access_token":"token-%d","expires_in":3600,"token_type":"Bearer"}`, n)
+	}))
+	t.Cleanup(server.Close)
+	original := metadataTokenURL
+	metadataTokenURL = server.URL
+	t.Cleanup(func() { metadataTokenURL = original })
+	return &minted
+}
+
+func TestResolveWorkloadIdentity(t *testing.T) {
+	minted := newMetadataServer(t)
+	repoDir, _ := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "kind: Task"},
+	}})
+	var expectedToken atomic.Value
+	expectedToken.Store("token-1")
+	server := newGitHTTPServer(t, repoDir, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer "+expectedToken.Load().(string) {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+
+	ctx := framework.InjectResolverConfigToContext(context.Background(), map[string]string{
+		ConfigFieldAuthMode: AuthModeWorkloadIdentity,
+	})
+	params := map[string]string{
+		URLParam:  server.URL + "/repo",
+		PathParam: "task.yaml",
+	}
+	fakeClock := clocktesting.NewFakePassiveClock(time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC))
+	resolver := &Resolver{clock: fakeClock}
+
+	for i := 0; i < 2; i++ {
+		resource, err := resolver.Resolve(ctx, params)
+		if err != nil {
+			t.Fatalf("unexpected error resolving with workload identity: %v", err)
+		}
+		if string(resource.Data()) != "kind: Task" {
+			t.Errorf("expected content %q received %q", "kind: Task", string(resource.Data()))
+		}
+	}
+	if n := atomic.LoadInt32(minted); n != 1 {
+		t.Errorf("expected token to be reused until it nears expiry, minted %d", n)
+	}
+
+	fakeClock.SetTime(fakeClock.Now().Add(time.Hour))
+	expectedToken.Store("token-2")
+	if _, err := resolver.Resolve(ctx, params); err != nil {
+		t.Fatalf("unexpected error resolving with refreshed token: %v", err)
+	}
+	if n := atomic.LoadInt32(minted); n != 2 {
+		t.Errorf("expected expired token to be refreshed, minted %d", n)
+	}
+}
+
+func TestResolveWorkloadIdentityTokenExpiresMidFlight(t *testing.T) {
+	minted := newMetadataServer(t)
+	repoDir, _ := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "kind: Task"},
+	}})
+	// The first token is accepted for listing refs and then expires
+	// before the clone, which is only accepted with a new token.
+	var expectedToken atomic.Value
+	expectedToken.Store("token-1")
+	server := newGitHTTPServer(t, repoDir, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer "+expectedToken.Load().(string) {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+			expectedToken.Store("token-2")
+		})
+	})
+
+	ctx := framework.InjectResolverConfigToContext(context.Background(), map[string]string{
+		ConfigFieldAuthMode:  AuthModeWorkloadIdentity,
+		ConfigFieldCacheSize: "10",
+	})
+	resolver := &Resolver{}
+	resource, err := resolver.Resolve(ctx, map[string]string{
+		URLParam:  server.URL + "/repo",
+		PathParam: "task.yaml",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error resolving with an expiring token: %v", err)
+	}
+	if string(resource.Data()) != "kind: Task" {
+		t.Errorf("expected content %q received %q", "kind: Task", string(resource.Data()))
+	}
+	if n := atomic.LoadInt32(minted); n != 2 {
+		t.Errorf("expected the rejected token to be refreshed once, minted %d", n)
+	}
+}
+
+func TestResolveWorkloadIdentityRetriesOnce(t *testing.T) {
+	minted := newMetadataServer(t)
+	repoDir, _ := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "kind: Task"},
+	}})
+	server := newGitHTTPServer(t, repoDir, func(http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+		})
+	})
+
+	ctx := framework.InjectResolverConfigToContext(context.Background(), map[string]string{
+		ConfigFieldAuthMode: AuthModeWorkloadIdentity,
+	})
+	resolver := &Resolver{}
+	if _, err := resolver.Resolve(ctx, map[string]string{
+		URLParam:  server.URL + "/repo",
+		PathParam: "task.yaml",
+	}); err == nil {
+		t.Fatalf("expected error when every token is rejected")
+	}
+	if n := atomic.LoadInt32(minted); n != 2 {
+		t.Errorf("expected a single retry with a refreshed token, minted %d", n)
+	}
+}
+
+func TestTokenSourceRefreshWindow(t *testing.T) {
+	minted := newMetadataServer(t)
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	var tokens tokenSource
+
+	for _, window := range []time.Duration{time.Minute, time.Minute, 2 * time.Hour} {
+		if _, err := tokens.get(context.Background(), now, window); err != nil {
+			t.Fatalf("unexpected error getting token: %v", err)
+		}
+	}
+	if n := atomic.LoadInt32(minted); n != 2 {
+		t.Errorf("expected a token expiring within the refresh window to be replaced, minted %d", n)
+	}
+}
+
+func TestTokenSourceConcurrentInvalidate(t *testing.T) {
+	minted := newMetadataServer(t)
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	var tokens tokenSource
+	stale, err := tokens.get(context.Background(), now, time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error getting token: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tokens.invalidate(stale)
+			if _, err := tokens.get(context.Background(), now, time.Minute); err != nil {
+				t.Errorf("unexpected error getting token: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := atomic.LoadInt32(minted); n != 2 {
+		t.Errorf("expected concurrent resolutions rejected with the same token to refresh it once, minted %d", n)
+	}
+}
+
+func TestResolveWorkloadIdentityMetadataError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "no identity", http.StatusNotFound)
+	}))
+	defer server.Close()
+	original := metadataTokenURL
+	metadataTokenURL = server.URL
+	defer func() { metadataTokenURL = original }()
+
+	ctx := framework.InjectResolverConfigToContext(context.Background(), map[string]string{
+		ConfigFieldAuthMode: AuthModeWorkloadIdentity,
+	})
+	resolver := &Resolver{}
+	_, err := resolver.Resolve(ctx, map[string]string{
+		URLParam:  "https://source.developers.google.com/p/project/r/repo",
+		PathParam: "task.yaml",
+	})
+	if err == nil {
+		t.Fatalf("expected error when metadata service fails")
+	}
+}
+
+func TestGetAuthModeInvalid(t *testing.T) {
+	if _, err := getAuthMode(map[string]string{ConfigFieldAuthMode: "password"}); err == nil {
+		t.Errorf("expected error for unknown auth mode")
+	}
+}
+
+func TestResolveTokenSecret(t *testing.T) {
+	repoDir, _ := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "kind: Task"},
+	}})
+	server := newGitHTTPServer(t, repoDir, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if username, password, ok := r.BasicAuth(); !ok || username != "token" || password != "ghp_secret" {
+				w.Header().Set("WWW-Authenticate", `Basic realm="git"`)
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+	kubeClient := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "git-token", Namespace: "team-a"},
+		Data: map[string][]byte{
+			"token": []byte("ghp_secret\n"),
+			"stale": []byte("ghp_revoked"),
+		},
+	})
+
+	for _, tc := range []struct {
+		name        string
+		namespace   string
+		params      map[string]string
+		expectedErr string
+	}{{
+		name:      "token",
+		namespace: "team-a",
+		params:    map[string]string{TokenSecretParam: "git-token"},
+	}, {
+		name:        "rejected token",
+		namespace:   "team-a",
+		params:      map[string]string{TokenSecretParam: "git-token", TokenSecretKeyParam: "stale"},
+		expectedErr: "authentication required",
+	}, {
+		name:        "no token",
+		namespace:   "team-a",
+		params:      map[string]string{},
+		expectedErr: "authentication required",
+	}, {
+		name:        "missing key",
+		namespace:   "team-a",
+		params:      map[string]string{TokenSecretParam: "git-token", TokenSecretKeyParam: "other"},
+		expectedErr: `token secret team-a/git-token has no "other" key`,
+	}, {
+		name:        "secret in another namespace",
+		namespace:   "team-b",
+		params:      map[string]string{TokenSecretParam: "git-token"},
+		expectedErr: "error reading token secret team-b/git-token",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := resolutioncommon.InjectRequestNamespace(context.Background(), tc.namespace)
+			resolver := &Resolver{kubeClientSet: kubeClient}
+			params := map[string]string{
+				URLParam:  server.URL + "/repo",
+				PathParam: "task.yaml",
+			}
+			for k, v := range tc.params {
+				params[k] = v
+			}
+			if err := resolver.ValidateParams(ctx, params); err != nil {
+				t.Fatalf("unexpected error validating params: %v", err)
+			}
+			resource, err := resolver.Resolve(ctx, params)
+			if tc.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectedErr) {
+					t.Fatalf("expected error containing %q, received %v", tc.expectedErr, err)
+				}
+				if strings.Contains(err.Error(), "ghp_") {
+					t.Errorf("expected the token to be left out of errors, received %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error resolving: %v", err)
+			}
+			if string(resource.Data()) != "kind: Task" {
+				t.Errorf("expected content %q received %q", "kind: Task", resource.Data())
+			}
+			for k, v := range resource.Annotations() {
+				if strings.Contains(v, "ghp_") {
+					t.Errorf("expected the token to be left out of annotations, received %s: %q", k, v)
+				}
+			}
+		})
+	}
+}
+
+func TestResolveTokenSecretNotShared(t *testing.T) {
+	repoDir, commits := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "kind: Task"},
+	}})
+	var requests int32
+	server := newGitHTTPServer(t, repoDir, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&requests, 1)
+			if _, password, ok := r.BasicAuth(); !ok || password != "ghp_secret" {
+				w.Header().Set("WWW-Authenticate", `Basic realm="git"`)
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+	kubeClient := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "git-token", Namespace: "team-a"},
+		Data:       map[string][]byte{"token": []byte("ghp_secret")},
+	}, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "git-token", Namespace: "team-b"},
+		Data:       map[string][]byte{"token": []byte("ghp_garbage")},
+	})
+	conf := map[string]string{
+		ConfigFieldCacheSize:        "10",
+		ConfigFieldNegativeCacheTTL: "5s",
+	}
+	resolver := &Resolver{kubeClientSet: kubeClient}
+	resolve := func(namespace, path string) error {
+		ctx := resolutioncommon.InjectRequestNamespace(framework.InjectResolverConfigToContext(context.Background(), conf), namespace)
+		_, err := resolver.Resolve(ctx, map[string]string{
+			URLParam:         server.URL + "/repo",
+			PathParam:        path,
+			CommitParam:      commits[0],
+			TokenSecretParam: "git-token",
+		})
+		return err
+	}
+
+	t.Run("result cache", func(t *testing.T) {
+		if err := resolve("team-a", "task.yaml"); err != nil {
+			t.Fatalf("unexpected error resolving with a valid token: %v", err)
+		}
+		if err := resolve("team-b", "task.yaml"); err == nil || !strings.Contains(err.Error(), "authentication required") {
+			t.Fatalf("expected another namespace's token to be rejected rather than served the cached file, received %v", err)
+		}
+	})
+
+	t.Run("negative cache", func(t *testing.T) {
+		if err := resolve("team-a", "missing.yaml"); !isNotFound(err) {
+			t.Fatalf("expected a not found error, received %v", err)
+		}
+		fetched := atomic.LoadInt32(&requests)
+		if err := resolve("team-a", "missing.yaml"); !isNotFound(err) {
+			t.Fatalf("expected a not found error, received %v", err)
+		}
+		if n := atomic.LoadInt32(&requests); n == fetched {
+			t.Errorf("expected a miss with a token secret not to be remembered")
+		}
+	})
+}
+
+func TestValidateParamsTokenSecret(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		params      map[string]string
+		expectedErr string
+	}{{
+		name: "ssh url",
+		params: map[string]string{
+			URLParam:         "git@github.com:tektoncd/catalog.git",
+			PathParam:        "task.yaml",
+			TokenSecretParam: "git-token",
+		},
+		expectedErr: `"tokenSecret" requires an http or https url`,
+	}, {
+		name: "file locator",
+		params: map[string]string{
+			LocatorParam:     "file:///repo@main//task.yaml",
+			TokenSecretParam: "git-token",
+		},
+		expectedErr: `"tokenSecret" requires an http or https url`,
+	}, {
+		name: "key without secret",
+		params: map[string]string{
+			URLParam:            "https://github.com/tektoncd/catalog",
+			PathParam:           "task.yaml",
+			TokenSecretKeyParam: "token",
+		},
+		expectedErr: `"tokenSecretKey" requires "tokenSecret"`,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			resolver := Resolver{}
+			err := resolver.ValidateParams(context.Background(), tc.params)
+			if err == nil || !strings.Contains(err.Error(), tc.expectedErr) {
+				t.Errorf("expected error containing %q, received %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// BlameResult is the json document returned in place of a file's
+// content when blame information is requested.
+type BlameResult struct {
+	Path   string      `json:"path"`
+	Commit string      `json:"commit"`
+	Lines  []BlameLine `json:"lines"`
+}
+
+// BlameLine describes the commit that last modified a single line of a
+// blamed file.
+type BlameLine struct {
+	Line   int       `json:"line"`
+	Commit string    `json:"commit"`
+	Author string    `json:"author"`
+	Date   time.Time `json:"date"`
+	Text   string    `json:"text"`
+}
+
+// blameFile computes per-line blame for path as of the given commit
+// and returns it as a json-encoded resource. Files larger than maxSize
+// are rejected before blaming since blame is expensive on large files.
+// A maxSize of zero disables the check.
+func blameFile(repository *git.Repository, commit, path string, maxSize int64) (*ResolvedGitResource, error) {
+	commitObj, err := repository.CommitObject(plumbing.NewHash(commit))
+	if err != nil {
+		return nil, fmt.Errorf("error reading commit %q: %w", commit, err)
+	}
+
+	file, err := commitObj.File(strings.TrimPrefix(path, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("error opening file %q: %v", path, err)
+	}
+	if maxSize > 0 && file.Size > maxSize {
+		return nil, fmt.Errorf("file %q exceeds max size %d bytes", path, maxSize)
+	}
+
+	blame, err := git.Blame(commitObj, file.Name)
+	if err != nil {
+		return nil, fmt.Errorf("error computing blame for %q: %w", path, err)
+	}
+
+	result := BlameResult{
+		Path:   path,
+		Commit: commit,
+		Lines:  make([]BlameLine, 0, len(blame.Lines)),
+	}
+	for i, line := range blame.Lines {
+		result.Lines = append(result.Lines, BlameLine{
+			Line:   i + 1,
+			Commit: line.Hash.String(),
+			Author: line.Author,
+			Date:   line.Date,
+			Text:   line.Text,
+		})
+	}
+
+	content, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("error serializing blame for %q: %w", path, err)
+	}
+
+	return &ResolvedGitResource{
+		Commit:      commit,
+		Content:     content,
+		ContentType: JSONContentType,
+	}, nil
+}
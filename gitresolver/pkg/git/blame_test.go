@@ -0,0 +1,88 @@
+package git
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	resolutioncommon "github.com/tektoncd/resolution/pkg/common"
+	"github.com/tektoncd/resolution/pkg/resolver/framework"
+)
+
+func TestResolveBlame(t *testing.T) {
+	repoDir, commits := createTestRepo(t, []testCommit{{
+		files:  map[string]string{"task.yaml": "line one\nline two\n"},
+		author: "alice",
+	}, {
+		files:  map[string]string{"task.yaml": "line one\nline 2\nline three\n"},
+		author: "bob",
+	}})
+
+	resolver := Resolver{}
+	resource, err := resolver.Resolve(context.Background(), map[string]string{
+		URLParam:   repoDir,
+		PathParam:  "task.yaml",
+		BlameParam: "true",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error resolving blame: %v", err)
+	}
+
+	if ct := resource.Annotations()[resolutioncommon.AnnotationKeyContentType]; ct != JSONContentType {
+		t.Errorf("expected content-type %q received %q", JSONContentType, ct)
+	}
+
+	result := BlameResult{}
+	if err := json.Unmarshal(resource.Data(), &result); err != nil {
+		t.Fatalf("error parsing blame result: %v", err)
+	}
+	if result.Commit != commits[1] {
+		t.Errorf("expected blame at commit %q received %q", commits[1], result.Commit)
+	}
+
+	expected := []BlameLine{
+		{Line: 1, Commit: commits[0], Author: "alice@example.com", Text: "line one"},
+		{Line: 2, Commit: commits[1], Author: "bob@example.com", Text: "line 2"},
+		{Line: 3, Commit: commits[1], Author: "bob@example.com", Text: "line three"},
+	}
+	if len(result.Lines) != len(expected) {
+		t.Fatalf("expected %d blame lines received %d", len(expected), len(result.Lines))
+	}
+	for i, line := range result.Lines {
+		want := expected[i]
+		if line.Line != want.Line || line.Commit != want.Commit || line.Author != want.Author || line.Text != want.Text {
+			t.Errorf("line %d: expected %+v received %+v", i+1, want, line)
+		}
+	}
+}
+
+func TestResolveBlameMaxSize(t *testing.T) {
+	repoDir, _ := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "this file is longer than the limit\n"},
+	}})
+
+	ctx := framework.InjectResolverConfigToContext(context.Background(), map[string]string{
+		ConfigFieldMaxSize: "8",
+	})
+	resolver := Resolver{}
+	_, err := resolver.Resolve(ctx, map[string]string{
+		URLParam:   repoDir,
+		PathParam:  "task.yaml",
+		BlameParam: "true",
+	})
+	if err == nil {
+		t.Fatalf("expected error blaming file larger than max size")
+	}
+}
+
+func TestValidateParamsBlame(t *testing.T) {
+	resolver := Resolver{}
+	params := map[string]string{
+		URLParam:   "foo",
+		PathParam:  "bar",
+		BlameParam: "not-a-bool",
+	}
+	if err := resolver.ValidateParams(context.Background(), params); err == nil {
+		t.Fatalf("expected error for invalid blame value")
+	}
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"context"
+	"time"
+)
+
+// attemptBudget divides the time left before a deadline between the
+// attempts planned to meet it, like fetching each candidate ref and
+// falling back to the git binary, so that one slow attempt can't leave
+// none of the time to those after it. Each attempt gets an equal share
+// of the time that's left when it starts, so time that earlier attempts
+// didn't use carries over to the later ones. A nil budget leaves every
+// attempt the whole deadline.
+type attemptBudget struct {
+	deadline  time.Time
+	remaining int
+}
+
+// newAttemptBudget returns a budget dividing the time before ctx's
+// deadline between the given number of attempts, or nil if ctx has no
+// deadline or there's only one attempt to make.
+func newAttemptBudget(ctx context.Context, attempts int) *attemptBudget {
+	deadline, ok := ctx.Deadline()
+	if !ok || attempts <= 1 {
+		return nil
+	}
+	return &attemptBudget{deadline: deadline, remaining: attempts}
+}
+
+// start returns a copy of ctx bounded by the next attempt's share of
+// the budget. The last planned attempt, and any made after it, get all
+// of the time that's left.
+func (b *attemptBudget) start(ctx context.Context) (context.Context, context.CancelFunc) {
+	if b == nil {
+		return context.WithCancel(ctx)
+	}
+	share := time.Until(b.deadline) / time.Duration(b.remaining)
+	if b.remaining > 1 {
+		b.remaining--
+	}
+	return context.WithTimeout(ctx, share)
+}
+
+// skip gives up the shares of n planned attempts that won't be made,
+// so that the attempts that will be share their time.
+func (b *attemptBudget) skip(n int) {
+	if b == nil {
+		return
+	}
+	b.remaining -= n
+	if b.remaining < 1 {
+		b.remaining = 1
+	}
+}
@@ -0,0 +1,153 @@
+package git
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tektoncd/resolution/pkg/resolver/framework"
+)
+
+// untilDeadline returns how long is left before ctx's deadline.
+func untilDeadline(t *testing.T, ctx context.Context) time.Duration {
+	t.Helper()
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		t.Fatalf("expected attempt to have a deadline")
+	}
+	return time.Until(deadline)
+}
+
+func TestAttemptBudget(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 8*time.Second)
+	defer cancel()
+	parentDeadline, _ := ctx.Deadline()
+	budget := newAttemptBudget(ctx, 4)
+
+	first, cancelFirst := budget.start(ctx)
+	defer cancelFirst()
+	if left := untilDeadline(t, first); left > 2*time.Second || left < 1500*time.Millisecond {
+		t.Errorf("expected the first of 4 attempts to get about a quarter of 8s, received %s", left)
+	}
+
+	// The first attempt returned at once, so the rest of the time is
+	// split between the remaining three.
+	second, cancelSecond := budget.start(ctx)
+	defer cancelSecond()
+	if left := untilDeadline(t, second); left > 8*time.Second/3 || left < 2*time.Second {
+		t.Errorf("expected unused time to carry over to the second attempt, received %s", left)
+	}
+
+	// Skipping an attempt leaves its share to the one after it, which
+	// is the last and gets everything that's left.
+	budget.skip(1)
+	last, cancelLast := budget.start(ctx)
+	defer cancelLast()
+	if deadline, _ := last.Deadline(); !deadline.Equal(parentDeadline) {
+		t.Errorf("expected the last attempt to end at the overall deadline %v, received %v", parentDeadline, deadline)
+	}
+	extra, cancelExtra := budget.start(ctx)
+	defer cancelExtra()
+	if deadline, _ := extra.Deadline(); deadline.After(parentDeadline) {
+		t.Errorf("expected attempts never to run past the overall deadline %v, received %v", parentDeadline, deadline)
+	}
+}
+
+func TestAttemptBudgetUnbounded(t *testing.T) {
+	var budget *attemptBudget
+	if budget = newAttemptBudget(context.Background(), 4); budget != nil {
+		t.Errorf("expected no budget without a deadline")
+	}
+	ctx, cancel := budget.start(context.Background())
+	defer cancel()
+	if _, ok := ctx.Deadline(); ok {
+		t.Errorf("expected attempts without a budget to have no deadline of their own")
+	}
+}
+
+func TestResolveSplitFetchBudget(t *testing.T) {
+	repoDir, _ := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "from the fallback"},
+	}})
+	// go-git's requests, which it sends as git/1.0, hang until they're
+	// given up on while the git binary's are served, so the fallback
+	// only succeeds if go-git's attempt was bounded.
+	var mu sync.Mutex
+	var gogitStopped, cliStarted time.Time
+	server := newGitHTTPServer(t, repoDir, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.UserAgent() == "git/1.0" {
+				<-r.Context().Done()
+				mu.Lock()
+				gogitStopped = time.Now()
+				mu.Unlock()
+				return
+			}
+			mu.Lock()
+			if cliStarted.IsZero() {
+				cliStarted = time.Now()
+			}
+			mu.Unlock()
+			next.ServeHTTP(w, r)
+		})
+	})
+
+	for _, tc := range []struct {
+		name        string
+		split       string
+		expectError bool
+	}{{
+		name:  "split",
+		split: "true",
+	}, {
+		name:        "not split",
+		split:       "false",
+		expectError: true,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			mu.Lock()
+			gogitStopped, cliStarted = time.Time{}, time.Time{}
+			mu.Unlock()
+			ctx := framework.InjectResolverConfigToContext(context.Background(), map[string]string{
+				ConfigFieldGitCLIFallback:   "true",
+				ConfigFieldSplitFetchBudget: tc.split,
+			})
+			timeout := 4 * time.Second
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			resolver := Resolver{}
+			start := time.Now()
+			resource, err := resolver.Resolve(ctx, map[string]string{
+				URLParam:  server.URL + "/repo",
+				PathParam: "task.yaml",
+			})
+			elapsed := time.Since(start)
+			if elapsed > timeout+time.Second {
+				t.Errorf("expected resolution to respect the %s deadline, took %s", timeout, elapsed)
+			}
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("expected go-git to use the whole deadline and the fallback to fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error resolving: %v", err)
+			}
+			if string(resource.Data()) != "from the fallback" {
+				t.Errorf("expected content %q received %q", "from the fallback", resource.Data())
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if gogitBound := gogitStopped.Sub(start); gogitBound > timeout/2+500*time.Millisecond {
+				t.Errorf("expected the go-git attempt to be bounded by half of %s, it ran for %s", timeout, gogitBound)
+			}
+			if cliStart := cliStarted.Sub(start); cliStart < timeout/2-250*time.Millisecond || cliStart > timeout {
+				t.Errorf("expected the git cli fallback to start once go-git's share was used, started after %s", cliStarted.Sub(start))
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/system"
+)
+
+// caBundleSecretKey is the key in the secret named by ca-bundle-secret
+// that holds the bundle, the same one that service account and
+// cert-manager secrets keep their ca in.
+const caBundleSecretKey = "ca.crt"
+
+// caBundle is a set of PEM encoded certificate authorities that https
+// git servers' certificates are verified against, along with the ones
+// that are trusted anyway.
+type caBundle struct {
+	pem    []byte
+	pool   *x509.CertPool
+	digest [sha256.Size]byte
+}
+
+// newCABundle parses the certificate authorities in pemCerts, read from
+// source, into a pool alongside the system's.
+func newCABundle(pemCerts []byte, source string) (*caBundle, error) {
+	var pool *x509.CertPool
+	if rootCAs != nil {
+		pool = rootCAs.Clone()
+	} else if systemPool, err := x509.SystemCertPool(); err == nil {
+		pool = systemPool
+	} else {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pemCerts) {
+		return nil, fmt.Errorf("no PEM encoded certificates found in ca bundle %s", source)
+	}
+	return &caBundle{pem: pemCerts, pool: pool, digest: sha256.Sum256(pemCerts)}, nil
+}
+
+// withCABundle returns a copy of ctx whose https requests trust the
+// certificate authorities in the file at ca-bundle, or in the secret in
+// the resolver's namespace named by ca-bundle-secret, for git servers
+// with self-signed or privately issued certificates. The bundle is read
+// on every resolution so that a rotated one is picked up straight away.
+func (r *Resolver) withCABundle(ctx context.Context, conf map[string]string) (context.Context, error) {
+	path, name := conf[ConfigFieldCABundle], conf[ConfigFieldCABundleSecret]
+	switch {
+	case path != "" && name != "":
+		return nil, fmt.Errorf("%s and %s can't both be set", ConfigFieldCABundle, ConfigFieldCABundleSecret)
+	case path != "":
+		pemCerts, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading ca bundle: %w", err)
+		}
+		bundle, err := newCABundle(pemCerts, path)
+		if err != nil {
+			return nil, err
+		}
+		return withCABundleContext(ctx, bundle), nil
+	case name != "":
+		namespace := system.Namespace()
+		secret, err := r.kubeClientSet.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("error reading ca bundle secret %s/%s: %w", namespace, name, err)
+		}
+		pemCerts, ok := secret.Data[caBundleSecretKey]
+		if !ok {
+			return nil, fmt.Errorf("ca bundle secret %s/%s has no %q key", namespace, name, caBundleSecretKey)
+		}
+		bundle, err := newCABundle(pemCerts, "secret "+namespace+"/"+name)
+		if err != nil {
+			return nil, err
+		}
+		return withCABundleContext(ctx, bundle), nil
+	}
+	return ctx, nil
+}
+
+// caBundleKey is the context key for the ca bundle of a resolution.
+type caBundleKey struct{}
+
+// withCABundleContext returns a context whose outgoing https requests
+// trust bundle.
+func withCABundleContext(ctx context.Context, bundle *caBundle) context.Context {
+	return context.WithValue(ctx, caBundleKey{}, bundle)
+}
+
+// caBundleFromContext returns the ca bundle stored in ctx, or nil.
+func caBundleFromContext(ctx context.Context) *caBundle {
+	bundle, _ := ctx.Value(caBundleKey{}).(*caBundle)
+	return bundle
+}
+
+// caBundleGitEnv writes the ca bundle in ctx, if there is one, to a
+// file that the git binary is pointed at with the returned environment,
+// which takes precedence over its http.sslCAInfo config, and returns a
+// func that removes it. Unlike go-git, the git binary trusts
+// only the bundle and not the system's certificate authorities too.
+func caBundleGitEnv(ctx context.Context) ([]string, func(), error) {
+	bundle := caBundleFromContext(ctx)
+	if bundle == nil {
+		return nil, func() {}, nil
+	}
+	file, err := os.CreateTemp("", "git-resolver-ca-")
+	if err != nil {
+		return nil, nil, fmt.Errorf("error writing ca bundle: %w", err)
+	}
+	cleanup := func() { os.Remove(file.Name()) }
+	_, err = file.Write(bundle.pem)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("error writing ca bundle: %w", err)
+	}
+	return []string{"GIT_SSL_CAINFO=" + file.Name()}, cleanup, nil
+}
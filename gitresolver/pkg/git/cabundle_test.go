@@ -0,0 +1,101 @@
+package git
+
+import (
+	"context"
+	"encoding/pem"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tektoncd/resolution/pkg/resolver/framework"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"knative.dev/pkg/system"
+)
+
+func TestResolveCABundle(t *testing.T) {
+	t.Setenv(system.NamespaceEnvKey, "tekton-remote-resolution")
+	installTransports()
+	repoDir, commits := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "over private tls"},
+	}})
+	server := httptest.NewTLSServer(newGitHTTPHandler(t, repoDir, nil))
+	t.Cleanup(server.Close)
+	bundle := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	bundleFile := filepath.Join(t.TempDir(), "ca.crt")
+	if err := os.WriteFile(bundleFile, bundle, 0o600); err != nil {
+		t.Fatalf("error writing ca bundle: %v", err)
+	}
+	invalidFile := filepath.Join(t.TempDir(), "invalid.crt")
+	if err := os.WriteFile(invalidFile, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatalf("error writing ca bundle: %v", err)
+	}
+	kubeClient := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "git-ca", Namespace: "tekton-remote-resolution"},
+		Data:       map[string][]byte{"ca.crt": bundle},
+	}, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "wrong-key", Namespace: "tekton-remote-resolution"},
+		Data:       map[string][]byte{"tls.crt": bundle},
+	})
+
+	for _, tc := range []struct {
+		name        string
+		conf        map[string]string
+		expectedErr string
+	}{{
+		name: "bundle file",
+		conf: map[string]string{ConfigFieldCABundle: bundleFile},
+	}, {
+		name: "bundle secret",
+		conf: map[string]string{ConfigFieldCABundleSecret: "git-ca"},
+	}, {
+		name: "bundle with git cli",
+		conf: map[string]string{ConfigFieldCABundle: bundleFile, ConfigFieldGitProtocolVersion: GitProtocolV2},
+	}, {
+		name:        "without bundle",
+		conf:        map[string]string{},
+		expectedErr: "certificate",
+	}, {
+		name:        "invalid bundle",
+		conf:        map[string]string{ConfigFieldCABundle: invalidFile},
+		expectedErr: "no PEM encoded certificates found in ca bundle",
+	}, {
+		name:        "missing bundle file",
+		conf:        map[string]string{ConfigFieldCABundle: filepath.Join(t.TempDir(), "missing.crt")},
+		expectedErr: "error reading ca bundle",
+	}, {
+		name:        "secret without key",
+		conf:        map[string]string{ConfigFieldCABundleSecret: "wrong-key"},
+		expectedErr: `ca bundle secret tekton-remote-resolution/wrong-key has no "ca.crt" key`,
+	}, {
+		name:        "both file and secret",
+		conf:        map[string]string{ConfigFieldCABundle: bundleFile, ConfigFieldCABundleSecret: "git-ca"},
+		expectedErr: "ca-bundle and ca-bundle-secret can't both be set",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			resolver := &Resolver{kubeClientSet: kubeClient}
+			resource, err := resolver.Resolve(framework.InjectResolverConfigToContext(context.Background(), tc.conf), map[string]string{
+				URLParam:  server.URL + "/repo",
+				PathParam: "task.yaml",
+			})
+			if tc.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectedErr) {
+					t.Fatalf("expected error containing %q, received %v", tc.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error resolving with ca bundle: %v", err)
+			}
+			if string(resource.Data()) != "over private tls" {
+				t.Errorf("expected content %q received %q", "over private tls", string(resource.Data()))
+			}
+			if commit := resource.Annotations()[AnnotationKeyCommitHash]; commit != commits[0] {
+				t.Errorf("expected commit %q received %q", commits[0], commit)
+			}
+		})
+	}
+}
@@ -0,0 +1,357 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/storage/memory"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+// refParams are the params that only select which revision of a repo
+// to resolve from. They're left out of cache keys in favour of the
+// revision they point to, so that requests for a branch and for the
+// commit at its tip share a cache entry.
+var refParams = map[string]bool{
+	URLParam:      true,
+	PathParam:     true,
+	CommitParam:   true,
+	BranchParam:   true,
+	TagParam:      true,
+	RevisionParam: true,
+	LocatorParam:  true,
+}
+
+// resultCache holds resolved files keyed by the revision they were read
+// from. The zero value is ready to use and is sized on first use.
+type resultCache struct {
+	mu      sync.Mutex
+	entries *lru.Cache
+}
+
+// get returns the cached result for key, if there is one.
+func (c *resultCache) get(size int, key string) (*ResolvedGitResource, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		return nil, false
+	}
+	c.entries.Resize(size)
+	value, ok := c.entries.Get(key)
+	if !ok {
+		return nil, false
+	}
+	return value.(*ResolvedGitResource), true
+}
+
+// add stores a result under key, evicting the least recently used
+// entry if the cache is full.
+func (c *resultCache) add(size int, key string, resolved *ResolvedGitResource) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		entries, err := lru.New(size)
+		if err != nil {
+			return
+		}
+		c.entries = entries
+	}
+	c.entries.Resize(size)
+	c.entries.Add(key, resolved)
+}
+
+// getCacheSize returns the number of resolved files to cache, as
+// configured with the cache-size field in the git-resolver-config
+// configmap. Zero disables the cache.
+func getCacheSize(conf map[string]string) (int, error) {
+	sizeString, ok := conf[ConfigFieldCacheSize]
+	if !ok || sizeString == "" {
+		return 0, nil
+	}
+	size, err := strconv.Atoi(sizeString)
+	if err != nil || size < 0 {
+		return 0, fmt.Errorf("invalid %s %q: must be a non-negative integer", ConfigFieldCacheSize, sizeString)
+	}
+	return size, nil
+}
+
+// resultCacheKey returns a key identifying the result of reading path
+// from the repo at url as of revision, a commit or tag hash. Every
+// param and config field that could change the result is included.
+func resultCacheKey(url, revision, path string, params, conf map[string]string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%s\x00", url, revision, path)
+	writeSorted := func(m map[string]string, skip map[string]bool) {
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			if !skip[k] {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Fprintf(h, "%s=%s\x00", k, m[k])
+		}
+		h.Write([]byte{0})
+	}
+	writeSorted(params, refParams)
+	writeSorted(conf, nil)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// lsRemote looks up the first of candidates to exist on the remote, or
+// the remote's HEAD if there are none, without fetching any objects.
+func lsRemote(ctx context.Context, url string, auth transport.AuthMethod, candidates []plumbing.ReferenceName) (*plumbing.Reference, error) {
+	remote := git.NewRemote(memory.NewStorage(), &config.RemoteConfig{
+		Name: remoteName,
+		URLs: []string{url},
+	})
+	refs, err := remote.ListContext(ctx, &git.ListOptions{Auth: auth})
+	if err != nil {
+		return nil, fmt.Errorf("error listing remote refs: %w", err)
+	}
+	maxDepth, err := getMaxSymrefDepth(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return pickRef(refs, candidates, maxDepth)
+}
+
+// pickRef returns the first of candidates in refs, or HEAD if there are
+// no candidates, with any symbolic ref replaced by the hash it points
+// to through at most maxDepth symbolic refs.
+func pickRef(refs []*plumbing.Reference, candidates []plumbing.ReferenceName, maxDepth int) (*plumbing.Reference, error) {
+	byName := map[plumbing.ReferenceName]*plumbing.Reference{}
+	for _, ref := range refs {
+		byName[ref.Name()] = ref
+	}
+	lookup := func(name plumbing.ReferenceName) (*plumbing.Reference, error) {
+		if ref, ok := byName[name]; ok {
+			return ref, nil
+		}
+		return nil, plumbing.ErrReferenceNotFound
+	}
+	if len(candidates) == 0 {
+		candidates = []plumbing.ReferenceName{plumbing.HEAD}
+	}
+	for _, name := range candidates {
+		ref, err := resolveSymref(name, maxDepth, lookup)
+		if errors.Is(err, plumbing.ErrReferenceNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return plumbing.NewHashReference(name, ref.Hash()), nil
+	}
+	return nil, fmt.Errorf("error listing remote refs: %w", plumbing.ErrReferenceNotFound)
+}
+
+// refCacheEntry is the ref that was picked from a moving ref's
+// candidates, and the hash it pointed to, when it was listed.
+type refCacheEntry struct {
+	ref      *plumbing.Reference
+	listedAt time.Time
+}
+
+// refCache remembers the hashes that moving refs like branches pointed
+// to so that the remote doesn't need to be listed on every request.
+type refCache struct {
+	mu      sync.Mutex
+	entries map[string]refCacheEntry
+}
+
+// get returns the ref remembered for key, if it was listed less than
+// ttl before now.
+func (c *refCache) get(now time.Time, ttl time.Duration, key string) (*plumbing.Reference, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || now.Sub(entry.listedAt) >= ttl {
+		return nil, false
+	}
+	return entry.ref, true
+}
+
+// add remembers that key was listed as ref at now, dropping any entries
+// that were listed ttl or longer before it.
+func (c *refCache) add(now time.Time, ttl time.Duration, key string, ref *plumbing.Reference) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = map[string]refCacheEntry{}
+	}
+	for k, entry := range c.entries {
+		if now.Sub(entry.listedAt) >= ttl {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = refCacheEntry{ref: ref, listedAt: now}
+}
+
+// resolveRevision returns the first of candidates to exist on the
+// remote along with the hash it points to. Hashes are reused for up to
+// ref-cache-ttl, unless they were listed longer ago than
+// max-ref-staleness, after which the remote is listed again.
+func (r *Resolver) resolveRevision(ctx context.Context, conf map[string]string, remoteURL, url string, auth transport.AuthMethod, candidates []plumbing.ReferenceName) (*plumbing.Reference, error) {
+	ttl, err := getDuration(conf, ConfigFieldRefCacheTTL)
+	if err != nil {
+		return nil, err
+	}
+	staleness, err := getDuration(conf, ConfigFieldMaxRefStaleness)
+	if err != nil {
+		return nil, err
+	}
+	if staleness > 0 && staleness < ttl {
+		ttl = staleness
+	}
+
+	names := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		names = append(names, c.String())
+	}
+	key := remoteURL + "\x00" + strings.Join(names, "\x00")
+	now := r.now()
+
+	if ref, ok := r.refs.get(now, ttl, key); ok {
+		return ref, nil
+	}
+
+	var ref *plumbing.Reference
+	protocolV2 := useProtocolV2(conf, auth)
+	if protocolV2 {
+		ref, err = lsRemoteWithCLI(ctx, url, candidates)
+	}
+	if !protocolV2 || isGitNotInstalled(err) {
+		ref, err = lsRemote(ctx, url, auth, candidates)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if ttl > 0 {
+		r.refs.add(now, ttl, key, ref)
+	}
+	return ref, nil
+}
+
+// getDuration parses the duration in the given config field. Unset
+// fields are zero.
+func getDuration(conf map[string]string, field string) (time.Duration, error) {
+	durationString, ok := conf[field]
+	if !ok || durationString == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(durationString)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q: %w", field, durationString, err)
+	}
+	return d, nil
+}
+
+// maxNegativeCacheTTL caps negative-cache-ttl so that a missing file or
+// ref isn't still reported missing long after it's been pushed.
+const maxNegativeCacheTTL = time.Minute
+
+// getNegativeCacheTTL returns how long requests that failed because a
+// file or ref doesn't exist are answered with the same failure, as
+// configured with the negative-cache-ttl field in the
+// git-resolver-config configmap. Zero disables the negative cache.
+func getNegativeCacheTTL(conf map[string]string) (time.Duration, error) {
+	ttl, err := getDuration(conf, ConfigFieldNegativeCacheTTL)
+	if err != nil {
+		return 0, err
+	}
+	if ttl > maxNegativeCacheTTL {
+		return 0, fmt.Errorf("invalid %s %q: must be at most %s", ConfigFieldNegativeCacheTTL, conf[ConfigFieldNegativeCacheTTL], maxNegativeCacheTTL)
+	}
+	return ttl, nil
+}
+
+// missCache remembers requests that failed because the file or ref they
+// asked for doesn't exist, so that clients repeating them don't cause a
+// clone every time.
+type missCache struct {
+	mu      sync.Mutex
+	entries map[string]missCacheEntry
+}
+
+// missCacheEntry is the error a request failed with and when it may
+// be retried.
+type missCacheEntry struct {
+	err     error
+	expires time.Time
+}
+
+// get returns the error that the request identified by key failed with,
+// if it failed recently enough.
+func (c *missCache) get(now time.Time, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || !now.Before(entry.expires) {
+		return nil
+	}
+	return entry.err
+}
+
+// add remembers that the request identified by key failed with err for
+// ttl, dropping any entries that have expired.
+func (c *missCache) add(now time.Time, ttl time.Duration, key string, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = map[string]missCacheEntry{}
+	}
+	for k, entry := range c.entries {
+		if !now.Before(entry.expires) {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = missCacheEntry{err: err, expires: now.Add(ttl)}
+}
+
+// missCacheKey returns a key identifying a request for path from the
+// repo at url at the given commit or refs. Unlike resultCacheKey the
+// refs aren't looked up, so that a miss can be answered without
+// contacting the remote.
+func missCacheKey(url, commit string, candidates []plumbing.ReferenceName, path string, params, conf map[string]string) string {
+	names := []string{commit}
+	for _, c := range candidates {
+		names = append(names, c.String())
+	}
+	return resultCacheKey(url, strings.Join(names, "\x00"), path, params, conf)
+}
+
+// isNotFound returns true if err is because the requested file or ref
+// doesn't exist.
+func isNotFound(err error) bool {
+	return isFileMissing(err) || isRefNotFound(err) || isCLIRefNotFound(err)
+}
@@ -0,0 +1,293 @@
+package git
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/tektoncd/resolution/pkg/resolver/framework"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+// newCountingGitHTTPServer serves repoDir over http and counts the
+// fetches made from it. Listing refs isn't counted.
+func newCountingGitHTTPServer(t *testing.T, repoDir string) (string, *int32) {
+	t.Helper()
+	var fetches int32
+	server := newGitHTTPServer(t, repoDir, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/git-upload-pack") {
+				atomic.AddInt32(&fetches, 1)
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+	return server.URL + "/repo", &fetches
+}
+
+func TestResolveCacheFollowsBranchTip(t *testing.T) {
+	repoDir, commits := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "one"},
+	}, {
+		files: map[string]string{"task.yaml": "two"},
+	}})
+	setTestRef(t, repoDir, "refs/heads/feature", commits[0])
+	repoURL, fetches := newCountingGitHTTPServer(t, repoDir)
+
+	ctx := framework.InjectResolverConfigToContext(context.Background(), map[string]string{
+		ConfigFieldCacheSize: "10",
+	})
+	params := map[string]string{
+		URLParam:    repoURL,
+		PathParam:   "task.yaml",
+		BranchParam: "feature",
+	}
+	resolver := &Resolver{}
+
+	for i := 0; i < 2; i++ {
+		resource, err := resolver.Resolve(ctx, params)
+		if err != nil {
+			t.Fatalf("unexpected error resolving: %v", err)
+		}
+		if string(resource.Data()) != "one" {
+			t.Errorf("expected content %q received %q", "one", string(resource.Data()))
+		}
+	}
+	if n := atomic.LoadInt32(fetches); n != 1 {
+		t.Errorf("expected unchanged branch tip to be served from the cache, fetched %d times", n)
+	}
+
+	setTestRef(t, repoDir, "refs/heads/feature", commits[1])
+	moved, err := resolver.Resolve(ctx, params)
+	if err != nil {
+		t.Fatalf("unexpected error resolving: %v", err)
+	}
+	if n := atomic.LoadInt32(fetches); n != 2 {
+		t.Fatalf("expected moved branch tip to miss the cache, fetched %d times", n)
+	}
+	if string(moved.Data()) != "two" {
+		t.Errorf("expected content from new tip %q received %q", "two", string(moved.Data()))
+	}
+	if commit := moved.Annotations()[AnnotationKeyCommitHash]; commit != commits[1] {
+		t.Errorf("expected commit %q received %q", commits[1], commit)
+	}
+
+	byCommit, err := resolver.Resolve(ctx, map[string]string{
+		URLParam:    repoURL,
+		PathParam:   "task.yaml",
+		CommitParam: commits[1],
+	})
+	if err != nil {
+		t.Fatalf("unexpected error resolving: %v", err)
+	}
+	if n := atomic.LoadInt32(fetches); n != 2 {
+		t.Errorf("expected request by commit to share the cache entry of its branch, fetched %d times", n)
+	}
+	metadata := byCommit.(*ResolvedGitResource).Metadata()
+	if metadata.Ref != "" || metadata.Commit != commits[1] {
+		t.Errorf("expected cached result to carry the ref of the request, received %+v", metadata)
+	}
+}
+
+func TestResolveRefCacheStaleness(t *testing.T) {
+	repoDir, commits := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "one"},
+	}, {
+		files: map[string]string{"task.yaml": "two"},
+	}})
+	setTestRef(t, repoDir, "refs/heads/feature", commits[0])
+
+	ctx := framework.InjectResolverConfigToContext(context.Background(), map[string]string{
+		ConfigFieldCacheSize:       "10",
+		ConfigFieldRefCacheTTL:     "1h",
+		ConfigFieldMaxRefStaleness: "5m",
+	})
+	params := map[string]string{
+		URLParam:    repoDir,
+		PathParam:   "task.yaml",
+		BranchParam: "feature",
+	}
+	fakeClock := clocktesting.NewFakePassiveClock(time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC))
+	resolver := &Resolver{clock: fakeClock}
+
+	if _, err := resolver.Resolve(ctx, params); err != nil {
+		t.Fatalf("unexpected error resolving: %v", err)
+	}
+	setTestRef(t, repoDir, "refs/heads/feature", commits[1])
+
+	fakeClock.SetTime(fakeClock.Now().Add(time.Minute))
+	fresh, err := resolver.Resolve(ctx, params)
+	if err != nil {
+		t.Fatalf("unexpected error resolving: %v", err)
+	}
+	if commit := fresh.Annotations()[AnnotationKeyCommitHash]; commit != commits[0] {
+		t.Errorf("expected remembered commit %q within staleness window, received %q", commits[0], commit)
+	}
+
+	fakeClock.SetTime(fakeClock.Now().Add(5 * time.Minute))
+	stale, err := resolver.Resolve(ctx, params)
+	if err != nil {
+		t.Fatalf("unexpected error resolving: %v", err)
+	}
+	if commit := stale.Annotations()[AnnotationKeyCommitHash]; commit != commits[1] {
+		t.Errorf("expected stale ref to be refreshed to %q within ttl, received %q", commits[1], commit)
+	}
+	if string(stale.Data()) != "two" {
+		t.Errorf("expected content from refreshed tip %q received %q", "two", string(stale.Data()))
+	}
+}
+
+func TestRefCacheEvictsExpired(t *testing.T) {
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	ref := plumbing.NewHashReference("refs/heads/main", plumbing.NewHash("8ba78f6474fb786924207001fb12d82439e32fad"))
+	cache := &refCache{}
+	cache.add(now, time.Minute, "first", ref)
+	cache.add(now.Add(30*time.Second), time.Minute, "second", ref)
+	if _, ok := cache.get(now.Add(30*time.Second), time.Minute, "first"); !ok {
+		t.Errorf("expected a ref listed within the ttl to be remembered")
+	}
+
+	cache.add(now.Add(time.Minute), time.Minute, "third", ref)
+	if _, ok := cache.entries["first"]; ok {
+		t.Errorf("expected the expired entry to be dropped")
+	}
+	if len(cache.entries) != 2 {
+		t.Errorf("expected 2 unexpired entries, found %d", len(cache.entries))
+	}
+	if _, ok := cache.get(now.Add(time.Minute), time.Minute, "second"); !ok {
+		t.Errorf("expected an unexpired entry to be kept")
+	}
+}
+
+func TestResolveCacheDisabled(t *testing.T) {
+	repoDir, _ := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "one"},
+	}})
+	repoURL, fetches := newCountingGitHTTPServer(t, repoDir)
+	params := map[string]string{
+		URLParam:  repoURL,
+		PathParam: "task.yaml",
+	}
+	resolver := &Resolver{}
+	for i := 0; i < 2; i++ {
+		if _, err := resolver.Resolve(context.Background(), params); err != nil {
+			t.Fatalf("unexpected error resolving: %v", err)
+		}
+	}
+	if n := atomic.LoadInt32(fetches); n != 2 {
+		t.Errorf("expected no caching without %s, fetched %d times", ConfigFieldCacheSize, n)
+	}
+}
+
+func TestResultCacheKeyIncludesOptions(t *testing.T) {
+	base := resultCacheKey("repo", "abc", "task.yaml", map[string]string{BranchParam: "main"}, map[string]string{})
+	if key := resultCacheKey("repo", "abc", "task.yaml", map[string]string{CommitParam: "abc"}, map[string]string{}); key != base {
+		t.Errorf("expected ref params to be left out of the key")
+	}
+	if key := resultCacheKey("repo", "abc", "task.yaml", map[string]string{BlameParam: "true"}, map[string]string{}); key == base {
+		t.Errorf("expected blame param to change the key")
+	}
+	if key := resultCacheKey("repo", "abc", "task.yaml", map[string]string{}, map[string]string{ConfigFieldComputeOCIDigest: "true"}); key == base {
+		t.Errorf("expected config to change the key")
+	}
+}
+
+func TestGetCacheSizeInvalid(t *testing.T) {
+	for _, size := range []string{"-1", "lots"} {
+		if _, err := getCacheSize(map[string]string{ConfigFieldCacheSize: size}); err == nil {
+			t.Errorf("expected error for cache size %q", size)
+		}
+	}
+}
+
+func TestResolveNegativeCache(t *testing.T) {
+	repoDir, _ := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "kind: Task"},
+	}})
+	var requests int32
+	server := newGitHTTPServer(t, repoDir, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&requests, 1)
+			next.ServeHTTP(w, r)
+		})
+	})
+
+	for _, tc := range []struct {
+		name   string
+		params map[string]string
+	}{{
+		name:   "missing file",
+		params: map[string]string{URLParam: server.URL + "/repo", PathParam: "missing.yaml"},
+	}, {
+		name:   "missing branch",
+		params: map[string]string{URLParam: server.URL + "/repo", PathParam: "task.yaml", BranchParam: "missing"},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := framework.InjectResolverConfigToContext(context.Background(), map[string]string{
+				ConfigFieldNegativeCacheTTL: "5s",
+			})
+			fakeClock := clocktesting.NewFakePassiveClock(time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC))
+			resolver := &Resolver{clock: fakeClock}
+
+			if _, err := resolver.Resolve(ctx, tc.params); err == nil {
+				t.Fatalf("expected error resolving %s", tc.name)
+			}
+			fetched := atomic.LoadInt32(&requests)
+			_, err := resolver.Resolve(ctx, tc.params)
+			if err == nil {
+				t.Fatalf("expected remembered error resolving %s again", tc.name)
+			}
+			if !isNotFound(err) {
+				t.Errorf("expected remembered error to be a not found error, received %v", err)
+			}
+			if n := atomic.LoadInt32(&requests); n != fetched {
+				t.Errorf("expected repeated request within the ttl not to contact the remote, made %d requests", n-fetched)
+			}
+
+			fakeClock.SetTime(fakeClock.Now().Add(5 * time.Second))
+			if _, err := resolver.Resolve(ctx, tc.params); err == nil {
+				t.Fatalf("expected error resolving %s after the ttl", tc.name)
+			}
+			if n := atomic.LoadInt32(&requests); n == fetched {
+				t.Errorf("expected request after the ttl to contact the remote")
+			}
+		})
+	}
+}
+
+func TestResolveNegativeCacheKey(t *testing.T) {
+	repoDir, _ := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "kind: Task"},
+	}})
+	ctx := framework.InjectResolverConfigToContext(context.Background(), map[string]string{
+		ConfigFieldNegativeCacheTTL: "5s",
+	})
+	resolver := &Resolver{}
+	if _, err := resolver.Resolve(ctx, map[string]string{URLParam: repoDir, PathParam: "missing.yaml"}); err == nil {
+		t.Fatalf("expected error resolving a missing file")
+	}
+	resource, err := resolver.Resolve(ctx, map[string]string{URLParam: repoDir, PathParam: "task.yaml"})
+	if err != nil {
+		t.Fatalf("expected a different path not to be failed by the negative cache: %v", err)
+	}
+	if string(resource.Data()) != "kind: Task" {
+		t.Errorf("expected content %q received %q", "kind: Task", string(resource.Data()))
+	}
+	resource, err = resolver.Resolve(ctx, map[string]string{URLParam: repoDir, PathParam: "missing.yaml", OnMissingParam: OnMissingEmpty})
+	if err != nil {
+		t.Fatalf("expected different params not to be failed by the negative cache: %v", err)
+	}
+	if len(resource.Data()) != 0 {
+		t.Errorf("expected empty default content received %q", string(resource.Data()))
+	}
+}
+
+func TestGetNegativeCacheTTLTooLong(t *testing.T) {
+	if _, err := getNegativeCacheTTL(map[string]string{ConfigFieldNegativeCacheTTL: "1h"}); err == nil {
+		t.Errorf("expected error for a negative cache ttl over %s", maxNegativeCacheTTL)
+	}
+}
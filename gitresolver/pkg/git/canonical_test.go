@@ -0,0 +1,114 @@
+package git
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tektoncd/resolution/pkg/resolver/framework"
+)
+
+const (
+	compactYAML = `kind: Task
+metadata: {name: build, labels: {b: "2", a: "1"}}
+apiVersion: tekton.dev/v1beta1
+`
+	expandedYAML = `# a comment that is dropped
+apiVersion:   tekton.dev/v1beta1
+kind: Task
+metadata:
+    labels:
+        a: "1"
+        b: "2"
+    name: build
+`
+)
+
+func TestCanonicalizeYAML(t *testing.T) {
+	compact, err := canonicalizeYAML([]byte(compactYAML))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expanded, err := canonicalizeYAML([]byte(expandedYAML))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(compact) != string(expanded) {
+		t.Errorf("expected identical canonical forms, received:\n%s\nand:\n%s", compact, expanded)
+	}
+	expected := `apiVersion: tekton.dev/v1beta1
+kind: Task
+metadata:
+  labels:
+    a: "1"
+    b: "2"
+  name: build
+`
+	if string(compact) != expected {
+		t.Errorf("expected:\n%s\nreceived:\n%s", expected, compact)
+	}
+}
+
+func TestCanonicalizeYAMLMultipleDocuments(t *testing.T) {
+	canonical, err := canonicalizeYAML([]byte("b: 2\na: 1\n---\nd: 4\nc: 3\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "a: 1\nb: 2\n---\nc: 3\nd: 4\n"
+	if string(canonical) != expected {
+		t.Errorf("expected %q received %q", expected, string(canonical))
+	}
+}
+
+func TestResolveCanonicalizeYAML(t *testing.T) {
+	repoDir, _ := createTestRepo(t, []testCommit{{
+		files: map[string]string{
+			"compact.yaml":  compactYAML,
+			"expanded.yaml": expandedYAML,
+			"script.sh":     "echo: [unterminated",
+		},
+	}})
+	ctx := framework.InjectResolverConfigToContext(context.Background(), map[string]string{
+		ConfigFieldCanonicalizeYAML: "true",
+		ConfigFieldComputeOCIDigest: "true",
+	})
+	resolver := &Resolver{}
+	digests := []string{}
+	for _, path := range []string{"compact.yaml", "expanded.yaml"} {
+		resource, err := resolver.Resolve(ctx, map[string]string{URLParam: repoDir, PathParam: path})
+		if err != nil {
+			t.Fatalf("unexpected error resolving %q: %v", path, err)
+		}
+		if resource.Annotations()[AnnotationKeyCanonicalized] != "true" {
+			t.Errorf("expected canonicalized annotation on %q", path)
+		}
+		digests = append(digests, resource.Annotations()[AnnotationKeyOCIDigest])
+	}
+	if digests[0] != digests[1] {
+		t.Errorf("expected equal digests for equivalent yaml, received %v", digests)
+	}
+
+	if _, err := resolver.Resolve(ctx, map[string]string{URLParam: repoDir, PathParam: "script.sh"}); err == nil {
+		t.Errorf("expected error canonicalizing invalid yaml")
+	}
+}
+
+func TestResolveCanonicalizeYAMLSkipInvalid(t *testing.T) {
+	repoDir, _ := createTestRepo(t, []testCommit{{
+		files: map[string]string{"script.sh": "echo: [unterminated"},
+	}})
+	ctx := framework.InjectResolverConfigToContext(context.Background(), map[string]string{
+		ConfigFieldCanonicalizeYAML:            "true",
+		ConfigFieldCanonicalizeYAMLSkipInvalid: "true",
+	})
+	resolver := &Resolver{}
+	resource, err := resolver.Resolve(ctx, map[string]string{URLParam: repoDir, PathParam: "script.sh"})
+	if err != nil {
+		t.Fatalf("unexpected error resolving: %v", err)
+	}
+	if string(resource.Data()) != "echo: [unterminated" {
+		t.Errorf("expected invalid yaml to be returned unchanged, received %q", string(resource.Data()))
+	}
+	if _, has := resource.Annotations()[AnnotationKeyCanonicalized]; has {
+		t.Errorf("expected no canonicalized annotation on skipped file")
+	}
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// parseCatalog parses the catalog configured with the catalog field in
+// the git-resolver-config configmap: one entry per line, or separated
+// by commas, of a short name and the locator it stands for, like
+// git-clone@0.9=github.com/tektoncd/catalog@main//task/git-clone/0.9/git-clone.yaml
+func parseCatalog(conf map[string]string) (map[string]string, error) {
+	catalog := map[string]string{}
+	for _, entry := range strings.FieldsFunc(conf[ConfigFieldCatalog], func(r rune) bool {
+		return r == '\n' || r == ','
+	}) {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, loc, ok := strings.Cut(entry, "=")
+		name, loc = strings.TrimSpace(name), strings.TrimSpace(loc)
+		if !ok || name == "" || loc == "" {
+			return nil, fmt.Errorf("invalid %s entry %q: expected <name>=<locator>", ConfigFieldCatalog, entry)
+		}
+		if _, err := parseLocator(loc); err != nil {
+			return nil, fmt.Errorf("invalid %s entry %q: %w", ConfigFieldCatalog, name, err)
+		}
+		if _, dup := catalog[name]; dup {
+			return nil, fmt.Errorf("invalid %s: entry %q is listed more than once", ConfigFieldCatalog, name)
+		}
+		catalog[name] = loc
+	}
+	return catalog, nil
+}
+
+// expandCatalogRef returns params with a CatalogRefParam replaced by
+// the locator that it names in the configured catalog, so that the
+// rest of a resolution is the same as for a locator. Params without a
+// CatalogRefParam are returned as they are.
+func expandCatalogRef(conf map[string]string, params map[string]string) (map[string]string, error) {
+	name := params[CatalogRefParam]
+	if name == "" {
+		return params, nil
+	}
+	catalog, err := parseCatalog(conf)
+	if err != nil {
+		return nil, err
+	}
+	loc, ok := catalog[name]
+	if !ok {
+		if len(catalog) == 0 {
+			return nil, fmt.Errorf("unknown %s %q: no %s is configured", CatalogRefParam, name, ConfigFieldCatalog)
+		}
+		available := make([]string, 0, len(catalog))
+		for n := range catalog {
+			available = append(available, n)
+		}
+		sort.Strings(available)
+		return nil, fmt.Errorf("unknown %s %q: available entries are %s", CatalogRefParam, name, strings.Join(available, ", "))
+	}
+	expanded := make(map[string]string, len(params))
+	for k, v := range params {
+		if k != CatalogRefParam {
+			expanded[k] = v
+		}
+	}
+	expanded[LocatorParam] = loc
+	return expanded, nil
+}
@@ -0,0 +1,98 @@
+package git
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/tektoncd/resolution/pkg/resolver/framework"
+)
+
+func TestResolveCatalogRef(t *testing.T) {
+	repoDir, commits := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task/git-clone/0.8/git-clone.yaml": "old"},
+	}, {
+		files: map[string]string{"task/git-clone/0.9/git-clone.yaml": "new"},
+	}})
+	ctx := framework.InjectResolverConfigToContext(context.Background(), map[string]string{
+		ConfigFieldCatalog: "git-clone@0.8=file://" + repoDir + "@" + commits[0] + "//task/git-clone/0.8/git-clone.yaml\n" +
+			"git-clone@0.9=file://" + repoDir + "//task/git-clone/0.9/git-clone.yaml\n",
+	})
+
+	for _, tc := range []struct {
+		name     string
+		expected string
+	}{{
+		name:     "git-clone@0.8",
+		expected: "old",
+	}, {
+		name:     "git-clone@0.9",
+		expected: "new",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			params := map[string]string{CatalogRefParam: tc.name}
+			resolver := Resolver{}
+			if err := resolver.ValidateParams(ctx, params); err != nil {
+				t.Fatalf("unexpected error validating params: %v", err)
+			}
+			resource, err := resolver.Resolve(ctx, params)
+			if err != nil {
+				t.Fatalf("unexpected error resolving: %v", err)
+			}
+			if string(resource.Data()) != tc.expected {
+				t.Errorf("expected content %q received %q", tc.expected, resource.Data())
+			}
+		})
+	}
+}
+
+func TestResolveCatalogRefUnknown(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		catalog     string
+		expectedErr string
+	}{{
+		name:        "unknown entry",
+		catalog:     "git-clone@0.9=github.com/tektoncd/catalog//task/git-clone/0.9/git-clone.yaml,buildah@0.5=github.com/tektoncd/catalog//task/buildah/0.5/buildah.yaml",
+		expectedErr: `unknown catalogRef "kaniko@0.6": available entries are buildah@0.5, git-clone@0.9`,
+	}, {
+		name:        "no catalog",
+		expectedErr: `unknown catalogRef "kaniko@0.6": no catalog is configured`,
+	}, {
+		name:        "invalid entry",
+		catalog:     "kaniko@0.6",
+		expectedErr: `invalid catalog entry "kaniko@0.6": expected <name>=<locator>`,
+	}, {
+		name:        "invalid locator",
+		catalog:     "kaniko@0.6=github.com/tektoncd/catalog",
+		expectedErr: `invalid catalog entry "kaniko@0.6"`,
+	}, {
+		name:        "duplicate entry",
+		catalog:     "kaniko@0.6=a.com/b//c.yaml\nkaniko@0.6=a.com/b//d.yaml",
+		expectedErr: `entry "kaniko@0.6" is listed more than once`,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := framework.InjectResolverConfigToContext(context.Background(), map[string]string{
+				ConfigFieldCatalog: tc.catalog,
+			})
+			resolver := Resolver{}
+			_, err := resolver.Resolve(ctx, map[string]string{CatalogRefParam: "kaniko@0.6"})
+			if err == nil || !strings.Contains(err.Error(), tc.expectedErr) {
+				t.Errorf("expected error containing %q, received %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateParamsCatalogRefConflicts(t *testing.T) {
+	for _, p := range []string{LocatorParam, URLParam, PathParam, CommitParam, BranchParam} {
+		resolver := Resolver{}
+		err := resolver.ValidateParams(context.Background(), map[string]string{
+			CatalogRefParam: "git-clone@0.9",
+			p:               "value",
+		})
+		if err == nil || !strings.Contains(err.Error(), CatalogRefParam) {
+			t.Errorf("expected error supplying both %q and %q, received %v", CatalogRefParam, p, err)
+		}
+	}
+}
@@ -0,0 +1,250 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-billy/v5/osfs"
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/tektoncd/resolution/pkg/resolver/framework"
+)
+
+// GitProtocolV2 is the git-protocol-version that fetches with git wire
+// protocol v2. Go-git only speaks v0 and v1, so these fetches use the
+// git binary.
+const GitProtocolV2 = "2"
+
+// gitBinary is the git executable used by the cli fallback. It's a
+// variable so that tests can substitute a stub.
+var gitBinary = "git"
+
+// nonInteractiveEnv returns the environment to run git commands with.
+// A resolver has no terminal and nobody to answer prompts, so anything
+// that might ask for credentials (terminal prompts, askpass programs,
+// credential managers or ssh passphrases) is disabled and fails fast
+// instead of blocking until the resolution times out.
+func nonInteractiveEnv() []string {
+	return append(os.Environ(),
+		"GIT_TERMINAL_PROMPT=0",
+		"GIT_ASKPASS=",
+		"SSH_ASKPASS=",
+		"GCM_INTERACTIVE=never",
+		"GIT_SSH_COMMAND=ssh -o BatchMode=yes",
+	)
+}
+
+// getGitProtocolVersion returns the git wire protocol version configured
+// with the git-protocol-version field in the git-resolver-config
+// configmap. Empty means go-git's default.
+func getGitProtocolVersion(conf map[string]string) (string, error) {
+	switch version := conf[ConfigFieldGitProtocolVersion]; version {
+	case "", "0", "1", GitProtocolV2:
+		return version, nil
+	default:
+		return "", fmt.Errorf("invalid %s %q: expected 0, 1 or 2", ConfigFieldGitProtocolVersion, version)
+	}
+}
+
+// useProtocolV2 returns true if fetches should use the git binary to
+// speak protocol v2. The git binary can't use the credentials that
+// go-git is given, so fetches with auth keep using go-git.
+func useProtocolV2(conf map[string]string, auth transport.AuthMethod) bool {
+	version, _ := getGitProtocolVersion(conf)
+	return version == GitProtocolV2 && auth == nil
+}
+
+// isGitNotInstalled returns true if err is from running a git binary
+// that isn't on the resolver's PATH.
+func isGitNotInstalled(err error) bool {
+	return errors.Is(err, exec.ErrNotFound)
+}
+
+// runGit runs the git binary non-interactively with the given
+// arguments, returning its stdout and including stderr in any error.
+// The git-protocol-version in ctx's config is passed on to git.
+func runGit(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	command := args[0]
+	// Credential helpers are cleared since some of them prompt.
+	config := []string{"-c", "credential.helper="}
+	if version, _ := getGitProtocolVersion(framework.GetResolverConfigFromContext(ctx)); version != "" {
+		config = append(config, "-c", "protocol.version="+version)
+	}
+	args = append(config, args...)
+	caEnv, cleanup, err := caBundleGitEnv(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+	// #nosec G204 -- the binary is fixed and arguments are passed without a shell.
+	cmd := exec.CommandContext(ctx, gitBinary, args...)
+	cmd.Dir = dir
+	cmd.Env = append(append(nonInteractiveEnv(), proxyEnv(framework.GetResolverConfigFromContext(ctx))...), caEnv...)
+	cmd.Stdin = nil
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("git %s: %w: %s", command, err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// cloneWithCLI clones the repository at url with the git binary into a
+// temporary directory and opens it with go-git. This is a fallback for
+// remotes that go-git can't talk to, e.g. because of unsupported
+// protocol capabilities. A configured alternates repository is passed
+// as a reference, which git only ever reads from. Tags, and refs that
+// aren't branches like a pull request's head, are fetched separately
+// after cloning the default branch, since git clone --branch prefers a
+// branch if one has the same name and can't clone other refs at all.
+//
+// If shallow-since is configured only history since that date is
+// cloned. A ref whose tip is older is cloned in full instead, and the
+// clone is deepened if the given commit isn't in the shallow history.
+// A clone depth in ctx limits the history to that many commits.
+func cloneWithCLI(ctx context.Context, url string, ref plumbing.ReferenceName, commit string) (*fetchedRepository, error) {
+	dir, err := os.MkdirTemp("", "git-resolver-clone-")
+	if err != nil {
+		return nil, fmt.Errorf("error creating clone directory: %w", err)
+	}
+	cleanup := func() { os.RemoveAll(dir) }
+
+	conf := framework.GetResolverConfigFromContext(ctx)
+	since, err := getShallowSince(conf)
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+	var shallowArgs []string
+	if !since.IsZero() {
+		shallowArgs = []string{"--shallow-since=" + since.Format(time.RFC3339)}
+	}
+	if depth := getCloneDepthFromContext(ctx); depth > 0 {
+		shallowArgs = append(shallowArgs, "--depth", strconv.Itoa(depth))
+	}
+
+	args := []string{"clone", "--quiet", "--no-checkout"}
+	if alternatesDir := conf[ConfigFieldAlternatesDir]; alternatesDir != "" {
+		args = append(args, "--reference-if-able", alternatesDir)
+	}
+	args = append(args, shallowArgs...)
+	fetchRef := ref != "" && !ref.IsBranch()
+	switch {
+	case fetchRef:
+		args = append(args, "--single-branch", "--no-tags")
+	case ref != "":
+		args = append(args, "--single-branch", "--branch", ref.Short())
+	}
+	args = append(args, "--", url, dir)
+	if _, err := runGit(ctx, "", args...); err != nil {
+		cleanup()
+		if isShallowSinceEmpty(err) {
+			return cloneWithCLI(withFullHistory(ctx), url, ref, commit)
+		}
+		return nil, err
+	}
+	if fetchRef {
+		refSpec := fmt.Sprintf("+%s:%[1]s", ref)
+		fetchArgs := append([]string{"fetch", "--quiet", "--no-tags"}, shallowArgs...)
+		if _, err := runGit(ctx, dir, append(fetchArgs, remoteName, refSpec)...); err != nil {
+			cleanup()
+			if isShallowSinceEmpty(err) {
+				return cloneWithCLI(withFullHistory(ctx), url, ref, commit)
+			}
+			return nil, err
+		}
+	}
+	if len(shallowArgs) > 0 && commit != "" {
+		if _, err := runGit(ctx, dir, "cat-file", "-e", commit+"^{commit}"); err != nil {
+			if _, err := runGit(ctx, dir, "fetch", "--quiet", "--unshallow", remoteName); err != nil {
+				cleanup()
+				return nil, fmt.Errorf("error deepening shallow clone: %w", err)
+			}
+		}
+	}
+
+	if err := checkClonedSize(ctx, dir); err != nil {
+		cleanup()
+		return nil, err
+	}
+
+	repository, err := git.PlainOpen(dir)
+	if err != nil {
+		cleanup()
+		return nil, fmt.Errorf("error opening cloned repository: %w", err)
+	}
+	headName := plumbing.HEAD
+	if fetchRef {
+		headName = ref
+	}
+	headRef, err := resolveRepositoryRef(ctx, repository, headName)
+	if err != nil {
+		cleanup()
+		return nil, fmt.Errorf("error reading repository %s value: %w", headName, err)
+	}
+	head, err := peelToCommit(repository, headRef.Hash())
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+	return &fetchedRepository{
+		repository: repository,
+		filesystem: osfs.New(dir),
+		head:       head,
+		ref:        ref,
+		cli:        true,
+		cleanup:    cleanup,
+	}, nil
+}
+
+// isCLIRefNotFound returns true if err is from cloning a branch or
+// fetching a tag with the git binary that the remote doesn't have.
+func isCLIRefNotFound(err error) bool {
+	return err != nil && (strings.Contains(err.Error(), "not found in upstream") || strings.Contains(err.Error(), "couldn't find remote ref"))
+}
+
+// lsRemoteWithCLI looks up the first of candidates to exist on the
+// remote, or the remote's HEAD if there are none, with the git binary.
+func lsRemoteWithCLI(ctx context.Context, url string, candidates []plumbing.ReferenceName) (*plumbing.Reference, error) {
+	out, err := runGit(ctx, "", "ls-remote", "--", url)
+	if err != nil {
+		return nil, fmt.Errorf("error listing remote refs: %w", err)
+	}
+	refs := []*plumbing.Reference{}
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 {
+			refs = append(refs, plumbing.NewHashReference(plumbing.ReferenceName(fields[1]), plumbing.NewHash(fields[0])))
+		}
+	}
+	maxDepth, err := getMaxSymrefDepth(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return pickRef(refs, candidates, maxDepth)
+}
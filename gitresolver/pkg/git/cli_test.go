@@ -0,0 +1,256 @@
+package git
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/tektoncd/resolution/pkg/resolver/framework"
+)
+
+func TestNonInteractiveEnv(t *testing.T) {
+	env := strings.Join(nonInteractiveEnv(), "\n")
+	for _, expected := range []string{"GIT_TERMINAL_PROMPT=0", "GIT_ASKPASS=", "SSH_ASKPASS="} {
+		if !strings.Contains(env, expected) {
+			t.Errorf("expected %q in git environment", expected)
+		}
+	}
+}
+
+func TestCloneWithCLI(t *testing.T) {
+	repoDir, commits := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "one"},
+	}, {
+		files: map[string]string{"task.yaml": "two"},
+	}})
+	setTestRef(t, repoDir, "refs/heads/feature", commits[0])
+
+	fetched, err := cloneWithCLI(context.Background(), repoDir, plumbing.NewBranchReferenceName("feature"), "")
+	if err != nil {
+		t.Fatalf("unexpected error cloning with git cli: %v", err)
+	}
+	defer fetched.cleanup()
+	if fetched.head.String() != commits[0] {
+		t.Errorf("expected head %q received %q", commits[0], fetched.head)
+	}
+}
+
+func TestResolveCLIFallbackAuthFailsFast(t *testing.T) {
+	repoDir, _ := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "secret"},
+	}})
+	server := newGitHTTPServer(t, repoDir, func(http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("WWW-Authenticate", `Basic realm="git"`)
+			w.WriteHeader(http.StatusUnauthorized)
+		})
+	})
+
+	ctx := framework.InjectResolverConfigToContext(context.Background(), map[string]string{
+		ConfigFieldGitCLIFallback: "true",
+	})
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	resolver := Resolver{}
+	start := time.Now()
+	_, err := resolver.Resolve(ctx, map[string]string{
+		URLParam:  server.URL + "/repo",
+		PathParam: "task.yaml",
+	})
+	elapsed := time.Since(start)
+	if err == nil {
+		t.Fatalf("expected error resolving from a repo requiring auth")
+	}
+	if !strings.Contains(err.Error(), "terminal prompts disabled") {
+		t.Errorf("expected git cli to refuse to prompt, received %q", err.Error())
+	}
+	if elapsed > 10*time.Second {
+		t.Errorf("expected auth failure to return promptly, took %s", elapsed)
+	}
+}
+
+func TestResolveCLIFallbackOverHTTP(t *testing.T) {
+	repoDir, commits := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "over http"},
+	}})
+	server := newGitHTTPServer(t, repoDir, nil)
+
+	ctx := framework.InjectResolverConfigToContext(context.Background(), map[string]string{
+		ConfigFieldGitCLIFallback: "true",
+	})
+	resolver := Resolver{}
+	resource, err := resolver.Resolve(ctx, map[string]string{
+		URLParam:  server.URL + "/repo",
+		PathParam: "task.yaml",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error resolving over http: %v", err)
+	}
+	if string(resource.Data()) != "over http" {
+		t.Errorf("expected content %q received %q", "over http", string(resource.Data()))
+	}
+	if resource.Annotations()[AnnotationKeyCommitHash] != commits[0] {
+		t.Errorf("expected commit %q received %q", commits[0], resource.Annotations()[AnnotationKeyCommitHash])
+	}
+}
+
+func TestResolveCLIFallbackWithAuth(t *testing.T) {
+	repoDir, _ := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "secret"},
+	}})
+	var cliRequests int32
+	server := newGitHTTPServer(t, repoDir, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// go-git identifies itself as git/1.0.
+			if strings.HasPrefix(r.UserAgent(), "git/2.") {
+				atomic.AddInt32(&cliRequests, 1)
+			}
+			if username, password, ok := r.BasicAuth(); !ok || username != "ip-user" || password != "ip-pass" {
+				w.Header().Set("WWW-Authenticate", `Basic realm="git"`)
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+
+	for _, tc := range []struct {
+		name        string
+		netrc       string
+		expectedErr string
+	}{{
+		name:  "accepted credentials",
+		netrc: "machine 127.0.0.1 login ip-user password ip-pass",
+	}, {
+		name:        "rejected credentials",
+		netrc:       "machine 127.0.0.1 login ip-user password stale",
+		expectedErr: "authentication required",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			netrcFile := filepath.Join(t.TempDir(), ".netrc")
+			if err := os.WriteFile(netrcFile, []byte(tc.netrc), 0o600); err != nil {
+				t.Fatalf("error writing netrc: %v", err)
+			}
+			ctx := framework.InjectResolverConfigToContext(context.Background(), map[string]string{
+				ConfigFieldGitCLIFallback: "true",
+				ConfigFieldNetrc:          netrcFile,
+			})
+			resolver := Resolver{}
+			resource, err := resolver.Resolve(ctx, map[string]string{
+				URLParam:  server.URL + "/repo",
+				PathParam: "task.yaml",
+			})
+			if tc.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectedErr) {
+					t.Fatalf("expected error containing %q, received %v", tc.expectedErr, err)
+				}
+				if strings.Contains(err.Error(), "git cli fallback") {
+					t.Errorf("expected no anonymous retry with the git cli, received %v", err)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error resolving: %v", err)
+			} else if string(resource.Data()) != "secret" {
+				t.Errorf("expected content %q received %q", "secret", resource.Data())
+			}
+			if n := atomic.LoadInt32(&cliRequests); n != 0 {
+				t.Errorf("expected the git cli not to be run without the credentials, made %d requests", n)
+			}
+		})
+	}
+}
+
+// newProtocolV2Server serves repoDir over http, rejecting requests that
+// don't ask for git protocol v2. The returned counter holds the number
+// of v2 requests served.
+func newProtocolV2Server(t *testing.T, repoDir string) (string, *int32) {
+	t.Helper()
+	var served int32
+	server := newGitHTTPServer(t, repoDir, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Git-Protocol") != "version=2" {
+				http.Error(w, "only git protocol v2 is supported", http.StatusBadRequest)
+				return
+			}
+			atomic.AddInt32(&served, 1)
+			next.ServeHTTP(w, r)
+		})
+	})
+	return server.URL + "/repo", &served
+}
+
+func TestResolveGitProtocolV2(t *testing.T) {
+	repoDir, commits := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "over v2"},
+	}})
+	setTestRef(t, repoDir, "refs/heads/feature", commits[0])
+
+	for _, tc := range []struct {
+		name string
+		conf map[string]string
+	}{{
+		name: "clone",
+		conf: map[string]string{ConfigFieldGitProtocolVersion: GitProtocolV2},
+	}, {
+		name: "cached ref lookup",
+		conf: map[string]string{ConfigFieldGitProtocolVersion: GitProtocolV2, ConfigFieldCacheSize: "10"},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			url, served := newProtocolV2Server(t, repoDir)
+			resolver := Resolver{}
+			resource, err := resolver.Resolve(framework.InjectResolverConfigToContext(context.Background(), tc.conf), map[string]string{
+				URLParam:    url,
+				BranchParam: "feature",
+				PathParam:   "task.yaml",
+			})
+			if err != nil {
+				t.Fatalf("unexpected error resolving over protocol v2: %v", err)
+			}
+			if string(resource.Data()) != "over v2" {
+				t.Errorf("expected content %q received %q", "over v2", string(resource.Data()))
+			}
+			if resource.Annotations()[AnnotationKeyCommitHash] != commits[0] {
+				t.Errorf("expected commit %q received %q", commits[0], resource.Annotations()[AnnotationKeyCommitHash])
+			}
+			if atomic.LoadInt32(served) == 0 {
+				t.Errorf("expected requests to ask for protocol v2")
+			}
+		})
+	}
+}
+
+func TestResolveGitProtocolV2WithoutGitBinary(t *testing.T) {
+	original := gitBinary
+	gitBinary = "git-not-installed"
+	t.Cleanup(func() { gitBinary = original })
+	repoDir, _ := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "over v1"},
+	}})
+	server := newGitHTTPServer(t, repoDir, nil)
+
+	resolver := Resolver{}
+	resource, err := resolver.Resolve(framework.InjectResolverConfigToContext(context.Background(), map[string]string{
+		ConfigFieldGitProtocolVersion: GitProtocolV2,
+	}), map[string]string{
+		URLParam:  server.URL + "/repo",
+		PathParam: "task.yaml",
+	})
+	if err != nil {
+		t.Fatalf("expected fallback to go-git without the git binary, received: %v", err)
+	}
+	if string(resource.Data()) != "over v1" {
+		t.Errorf("expected content %q received %q", "over v1", string(resource.Data()))
+	}
+}
+
+func TestGetGitProtocolVersionInvalid(t *testing.T) {
+	if _, err := getGitProtocolVersion(map[string]string{ConfigFieldGitProtocolVersion: "3"}); err == nil {
+		t.Errorf("expected error for unsupported protocol version")
+	}
+}
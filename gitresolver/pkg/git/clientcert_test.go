@@ -0,0 +1,168 @@
+package git
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	resolutioncommon "github.com/tektoncd/resolution/pkg/common"
+	"github.com/tektoncd/resolution/pkg/resolver/framework"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// newTestClientCA returns a certificate authority and a PEM encoded
+// client certificate and key that it issued.
+func newTestClientCA(t *testing.T) (*x509.Certificate, []byte, []byte) {
+	t.Helper()
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("error generating ca key: %v", err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("error creating ca certificate: %v", err)
+	}
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		t.Fatalf("error parsing ca certificate: %v", err)
+	}
+
+	clientKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("error generating client key: %v", err)
+	}
+	clientTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "resolver"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	clientDER, err := x509.CreateCertificate(rand.Reader, clientTemplate, ca, &clientKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("error creating client certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(clientKey)
+	if err != nil {
+		t.Fatalf("error encoding client key: %v", err)
+	}
+	return ca, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: clientDER}),
+		pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+}
+
+func TestResolveClientCertificate(t *testing.T) {
+	repoDir, commits := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "over mtls"},
+	}})
+	ca, certPEM, keyPEM := newTestClientCA(t)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca)
+	server := httptest.NewUnstartedServer(newGitHTTPHandler(t, repoDir, nil))
+	server.TLS = &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  clientCAs,
+	}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	original := rootCAs
+	rootCAs = x509.NewCertPool()
+	rootCAs.AddCert(server.Certificate())
+	installTransports()
+	t.Cleanup(func() {
+		rootCAs = original
+		installTransports()
+	})
+
+	kubeClient := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "git-client-cert", Namespace: "team-a"},
+		Type:       corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			corev1.TLSCertKey:       certPEM,
+			corev1.TLSPrivateKeyKey: keyPEM,
+		},
+	}, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "git-client-cert", Namespace: "team-b"},
+		Data: map[string][]byte{
+			corev1.TLSCertKey:       []byte("not a certificate"),
+			corev1.TLSPrivateKeyKey: []byte("not a key"),
+		},
+	})
+
+	for _, tc := range []struct {
+		name        string
+		namespace   string
+		secret      string
+		expectedErr string
+	}{{
+		name:      "with certificate",
+		namespace: "team-a",
+		secret:    "git-client-cert",
+	}, {
+		name:        "without certificate",
+		namespace:   "team-a",
+		expectedErr: "certificate required",
+	}, {
+		name:        "secret in another namespace",
+		namespace:   "team-c",
+		secret:      "git-client-cert",
+		expectedErr: "error reading client certificate secret team-c/git-client-cert",
+	}, {
+		name:        "invalid certificate",
+		namespace:   "team-b",
+		secret:      "git-client-cert",
+		expectedErr: "error parsing client certificate secret team-b/git-client-cert",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := framework.InjectResolverConfigToContext(context.Background(), map[string]string{
+				ConfigFieldClientCertSecret: tc.secret,
+			})
+			ctx = resolutioncommon.InjectRequestNamespace(ctx, tc.namespace)
+			resolver := &Resolver{kubeClientSet: kubeClient}
+			resource, err := resolver.Resolve(ctx, map[string]string{
+				URLParam:  server.URL + "/repo",
+				PathParam: "task.yaml",
+			})
+			if tc.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectedErr) {
+					t.Fatalf("expected error containing %q, received %v", tc.expectedErr, err)
+				}
+				if strings.Contains(err.Error(), "not a key") {
+					t.Errorf("expected the secret's content to be left out of errors, received %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error resolving: %v", err)
+			}
+			if string(resource.Data()) != "over mtls" {
+				t.Errorf("expected content %q received %q", "over mtls", resource.Data())
+			}
+			if resource.Annotations()[AnnotationKeyCommitHash] != commits[0] {
+				t.Errorf("expected commit %q received %q", commits[0], resource.Annotations()[AnnotationKeyCommitHash])
+			}
+		})
+	}
+}
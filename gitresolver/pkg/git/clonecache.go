@@ -0,0 +1,351 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/osfs"
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/cache"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"knative.dev/pkg/logging"
+)
+
+// cloneCaches tracks the cached clones that resolutions are using.
+var cloneCaches = &cloneCache{entries: map[string]*cachedClone{}}
+
+// cloneCacheOptions are how clones are cached on disk, as configured
+// with the clone-cache-dir, clone-cache-max-age and clone-cache-max-size
+// fields in the git-resolver-config configmap.
+type cloneCacheOptions struct {
+	// dir is the directory that clones are kept in. Clones aren't
+	// cached when it's empty.
+	dir string
+
+	// maxAge is how long a clone may go unused before it's removed.
+	// Zero means clones are kept however long they go unused.
+	maxAge time.Duration
+
+	// maxSize is the most disk space, in bytes, that clones may take
+	// up before the least recently used are removed. Zero means no
+	// limit.
+	maxSize int64
+}
+
+// getCloneCacheOptions returns how clones are cached, if they are.
+func getCloneCacheOptions(conf map[string]string) (cloneCacheOptions, error) {
+	opts := cloneCacheOptions{dir: conf[ConfigFieldCloneCacheDir]}
+	maxAge, err := getDuration(conf, ConfigFieldCloneCacheMaxAge)
+	if err != nil {
+		return cloneCacheOptions{}, err
+	}
+	if maxAge < 0 {
+		return cloneCacheOptions{}, fmt.Errorf("invalid %s %q: must not be negative", ConfigFieldCloneCacheMaxAge, conf[ConfigFieldCloneCacheMaxAge])
+	}
+	opts.maxAge = maxAge
+	if sizeString := conf[ConfigFieldCloneCacheMaxSize]; sizeString != "" {
+		size, err := resource.ParseQuantity(sizeString)
+		if err != nil {
+			return cloneCacheOptions{}, fmt.Errorf("invalid %s %q: %w", ConfigFieldCloneCacheMaxSize, sizeString, err)
+		}
+		opts.maxSize = size.Value()
+	}
+	return opts, nil
+}
+
+// cloneCache serializes the resolutions that use the same cached clone,
+// so that one resolution's fetch or checkout can't change the clone
+// while another is reading from it, and keeps clones that are in use
+// from being evicted.
+type cloneCache struct {
+	mu      sync.Mutex
+	entries map[string]*cachedClone
+
+	// evicting is held while the cache directory is swept, so that
+	// resolutions finishing together don't sweep it at once.
+	evicting sync.Mutex
+}
+
+type cachedClone struct {
+	mu    sync.Mutex
+	users int
+}
+
+// acquire waits until the clone at path is free and returns it locked.
+// It must be released once the resolution is done with it.
+func (c *cloneCache) acquire(path string) *cachedClone {
+	c.mu.Lock()
+	entry, ok := c.entries[path]
+	if !ok {
+		entry = &cachedClone{}
+		c.entries[path] = entry
+	}
+	entry.users++
+	c.mu.Unlock()
+	entry.mu.Lock()
+	return entry
+}
+
+// release unlocks the clone at path for the next resolution waiting
+// for it.
+func (c *cloneCache) release(path string, entry *cachedClone) {
+	entry.mu.Unlock()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry.users--
+	if entry.users == 0 {
+		delete(c.entries, path)
+	}
+}
+
+// removeUnused removes the clone at path unless a resolution is using
+// or waiting for it, and returns true if it was removed.
+func (c *cloneCache) removeUnused(path string) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[path]; ok {
+		return false, nil
+	}
+	if err := os.RemoveAll(path); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// cloneCachePath returns the directory in cacheDir that the repo at url
+// is cloned into.
+func cloneCachePath(cacheDir, url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return filepath.Join(cacheDir, hex.EncodeToString(sum[:]))
+}
+
+// fetchCached fetches the refs needed to resolve ref or commit from the
+// repo at url into its clone in the cache directory, cloning it there
+// if it hasn't been already, so that only the objects the clone is
+// missing are transferred. The clone is found by the url in ctx, if
+// there is one, and otherwise by url. Resolutions of the same url wait for each
+// other: the clone is held until the returned repository's cleanup is
+// called, which then evicts clones that are too old or take up too
+// much space. Cached clones always keep all history, since they're
+// fetched into rather than cloned again. The remote is fetched from
+// even when the clone already holds what's requested, so that the
+// request's credentials are always checked.
+func fetchCached(ctx context.Context, opts cloneCacheOptions, url string, auth transport.AuthMethod, ref plumbing.ReferenceName, commit string) (*fetchedRepository, error) {
+	path := cloneCachePath(opts.dir, getCloneCacheURLFromContext(ctx, url))
+	entry := cloneCaches.acquire(path)
+	release := func() { cloneCaches.release(path, entry) }
+
+	filesystem := memfs.New()
+	repository, remote, err := openCachedClone(path, filesystem, url)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	head, err := fetchRefs(withCloneDepth(ctx, 0), repository, remote, auth, ref, commit)
+	if err == nil {
+		err = checkClonedSize(ctx, path)
+		if err != nil {
+			// A clone over the limit is never made smaller by later
+			// fetches, so it's removed rather than kept around.
+			_ = os.RemoveAll(path)
+		}
+	}
+	if err != nil {
+		release()
+		return nil, err
+	}
+	now := time.Now()
+	if err := os.Chtimes(path, now, now); err != nil {
+		release()
+		return nil, fmt.Errorf("error marking cached clone of %s as used: %w", url, err)
+	}
+	return &fetchedRepository{
+		repository: repository,
+		filesystem: filesystem,
+		head:       head,
+		ref:        ref,
+		cleanup: func() {
+			release()
+			if err := evictCloneCache(opts, time.Now()); err != nil {
+				logging.FromContext(ctx).Warnf("error evicting cached clones: %v", err)
+			}
+		},
+	}, nil
+}
+
+// openCachedClone opens the clone at path with its worktree in
+// worktree, initializing an empty one with url as its remote if
+// there isn't one. The returned remote fetches from url even if the
+// clone was made from another, since a repo reached through an ssh
+// tunnel is at a new url every time.
+func openCachedClone(path string, worktree billy.Filesystem, url string) (*git.Repository, *git.Remote, error) {
+	store := filesystem.NewStorage(osfs.New(filepath.Join(path, git.GitDirName)), cache.NewObjectLRUDefault())
+	repository, err := git.Open(store, worktree)
+	if errors.Is(err, git.ErrRepositoryNotExists) {
+		repository, err = git.Init(store, worktree)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("error opening cached clone of %s: %w", url, err)
+	}
+	// The index left by the last checkout describes a worktree that's
+	// since been discarded, so it's emptied to match the new one.
+	if err := store.SetIndex(&index.Index{Version: 2}); err != nil {
+		return nil, nil, fmt.Errorf("error opening cached clone of %s: %w", url, err)
+	}
+	remoteConfig := &config.RemoteConfig{
+		Name: remoteName,
+		URLs: []string{url},
+	}
+	_, err = repository.Remote(remoteName)
+	if errors.Is(err, git.ErrRemoteNotFound) {
+		_, err = repository.CreateRemote(remoteConfig)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("error opening cached clone of %s: %w", url, err)
+	}
+	return repository, git.NewRemote(repository.Storer, remoteConfig), nil
+}
+
+// cloneCacheURLKey is the context key for the url that a repo's clone
+// is cached under.
+type cloneCacheURLKey struct{}
+
+// withCloneCacheURL returns a copy of ctx whose cached clones are found
+// by url, the repo's own url, rather than the one it's fetched from,
+// which for a repo reached through an ssh tunnel is the tunnel's local
+// end and changes with every resolution.
+func withCloneCacheURL(ctx context.Context, url string) context.Context {
+	return context.WithValue(ctx, cloneCacheURLKey{}, url)
+}
+
+// getCloneCacheURLFromContext returns the url stored in ctx that clones
+// are cached under, or fallback if there isn't one.
+func getCloneCacheURLFromContext(ctx context.Context, fallback string) string {
+	if url, ok := ctx.Value(cloneCacheURLKey{}).(string); ok && url != "" {
+		return url
+	}
+	return fallback
+}
+
+// cachedCloneInfo is a clone found in the cache directory.
+type cachedCloneInfo struct {
+	path     string
+	lastUsed time.Time
+	size     int64
+}
+
+// evictCloneCache removes the clones in the cache directory that were
+// last used longer than the max age before now, and then the least
+// recently used until the rest fit within the max size. Clones that are
+// in use are never removed.
+func evictCloneCache(opts cloneCacheOptions, now time.Time) error {
+	if opts.maxAge <= 0 && opts.maxSize <= 0 {
+		return nil
+	}
+	cloneCaches.evicting.Lock()
+	defer cloneCaches.evicting.Unlock()
+	clones, err := listCachedClones(opts.dir)
+	if err != nil {
+		return err
+	}
+	sort.Slice(clones, func(i, j int) bool {
+		return clones[i].lastUsed.Before(clones[j].lastUsed)
+	})
+	var total int64
+	for _, clone := range clones {
+		total += clone.size
+	}
+	for _, clone := range clones {
+		expired := opts.maxAge > 0 && now.Sub(clone.lastUsed) > opts.maxAge
+		if !expired && (opts.maxSize <= 0 || total <= opts.maxSize) {
+			continue
+		}
+		removed, err := cloneCaches.removeUnused(clone.path)
+		if err != nil {
+			return fmt.Errorf("error removing cached clone %s: %w", clone.path, err)
+		}
+		if removed {
+			total -= clone.size
+		}
+	}
+	return nil
+}
+
+// listCachedClones returns the clones in cacheDir along with when each
+// was last used and the disk space it takes up. Anything in cacheDir
+// that isn't named like a clone is left alone.
+func listCachedClones(cacheDir string) ([]cachedCloneInfo, error) {
+	entries, err := os.ReadDir(cacheDir)
+	if err != nil {
+		return nil, fmt.Errorf("error listing cached clones: %w", err)
+	}
+	clones := []cachedCloneInfo{}
+	for _, entry := range entries {
+		if !entry.IsDir() || !isCloneCacheName(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, fmt.Errorf("error listing cached clones: %w", err)
+		}
+		clone := cachedCloneInfo{path: filepath.Join(cacheDir, entry.Name()), lastUsed: info.ModTime()}
+		err = filepath.WalkDir(clone.path, func(_ string, entry fs.DirEntry, err error) error {
+			if err != nil || entry.IsDir() {
+				return err
+			}
+			info, err := entry.Info()
+			if err != nil {
+				return err
+			}
+			clone.size += info.Size()
+			return nil
+		})
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("error measuring cached clone %s: %w", clone.path, err)
+		}
+		clones = append(clones, clone)
+	}
+	return clones, nil
+}
+
+// isCloneCacheName returns true if name is one that cloneCachePath
+// gives clones.
+func isCloneCacheName(name string) bool {
+	if len(name) != hex.EncodedLen(sha256.Size) {
+		return false
+	}
+	_, err := hex.DecodeString(name)
+	return err == nil
+}
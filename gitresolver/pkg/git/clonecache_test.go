@@ -0,0 +1,226 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	resolutioncommon "github.com/tektoncd/resolution/pkg/common"
+	"github.com/tektoncd/resolution/pkg/resolver/framework"
+)
+
+// uploadPackRecorder records the upload-pack requests that a git http
+// server receives.
+type uploadPackRecorder struct {
+	mu sync.Mutex
+
+	// requests counts upload-pack requests.
+	requests int
+
+	// haves counts the upload-pack requests that offered objects the
+	// client already has, as fetches into an existing clone do.
+	haves int
+}
+
+func (u *uploadPackRecorder) wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/git-upload-pack") {
+			body, _ := io.ReadAll(r.Body)
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			u.mu.Lock()
+			u.requests++
+			if bytes.Contains(body, []byte("have ")) {
+				u.haves++
+			}
+			u.mu.Unlock()
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (u *uploadPackRecorder) reset() (int, int) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	requests, haves := u.requests, u.haves
+	u.requests, u.haves = 0, 0
+	return requests, haves
+}
+
+func TestResolveCloneCache(t *testing.T) {
+	repoDir, commits := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "first"},
+	}, {
+		files: map[string]string{"task.yaml": "second"},
+	}})
+	setTestRef(t, repoDir, "refs/heads/feature", commits[0])
+	recorder := &uploadPackRecorder{}
+	server := newGitHTTPServer(t, repoDir, recorder.wrap)
+	cacheDir := t.TempDir()
+	ctx := framework.InjectResolverConfigToContext(context.Background(), map[string]string{
+		ConfigFieldCloneCacheDir: cacheDir,
+	})
+
+	resolver := &Resolver{}
+	resolve := func(t *testing.T, params map[string]string, expectedCommit, expectedContent string) {
+		t.Helper()
+		params[URLParam] = server.URL + "/repo"
+		params[PathParam] = "task.yaml"
+		resource, err := resolver.Resolve(ctx, params)
+		if err != nil {
+			t.Fatalf("unexpected error resolving: %v", err)
+		}
+		if string(resource.Data()) != expectedContent {
+			t.Errorf("expected content %q received %q", expectedContent, resource.Data())
+		}
+		if commit := resource.Annotations()[AnnotationKeyCommitHash]; commit != expectedCommit {
+			t.Errorf("expected commit %q received %q", expectedCommit, commit)
+		}
+	}
+
+	resolve(t, map[string]string{BranchParam: "feature"}, commits[0], "first")
+	if requests, haves := recorder.reset(); requests != 1 || haves != 0 {
+		t.Errorf("expected the first resolution to clone, received %d upload-pack requests with %d haves", requests, haves)
+	}
+	if clones, err := listCachedClones(cacheDir); err != nil || len(clones) != 1 {
+		t.Fatalf("expected one cached clone, received %v: %v", clones, err)
+	}
+
+	// The branch hasn't moved, so there's nothing to fetch.
+	resolve(t, map[string]string{BranchParam: "feature"}, commits[0], "first")
+	if requests, _ := recorder.reset(); requests != 0 {
+		t.Errorf("expected an up to date clone to fetch nothing, received %d upload-pack requests", requests)
+	}
+
+	setTestRef(t, repoDir, "refs/heads/feature", commits[1])
+	resolve(t, map[string]string{BranchParam: "feature"}, commits[1], "second")
+	if requests, haves := recorder.reset(); requests != 1 || haves != 1 {
+		t.Errorf("expected a fetch into the cached clone, received %d upload-pack requests with %d haves", requests, haves)
+	}
+
+	resolve(t, map[string]string{CommitParam: commits[0]}, commits[0], "first")
+	resolve(t, map[string]string{}, commits[1], "second")
+	if clones, err := listCachedClones(cacheDir); err != nil || len(clones) != 1 {
+		t.Errorf("expected the url to keep using one cached clone, received %v: %v", clones, err)
+	}
+}
+
+func TestResolveCloneCacheConcurrent(t *testing.T) {
+	repoDir, commits := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "first"},
+	}, {
+		files: map[string]string{"task.yaml": "second"},
+	}})
+	server := newGitHTTPServer(t, repoDir, nil)
+	ctx := framework.InjectResolverConfigToContext(context.Background(), map[string]string{
+		ConfigFieldCloneCacheDir: t.TempDir(),
+	})
+
+	resolver := &Resolver{}
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		commit, expected := commits[i%2], []string{"first", "second"}[i%2]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resource, err := resolver.Resolve(resolutioncommon.InjectRequestNamespace(ctx, "default"), map[string]string{
+				URLParam:    server.URL + "/repo",
+				PathParam:   "task.yaml",
+				CommitParam: commit,
+			})
+			if err == nil && string(resource.Data()) != expected {
+				t.Errorf("expected content %q at %s received %q", expected, commit, resource.Data())
+			}
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("unexpected error resolving: %v", err)
+		}
+	}
+}
+
+func TestEvictCloneCache(t *testing.T) {
+	cacheDir := t.TempDir()
+	now := time.Now()
+	newClone := func(name string, size int, lastUsed time.Duration) string {
+		path := cloneCachePath(cacheDir, name)
+		if err := os.MkdirAll(path, 0755); err != nil {
+			t.Fatalf("error creating clone: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(path, "pack"), make([]byte, size), 0644); err != nil {
+			t.Fatalf("error writing clone: %v", err)
+		}
+		used := now.Add(-lastUsed)
+		if err := os.Chtimes(path, used, used); err != nil {
+			t.Fatalf("error setting clone's last use: %v", err)
+		}
+		return path
+	}
+	exists := func(path string) bool {
+		_, err := os.Stat(path)
+		return err == nil
+	}
+
+	stale := newClone("stale", 10, 2*time.Hour)
+	oldest := newClone("oldest", 100, 30*time.Minute)
+	inUse := newClone("in use", 100, 20*time.Minute)
+	recent := newClone("recent", 100, time.Minute)
+	other := filepath.Join(cacheDir, "not-a-clone")
+	if err := os.Mkdir(other, 0755); err != nil {
+		t.Fatalf("error creating directory: %v", err)
+	}
+	os.Chtimes(other, now.Add(-24*time.Hour), now.Add(-24*time.Hour))
+
+	entry := cloneCaches.acquire(inUse)
+	defer cloneCaches.release(inUse, entry)
+	if err := evictCloneCache(cloneCacheOptions{dir: cacheDir, maxAge: time.Hour, maxSize: 250}, now); err != nil {
+		t.Fatalf("unexpected error evicting: %v", err)
+	}
+	for _, tc := range []struct {
+		name     string
+		path     string
+		expected bool
+	}{
+		{name: "unused for longer than the max age", path: stale},
+		{name: "least recently used", path: oldest},
+		{name: "in use", path: inUse, expected: true},
+		{name: "most recently used", path: recent, expected: true},
+		{name: "not a clone", path: other, expected: true},
+	} {
+		if exists(tc.path) != tc.expected {
+			t.Errorf("expected %s clone to be kept %t", tc.name, tc.expected)
+		}
+	}
+}
+
+func TestGetCloneCacheOptionsInvalid(t *testing.T) {
+	for _, tc := range []struct {
+		conf        map[string]string
+		expectedErr string
+	}{{
+		conf:        map[string]string{ConfigFieldCloneCacheMaxAge: "a day"},
+		expectedErr: `invalid clone-cache-max-age "a day"`,
+	}, {
+		conf:        map[string]string{ConfigFieldCloneCacheMaxAge: "-1h"},
+		expectedErr: `invalid clone-cache-max-age "-1h": must not be negative`,
+	}, {
+		conf:        map[string]string{ConfigFieldCloneCacheMaxSize: "lots"},
+		expectedErr: `invalid clone-cache-max-size "lots"`,
+	}} {
+		_, err := getCloneCacheOptions(tc.conf)
+		if err == nil || !strings.Contains(err.Error(), tc.expectedErr) {
+			t.Errorf("expected error containing %q, received %v", tc.expectedErr, err)
+		}
+	}
+}
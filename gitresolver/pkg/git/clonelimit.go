@@ -0,0 +1,142 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+)
+
+var (
+	clonesInFlight = stats.Int64(
+		"git_resolver_clones_in_flight",
+		"Number of clones being made at once",
+		stats.UnitDimensionless)
+
+	clonesWaiting = stats.Int64(
+		"git_resolver_clones_waiting",
+		"Number of clones waiting for max-concurrent-clones to allow them",
+		stats.UnitDimensionless)
+)
+
+// registerCloneMetricViews registers the views over the clone
+// concurrency metrics, replacing any registered before.
+func registerCloneMetricViews() error {
+	views := []*view.View{{
+		Description: clonesInFlight.Description(),
+		Measure:     clonesInFlight,
+		Aggregation: view.LastValue(),
+	}, {
+		Description: clonesWaiting.Description(),
+		Measure:     clonesWaiting,
+		Aggregation: view.LastValue(),
+	}}
+	for _, v := range views {
+		if existing := view.Find(v.Measure.Name()); existing != nil {
+			view.Unregister(existing)
+		}
+	}
+	return view.Register(views...)
+}
+
+// getMaxConcurrentClones returns the most clones that may be made at
+// once, as configured with the max-concurrent-clones field in the
+// git-resolver-config configmap. Zero means no limit is enforced.
+func getMaxConcurrentClones(conf map[string]string) (int, error) {
+	maxString, ok := conf[ConfigFieldMaxConcurrentClones]
+	if !ok || maxString == "" {
+		return 0, nil
+	}
+	max, err := strconv.Atoi(maxString)
+	if err != nil || max <= 0 {
+		return 0, fmt.Errorf("invalid %s %q: must be a positive integer", ConfigFieldMaxConcurrentClones, maxString)
+	}
+	return max, nil
+}
+
+// cloneLimiter bounds how many clones are made at once. The limit is
+// given to each call to acquire, rather than fixed, so that a change to
+// the configmap applies to the clones that start after it. The zero
+// value is ready to use.
+type cloneLimiter struct {
+	mu       sync.Mutex
+	inFlight int
+	waiting  int
+
+	// released is closed, and replaced, whenever a clone finishes, to
+	// wake the clones waiting for it.
+	released chan struct{}
+}
+
+// acquire waits until fewer than max clones are in flight, or returns
+// ctx's error if it's done first, and then counts one more until the
+// returned func is called. A max of zero or less doesn't wait.
+func (l *cloneLimiter) acquire(ctx context.Context, max int) (func(), error) {
+	l.mu.Lock()
+	for max > 0 && l.inFlight >= max {
+		if l.released == nil {
+			l.released = make(chan struct{})
+		}
+		released := l.released
+		l.waiting++
+		l.record(ctx)
+		l.mu.Unlock()
+
+		var err error
+		select {
+		case <-released:
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+
+		l.mu.Lock()
+		l.waiting--
+		if err != nil {
+			l.record(ctx)
+			l.mu.Unlock()
+			return nil, fmt.Errorf("error waiting for one of the %d clones allowed by %s to finish: %w", max, ConfigFieldMaxConcurrentClones, err)
+		}
+	}
+	l.inFlight++
+	l.record(ctx)
+	l.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			l.inFlight--
+			if l.released != nil {
+				close(l.released)
+				l.released = nil
+			}
+			l.record(ctx)
+		})
+	}, nil
+}
+
+// record reports the number of clones in flight and waiting. It must
+// be called with l.mu held.
+func (l *cloneLimiter) record(ctx context.Context) {
+	stats.Record(ctx, clonesInFlight.M(int64(l.inFlight)), clonesWaiting.M(int64(l.waiting)))
+}
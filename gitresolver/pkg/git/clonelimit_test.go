@@ -0,0 +1,143 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tektoncd/resolution/pkg/resolver/framework"
+)
+
+func TestResolveMaxConcurrentClones(t *testing.T) {
+	repoDir, _ := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "kind: Task"},
+	}})
+	// Each clone's requests are held for a moment so that clones
+	// allowed to run at once overlap.
+	var inFlight, peak int64
+	server := newGitHTTPServer(t, repoDir, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			n := atomic.AddInt64(&inFlight, 1)
+			defer atomic.AddInt64(&inFlight, -1)
+			for {
+				p := atomic.LoadInt64(&peak)
+				if n <= p || atomic.CompareAndSwapInt64(&peak, p, n) {
+					break
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+			next.ServeHTTP(w, req)
+		})
+	})
+
+	const limit, resolves = 2, 8
+	ctx := framework.InjectResolverConfigToContext(context.Background(), map[string]string{
+		ConfigFieldMaxConcurrentClones: "2",
+	})
+	resolver := &Resolver{}
+	var wg sync.WaitGroup
+	errs := make(chan error, resolves)
+	for i := 0; i < resolves; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := resolver.Resolve(ctx, map[string]string{
+				URLParam:  server.URL + "/repo",
+				PathParam: "task.yaml",
+			})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("unexpected error resolving: %v", err)
+		}
+	}
+	if peak > limit || peak == 0 {
+		t.Errorf("expected at most %d clones at once, saw %d", limit, peak)
+	}
+	if resolver.clones.inFlight != 0 || resolver.clones.waiting != 0 {
+		t.Errorf("expected no clones left in flight or waiting, got %d and %d", resolver.clones.inFlight, resolver.clones.waiting)
+	}
+}
+
+func TestCloneLimiterWaitsForRelease(t *testing.T) {
+	l := &cloneLimiter{}
+	release, err := l.acquire(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("unexpected error acquiring: %v", err)
+	}
+
+	acquired := make(chan struct{})
+	go func() {
+		second, err := l.acquire(context.Background(), 1)
+		if err != nil {
+			t.Errorf("unexpected error acquiring: %v", err)
+			return
+		}
+		second()
+		close(acquired)
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("expected the second clone to wait for the first")
+	case <-time.After(20 * time.Millisecond):
+	}
+	release()
+	// Releasing again is a no-op rather than freeing another slot.
+	release()
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("expected the second clone to start once the first finished")
+	}
+	if l.inFlight != 0 {
+		t.Errorf("expected no clones in flight, got %d", l.inFlight)
+	}
+}
+
+func TestCloneLimiterRespectsDeadline(t *testing.T) {
+	l := &cloneLimiter{}
+	release, err := l.acquire(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("unexpected error acquiring: %v", err)
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = l.acquire(ctx, 1)
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), ConfigFieldMaxConcurrentClones) {
+		t.Fatalf("expected the wait to end at the deadline, got %v", err)
+	}
+	if l.waiting != 0 {
+		t.Errorf("expected no clones left waiting, got %d", l.waiting)
+	}
+}
+
+func TestCloneLimiterUnlimited(t *testing.T) {
+	l := &cloneLimiter{}
+	for i := 0; i < 3; i++ {
+		if _, err := l.acquire(context.Background(), 0); err != nil {
+			t.Fatalf("unexpected error acquiring: %v", err)
+		}
+	}
+	if l.inFlight != 3 {
+		t.Errorf("expected 3 clones in flight, got %d", l.inFlight)
+	}
+}
+
+func TestGetMaxConcurrentClonesInvalid(t *testing.T) {
+	for _, value := range []string{"0", "-1", "some"} {
+		if _, err := getMaxConcurrentClones(map[string]string{ConfigFieldMaxConcurrentClones: value}); err == nil {
+			t.Errorf("expected an error for %q", value)
+		}
+	}
+}
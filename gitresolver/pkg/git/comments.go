@@ -0,0 +1,86 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"bytes"
+	"errors"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// stripComments returns content without its comments or blank lines.
+// yaml made up of mappings and sequences is parsed and emitted again
+// with the same keys in the same order, so comments are removed without
+// changing what it means. Anything else, like plain text or yaml that
+// doesn't parse, has its lines starting with # removed instead.
+func stripComments(content []byte) []byte {
+	if stripped, ok := stripYAMLComments(content); ok {
+		return stripped
+	}
+	buf := &bytes.Buffer{}
+	for _, line := range bytes.SplitAfter(content, []byte("\n")) {
+		trimmed := bytes.TrimSpace(line)
+		if len(trimmed) == 0 || trimmed[0] == '#' {
+			continue
+		}
+		buf.Write(line)
+	}
+	return buf.Bytes()
+}
+
+// stripYAMLComments parses every document in content and emits it again
+// without comments. It returns false if content isn't yaml or has a
+// document that isn't a mapping or sequence, since re-emitting a scalar
+// could reflow text that only happens to parse as yaml.
+func stripYAMLComments(content []byte) ([]byte, bool) {
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	buf := &bytes.Buffer{}
+	encoder := yaml.NewEncoder(buf)
+	encoder.SetIndent(2)
+	for {
+		var doc yaml.Node
+		err := decoder.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil || len(doc.Content) != 1 {
+			return nil, false
+		}
+		if kind := doc.Content[0].Kind; kind != yaml.MappingNode && kind != yaml.SequenceNode {
+			return nil, false
+		}
+		clearComments(&doc)
+		if err := encoder.Encode(&doc); err != nil {
+			return nil, false
+		}
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, false
+	}
+	return buf.Bytes(), true
+}
+
+// clearComments removes the comments attached to node and everything
+// beneath it.
+func clearComments(node *yaml.Node) {
+	node.HeadComment, node.LineComment, node.FootComment = "", "", ""
+	for _, child := range node.Content {
+		clearComments(child)
+	}
+}
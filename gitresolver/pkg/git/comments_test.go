@@ -0,0 +1,128 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/tektoncd/resolution/pkg/resolver/framework"
+	"gopkg.in/yaml.v3"
+)
+
+const commentedTask = `# A task that builds things.
+apiVersion: tekton.dev/v1beta1
+kind: Task # the kind
+
+metadata:
+  # its name
+  name: build
+spec:
+  steps:
+  # the only step
+  - name: build
+    image: golang # pinned elsewhere
+    script: |
+      # this is part of the script, not a comment
+      go build ./...
+---
+# second document
+kind: Pipeline
+`
+
+func TestStripComments(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		content  string
+		expected string
+	}{{
+		name:    "yaml",
+		content: commentedTask,
+		expected: `apiVersion: tekton.dev/v1beta1
+kind: Task
+metadata:
+  name: build
+spec:
+  steps:
+    - name: build
+      image: golang
+      script: |
+        # this is part of the script, not a comment
+        go build ./...
+---
+kind: Pipeline
+`,
+	}, {
+		name:     "plain text",
+		content:  "#!/bin/sh\n# say hello\n\necho hello\n  # indented comment\necho '# not a comment'\n",
+		expected: "echo hello\necho '# not a comment'\n",
+	}, {
+		name:     "invalid yaml",
+		content:  "# comment\nkey: [unclosed\n",
+		expected: "key: [unclosed\n",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			stripped := string(stripComments([]byte(tc.content)))
+			if stripped != tc.expected {
+				t.Errorf("expected:\n%s\nreceived:\n%s", tc.expected, stripped)
+			}
+		})
+	}
+}
+
+func TestStripCommentsPreservesContent(t *testing.T) {
+	decode := func(content []byte) []interface{} {
+		docs := []interface{}{}
+		decoder := yaml.NewDecoder(bytes.NewReader(content))
+		for {
+			var doc interface{}
+			if err := decoder.Decode(&doc); err != nil {
+				return docs
+			}
+			docs = append(docs, doc)
+		}
+	}
+	original, stripped := decode([]byte(commentedTask)), decode(stripComments([]byte(commentedTask)))
+	if len(original) != 2 || !reflect.DeepEqual(original, stripped) {
+		t.Errorf("expected stripped content to match %v, received %v", original, stripped)
+	}
+}
+
+func TestResolveStripComments(t *testing.T) {
+	repoDir, _ := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "# comment\nkind: Task\n"},
+	}})
+
+	for _, tc := range []struct {
+		strip            string
+		expectedContent  string
+		expectAnnotation bool
+	}{{
+		strip:           "",
+		expectedContent: "# comment\nkind: Task\n",
+	}, {
+		strip:            "true",
+		expectedContent:  "kind: Task\n",
+		expectAnnotation: true,
+	}} {
+		t.Run("strip "+tc.strip, func(t *testing.T) {
+			ctx := framework.InjectResolverConfigToContext(context.Background(), map[string]string{
+				ConfigFieldStripComments: tc.strip,
+			})
+			resolver := Resolver{}
+			resource, err := resolver.Resolve(ctx, map[string]string{
+				URLParam:  repoDir,
+				PathParam: "task.yaml",
+			})
+			if err != nil {
+				t.Fatalf("unexpected error resolving: %v", err)
+			}
+			if string(resource.Data()) != tc.expectedContent {
+				t.Errorf("expected content %q received %q", tc.expectedContent, resource.Data())
+			}
+			if _, ok := resource.Annotations()[AnnotationKeyCommentsStripped]; ok != tc.expectAnnotation {
+				t.Errorf("expected %s annotation %t, received annotations %v", AnnotationKeyCommentsStripped, tc.expectAnnotation, resource.Annotations())
+			}
+		})
+	}
+}
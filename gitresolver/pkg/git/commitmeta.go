@@ -0,0 +1,137 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// CommitMetadata is the json document returned in place of a file's
+// content when the metadata of the resolved commit is requested.
+type CommitMetadata struct {
+	Hash      string          `json:"hash"`
+	Tree      string          `json:"tree"`
+	Parents   []string        `json:"parents"`
+	Author    CommitSignature `json:"author"`
+	Committer CommitSignature `json:"committer"`
+	Message   string          `json:"message"`
+
+	// PGPSignature is the commit's ascii armored signature, if it's
+	// signed.
+	PGPSignature string `json:"pgpSignature,omitempty"`
+
+	// Signer is the id of the trusted key that the signature was
+	// verified against, when trusted-keys-secret is configured.
+	Signer string `json:"signer,omitempty"`
+}
+
+// CommitSignature is who authored or committed a commit, and when.
+type CommitSignature struct {
+	Name  string    `json:"name"`
+	Email string    `json:"email"`
+	When  time.Time `json:"when"`
+}
+
+// commitMetadata returns the metadata of the given commit as a
+// json-encoded resource. signer is the id of the trusted key the commit
+// was verified as signed by, if it was checked.
+func commitMetadata(repository *git.Repository, commit, signer string) (*ResolvedGitResource, error) {
+	commitObj, err := repository.CommitObject(plumbing.NewHash(commit))
+	if err != nil {
+		return nil, fmt.Errorf("error reading commit %q: %w", commit, err)
+	}
+
+	metadata := CommitMetadata{
+		Hash:         commitObj.Hash.String(),
+		Tree:         commitObj.TreeHash.String(),
+		Parents:      make([]string, 0, len(commitObj.ParentHashes)),
+		Author:       newCommitSignature(commitObj.Author),
+		Committer:    newCommitSignature(commitObj.Committer),
+		Message:      commitObj.Message,
+		PGPSignature: commitObj.PGPSignature,
+		Signer:       signer,
+	}
+	for _, parent := range commitObj.ParentHashes {
+		metadata.Parents = append(metadata.Parents, parent.String())
+	}
+
+	content, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, fmt.Errorf("error serializing metadata of commit %q: %w", commit, err)
+	}
+	return &ResolvedGitResource{
+		Commit:      commit,
+		Content:     content,
+		ContentType: JSONContentType,
+	}, nil
+}
+
+func newCommitSignature(signature object.Signature) CommitSignature {
+	return CommitSignature{
+		Name:  signature.Name,
+		Email: signature.Email,
+		When:  signature.When,
+	}
+}
+
+// maxCommitSubjectLength is the most characters of a commit's subject
+// that are recorded in its annotation, so that a long first line
+// doesn't take up much of the room the ResolutionRequest has.
+const maxCommitSubjectLength = 200
+
+// commitProvenance is who made the resolved commit, when, and what its
+// message starts with, as recorded in the resolution's annotations.
+type commitProvenance struct {
+	AuthorName  string
+	AuthorEmail string
+	CommittedAt time.Time
+	Subject     string
+}
+
+// readCommitProvenance returns the provenance of the given commit.
+func readCommitProvenance(repository *git.Repository, commit string) (commitProvenance, error) {
+	commitObj, err := repository.CommitObject(plumbing.NewHash(commit))
+	if err != nil {
+		return commitProvenance{}, fmt.Errorf("error reading commit %q: %w", commit, err)
+	}
+	return commitProvenance{
+		AuthorName:  commitObj.Author.Name,
+		AuthorEmail: commitObj.Author.Email,
+		CommittedAt: commitObj.Committer.When,
+		Subject:     commitSubject(commitObj.Message),
+	}, nil
+}
+
+// commitSubject returns the first line of message, cut short with an
+// ellipsis if it's longer than maxCommitSubjectLength characters.
+func commitSubject(message string) string {
+	subject, _, _ := strings.Cut(strings.TrimSpace(message), "\n")
+	subject = strings.TrimSpace(subject)
+	if utf8.RuneCountInString(subject) <= maxCommitSubjectLength {
+		return subject
+	}
+	runes := []rune(subject)
+	return string(runes[:maxCommitSubjectLength-1]) + "…"
+}
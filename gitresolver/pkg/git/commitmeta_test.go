@@ -0,0 +1,143 @@
+package git
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResolveCommitMetadata(t *testing.T) {
+	signer, _ := newSigningKey(t)
+	repoDir, commits := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "first"},
+	}, {
+		files:   map[string]string{"task.yaml": "second"},
+		author:  "someone",
+		when:    time.Date(2022, time.March, 4, 5, 6, 7, 0, time.UTC),
+		signKey: signer,
+	}})
+
+	params := map[string]string{
+		URLParam:            repoDir,
+		CommitMetadataParam: "true",
+	}
+	resolver := Resolver{}
+	if err := resolver.ValidateParams(context.Background(), params); err != nil {
+		t.Fatalf("unexpected error validating params without a path: %v", err)
+	}
+	resource, err := resolver.Resolve(context.Background(), params)
+	if err != nil {
+		t.Fatalf("unexpected error resolving: %v", err)
+	}
+	if ct := resource.(*ResolvedGitResource).ContentType; ct != JSONContentType {
+		t.Errorf("expected content type %q received %q", JSONContentType, ct)
+	}
+	metadata := CommitMetadata{}
+	if err := json.Unmarshal(resource.Data(), &metadata); err != nil {
+		t.Fatalf("error parsing commit metadata: %v", err)
+	}
+
+	if metadata.Hash != commits[1] {
+		t.Errorf("expected hash %q received %q", commits[1], metadata.Hash)
+	}
+	if len(metadata.Tree) != 40 {
+		t.Errorf("expected a tree hash, received %q", metadata.Tree)
+	}
+	if len(metadata.Parents) != 1 || metadata.Parents[0] != commits[0] {
+		t.Errorf("expected parents [%s] received %v", commits[0], metadata.Parents)
+	}
+	expectedAuthor := CommitSignature{
+		Name:  "someone",
+		Email: "someone@example.com",
+		When:  time.Date(2022, time.March, 4, 5, 6, 7, 0, time.UTC),
+	}
+	if metadata.Author.Name != expectedAuthor.Name || metadata.Author.Email != expectedAuthor.Email || !metadata.Author.When.Equal(expectedAuthor.When) {
+		t.Errorf("expected author %+v received %+v", expectedAuthor, metadata.Author)
+	}
+	if metadata.Committer.Name != expectedAuthor.Name {
+		t.Errorf("expected committer to default to the author, received %+v", metadata.Committer)
+	}
+	if metadata.Message != "commit 1" {
+		t.Errorf("expected message %q received %q", "commit 1", metadata.Message)
+	}
+	if !strings.HasPrefix(metadata.PGPSignature, "-----BEGIN PGP SIGNATURE-----") {
+		t.Errorf("expected the commit's pgp signature, received %q", metadata.PGPSignature)
+	}
+
+	// The root commit has no parents and isn't signed.
+	params[CommitParam] = commits[0]
+	resource, err = resolver.Resolve(context.Background(), params)
+	if err != nil {
+		t.Fatalf("unexpected error resolving: %v", err)
+	}
+	metadata = CommitMetadata{}
+	if err := json.Unmarshal(resource.Data(), &metadata); err != nil {
+		t.Fatalf("error parsing commit metadata: %v", err)
+	}
+	if metadata.Hash != commits[0] || len(metadata.Parents) != 0 || metadata.PGPSignature != "" {
+		t.Errorf("expected unsigned root commit %q, received %+v", commits[0], metadata)
+	}
+}
+
+func TestValidateParamsCommitMetadata(t *testing.T) {
+	resolver := Resolver{}
+	err := resolver.ValidateParams(context.Background(), map[string]string{
+		URLParam:            "foo",
+		CommitMetadataParam: "true",
+		BlameParam:          "true",
+	})
+	if err == nil || !strings.Contains(err.Error(), `supplied both "commitMetadata" and "blame"`) {
+		t.Errorf("expected conflicting params error, received %v", err)
+	}
+}
+
+func TestResolveCommitProvenanceAnnotations(t *testing.T) {
+	when := time.Date(2022, time.March, 4, 5, 6, 7, 0, time.FixedZone("", 2*60*60))
+	repoDir, _ := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "first"},
+	}, {
+		files:  map[string]string{"task.yaml": "second"},
+		author: "someone",
+		when:   when,
+	}})
+
+	resolver := Resolver{}
+	resource, err := resolver.Resolve(context.Background(), map[string]string{
+		URLParam:  repoDir,
+		PathParam: "task.yaml",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error resolving: %v", err)
+	}
+	annotations := resource.Annotations()
+	for key, expected := range map[string]string{
+		AnnotationKeyCommitAuthorName:  "someone",
+		AnnotationKeyCommitAuthorEmail: "someone@example.com",
+		AnnotationKeyCommitDate:        "2022-03-04T05:06:07+02:00",
+		AnnotationKeyCommitSubject:     "commit 1",
+	} {
+		if annotations[key] != expected {
+			t.Errorf("expected annotation %q to be %q, received %q", key, expected, annotations[key])
+		}
+	}
+}
+
+func TestCommitSubject(t *testing.T) {
+	long := strings.Repeat("a", maxCommitSubjectLength+10)
+	for _, tc := range []struct {
+		message  string
+		expected string
+	}{
+		{message: "Fix the build\n\nThe details.\n", expected: "Fix the build"},
+		{message: "\n  Leading blank lines  \nmore", expected: "Leading blank lines"},
+		{message: strings.Repeat("a", maxCommitSubjectLength), expected: strings.Repeat("a", maxCommitSubjectLength)},
+		{message: long + "\nbody", expected: strings.Repeat("a", maxCommitSubjectLength-1) + "…"},
+		{message: strings.Repeat("é", maxCommitSubjectLength+1), expected: strings.Repeat("é", maxCommitSubjectLength-1) + "…"},
+	} {
+		if subject := commitSubject(tc.message); subject != tc.expected {
+			t.Errorf("expected subject %q for message %q, received %q", tc.expected, tc.message, subject)
+		}
+	}
+}
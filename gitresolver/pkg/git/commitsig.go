@@ -0,0 +1,126 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	resolutioncommon "github.com/tektoncd/resolution/pkg/common"
+	"gopkg.in/yaml.v3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/system"
+)
+
+// trustedKeysKey is the key of the trusted keys secret that lists the
+// keys resolved commits may be signed with.
+const trustedKeysKey = "keys.yaml"
+
+// ReasonUntrustedCommit is the reason that resolutions fail with when
+// the resolved commit isn't signed, or isn't signed by a trusted key.
+const ReasonUntrustedCommit = "UntrustedCommit"
+
+// trustedKey is a pgp public key that commits may be signed with while
+// it is valid. Either end of its validity window may be left open so
+// that keys can be added ahead of a rotation and retired after it.
+type trustedKey struct {
+	Name      string    `yaml:"name"`
+	PublicKey string    `yaml:"publicKey"`
+	NotBefore time.Time `yaml:"notBefore"`
+	NotAfter  time.Time `yaml:"notAfter"`
+}
+
+// validAt returns true if now is within the key's validity window.
+func (k trustedKey) validAt(now time.Time) bool {
+	return (k.NotBefore.IsZero() || !now.Before(k.NotBefore)) && (k.NotAfter.IsZero() || now.Before(k.NotAfter))
+}
+
+// trustedCommitKeys reads the keys listed in the secret named by
+// trusted-keys-secret in the resolver's namespace and returns the ones
+// that are valid now.
+func (r *Resolver) trustedCommitKeys(ctx context.Context, conf map[string]string) (openpgp.EntityList, error) {
+	return r.readTrustedKeys(ctx, system.Namespace(), conf[ConfigFieldTrustedKeys])
+}
+
+// requestTrustedKeys reads the keys listed in the secret named by the
+// trustedKeysSecret param in the namespace of the request being
+// resolved and returns the ones that are valid now.
+func (r *Resolver) requestTrustedKeys(ctx context.Context, params map[string]string) (openpgp.EntityList, error) {
+	return r.readTrustedKeys(ctx, resolutioncommon.RequestNamespace(ctx), params[TrustedKeysSecretParam])
+}
+
+// readTrustedKeys returns the keys listed in the keys.yaml of the
+// secret namespace/name that are valid now.
+func (r *Resolver) readTrustedKeys(ctx context.Context, namespace, name string) (openpgp.EntityList, error) {
+	secret, err := r.kubeClientSet.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error reading trusted keys secret %s/%s: %w", namespace, name, err)
+	}
+	data, ok := secret.Data[trustedKeysKey]
+	if !ok {
+		return nil, fmt.Errorf("trusted keys secret %s/%s has no %q key", namespace, name, trustedKeysKey)
+	}
+	var keys []trustedKey
+	if err := yaml.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("error parsing trusted keys secret %s/%s: %w", namespace, name, err)
+	}
+
+	now := r.now()
+	valid := openpgp.EntityList{}
+	for _, key := range keys {
+		if !key.validAt(now) {
+			continue
+		}
+		entities, err := openpgp.ReadArmoredKeyRing(strings.NewReader(key.PublicKey))
+		if err != nil {
+			return nil, fmt.Errorf("error parsing trusted key %q: %w", key.Name, err)
+		}
+		valid = append(valid, entities...)
+	}
+	return valid, nil
+}
+
+// verifyCommitSignature checks that the given commit carries a pgp
+// signature made by one of keys and returns the id of the signing key.
+// A missing or untrusted signature fails with ReasonUntrustedCommit.
+func verifyCommitSignature(repository *git.Repository, commit string, keys openpgp.EntityList) (string, error) {
+	c, err := repository.CommitObject(plumbing.NewHash(commit))
+	if err != nil {
+		return "", fmt.Errorf("error reading commit %s: %w", commit, err)
+	}
+	if c.PGPSignature == "" {
+		return "", resolutioncommon.NewError(ReasonUntrustedCommit, fmt.Errorf("commit %s is not signed", commit))
+	}
+	encoded := &plumbing.MemoryObject{}
+	if err := c.EncodeWithoutSignature(encoded); err != nil {
+		return "", fmt.Errorf("error encoding commit %s: %w", commit, err)
+	}
+	reader, err := encoded.Reader()
+	if err != nil {
+		return "", fmt.Errorf("error encoding commit %s: %w", commit, err)
+	}
+	signer, err := openpgp.CheckArmoredDetachedSignature(keys, reader, strings.NewReader(c.PGPSignature), nil)
+	if err != nil {
+		return "", resolutioncommon.NewError(ReasonUntrustedCommit, fmt.Errorf("commit %s is not signed by a currently trusted key: %w", commit, err))
+	}
+	return signer.PrimaryKey.KeyIdString(), nil
+}
@@ -0,0 +1,216 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	resolutioncommon "github.com/tektoncd/resolution/pkg/common"
+	"github.com/tektoncd/resolution/pkg/resolver/framework"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	clocktesting "k8s.io/utils/clock/testing"
+	"knative.dev/pkg/system"
+)
+
+func TestResolveTrustedCommitSigners(t *testing.T) {
+	t.Setenv(system.NamespaceEnvKey, "tekton-remote-resolution")
+	current, currentPublic := newSigningKey(t)
+	next, nextPublic := newSigningKey(t)
+	expired, expiredPublic := newSigningKey(t)
+	untrusted, _ := newSigningKey(t)
+	keys := fmt.Sprintf(`- name: current
+  publicKey: %q
+  notBefore: 2022-01-01T00:00:00Z
+  notAfter: 2023-01-01T00:00:00Z
+- name: next
+  publicKey: %q
+  notBefore: 2022-06-01T00:00:00Z
+- name: expired
+  publicKey: %q
+  notAfter: 2022-03-01T00:00:00Z
+`, currentPublic, nextPublic, expiredPublic)
+
+	for _, tc := range []struct {
+		name          string
+		signKey       *openpgp.Entity
+		expectedError string
+	}{{
+		name:    "valid key",
+		signKey: current,
+	}, {
+		name:    "rotated in key",
+		signKey: next,
+	}, {
+		name:          "expired key",
+		signKey:       expired,
+		expectedError: "not signed by a currently trusted key",
+	}, {
+		name:          "untrusted key",
+		signKey:       untrusted,
+		expectedError: "not signed by a currently trusted key",
+	}, {
+		name:          "unsigned",
+		expectedError: "is not signed",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			repoDir, commits := createTestRepo(t, []testCommit{{
+				files:   map[string]string{"task.yaml": "kind: Task"},
+				signKey: tc.signKey,
+			}})
+			resolver := &Resolver{
+				kubeClientSet: fake.NewSimpleClientset(&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "signers", Namespace: "tekton-remote-resolution"},
+					Data:       map[string][]byte{trustedKeysKey: []byte(keys)},
+				}),
+				clock: clocktesting.NewFakePassiveClock(time.Date(2022, 7, 1, 0, 0, 0, 0, time.UTC)),
+			}
+			ctx := framework.InjectResolverConfigToContext(context.Background(), map[string]string{
+				ConfigFieldTrustedKeys: "signers",
+			})
+			resource, err := resolver.Resolve(ctx, map[string]string{
+				URLParam:  repoDir,
+				PathParam: "task.yaml",
+			})
+			if tc.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectedError) {
+					t.Fatalf("expected error containing %q received %v", tc.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error resolving signed commit %s: %v", commits[0], err)
+			}
+			if signer := resource.Annotations()[AnnotationKeyCommitSigner]; signer != tc.signKey.PrimaryKey.KeyIdString() {
+				t.Errorf("expected commit signer %q received %q", tc.signKey.PrimaryKey.KeyIdString(), signer)
+			}
+		})
+	}
+}
+
+func TestTrustedKeyValidAt(t *testing.T) {
+	key := trustedKey{
+		NotBefore: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+		NotAfter:  time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	for _, tc := range []struct {
+		now      time.Time
+		expected bool
+	}{
+		{now: time.Date(2021, 12, 31, 0, 0, 0, 0, time.UTC), expected: false},
+		{now: key.NotBefore, expected: true},
+		{now: time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC), expected: true},
+		{now: key.NotAfter, expected: false},
+	} {
+		if valid := key.validAt(tc.now); valid != tc.expected {
+			t.Errorf("expected key valid at %s to be %t", tc.now, tc.expected)
+		}
+	}
+}
+
+func TestResolveRequireSignature(t *testing.T) {
+	trusted, trustedPublic := newSigningKey(t)
+	untrusted, _ := newSigningKey(t)
+	keys := fmt.Sprintf("- name: team-a\n  publicKey: %q\n", trustedPublic)
+	kubeClient := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "signers", Namespace: "team-a"},
+		Data:       map[string][]byte{trustedKeysKey: []byte(keys)},
+	})
+
+	for _, tc := range []struct {
+		name          string
+		signKey       *openpgp.Entity
+		namespace     string
+		expectedError string
+		expectedCode  string
+	}{{
+		name:      "valid key",
+		signKey:   trusted,
+		namespace: "team-a",
+	}, {
+		name:          "wrong key",
+		signKey:       untrusted,
+		namespace:     "team-a",
+		expectedError: "not signed by a currently trusted key",
+		expectedCode:  ReasonUntrustedCommit,
+	}, {
+		name:          "unsigned",
+		namespace:     "team-a",
+		expectedError: "is not signed",
+		expectedCode:  ReasonUntrustedCommit,
+	}, {
+		name:          "secret in another namespace",
+		signKey:       trusted,
+		namespace:     "team-b",
+		expectedError: "error reading trusted keys secret team-b/signers",
+		expectedCode:  resolutioncommon.ReasonResolutionFailed,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			repoDir, commits := createTestRepo(t, []testCommit{{
+				files:   map[string]string{"task.yaml": "kind: Task"},
+				signKey: tc.signKey,
+			}})
+			params := map[string]string{
+				URLParam:               repoDir,
+				PathParam:              "task.yaml",
+				RequireSignatureParam:  "true",
+				TrustedKeysSecretParam: "signers",
+			}
+			resolver := &Resolver{kubeClientSet: kubeClient}
+			ctx := resolutioncommon.InjectRequestNamespace(context.Background(), tc.namespace)
+			if err := resolver.ValidateParams(ctx, params); err != nil {
+				t.Fatalf("unexpected error validating params: %v", err)
+			}
+			resource, err := resolver.Resolve(ctx, params)
+			if tc.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectedError) {
+					t.Fatalf("expected error containing %q received %v", tc.expectedError, err)
+				}
+				// The framework wraps resolver errors, which mustn't hide
+				// the reason.
+				wrapped := &resolutioncommon.ErrorGettingResource{ResolverName: GitResolverName, Original: err}
+				if reason, _ := resolutioncommon.ReasonError(wrapped); reason != tc.expectedCode {
+					t.Errorf("expected reason %q received %q", tc.expectedCode, reason)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error resolving: %v", err)
+			}
+			if resource.Annotations()[AnnotationKeyCommitHash] != commits[0] {
+				t.Errorf("expected commit %q received %q", commits[0], resource.Annotations()[AnnotationKeyCommitHash])
+			}
+			if signer := resource.Annotations()[AnnotationKeyCommitSigner]; signer != trusted.PrimaryKey.KeyIdString() {
+				t.Errorf("expected commit signer %q received %q", trusted.PrimaryKey.KeyIdString(), signer)
+			}
+		})
+	}
+}
+
+func TestValidateParamsRequireSignature(t *testing.T) {
+	for _, tc := range []struct {
+		params      map[string]string
+		expectedErr string
+	}{{
+		params:      map[string]string{RequireSignatureParam: "true"},
+		expectedErr: `"requireSignature" requires "trustedKeysSecret"`,
+	}, {
+		params:      map[string]string{TrustedKeysSecretParam: "signers"},
+		expectedErr: `"trustedKeysSecret" requires "requireSignature"`,
+	}, {
+		params:      map[string]string{RequireSignatureParam: "yes", TrustedKeysSecretParam: "signers"},
+		expectedErr: `invalid value for "requireSignature"`,
+	}} {
+		tc.params[URLParam] = "https://github.com/tektoncd/catalog"
+		tc.params[PathParam] = "task.yaml"
+		resolver := Resolver{}
+		err := resolver.ValidateParams(context.Background(), tc.params)
+		if err == nil || !strings.Contains(err.Error(), tc.expectedErr) {
+			t.Errorf("expected error containing %q, received %v", tc.expectedErr, err)
+		}
+	}
+}
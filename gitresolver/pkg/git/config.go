@@ -3,3 +3,366 @@ package git
 // ConfigFieldTimeout is the configuration field name for controlling
 // the maximum duration of a resolution request for a file from git.
 const ConfigFieldTimeout = "fetch-timeout"
+
+// ConfigFieldMaxSize is the configuration field name for controlling
+// the maximum size of a file that may be resolved from git. The value
+// is a quantity like "1Mi" or "500k".
+const ConfigFieldMaxSize = "max-size"
+
+// ConfigFieldMinimalFetch is the configuration field name for enabling
+// fetches that only transfer the refs a request needs instead of
+// cloning the whole repository.
+const ConfigFieldMinimalFetch = "minimal-fetch"
+
+// ConfigFieldRejectEmpty is the configuration field name for failing
+// resolutions that return an empty file, unless the request explicitly
+// allows empty content.
+const ConfigFieldRejectEmpty = "reject-empty"
+
+// ConfigFieldComputeOCIDigest is the configuration field name for
+// recording the sha256 OCI blob digest of resolved content in an
+// annotation.
+const ConfigFieldComputeOCIDigest = "compute-oci-digest"
+
+// ConfigFieldConnectTimeout is the configuration field name for
+// controlling the maximum duration of establishing a connection to a
+// git server, separate from the overall fetch-timeout.
+const ConfigFieldConnectTimeout = "connect-timeout"
+
+// ConfigFieldRenderer is the configuration field name for selecting a
+// templating engine that resolved files are rendered with. One of
+// "none" or "ytt".
+const ConfigFieldRenderer = "renderer"
+
+// ConfigFieldGitCLIFallback is the configuration field name for
+// retrying a failed go-git clone with the git binary.
+const ConfigFieldGitCLIFallback = "git-cli-fallback"
+
+// ConfigFieldUseWorktreeContent is the configuration field name for
+// reading resolved files from the checked out worktree, with any
+// checkout filters such as line ending conversion applied, instead of
+// the raw blob in the object store.
+const ConfigFieldUseWorktreeContent = "use-worktree-content"
+
+// ConfigFieldSSHProxyJump is the configuration field name for a jump
+// host, written as user@host[:port], that ssh connections to git
+// servers are routed through.
+const ConfigFieldSSHProxyJump = "ssh-proxy-jump"
+
+// ConfigFieldSSHProxyJumpSecret is the configuration field name for the
+// secret in the resolver's namespace holding the ssh-privatekey and
+// known_hosts used for both the jump host and the git servers behind it.
+const ConfigFieldSSHProxyJumpSecret = "ssh-proxy-jump-secret"
+
+// ConfigFieldCacheSize is the configuration field name for controlling
+// how many resolved files are cached in memory, keyed by the commit
+// they were read from. Zero or unset disables the cache.
+const ConfigFieldCacheSize = "cache-size"
+
+// ConfigFieldRefCacheTTL is the configuration field name for how long
+// the commit that a moving ref like a branch points to is remembered
+// before the remote is listed again. Only used when cache-size is set.
+const ConfigFieldRefCacheTTL = "ref-cache-ttl"
+
+// ConfigFieldMaxRefStaleness is the configuration field name for the
+// oldest a remembered moving ref may be before it is looked up again,
+// even if it is still within ref-cache-ttl. Commits are never looked
+// up so they aren't affected.
+const ConfigFieldMaxRefStaleness = "max-ref-staleness"
+
+// ConfigFieldAuthMode is the configuration field name for selecting how
+// fetches authenticate to git servers. One of "none" or
+// "workload-identity".
+const ConfigFieldAuthMode = "auth-mode"
+
+// ConfigFieldCanonicalizeYAML is the configuration field name for
+// re-emitting resolved yaml with sorted keys and consistent indentation
+// so that its digest is stable across cosmetic changes.
+const ConfigFieldCanonicalizeYAML = "canonicalize-yaml"
+
+// ConfigFieldCanonicalizeYAMLSkipInvalid is the configuration field
+// name for returning files that can't be parsed as yaml unchanged when
+// canonicalize-yaml is set, instead of failing the resolution.
+const ConfigFieldCanonicalizeYAMLSkipInvalid = "canonicalize-yaml-skip-invalid"
+
+// ConfigFieldProxyAuthSecret is the configuration field name for a
+// kubernetes.io/basic-auth secret in the resolver's namespace whose
+// username and password are sent to http proxies, separately from any
+// credentials for the repo itself.
+const ConfigFieldProxyAuthSecret = "proxy-auth-secret"
+
+// ConfigFieldGitURLSuffix is the configuration field name for how the
+// ".git" suffix of remote repo urls is handled before cloning. One of
+// "keep", "add" or "strip".
+const ConfigFieldGitURLSuffix = "git-url-suffix"
+
+// ConfigFieldEnforceCommitOnBranch is the configuration field name for
+// allowing requests to supply both a commit and a branch, failing them
+// unless the commit is an ancestor of the branch's tip.
+const ConfigFieldEnforceCommitOnBranch = "enforce-commit-on-branch"
+
+// ConfigFieldMaxFiles is the configuration field name for the most
+// files that resolving a directory may include.
+const ConfigFieldMaxFiles = "max-files"
+
+// ConfigFieldTokenRefreshWindow is the configuration field name for how
+// long before their expiry minted access tokens are replaced.
+const ConfigFieldTokenRefreshWindow = "token-refresh-window"
+
+// ConfigFieldMaxRedirects is the configuration field name for the
+// number of redirects that a request to an http or https git server may
+// follow.
+const ConfigFieldMaxRedirects = "max-redirects"
+
+// ConfigFieldTrustedSigningKeys is the configuration field name for the
+// ascii armored pgp public keys that detached file signatures are
+// verified against.
+const ConfigFieldTrustedSigningKeys = "trusted-signing-keys"
+
+// ConfigFieldSSHIdleTimeout is the configuration field name for how
+// long an idle ssh connection to a git server is kept open for reuse by
+// later resolutions.
+const ConfigFieldSSHIdleTimeout = "ssh-idle-timeout"
+
+// ConfigFieldGitProtocolVersion is the configuration field name for the
+// git wire protocol version that fetches use.
+const ConfigFieldGitProtocolVersion = "git-protocol-version"
+
+// ConfigFieldNegativeCacheTTL is the configuration field name for how
+// long a request that failed because its file or ref doesn't exist is
+// answered with the same failure instead of fetching again.
+const ConfigFieldNegativeCacheTTL = "negative-cache-ttl"
+
+// ConfigFieldTrustedKeys is the configuration field name for a secret
+// in the resolver's namespace listing the pgp keys, and when they are
+// valid, that resolved commits must be signed with.
+const ConfigFieldTrustedKeys = "trusted-keys-secret"
+
+// ConfigFieldMaxDependencyDepth is the configuration field name for the
+// longest chain of references followed when resolving a file's
+// dependencies.
+const ConfigFieldMaxDependencyDepth = "max-dependency-depth"
+
+// ConfigFieldRejectUnresolvedPlaceholders is the configuration field
+// name for failing resolutions whose content still contains template
+// placeholders once any rendering or conversion is done.
+const ConfigFieldRejectUnresolvedPlaceholders = "reject-unresolved-placeholders"
+
+// ConfigFieldPlaceholderPattern is the configuration field name for the
+// regular expression that reject-unresolved-placeholders looks for.
+const ConfigFieldPlaceholderPattern = "placeholder-pattern"
+
+// ConfigFieldAlternatesDir is the configuration field name for the path
+// to a local git repository whose objects are reused, without being
+// modified, instead of being fetched again.
+const ConfigFieldAlternatesDir = "alternates-dir"
+
+// ConfigFieldTagResolutionOrder is the configuration field name for
+// whether a name that's both a branch and a tag resolves to the branch
+// or the tag.
+const ConfigFieldTagResolutionOrder = "tag-resolution-order"
+
+// ConfigFieldTranscodeToUTF8 is the configuration field name for
+// converting resolved files committed in another text encoding to
+// UTF-8 before returning them.
+const ConfigFieldTranscodeToUTF8 = "transcode-to-utf8"
+
+// ConfigFieldShallowSince is the configuration field name for the date
+// that clones only fetch history since.
+const ConfigFieldShallowSince = "shallow-since"
+
+// ConfigFieldCloneDepth is the configuration field name for the number
+// of commits of history that branches and tags are cloned with by
+// default.
+const ConfigFieldCloneDepth = "clone-depth"
+
+// ConfigFieldFetchMode is the configuration field name for how files
+// are fetched when a request doesn't say.
+const ConfigFieldFetchMode = "fetch-mode"
+
+// ConfigFieldGitHubAPIHosts is the configuration field name for the
+// GitHub Enterprise hosts, besides github.com, whose api files may be
+// read from.
+const ConfigFieldGitHubAPIHosts = "github-api-hosts"
+
+// ConfigFieldCloneCacheDir is the configuration field name for the
+// directory that clones are kept in, to be fetched into again rather
+// than cloned from scratch.
+const ConfigFieldCloneCacheDir = "clone-cache-dir"
+
+// ConfigFieldCloneCacheMaxAge is the configuration field name for how
+// long a cached clone may go unused before it's removed.
+const ConfigFieldCloneCacheMaxAge = "clone-cache-max-age"
+
+// ConfigFieldCloneCacheMaxSize is the configuration field name for the
+// most disk space cached clones may take up. The value is a quantity
+// like "10Gi".
+const ConfigFieldCloneCacheMaxSize = "clone-cache-max-size"
+
+// ConfigFieldMaxRefs is the configuration field name for the most
+// branches and tags that are checked for a commit when the refs
+// containing it are requested.
+const ConfigFieldMaxRefs = "max-refs"
+
+// ConfigFieldStripComments is the configuration field name for removing
+// comments and blank lines from resolved files before returning them.
+const ConfigFieldStripComments = "strip-comments"
+
+// ConfigFieldMaxRepoSize is the configuration field name for the most
+// a clone of a repository may fetch. The value is a quantity like
+// "100Mi".
+const ConfigFieldMaxRepoSize = "max-repo-size"
+
+// ConfigFieldPerHostMaxRepoSize is the configuration field name for
+// per host overrides of max-repo-size, as a comma separated list of
+// host=quantity pairs like "github.com=100Mi,git.internal=2Gi".
+const ConfigFieldPerHostMaxRepoSize = "per-host-max-repo-size"
+
+// ConfigFieldWrapWithManifest is the configuration field name for
+// returning resolved files wrapped in a json manifest of their
+// provenance. One of "inline" or "digest".
+const ConfigFieldWrapWithManifest = "wrap-with-manifest"
+
+// ConfigFieldRateLimitRetries is the configuration field name for how
+// many times an http request that a git server rate limits, with a 429
+// or 503 response carrying a Retry-After header, is retried.
+const ConfigFieldRateLimitRetries = "rate-limit-retries"
+
+// ConfigFieldMaxRetryAfter is the configuration field name for the
+// longest a rate limited request waits before it's retried, however
+// long the server's Retry-After asks for.
+const ConfigFieldMaxRetryAfter = "max-retry-after"
+
+// ConfigFieldCrossCheckBackends is the configuration field name for
+// resolving files with both go-git and the git binary and comparing
+// the results. One of "warn" or "fail".
+const ConfigFieldCrossCheckBackends = "cross-check-backends"
+
+// ConfigFieldArchiveOwner is the configuration field name for the
+// numeric uid:gid that the entries of tar archives are owned by.
+const ConfigFieldArchiveOwner = "archive-owner"
+
+// ConfigFieldArchiveFileMode is the configuration field name for the
+// octal permissions that every regular file in tar archives is given,
+// in place of the ones git records.
+const ConfigFieldArchiveFileMode = "archive-file-mode"
+
+// ConfigFieldMaxSymrefDepth is the configuration field name for the
+// most symbolic refs, like HEAD pointing to a branch, that are followed
+// to reach the commit a ref points to.
+const ConfigFieldMaxSymrefDepth = "max-symref-depth"
+
+// ConfigFieldCatalog is the configuration field name for the catalog of
+// short names that CatalogRefParam looks up, each mapped to a locator.
+const ConfigFieldCatalog = "catalog"
+
+// ConfigFieldRequireForkOf is the configuration field name for the
+// upstream repos that resolved repos must be, or be forks of.
+const ConfigFieldRequireForkOf = "require-fork-of"
+
+// ConfigFieldForkAPIURL is the configuration field name for the GitHub
+// compatible api that require-fork-of looks repos up in.
+const ConfigFieldForkAPIURL = "fork-api-url"
+
+// ConfigFieldAttestationKeySecret is the configuration field name for a
+// secret in the resolver's namespace holding the private key that
+// resolved content is attested with.
+const ConfigFieldAttestationKeySecret = "attestation-key-secret"
+
+// ConfigFieldSplitFetchBudget is the configuration field name for
+// dividing the time left for a fetch between the candidate refs and
+// fallbacks that it may try.
+const ConfigFieldSplitFetchBudget = "split-fetch-budget"
+
+// ConfigFieldPinOnFirstResolve is the configuration field name for
+// pinning refs to the commit they're first resolved to.
+const ConfigFieldPinOnFirstResolve = "pin-on-first-resolve"
+
+// ConfigFieldClientCertSecret is the configuration field name for a
+// TLS secret in the requesting namespace holding the client certificate
+// presented to git servers that require mutual TLS.
+const ConfigFieldClientCertSecret = "client-cert-secret"
+
+// ConfigFieldAllowedURLs is the configuration field name for the hosts,
+// or glob patterns of hosts, that repos may be resolved from.
+const ConfigFieldAllowedURLs = "allowed-urls"
+
+// ConfigFieldMaxRetries is the configuration field name for how many
+// times a clone or fetch that fails with a transient error, like a
+// dropped connection or a 5xx response, is retried.
+const ConfigFieldMaxRetries = "max-retries"
+
+// ConfigFieldBackoff is the configuration field name for how long the
+// first retry of a failed clone or fetch waits. Each later retry waits
+// twice as long as the one before.
+const ConfigFieldBackoff = "retry-backoff"
+
+// ConfigFieldPullRequestRef is the configuration field name for the ref
+// that the head of a pull request is fetched from, with {number} in
+// place of the pull request's number.
+const ConfigFieldPullRequestRef = "pull-request-ref"
+
+// ConfigFieldHTTPProxy is the configuration field name for the url of
+// the proxy that http and https git servers are reached through, in
+// place of the HTTP_PROXY and HTTPS_PROXY environment variables.
+const ConfigFieldHTTPProxy = "http-proxy"
+
+// ConfigFieldNoProxy is the configuration field name for the hosts that
+// are reached directly rather than through a proxy, in place of the
+// NO_PROXY environment variable.
+const ConfigFieldNoProxy = "no-proxy"
+
+// ConfigFieldCABundle is the configuration field name for the path of a
+// mounted file of PEM encoded certificate authorities that https git
+// servers' certificates are trusted if issued by.
+const ConfigFieldCABundle = "ca-bundle"
+
+// ConfigFieldCABundleSecret is the configuration field name for a
+// secret in the resolver's namespace holding, under ca.crt, PEM encoded
+// certificate authorities that https git servers' certificates are
+// trusted if issued by.
+const ConfigFieldCABundleSecret = "ca-bundle-secret"
+
+// ConfigFieldSubmodules is the configuration field name for whether
+// paths that lead into a submodule are read from it, when a request
+// doesn't set the submodules param.
+const ConfigFieldSubmodules = "submodules"
+
+// ConfigFieldMaxSubmoduleDepth is the configuration field name for how
+// many nested submodules a path may lead through.
+const ConfigFieldMaxSubmoduleDepth = "max-submodule-depth"
+
+// ConfigFieldDefaultURL is the configuration field name for the url of
+// the repo that files are resolved from when a request doesn't give
+// one.
+const ConfigFieldDefaultURL = "default-url"
+
+// ConfigFieldDefaultRevision is the configuration field name for the
+// revision that files are resolved from when a request doesn't pick a
+// branch, tag, commit or revision.
+const ConfigFieldDefaultRevision = "default-revision"
+
+// ConfigFieldInMemoryClone is the configuration field name for whether
+// small clones are made with go-git into memory, rather than with the
+// git binary or into the clone cache on disk as they otherwise would
+// be.
+const ConfigFieldInMemoryClone = "in-memory-clone"
+
+// ConfigFieldInMemoryCloneMaxDepth is the configuration field name for
+// the deepest clone that in-memory-clone keeps in memory.
+const ConfigFieldInMemoryCloneMaxDepth = "in-memory-clone-max-depth"
+
+// ConfigFieldMaxConcurrentClones is the configuration field name for
+// the most clones that are made at once. Resolutions beyond the limit
+// wait for one to finish.
+const ConfigFieldMaxConcurrentClones = "max-concurrent-clones"
+
+// ConfigFieldNetrc is the configuration field name for the path of a
+// mounted netrc file whose machine entries give the credentials that
+// http and https git servers are fetched from with.
+const ConfigFieldNetrc = "netrc"
+
+// ConfigFieldNetrcSecret is the configuration field name for a secret
+// in the resolver's namespace holding, under .netrc, a netrc file used
+// as with netrc.
+const ConfigFieldNetrcSecret = "netrc-secret"
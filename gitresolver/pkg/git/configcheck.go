@@ -0,0 +1,83 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/tektoncd/resolution/pkg/resolver/framework"
+)
+
+var _ framework.ConfigValidator = &Resolver{}
+
+// ValidateConfig parses every field of the git-resolver-config
+// configmap that has a format, returning an error that lists each
+// malformed one. Requests fall back to a default for some fields, like
+// fetch-timeout, that can't be parsed, so checking them up front is
+// the only way a mistake is noticed before requests start failing.
+func (r *Resolver) ValidateConfig(ctx context.Context, conf map[string]string) error {
+	ctx = framework.InjectResolverConfigToContext(ctx, conf)
+	checks := []func() error{
+		func() error {
+			if timeoutString, ok := conf[ConfigFieldTimeout]; ok {
+				if _, err := time.ParseDuration(timeoutString); err != nil {
+					return fmt.Errorf("invalid %s %q: %w", ConfigFieldTimeout, timeoutString, err)
+				}
+			}
+			return nil
+		},
+		func() error { _, err := getMaxSize(ctx); return err },
+		func() error { _, err := getRetryPolicy(conf); return err },
+		func() error { _, err := getMaxConcurrentClones(conf); return err },
+		func() error { _, err := getRepoSizeLimit(conf, ""); return err },
+		func() error { _, err := getCacheSize(conf); return err },
+		func() error { _, err := getNegativeCacheTTL(conf); return err },
+		func() error { _, err := getCloneCacheOptions(conf); return err },
+		func() error { _, err := getCloneDepth(conf, nil); return err },
+		func() error { _, err := getShallowSince(conf); return err },
+		func() error { _, err := getInMemoryCloneMaxDepth(conf); return err },
+		func() error { _, err := getFetchMode(conf, nil); return err },
+		func() error { _, err := getAuthMode(conf); return err },
+		func() error { _, err := getTokenRefreshWindow(conf); return err },
+		func() error { _, err := getGitProtocolVersion(conf); return err },
+		func() error { _, err := getArchiveOptions(conf); return err },
+		func() error { _, err := getMaxRefs(conf); return err },
+		func() error { _, err := getMaxFiles(conf); return err },
+		func() error { _, err := getMaxDependencyDepth(conf); return err },
+		func() error { _, err := getMaxSubmoduleDepth(conf); return err },
+		func() error { _, err := getMaxSymrefDepth(ctx); return err },
+		func() error { _, err := getCrossCheckMode(conf); return err },
+		func() error { _, err := getTagResolutionOrder(conf); return err },
+		func() error { _, err := getRenderer(conf); return err },
+		func() error { _, err := getPlaceholderPattern(conf); return err },
+		func() error { _, err := getGitSuffixMode(conf); return err },
+	}
+	problems := []string{}
+	for _, check := range checks {
+		if err := check(); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package git
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestValidateConfig(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		conf    map[string]string
+		wantErr []string
+	}{{
+		name: "empty",
+		conf: map[string]string{},
+	}, {
+		name: "valid",
+		conf: map[string]string{
+			ConfigFieldTimeout:    "30s",
+			ConfigFieldMaxSize:    "2Mi",
+			ConfigFieldMaxRetries: "3",
+		},
+	}, {
+		name:    "malformed timeout",
+		conf:    map[string]string{ConfigFieldTimeout: "1 minute"},
+		wantErr: []string{ConfigFieldTimeout, "1 minute"},
+	}, {
+		name: "every malformed field is reported",
+		conf: map[string]string{
+			ConfigFieldTimeout:    "soon",
+			ConfigFieldMaxSize:    "big",
+			ConfigFieldMaxRetries: "-1",
+		},
+		wantErr: []string{ConfigFieldTimeout, ConfigFieldMaxSize, ConfigFieldMaxRetries},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := (&Resolver{}).ValidateConfig(context.Background(), tc.conf)
+			if len(tc.wantErr) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			for _, want := range tc.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("expected error to mention %q, got %v", want, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,213 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+// defaultMaxRefs is the most refs that are checked for a commit when
+// max-refs isn't configured.
+const defaultMaxRefs = 1000
+
+// refSpecsBranchesAndTags fetch every branch and tag under their own
+// names, so that they can be enumerated once fetched.
+var refSpecsBranchesAndTags = []config.RefSpec{
+	"+refs/heads/*:refs/heads/*",
+	"+refs/tags/*:refs/tags/*",
+}
+
+// ContainingRefsResult is the json document returned in place of a
+// file's content when the refs containing a commit are requested.
+type ContainingRefsResult struct {
+	Commit   string   `json:"commit"`
+	Branches []string `json:"branches"`
+	Tags     []string `json:"tags"`
+}
+
+// getMaxRefs returns the most refs that may be checked for a commit, as
+// configured with the max-refs field in the git-resolver-config
+// configmap.
+func getMaxRefs(conf map[string]string) (int, error) {
+	maxString, ok := conf[ConfigFieldMaxRefs]
+	if !ok || maxString == "" {
+		return defaultMaxRefs, nil
+	}
+	max, err := strconv.Atoi(maxString)
+	if err != nil || max <= 0 {
+		return 0, fmt.Errorf("invalid %s %q: must be a positive integer", ConfigFieldMaxRefs, maxString)
+	}
+	return max, nil
+}
+
+// fetchBranchesAndTags retrieves every branch and tag of the repository
+// at url along with their full history. The configured fetch strategy
+// isn't used since it only fetches a single ref.
+func fetchBranchesAndTags(ctx context.Context, conf map[string]string, url string, auth transport.AuthMethod) (*git.Repository, error) {
+	store, err := newStorage(conf[ConfigFieldAlternatesDir])
+	if err != nil {
+		return nil, err
+	}
+	store = limitRepoSize(ctx, store)
+	repository, err := git.Init(store, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error initializing repository: %w", err)
+	}
+	remote, err := repository.CreateRemote(&config.RemoteConfig{
+		Name: remoteName,
+		URLs: []string{url},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error creating remote: %w", err)
+	}
+	err = remote.FetchContext(ctx, &git.FetchOptions{
+		RemoteName: remoteName,
+		RefSpecs:   refSpecsBranchesAndTags,
+		Auth:       auth,
+		Tags:       git.NoTags,
+	})
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return nil, fmt.Errorf("error fetching refs from %q: %w", url, err)
+	}
+	return repository, nil
+}
+
+// containingRefs returns the branches and tags of repository that
+// commit is reachable from, as a json-encoded resource. Repositories
+// with more than maxRefs branches and tags are rejected rather than
+// partially checked, since each ref means a walk of its history.
+func containingRefs(repository *git.Repository, commit string, maxRefs int) (*ResolvedGitResource, error) {
+	target, err := repository.CommitObject(plumbing.NewHash(commit))
+	if err != nil {
+		return nil, fmt.Errorf("error reading commit %q: %w", commit, err)
+	}
+
+	iter, err := repository.References()
+	if err != nil {
+		return nil, fmt.Errorf("error listing refs: %w", err)
+	}
+	refs := []*plumbing.Reference{}
+	err = iter.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Type() == plumbing.HashReference && (ref.Name().IsBranch() || ref.Name().IsTag()) {
+			refs = append(refs, ref)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing refs: %w", err)
+	}
+	if len(refs) > maxRefs {
+		return nil, fmt.Errorf("repository has %d branches and tags, exceeds limit %d", len(refs), maxRefs)
+	}
+
+	result := ContainingRefsResult{
+		Commit:   commit,
+		Branches: []string{},
+		Tags:     []string{},
+	}
+	// Several refs often point to the same commit, so each commit is
+	// only checked once.
+	reachable := map[plumbing.Hash]bool{}
+	for _, ref := range refs {
+		hash, err := peelToCommit(repository, ref.Hash())
+		if err != nil {
+			return nil, err
+		}
+		contains, checked := reachable[hash]
+		if !checked {
+			tip, err := repository.CommitObject(hash)
+			if err != nil {
+				// Tags can point to trees and blobs, which
+				// contain no commits.
+				reachable[hash] = false
+				continue
+			}
+			if hash == target.Hash {
+				contains = true
+			} else if contains, err = target.IsAncestor(tip); err != nil {
+				return nil, fmt.Errorf("error checking whether %q contains commit %q: %w", ref.Name(), commit, err)
+			}
+			reachable[hash] = contains
+		}
+		if !contains {
+			continue
+		}
+		if ref.Name().IsBranch() {
+			result.Branches = append(result.Branches, ref.Name().Short())
+		} else {
+			result.Tags = append(result.Tags, strings.TrimPrefix(ref.Name().String(), "refs/tags/"))
+		}
+	}
+	sort.Strings(result.Branches)
+	sort.Strings(result.Tags)
+
+	content, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("error serializing refs containing commit %q: %w", commit, err)
+	}
+	return &ResolvedGitResource{
+		Commit:      commit,
+		Content:     content,
+		ContentType: JSONContentType,
+	}, nil
+}
+
+// resolveContainingRefs fetches every branch and tag of the repository
+// at url and returns those that commit is reachable from. remoteURL is
+// the url as requested, which url may differ from when tunnelled.
+func (r *Resolver) resolveContainingRefs(ctx context.Context, conf map[string]string, remoteURL, url string, auth transport.AuthMethod, commit string) (*ResolvedGitResource, error) {
+	maxRefs, err := getMaxRefs(conf)
+	if err != nil {
+		return nil, err
+	}
+	retries, err := getRetryPolicy(conf)
+	if err != nil {
+		return nil, err
+	}
+	cloneCtx, cloneSpan := startSpan(ctx, "git.clone", attrHost.String(repoHost(remoteURL)), attrURL.String(sanitizeURL(remoteURL)))
+	cloneCtx, fetchedBytes := withFetchedBytesCounter(cloneCtx)
+	var repository *git.Repository
+	_, err = r.withTokenRetry(cloneCtx, conf, url, auth, func(auth transport.AuthMethod) error {
+		return withRetries(cloneCtx, retries, func() (err error) {
+			repository, err = fetchBranchesAndTags(cloneCtx, conf, url, auth)
+			return err
+		})
+	})
+	cloneSpan.SetAttributes(attrFetchedBytes.Int64(atomic.LoadInt64(fetchedBytes)))
+	endSpan(cloneSpan, err)
+	if err != nil {
+		return nil, err
+	}
+	if commit, err = expandCommitHash(repository, commit); err != nil {
+		return nil, err
+	}
+	_, readSpan := startSpan(ctx, "git.read", attrCommit.String(commit))
+	resolved, err := containingRefs(repository, commit, maxRefs)
+	endSpan(readSpan, err)
+	return resolved, err
+}
@@ -0,0 +1,154 @@
+package git
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/tektoncd/resolution/pkg/resolver/framework"
+)
+
+// createReleaseRefsRepo makes a repo with three commits on master, a
+// release branch and annotated tag at the second, and a lightweight tag
+// and an unrelated branch at the first.
+func createReleaseRefsRepo(t *testing.T) (string, []string) {
+	t.Helper()
+	repoDir, commits := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "one"},
+	}, {
+		files: map[string]string{"task.yaml": "two"},
+	}, {
+		files: map[string]string{"task.yaml": "three"},
+	}})
+	setTestRef(t, repoDir, "refs/heads/release-1", commits[1])
+	setTestRef(t, repoDir, "refs/heads/old", commits[0])
+	setTestRef(t, repoDir, "refs/tags/v0", commits[0])
+	repo, err := git.PlainOpen(repoDir)
+	if err != nil {
+		t.Fatalf("error opening repo: %v", err)
+	}
+	if _, err := repo.CreateTag("v1", plumbing.NewHash(commits[1]), &git.CreateTagOptions{
+		Tagger:  &object.Signature{Name: "Someone", Email: "someone@example.com", When: time.Now()},
+		Message: "v1",
+	}); err != nil {
+		t.Fatalf("error creating annotated tag: %v", err)
+	}
+	return repoDir, commits
+}
+
+func TestResolveContainingRefs(t *testing.T) {
+	repoDir, commits := createReleaseRefsRepo(t)
+
+	for _, tc := range []struct {
+		name     string
+		commit   string
+		expected ContainingRefsResult
+	}{{
+		name:   "first commit",
+		commit: commits[0],
+		expected: ContainingRefsResult{
+			Branches: []string{"master", "old", "release-1"},
+			Tags:     []string{"v0", "v1"},
+		},
+	}, {
+		name:   "released commit",
+		commit: commits[1],
+		expected: ContainingRefsResult{
+			Branches: []string{"master", "release-1"},
+			Tags:     []string{"v1"},
+		},
+	}, {
+		name:   "unreleased commit",
+		commit: commits[2],
+		expected: ContainingRefsResult{
+			Branches: []string{"master"},
+			Tags:     []string{},
+		},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			params := map[string]string{
+				URLParam:            repoDir,
+				CommitParam:         tc.commit,
+				ContainingRefsParam: "true",
+			}
+			resolver := Resolver{}
+			if err := resolver.ValidateParams(context.Background(), params); err != nil {
+				t.Fatalf("unexpected error validating params: %v", err)
+			}
+			resource, err := resolver.Resolve(context.Background(), params)
+			if err != nil {
+				t.Fatalf("unexpected error resolving: %v", err)
+			}
+			if ct := resource.(*ResolvedGitResource).ContentType; ct != JSONContentType {
+				t.Errorf("expected content type %q received %q", JSONContentType, ct)
+			}
+			result := ContainingRefsResult{}
+			if err := json.Unmarshal(resource.Data(), &result); err != nil {
+				t.Fatalf("error parsing containing refs result: %v", err)
+			}
+			tc.expected.Commit = tc.commit
+			if !reflect.DeepEqual(result, tc.expected) {
+				t.Errorf("expected %+v received %+v", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestResolveContainingRefsMaxRefs(t *testing.T) {
+	repoDir, commits := createReleaseRefsRepo(t)
+	ctx := framework.InjectResolverConfigToContext(context.Background(), map[string]string{
+		ConfigFieldMaxRefs: "4",
+	})
+
+	resolver := Resolver{}
+	_, err := resolver.Resolve(ctx, map[string]string{
+		URLParam:            repoDir,
+		CommitParam:         commits[0],
+		ContainingRefsParam: "true",
+	})
+	if err == nil || !strings.Contains(err.Error(), "exceeds limit 4") {
+		t.Errorf("expected ref limit error, received %v", err)
+	}
+}
+
+func TestValidateParamsContainingRefs(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		params   map[string]string
+		expected string
+	}{{
+		name:     "missing commit",
+		params:   map[string]string{URLParam: "foo"},
+		expected: "missing commit",
+	}, {
+		name:     "with branch",
+		params:   map[string]string{URLParam: "foo", CommitParam: "abc1234", BranchParam: "main"},
+		expected: `supplied both "containingRefs" and "branch"`,
+	}, {
+		name:     "with blame",
+		params:   map[string]string{URLParam: "foo", CommitParam: "abc1234", BlameParam: "true"},
+		expected: `supplied both "containingRefs" and "blame"`,
+	}, {
+		name:   "without path",
+		params: map[string]string{URLParam: "foo", CommitParam: "abc1234"},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.params[ContainingRefsParam] = "true"
+			resolver := Resolver{}
+			err := resolver.ValidateParams(context.Background(), tc.params)
+			if tc.expected == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tc.expected) {
+				t.Errorf("expected error containing %q, received %v", tc.expected, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package git
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/osfs"
+	git "github.com/go-git/go-git/v5"
+	"github.com/tektoncd/resolution/pkg/resolver/framework"
+)
+
+const (
+	lfContent   = "a: 1\nb: 2\n"
+	crlfContent = "a: 1\r\nb: 2\r\n"
+)
+
+// createCRLFTestRepo uses the git binary to commit a file with CRLF line
+// endings to a repo configured with core.autocrlf, so that the blob is
+// stored with LF line endings while the worktree has CRLF.
+func createCRLFTestRepo(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	run := func(args ...string) {
+		cmd := exec.Command(gitBinary, args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), nonInteractiveEnv()...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+	run("init", "--quiet", "--initial-branch", "master")
+	run("config", "core.autocrlf", "true")
+	if err := os.WriteFile(filepath.Join(dir, "task.yaml"), []byte(crlfContent), 0600); err != nil {
+		t.Fatalf("error writing file: %v", err)
+	}
+	run("add", "task.yaml")
+	run("-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "-m", "add task")
+	return dir
+}
+
+func TestReadBlobIgnoresAutoCRLF(t *testing.T) {
+	repoDir := createCRLFTestRepo(t)
+	repository, err := git.PlainOpen(repoDir)
+	if err != nil {
+		t.Fatalf("error opening repo: %v", err)
+	}
+	head, err := repository.Head()
+	if err != nil {
+		t.Fatalf("error reading head: %v", err)
+	}
+
+	blob, err := readBlob(repository, head.Hash().String(), "/task.yaml")
+	if err != nil {
+		t.Fatalf("unexpected error reading blob: %v", err)
+	}
+	if string(blob) != lfContent {
+		t.Errorf("expected object store content %q received %q", lfContent, string(blob))
+	}
+
+	worktree, err := readFile(osfs.New(repoDir), "task.yaml")
+	if err != nil {
+		t.Fatalf("unexpected error reading worktree file: %v", err)
+	}
+	if string(worktree) != crlfContent {
+		t.Errorf("expected worktree content %q received %q", crlfContent, string(worktree))
+	}
+}
+
+// TestResolveContentSource checks that the resolver doesn't pick up the
+// line endings of the source repo's checkout. go-git applies no checkout
+// filters to its own worktree, so both content sources agree here.
+func TestResolveContentSource(t *testing.T) {
+	repoDir := createCRLFTestRepo(t)
+	for _, tc := range []struct {
+		name   string
+		config map[string]string
+	}{{
+		name:   "object store",
+		config: map[string]string{},
+	}, {
+		name:   "worktree",
+		config: map[string]string{ConfigFieldUseWorktreeContent: "true"},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := framework.InjectResolverConfigToContext(context.Background(), tc.config)
+			resolver := Resolver{}
+			resource, err := resolver.Resolve(ctx, map[string]string{
+				URLParam:  repoDir,
+				PathParam: "task.yaml",
+			})
+			if err != nil {
+				t.Fatalf("unexpected error resolving: %v", err)
+			}
+			if string(resource.Data()) != lfContent {
+				t.Errorf("expected %q received %q", lfContent, string(resource.Data()))
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+const (
+	// FetchModeClone reads files from a clone of their repo.
+	FetchModeClone = "clone"
+
+	// FetchModeAPI reads files from GitHub's contents api, without a
+	// clone, when the repo is on a GitHub host and nothing but the
+	// file is needed, and from a clone otherwise.
+	FetchModeAPI = "api"
+)
+
+// getFetchMode returns how a file is fetched, as requested with the
+// fetchMode param or else configured with the fetch-mode field in the
+// git-resolver-config configmap, defaulting to a clone.
+func getFetchMode(conf, params map[string]string) (string, error) {
+	mode, name := params[FetchModeParam], FetchModeParam
+	if mode == "" {
+		mode, name = conf[ConfigFieldFetchMode], ConfigFieldFetchMode
+	}
+	switch mode {
+	case "", FetchModeClone:
+		return FetchModeClone, nil
+	case FetchModeAPI:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid %s %q: must be %q or %q", name, mode, FetchModeClone, FetchModeAPI)
+	}
+}
+
+// contentsAPIRepo returns the base url of the api that serves the repo
+// at repoURL, along with the repo's full name, like tektoncd/catalog.
+// It returns false unless repoURL is an https url on github.com or one
+// of the hosts configured with the github-api-hosts field.
+func contentsAPIRepo(conf map[string]string, repoURL string) (string, string, bool) {
+	u, err := url.Parse(repoURL)
+	if err != nil || u.Scheme != "https" || u.User != nil {
+		return "", "", false
+	}
+	host := strings.ToLower(u.Host)
+	known := host == "github.com"
+	for _, h := range strings.Split(conf[ConfigFieldGitHubAPIHosts], ",") {
+		if h = strings.ToLower(strings.TrimSpace(h)); h != "" && h == host {
+			known = true
+		}
+	}
+	fullName := strings.TrimSuffix(strings.Trim(u.Path, "/"), ".git")
+	if !known || strings.Count(fullName, "/") != 1 {
+		return "", "", false
+	}
+	return githubAPIURL(host), fullName, true
+}
+
+// canUseContentsAPI returns false if resolving params needs more of the
+// repo than the content of a single file, such as its history, other
+// files, or the worktree that a checkout would produce.
+func canUseContentsAPI(conf, params map[string]string, path string) bool {
+	if isGlobPattern(path) {
+		return false
+	}
+	for _, p := range []string{BlameParam, StatOnlyParam, CommitMetadataParam, ContainingRefsParam, IncludeDependenciesParam, IncludeLicenseParam, VerifySignatureParam, RequireSignatureParam, SubmodulesParam} {
+		if enabled, _ := strconv.ParseBool(params[p]); enabled {
+			return false
+		}
+	}
+	if params[GrepParam] != "" || params[FormatParam] == FormatTar || params[PullRequestParam] != "" || params[DateParam] != "" || params[RefSpecParam] != "" {
+		return false
+	}
+	if followSubmodules(conf, params) {
+		return false
+	}
+	for _, field := range []string{ConfigFieldTrustedKeys, ConfigFieldCrossCheckBackends} {
+		if conf[field] != "" {
+			return false
+		}
+	}
+	useWorktree, _ := strconv.ParseBool(conf[ConfigFieldUseWorktreeContent])
+	return !useWorktree
+}
+
+// apiContent is a file as GitHub's contents api returns it.
+type apiContent struct {
+	Type     string `json:"type"`
+	Encoding string `json:"encoding"`
+	Content  string `json:"content"`
+	SHA      string `json:"sha"`
+}
+
+// fetchFromContentsAPI returns the content of the file at path, and the
+// commit it was read from, using the api at apiURL. The commit is the
+// one given, or else the first of candidates that exists, or the
+// default branch if there are none. The content is checked against the
+// blob hash the api reports for it.
+func fetchFromContentsAPI(ctx context.Context, apiURL, fullName string, auth transport.AuthMethod, candidates []plumbing.ReferenceName, commit, path string) ([]byte, string, error) {
+	repoAPI := strings.TrimSuffix(apiURL, "/") + "/repos/" + fullName
+	if commit == "" {
+		refs := []string{"HEAD"}
+		if len(candidates) > 0 {
+			refs = refs[:0]
+			for _, candidate := range candidates {
+				// The api takes heads/<branch> and tags/<tag>
+				// to tell apart a branch and a tag of the same
+				// name.
+				refs = append(refs, strings.TrimPrefix(candidate.String(), "refs/"))
+			}
+		}
+		var err error
+		for _, ref := range refs {
+			var body []byte
+			body, err = getAPI(ctx, repoAPI+"/commits/"+escapeAPIPath(ref), "application/vnd.github.sha", auth)
+			if errors.Is(err, errAPINotFound) {
+				continue
+			}
+			if err != nil {
+				return nil, "", err
+			}
+			commit = strings.TrimSpace(string(body))
+			break
+		}
+		if commit == "" {
+			return nil, "", err
+		}
+		if !isFullCommitHash(commit) {
+			return nil, "", fmt.Errorf("unexpected commit %q from %s", commit, repoAPI)
+		}
+	}
+
+	body, err := getAPI(ctx, repoAPI+"/contents/"+escapeAPIPath(strings.TrimPrefix(path, "/"))+"?ref="+commit, "application/vnd.github+json", auth)
+	if err != nil {
+		return nil, "", err
+	}
+	file := apiContent{}
+	if err := json.Unmarshal(body, &file); err != nil || file.Type != "file" {
+		return nil, "", fmt.Errorf("%q is not a file", path)
+	}
+	// Files over a megabyte are returned without their content.
+	if file.Encoding != "base64" {
+		return nil, "", fmt.Errorf("file %q is too large for the contents api", path)
+	}
+	content, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(file.Content, "\n", ""))
+	if err != nil {
+		return nil, "", fmt.Errorf("error decoding file %q from the contents api: %w", path, err)
+	}
+	if hash := plumbing.ComputeHash(plumbing.BlobObject, content).String(); hash != file.SHA {
+		return nil, "", fmt.Errorf("file %q from the contents api has hash %s, not the %s it was reported with", path, hash, file.SHA)
+	}
+	return content, commit, nil
+}
+
+// escapeAPIPath escapes each segment of a slash separated path for use
+// in an api url.
+func escapeAPIPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,358 @@
+package git
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/tektoncd/resolution/pkg/resolver/framework"
+)
+
+// contentsAPIServer is a GitHub host that serves repoDir both as the
+// git repo org/repo and through a fake of the commits and contents
+// apis.
+type contentsAPIServer struct {
+	*httptest.Server
+	apiRequests, gitRequests int32
+
+	// rateLimited turns every api request away as over its limit.
+	rateLimited int32
+}
+
+func newContentsAPIServer(t *testing.T, repoDir string) *contentsAPIServer {
+	t.Helper()
+	repo, err := git.PlainOpen(repoDir)
+	if err != nil {
+		t.Fatalf("error opening repo: %v", err)
+	}
+	s := &contentsAPIServer{}
+	gitHandler := http.StripPrefix("/org", newGitHTTPHandler(t, repoDir, nil))
+	mux := http.NewServeMux()
+	mux.Handle("/org/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&s.gitRequests, 1)
+		gitHandler.ServeHTTP(w, r)
+	}))
+	mux.Handle("/api/v3/repos/org/repo/commits/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.countAPIRequest(w) {
+			return
+		}
+		ref := strings.TrimPrefix(r.URL.Path, "/api/v3/repos/org/repo/commits/")
+		name := plumbing.ReferenceName("refs/" + ref)
+		if ref == "HEAD" {
+			name = plumbing.HEAD
+		}
+		resolved, err := repo.Reference(name, true)
+		if err != nil || r.Header.Get("Accept") != "application/vnd.github.sha" {
+			http.NotFound(w, r)
+			return
+		}
+		hash := resolved.Hash()
+		if tag, err := repo.TagObject(hash); err == nil {
+			hash = tag.Target
+		}
+		w.Write([]byte(hash.String()))
+	}))
+	mux.Handle("/api/v3/repos/org/repo/contents/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.countAPIRequest(w) {
+			return
+		}
+		commit, err := repo.CommitObject(plumbing.NewHash(r.URL.Query().Get("ref")))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		file, err := commit.File(strings.TrimPrefix(r.URL.Path, "/api/v3/repos/org/repo/contents/"))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		content, err := file.Contents()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(apiContent{
+			Type:     "file",
+			Encoding: "base64",
+			Content:  base64.StdEncoding.EncodeToString([]byte(content)),
+			SHA:      file.Hash.String(),
+		})
+	}))
+	s.Server = httptest.NewTLSServer(mux)
+	t.Cleanup(s.Close)
+
+	original := rootCAs
+	rootCAs = x509.NewCertPool()
+	rootCAs.AddCert(s.Certificate())
+	installTransports()
+	t.Cleanup(func() {
+		rootCAs = original
+		installTransports()
+	})
+	return s
+}
+
+// countAPIRequest records an api request, failing it if the server is
+// rate limited.
+func (s *contentsAPIServer) countAPIRequest(w http.ResponseWriter) bool {
+	atomic.AddInt32(&s.apiRequests, 1)
+	if atomic.LoadInt32(&s.rateLimited) != 0 {
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		http.Error(w, "API rate limit exceeded", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+func TestResolveContentsAPI(t *testing.T) {
+	repoDir, commits := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "first"},
+	}, {
+		files: map[string]string{"task.yaml": "second"},
+	}})
+	setTestRef(t, repoDir, "refs/heads/feature", commits[0])
+	setTestRef(t, repoDir, "refs/tags/v1", commits[0])
+	server := newContentsAPIServer(t, repoDir)
+	host := strings.TrimPrefix(server.URL, "https://")
+
+	for _, tc := range []struct {
+		name            string
+		params          map[string]string
+		conf            map[string]string
+		rateLimited     bool
+		expectedCommit  string
+		expectedContent string
+		expectAPI       bool
+		expectClone     bool
+	}{{
+		name:            "default branch",
+		params:          map[string]string{FetchModeParam: FetchModeAPI},
+		expectedCommit:  commits[1],
+		expectedContent: "second",
+		expectAPI:       true,
+	}, {
+		name:            "branch",
+		params:          map[string]string{FetchModeParam: FetchModeAPI, BranchParam: "feature"},
+		expectedCommit:  commits[0],
+		expectedContent: "first",
+		expectAPI:       true,
+	}, {
+		name:            "tag",
+		params:          map[string]string{FetchModeParam: FetchModeAPI, TagParam: "v1"},
+		expectedCommit:  commits[0],
+		expectedContent: "first",
+		expectAPI:       true,
+	}, {
+		name:            "commit",
+		params:          map[string]string{FetchModeParam: FetchModeAPI, CommitParam: commits[0]},
+		expectedCommit:  commits[0],
+		expectedContent: "first",
+		expectAPI:       true,
+	}, {
+		name:            "configured default",
+		params:          map[string]string{},
+		conf:            map[string]string{ConfigFieldFetchMode: FetchModeAPI},
+		expectedCommit:  commits[1],
+		expectedContent: "second",
+		expectAPI:       true,
+	}, {
+		name:            "rate limited",
+		params:          map[string]string{FetchModeParam: FetchModeAPI},
+		rateLimited:     true,
+		expectedCommit:  commits[1],
+		expectedContent: "second",
+		expectAPI:       true,
+		expectClone:     true,
+	}, {
+		name:            "clone",
+		params:          map[string]string{},
+		expectedCommit:  commits[1],
+		expectedContent: "second",
+		expectClone:     true,
+	}, {
+		name:            "needs the repo",
+		params:          map[string]string{FetchModeParam: FetchModeAPI, IncludeLicenseParam: "true"},
+		expectedCommit:  commits[1],
+		expectedContent: "second",
+		expectClone:     true,
+	}, {
+		name:            "host without the api",
+		params:          map[string]string{FetchModeParam: FetchModeAPI},
+		conf:            map[string]string{ConfigFieldGitHubAPIHosts: "github.example.com"},
+		expectedCommit:  commits[1],
+		expectedContent: "second",
+		expectClone:     true,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			atomic.StoreInt32(&server.apiRequests, 0)
+			atomic.StoreInt32(&server.gitRequests, 0)
+			rateLimited := int32(0)
+			if tc.rateLimited {
+				rateLimited = 1
+			}
+			atomic.StoreInt32(&server.rateLimited, rateLimited)
+			conf := map[string]string{ConfigFieldGitHubAPIHosts: host}
+			for k, v := range tc.conf {
+				conf[k] = v
+			}
+			ctx := framework.InjectResolverConfigToContext(context.Background(), conf)
+			params := map[string]string{
+				URLParam:  server.URL + "/org/repo",
+				PathParam: "task.yaml",
+			}
+			for k, v := range tc.params {
+				params[k] = v
+			}
+
+			resolver := Resolver{}
+			if err := resolver.ValidateParams(ctx, params); err != nil {
+				t.Fatalf("unexpected error validating params: %v", err)
+			}
+			resource, err := resolver.Resolve(ctx, params)
+			if err != nil {
+				t.Fatalf("unexpected error resolving: %v", err)
+			}
+			if string(resource.Data()) != tc.expectedContent {
+				t.Errorf("expected content %q received %q", tc.expectedContent, resource.Data())
+			}
+			if commit := resource.Annotations()[AnnotationKeyCommitHash]; commit != tc.expectedCommit {
+				t.Errorf("expected commit %q received %q", tc.expectedCommit, commit)
+			}
+			if usedAPI := atomic.LoadInt32(&server.apiRequests) > 0; usedAPI != tc.expectAPI {
+				t.Errorf("expected api to be used %t, received %d api requests", tc.expectAPI, server.apiRequests)
+			}
+			if cloned := atomic.LoadInt32(&server.gitRequests) > 0; cloned != tc.expectClone {
+				t.Errorf("expected a clone %t, received %d git requests", tc.expectClone, server.gitRequests)
+			}
+		})
+	}
+}
+
+func TestResolveContentsAPIMissingFile(t *testing.T) {
+	repoDir, _ := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "kind: Task"},
+	}})
+	server := newContentsAPIServer(t, repoDir)
+	ctx := framework.InjectResolverConfigToContext(context.Background(), map[string]string{
+		ConfigFieldGitHubAPIHosts: strings.TrimPrefix(server.URL, "https://"),
+	})
+
+	resolver := Resolver{}
+	_, err := resolver.Resolve(ctx, map[string]string{
+		URLParam:       server.URL + "/org/repo",
+		PathParam:      "missing.yaml",
+		FetchModeParam: FetchModeAPI,
+	})
+	// The api's 404 falls back to a clone, which reports the missing
+	// file as it always does.
+	if !isFileMissing(err) {
+		t.Errorf("expected file not found error, received %v", err)
+	}
+	if atomic.LoadInt32(&server.apiRequests) == 0 || atomic.LoadInt32(&server.gitRequests) == 0 {
+		t.Errorf("expected the api to be tried before cloning, received %d api and %d git requests", server.apiRequests, server.gitRequests)
+	}
+}
+
+func TestFetchFromContentsAPIErrors(t *testing.T) {
+	commit := strings.Repeat("a", 40)
+	for _, tc := range []struct {
+		name        string
+		handler     http.HandlerFunc
+		expectedErr string
+	}{{
+		name: "not found",
+		handler: func(w http.ResponseWriter, r *http.Request) {
+			http.NotFound(w, r)
+		},
+		expectedErr: "404 Not Found: not found",
+	}, {
+		name: "rate limited",
+		handler: func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			http.Error(w, "API rate limit exceeded", http.StatusForbidden)
+		},
+		expectedErr: "403 Forbidden: rate limited",
+	}, {
+		name: "directory",
+		handler: func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`[{"type":"file","name":"task.yaml"}]`))
+		},
+		expectedErr: `"tasks" is not a file`,
+	}, {
+		name: "too large",
+		handler: func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"type":"file","encoding":"none","content":""}`))
+		},
+		expectedErr: `file "tasks" is too large for the contents api`,
+	}, {
+		name: "wrong hash",
+		handler: func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"type":"file","encoding":"base64","content":"a2luZDogVGFzaw==","sha":"` + commit + `"}`))
+		},
+		expectedErr: "not the " + commit + " it was reported with",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(tc.handler)
+			defer server.Close()
+			_, _, err := fetchFromContentsAPI(context.Background(), server.URL, "org/repo", nil, nil, commit, "tasks")
+			if err == nil || !strings.Contains(err.Error(), tc.expectedErr) {
+				t.Errorf("expected error containing %q, received %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestContentsAPIRepo(t *testing.T) {
+	conf := map[string]string{ConfigFieldGitHubAPIHosts: "github.example.com, GHE.example.org"}
+	for _, tc := range []struct {
+		url         string
+		expectedAPI string
+		expectedOK  bool
+	}{{
+		url:         "https://github.com/tektoncd/catalog.git",
+		expectedAPI: defaultGitHubAPIURL,
+		expectedOK:  true,
+	}, {
+		url:         "https://ghe.example.org/tektoncd/catalog",
+		expectedAPI: "https://ghe.example.org/api/v3",
+		expectedOK:  true,
+	}, {
+		url: "https://gitlab.com/tektoncd/catalog",
+	}, {
+		url: "http://github.com/tektoncd/catalog",
+	}, {
+		url: "git@github.com:tektoncd/catalog.git",
+	}, {
+		url: "https://github.com/tektoncd/catalog/extra",
+	}} {
+		t.Run(tc.url, func(t *testing.T) {
+			api, fullName, ok := contentsAPIRepo(conf, tc.url)
+			if ok != tc.expectedOK || api != tc.expectedAPI {
+				t.Errorf("expected api %q and ok %t, received %q and %t", tc.expectedAPI, tc.expectedOK, api, ok)
+			}
+			if ok && fullName != "tektoncd/catalog" {
+				t.Errorf("expected full name tektoncd/catalog, received %q", fullName)
+			}
+		})
+	}
+}
+
+func TestValidateParamsFetchMode(t *testing.T) {
+	resolver := Resolver{}
+	err := resolver.ValidateParams(context.Background(), map[string]string{
+		URLParam:       "https://github.com/tektoncd/catalog",
+		PathParam:      "task.yaml",
+		FetchModeParam: "archive",
+	})
+	if err == nil || !strings.Contains(err.Error(), `invalid fetchMode "archive"`) {
+		t.Errorf("expected invalid fetch mode error, received %v", err)
+	}
+}
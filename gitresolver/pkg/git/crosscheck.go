@@ -0,0 +1,133 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/tektoncd/resolution/pkg/resolver/framework"
+	"knative.dev/pkg/logging"
+)
+
+// The ways that cross-check-backends can handle go-git and the git
+// binary resolving a file differently.
+const (
+	// CrossCheckWarn logs the difference and records it in the
+	// backend-mismatch annotation.
+	CrossCheckWarn = "warn"
+
+	// CrossCheckFail fails the resolution.
+	CrossCheckFail = "fail"
+)
+
+// getCrossCheckMode returns how resolved files are checked against a
+// second clone made with the other backend, as configured with the
+// cross-check-backends field in the git-resolver-config configmap.
+// Empty means they aren't checked.
+func getCrossCheckMode(conf map[string]string) (string, error) {
+	switch mode := conf[ConfigFieldCrossCheckBackends]; mode {
+	case "", CrossCheckWarn, CrossCheckFail:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid %s %q: must be %q or %q", ConfigFieldCrossCheckBackends, mode, CrossCheckWarn, CrossCheckFail)
+	}
+}
+
+// crossCheckBackends reads path as of commit from a second clone of the
+// repo at url, made with whichever of go-git and the git binary didn't
+// make fetched, and compares its digest with that of content. This
+// catches differences like checkout filters or git config that only
+// one backend applies. It returns a description of any difference for
+// the backend-mismatch annotation, or an error if mode is "fail".
+func crossCheckBackends(ctx context.Context, mode, url string, auth transport.AuthMethod, fetched *fetchedRepository, commit, path string, content []byte) (string, error) {
+	conf := framework.GetResolverConfigFromContext(ctx)
+	backend, other := "go-git", "git cli"
+	if fetched.cli {
+		backend, other = other, backend
+	}
+
+	otherContent, err := readWithOtherBackend(ctx, conf, url, auth, fetched, commit, path)
+	if err != nil {
+		err = fmt.Errorf("error cross-checking %q with %s: %w", path, other, err)
+		if mode == CrossCheckFail {
+			return "", err
+		}
+		logging.FromContext(ctx).Warn(err)
+		return "", nil
+	}
+
+	digest, _, err := v1.SHA256(bytes.NewReader(content))
+	if err != nil {
+		return "", fmt.Errorf("error computing digest: %w", err)
+	}
+	otherDigest, _, err := v1.SHA256(bytes.NewReader(otherContent))
+	if err != nil {
+		return "", fmt.Errorf("error computing digest: %w", err)
+	}
+	if digest == otherDigest {
+		return "", nil
+	}
+	mismatch := fmt.Sprintf("%s resolved %s, %s resolved %s", backend, digest, other, otherDigest)
+	if mode == CrossCheckFail {
+		return "", fmt.Errorf("backends disagree on the content of %q at %s: %s", path, commit, mismatch)
+	}
+	logging.FromContext(ctx).Warnf("backends disagree on the content of %q at %s: %s", path, commit, mismatch)
+	return mismatch, nil
+}
+
+// readWithOtherBackend clones the repo at url with the backend that
+// didn't make fetched and reads path from it as of commit.
+func readWithOtherBackend(ctx context.Context, conf map[string]string, url string, auth transport.AuthMethod, fetched *fetchedRepository, commit, path string) ([]byte, error) {
+	if fetched.cli {
+		store, err := newStorage(conf[ConfigFieldAlternatesDir])
+		if err != nil {
+			return nil, err
+		}
+		filesystem := memfs.New()
+		repository, _, err := cloneRepository(ctx, limitRepoSize(ctx, store), filesystem, url, auth, fetched.ref)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkoutCommit(repository, commit); err != nil {
+			return nil, err
+		}
+		return readPath(repository, filesystem, conf, commit, path)
+	}
+
+	// The git binary is run without credentials, so it would clone a
+	// repo that needs them anonymously.
+	if auth != nil {
+		return nil, errors.New("the git binary can't clone with credentials")
+	}
+	other, err := cloneWithCLI(ctx, url, fetched.ref, commit)
+	if err != nil {
+		return nil, err
+	}
+	defer other.cleanup()
+	// The git binary checks the commit out itself so that its filters
+	// and config apply to the worktree as they would for a user.
+	if _, err := runGit(ctx, other.filesystem.Root(), "checkout", "--quiet", "--detach", commit); err != nil {
+		return nil, err
+	}
+	return readPath(other.repository, other.filesystem, conf, commit, path)
+}
@@ -0,0 +1,128 @@
+package git
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tektoncd/resolution/pkg/resolver/framework"
+)
+
+func TestResolveCrossCheckBackends(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	// The git binary converts line endings on checkout as the
+	// attributes ask, which go-git doesn't, so the backends only agree
+	// on the worktree content of files the attributes don't cover.
+	repoDir, _ := createTestRepo(t, []testCommit{{
+		files: map[string]string{
+			".gitattributes": "*.crlf.yaml text eol=crlf\n",
+			"task.yaml":      "kind: Task\nmetadata:\n  name: build\n",
+			"task.crlf.yaml": "kind: Task\nmetadata:\n  name: build\n",
+		},
+	}})
+
+	for _, tc := range []struct {
+		name             string
+		mode             string
+		protocol         string
+		path             string
+		expectedErr      string
+		expectedMismatch bool
+	}{{
+		name: "backends agree",
+		mode: CrossCheckFail,
+		path: "task.yaml",
+	}, {
+		name:     "backends agree when cloned with the git binary",
+		mode:     CrossCheckFail,
+		protocol: GitProtocolV2,
+		path:     "task.yaml",
+	}, {
+		name:        "backends disagree",
+		mode:        CrossCheckFail,
+		path:        "task.crlf.yaml",
+		expectedErr: "backends disagree",
+	}, {
+		name:             "backends disagree with a warning",
+		mode:             CrossCheckWarn,
+		path:             "task.crlf.yaml",
+		expectedMismatch: true,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := framework.InjectResolverConfigToContext(context.Background(), map[string]string{
+				ConfigFieldCrossCheckBackends: tc.mode,
+				ConfigFieldUseWorktreeContent: "true",
+				ConfigFieldGitProtocolVersion: tc.protocol,
+			})
+			resolver := Resolver{}
+			resource, err := resolver.Resolve(ctx, map[string]string{
+				URLParam:  "file://" + repoDir,
+				PathParam: tc.path,
+			})
+			if tc.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectedErr) {
+					t.Fatalf("expected error containing %q, received %v", tc.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error resolving: %v", err)
+			}
+			mismatch := resource.Annotations()[AnnotationKeyBackendMismatch]
+			if tc.expectedMismatch {
+				if !strings.Contains(mismatch, "go-git resolved sha256:") || !strings.Contains(mismatch, "git cli resolved sha256:") {
+					t.Errorf("expected %s annotation with both digests, received %q", AnnotationKeyBackendMismatch, mismatch)
+				}
+				if strings.Contains(string(resource.Data()), "\r\n") {
+					t.Errorf("expected go-git's content to be returned, received %q", resource.Data())
+				}
+			} else if mismatch != "" {
+				t.Errorf("expected no %s annotation, received %q", AnnotationKeyBackendMismatch, mismatch)
+			}
+		})
+	}
+}
+
+func TestResolveCrossCheckBackendsWithAuth(t *testing.T) {
+	repoDir, _ := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "kind: Task"},
+	}})
+	server := newGitHTTPServer(t, repoDir, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, password, ok := r.BasicAuth(); !ok || password != "ip-pass" {
+				w.Header().Set("WWW-Authenticate", `Basic realm="git"`)
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+	netrcFile := filepath.Join(t.TempDir(), ".netrc")
+	if err := os.WriteFile(netrcFile, []byte("machine 127.0.0.1 login ip-user password ip-pass"), 0o600); err != nil {
+		t.Fatalf("error writing netrc: %v", err)
+	}
+	ctx := framework.InjectResolverConfigToContext(context.Background(), map[string]string{
+		ConfigFieldCrossCheckBackends: CrossCheckFail,
+		ConfigFieldNetrc:              netrcFile,
+	})
+	resolver := Resolver{}
+	_, err := resolver.Resolve(ctx, map[string]string{
+		URLParam:  server.URL + "/repo",
+		PathParam: "task.yaml",
+	})
+	if err == nil || !strings.Contains(err.Error(), "the git binary can't clone with credentials") {
+		t.Fatalf("expected the cross-check to refuse an anonymous clone, received %v", err)
+	}
+}
+
+func TestGetCrossCheckModeInvalid(t *testing.T) {
+	if _, err := getCrossCheckMode(map[string]string{ConfigFieldCrossCheckBackends: "true"}); err == nil || !strings.Contains(err.Error(), ConfigFieldCrossCheckBackends) {
+		t.Errorf("expected invalid %s error, received %v", ConfigFieldCrossCheckBackends, err)
+	}
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"fmt"
+	"time"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// getDate returns the time given by the date param, or the zero time if
+// it's unset.
+func getDate(params map[string]string) (time.Time, error) {
+	s := params[DateParam]
+	if s == "" {
+		return time.Time{}, nil
+	}
+	date, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid value for %q: %q is not an RFC 3339 time", DateParam, s)
+	}
+	return date, nil
+}
+
+// commitAsOf returns the commit reachable from head with the latest
+// committer date at or before date. Committer dates needn't increase
+// along history, so every reachable commit is considered.
+func commitAsOf(repository *git.Repository, head string, date time.Time) (string, error) {
+	iter, err := repository.Log(&git.LogOptions{From: plumbing.NewHash(head)})
+	if err != nil {
+		return "", fmt.Errorf("error reading history of commit %q: %w", head, err)
+	}
+	var latest *object.Commit
+	err = iter.ForEach(func(c *object.Commit) error {
+		if when := c.Committer.When; !when.After(date) && (latest == nil || when.After(latest.Committer.When)) {
+			latest = c
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("error reading history of commit %q: %w", head, err)
+	}
+	if latest == nil {
+		return "", fmt.Errorf("no commit reachable from %s was made at or before %s: %w", head, date.Format(time.RFC3339), plumbing.ErrObjectNotFound)
+	}
+	return latest.Hash.String(), nil
+}
@@ -0,0 +1,100 @@
+package git
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/tektoncd/resolution/pkg/resolver/framework"
+)
+
+func TestResolveDate(t *testing.T) {
+	day := func(month time.Month, d int) time.Time {
+		return time.Date(2024, month, d, 12, 0, 0, 0, time.UTC)
+	}
+	// The last commit was made with an earlier date than the one before
+	// it, as happens when commits are cherry-picked or rebased.
+	repoDir, commits := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "january"},
+		when:  day(time.January, 1),
+	}, {
+		files: map[string]string{"task.yaml": "march"},
+		when:  day(time.March, 1),
+	}, {
+		files: map[string]string{"task.yaml": "february"},
+		when:  day(time.February, 1),
+	}})
+	setTestRef(t, repoDir, "refs/heads/release", commits[1])
+
+	for _, tc := range []struct {
+		name     string
+		conf     map[string]string
+		params   map[string]string
+		expected int
+	}{{
+		name:     "between commits",
+		params:   map[string]string{DateParam: "2024-01-15T00:00:00Z"},
+		expected: 0,
+	}, {
+		name:     "at a commit",
+		params:   map[string]string{DateParam: "2024-02-01T12:00:00Z"},
+		expected: 2,
+	}, {
+		name:     "latest date out of order",
+		params:   map[string]string{DateParam: "2024-03-15T00:00:00Z"},
+		expected: 1,
+	}, {
+		name:     "time zone",
+		params:   map[string]string{DateParam: "2024-02-01T13:00:00+02:00"},
+		expected: 0,
+	}, {
+		name:     "branch",
+		params:   map[string]string{BranchParam: "release", DateParam: "2024-12-31T00:00:00Z"},
+		expected: 1,
+	}, {
+		name:     "cache",
+		conf:     map[string]string{ConfigFieldCacheSize: "10"},
+		params:   map[string]string{DateParam: "2024-01-15T00:00:00Z"},
+		expected: 0,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			params := map[string]string{URLParam: repoDir, PathParam: "task.yaml"}
+			for k, v := range tc.params {
+				params[k] = v
+			}
+			resolver := Resolver{}
+			resource, err := resolver.Resolve(framework.InjectResolverConfigToContext(context.Background(), tc.conf), params)
+			if err != nil {
+				t.Fatalf("unexpected error resolving: %v", err)
+			}
+			if commit := resource.Annotations()[AnnotationKeyCommitHash]; commit != commits[tc.expected] {
+				t.Errorf("expected commit %s, received %s with content %q", commits[tc.expected], commit, string(resource.Data()))
+			}
+		})
+	}
+
+	t.Run("before history", func(t *testing.T) {
+		resolver := Resolver{}
+		_, err := resolver.Resolve(context.Background(), map[string]string{URLParam: repoDir, PathParam: "task.yaml", DateParam: "2023-01-01T00:00:00Z"})
+		if err == nil {
+			t.Fatalf("expected no commit to be found before the first")
+		}
+	})
+}
+
+func TestValidateParamsDate(t *testing.T) {
+	for _, params := range []map[string]string{
+		{DateParam: "2024-01-01T00:00:00Z", CommitParam: "aeb957601cf41c012be462827053a21a420befca"},
+		{DateParam: "2024-01-01"},
+		{DateParam: "yesterday"},
+	} {
+		params[URLParam], params[PathParam] = "https://github.com/tektoncd/catalog", "task.yaml"
+		if err := (&Resolver{}).ValidateParams(context.Background(), params); err == nil {
+			t.Errorf("expected params %v to be invalid", params)
+		}
+	}
+	params := map[string]string{URLParam: "https://github.com/tektoncd/catalog", PathParam: "task.yaml", BranchParam: "main", DateParam: "2024-01-01T00:00:00Z"}
+	if err := (&Resolver{}).ValidateParams(context.Background(), params); err != nil {
+		t.Errorf("unexpected error validating %q with a branch: %v", DateParam, err)
+	}
+}
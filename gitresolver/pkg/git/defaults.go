@@ -0,0 +1,55 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import "strconv"
+
+// withConfigDefaults returns params with the url and revision
+// configured with the default-url and default-revision fields in the
+// git-resolver-config configmap filled in where params don't give
+// them, either directly or through a locator or catalog entry. Params
+// that need nothing filled in are returned as they are.
+func withConfigDefaults(conf, params map[string]string) map[string]string {
+	if params[LocatorParam] != "" || params[CatalogRefParam] != "" {
+		return params
+	}
+	defaults := map[string]string{}
+	if url := conf[ConfigFieldDefaultURL]; url != "" && params[URLParam] == "" {
+		defaults[URLParam] = url
+	}
+	// Containing refs are looked up for a commit, never a revision.
+	picked, _ := strconv.ParseBool(params[ContainingRefsParam])
+	for _, p := range refSelectingParams {
+		if params[p] != "" {
+			picked = true
+		}
+	}
+	if revision := conf[ConfigFieldDefaultRevision]; revision != "" && !picked {
+		defaults[RevisionParam] = revision
+	}
+	if len(defaults) == 0 {
+		return params
+	}
+	withDefaults := make(map[string]string, len(params)+len(defaults))
+	for k, v := range params {
+		withDefaults[k] = v
+	}
+	for k, v := range defaults {
+		withDefaults[k] = v
+	}
+	return withDefaults
+}
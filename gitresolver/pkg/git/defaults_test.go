@@ -0,0 +1,123 @@
+package git
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tektoncd/resolution/pkg/resolver/framework"
+)
+
+func TestWithConfigDefaults(t *testing.T) {
+	conf := map[string]string{ConfigFieldDefaultURL: "https://github.com/tektoncd/catalog", ConfigFieldDefaultRevision: "main"}
+	for _, tc := range []struct {
+		name     string
+		conf     map[string]string
+		params   map[string]string
+		expected map[string]string
+	}{{
+		name:     "path only",
+		conf:     conf,
+		params:   map[string]string{PathParam: "task.yaml"},
+		expected: map[string]string{URLParam: "https://github.com/tektoncd/catalog", PathParam: "task.yaml", RevisionParam: "main"},
+	}, {
+		name:     "url given",
+		conf:     conf,
+		params:   map[string]string{URLParam: "https://github.com/tektoncd/pipeline", PathParam: "task.yaml"},
+		expected: map[string]string{URLParam: "https://github.com/tektoncd/pipeline", PathParam: "task.yaml", RevisionParam: "main"},
+	}, {
+		name:     "branch given",
+		conf:     conf,
+		params:   map[string]string{PathParam: "task.yaml", BranchParam: "release"},
+		expected: map[string]string{URLParam: "https://github.com/tektoncd/catalog", PathParam: "task.yaml", BranchParam: "release"},
+	}, {
+		name:     "commit given",
+		conf:     conf,
+		params:   map[string]string{PathParam: "task.yaml", CommitParam: "aeb9576"},
+		expected: map[string]string{URLParam: "https://github.com/tektoncd/catalog", PathParam: "task.yaml", CommitParam: "aeb9576"},
+	}, {
+		name:     "containing refs",
+		conf:     conf,
+		params:   map[string]string{CommitParam: "aeb9576", ContainingRefsParam: "true"},
+		expected: map[string]string{URLParam: "https://github.com/tektoncd/catalog", CommitParam: "aeb9576", ContainingRefsParam: "true"},
+	}, {
+		name:     "locator",
+		conf:     conf,
+		params:   map[string]string{LocatorParam: "github.com/tektoncd/catalog//task.yaml"},
+		expected: map[string]string{LocatorParam: "github.com/tektoncd/catalog//task.yaml"},
+	}, {
+		name:     "no defaults",
+		params:   map[string]string{PathParam: "task.yaml"},
+		expected: map[string]string{PathParam: "task.yaml"},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			if params := withConfigDefaults(tc.conf, tc.params); !reflect.DeepEqual(params, tc.expected) {
+				t.Errorf("expected params %v, received %v", tc.expected, params)
+			}
+		})
+	}
+}
+
+func TestResolveConfigDefaults(t *testing.T) {
+	repoDir, commits := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "release"},
+	}, {
+		files: map[string]string{"task.yaml": "main"},
+	}})
+	setTestRef(t, repoDir, "refs/heads/release", commits[0])
+	otherDir, others := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "other"},
+	}})
+	setTestRef(t, otherDir, "refs/heads/release", others[0])
+	conf := map[string]string{ConfigFieldDefaultURL: repoDir, ConfigFieldDefaultRevision: "release"}
+
+	for _, tc := range []struct {
+		name     string
+		params   map[string]string
+		expected string
+	}{{
+		name:     "defaults",
+		params:   map[string]string{},
+		expected: "release",
+	}, {
+		name:     "url param",
+		params:   map[string]string{URLParam: otherDir},
+		expected: "other",
+	}, {
+		name:     "revision param",
+		params:   map[string]string{RevisionParam: "master"},
+		expected: "main",
+	}, {
+		name:     "commit param",
+		params:   map[string]string{CommitParam: commits[1]},
+		expected: "main",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			params := map[string]string{PathParam: "task.yaml"}
+			for k, v := range tc.params {
+				params[k] = v
+			}
+			ctx := framework.InjectResolverConfigToContext(context.Background(), conf)
+			resolver := Resolver{}
+			if err := resolver.ValidateParams(ctx, params); err != nil {
+				t.Fatalf("unexpected error validating params: %v", err)
+			}
+			resource, err := resolver.Resolve(ctx, params)
+			if err != nil {
+				t.Fatalf("unexpected error resolving: %v", err)
+			}
+			if string(resource.Data()) != tc.expected {
+				t.Errorf("expected content %q, received %q", tc.expected, string(resource.Data()))
+			}
+		})
+	}
+}
+
+func TestValidateParamsWithoutDefaultURL(t *testing.T) {
+	ctx := framework.InjectResolverConfigToContext(context.Background(), map[string]string{ConfigFieldDefaultRevision: "main"})
+	err := (&Resolver{}).ValidateParams(ctx, map[string]string{PathParam: "task.yaml"})
+	if err == nil || !strings.Contains(err.Error(), "missing "+URLParam) {
+		t.Errorf("expected the url to be required without %s, received %v", ConfigFieldDefaultURL, err)
+	}
+}
@@ -0,0 +1,205 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	pathpkg "path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultMaxDependencyDepth is the longest chain of references followed
+// when max-dependency-depth isn't configured.
+const defaultMaxDependencyDepth = 10
+
+// getMaxDependencyDepth returns the longest chain of references that
+// resolving dependencies may follow, as configured with the
+// max-dependency-depth field in the git-resolver-config configmap.
+func getMaxDependencyDepth(conf map[string]string) (int, error) {
+	depthString, ok := conf[ConfigFieldMaxDependencyDepth]
+	if !ok || depthString == "" {
+		return defaultMaxDependencyDepth, nil
+	}
+	depth, err := strconv.Atoi(depthString)
+	if err != nil || depth <= 0 {
+		return 0, fmt.Errorf("invalid %s %q: must be a positive integer", ConfigFieldMaxDependencyDepth, depthString)
+	}
+	return depth, nil
+}
+
+// bundleDependencies returns content, read from root, followed by every
+// file in the same repo that it references directly or transitively, as
+// a multi-document yaml stream in the order they're first referenced.
+// It also returns the paths of the dependencies. A reference is any
+// mapping with "resolver: git" whose params, or the deprecated resource
+// list, set a path without choosing a different url, commit, branch or
+// tag from the ones root was resolved from. Reference cycles and chains
+// longer than maxDepth are rejected.
+func bundleDependencies(root string, content []byte, url, commit, branch string, maxDepth int, read func(path string) ([]byte, error)) ([]byte, []string, error) {
+	b := &dependencyBundler{
+		url:      canonicalRepoURL(url),
+		commit:   commit,
+		branch:   branch,
+		maxDepth: maxDepth,
+		read:     read,
+		visited:  map[string]bool{},
+		buf:      &bytes.Buffer{},
+	}
+	if err := b.add([]string{cleanRepoPath(root)}, content); err != nil {
+		return nil, nil, err
+	}
+	return b.buf.Bytes(), b.dependencies, nil
+}
+
+type dependencyBundler struct {
+	url, commit  string
+	branch       string
+	maxDepth     int
+	read         func(path string) ([]byte, error)
+	visited      map[string]bool
+	dependencies []string
+	buf          *bytes.Buffer
+}
+
+// add appends the file at the end of chain to the bundle and then
+// follows its references.
+func (b *dependencyBundler) add(chain []string, content []byte) error {
+	path := chain[len(chain)-1]
+	b.visited[path] = true
+	if b.buf.Len() > 0 {
+		b.buf.WriteString("---\n")
+	}
+	b.buf.Write(content)
+	if len(content) > 0 && !bytes.HasSuffix(content, []byte("\n")) {
+		b.buf.WriteString("\n")
+	}
+
+	refs, err := b.references(content)
+	if err != nil {
+		return fmt.Errorf("error reading references of %q: %w", path, err)
+	}
+	for _, ref := range refs {
+		for _, p := range chain {
+			if p == ref {
+				return fmt.Errorf("reference cycle: %s -> %s", strings.Join(chain, " -> "), ref)
+			}
+		}
+		if b.visited[ref] {
+			continue
+		}
+		if len(chain) > b.maxDepth {
+			return fmt.Errorf("error resolving %q: dependency chain %s exceeds depth limit %d", ref, strings.Join(chain, " -> "), b.maxDepth)
+		}
+		dep, err := b.read(ref)
+		if err != nil {
+			return fmt.Errorf("error resolving dependency %q of %q: %w", ref, path, err)
+		}
+		b.dependencies = append(b.dependencies, ref)
+		if err := b.add(append(chain[:len(chain):len(chain)], ref), dep); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// references returns the in-repo paths that the yaml documents in
+// content refer to, in the order they appear.
+func (b *dependencyBundler) references(content []byte) ([]string, error) {
+	refs := []string{}
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	for {
+		var doc interface{}
+		err := decoder.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			return refs, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid yaml: %w", err)
+		}
+		b.walk(doc, &refs)
+	}
+}
+
+func (b *dependencyBundler) walk(node interface{}, refs *[]string) {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		if ref, ok := b.reference(n); ok {
+			*refs = append(*refs, ref)
+		}
+		keys := make([]string, 0, len(n))
+		for k := range n {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			b.walk(n[k], refs)
+		}
+	case []interface{}:
+		for _, v := range n {
+			b.walk(v, refs)
+		}
+	}
+}
+
+// reference returns the path that a git resolver reference in node
+// points to, if it's in the same repo and revision as the request.
+func (b *dependencyBundler) reference(node map[string]interface{}) (string, bool) {
+	if node["resolver"] != "git" {
+		return "", false
+	}
+	refParams := map[string]string{}
+	for _, key := range []string{"params", "resource"} {
+		list, _ := node[key].([]interface{})
+		for _, item := range list {
+			param, _ := item.(map[string]interface{})
+			name, _ := param["name"].(string)
+			value, _ := param["value"].(string)
+			if name != "" {
+				refParams[name] = value
+			}
+		}
+	}
+	path := refParams[PathParam]
+	if path == "" {
+		return "", false
+	}
+	if url := refParams[URLParam]; url != "" && canonicalRepoURL(url) != b.url {
+		return "", false
+	}
+	if commit := refParams[CommitParam]; commit != "" && commit != b.commit {
+		return "", false
+	}
+	if branch := refParams[BranchParam]; branch != "" && branch != b.branch {
+		return "", false
+	}
+	if tag := refParams[TagParam]; tag != "" && tag != b.branch {
+		return "", false
+	}
+	return cleanRepoPath(path), true
+}
+
+// cleanRepoPath returns path relative to the root of the repo.
+func cleanRepoPath(path string) string {
+	return strings.TrimPrefix(pathpkg.Clean("/"+path), "/")
+}
@@ -0,0 +1,105 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestResolveIncludeDependencies(t *testing.T) {
+	pipeline := `kind: Pipeline
+spec:
+  tasks:
+  - name: build
+    taskRef:
+      resolver: git
+      params:
+      - name: path
+        value: tasks/build.yaml
+  - name: test
+    taskRef:
+      resolver: git
+      resource:
+      - name: path
+        value: /tasks/test.yaml
+  - name: lint
+    taskRef:
+      resolver: git
+      params:
+      - name: url
+        value: https://github.com/tektoncd/catalog.git
+      - name: path
+        value: task/lint.yaml
+`
+	repoDir, _ := createTestRepo(t, []testCommit{{
+		files: map[string]string{
+			"pipeline.yaml":    pipeline,
+			"tasks/build.yaml": "kind: Task\nmetadata:\n  name: build\n",
+			"tasks/test.yaml":  "kind: Task\nmetadata:\n  name: test\n",
+		},
+	}})
+
+	resolver := &Resolver{}
+	resource, err := resolver.Resolve(context.Background(), map[string]string{
+		URLParam:                 repoDir,
+		PathParam:                "pipeline.yaml",
+		IncludeDependenciesParam: "true",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error resolving dependencies: %v", err)
+	}
+	expected := pipeline + "---\nkind: Task\nmetadata:\n  name: build\n---\nkind: Task\nmetadata:\n  name: test\n"
+	if string(resource.Data()) != expected {
+		t.Errorf("expected bundle %q received %q", expected, string(resource.Data()))
+	}
+	if deps := resource.Annotations()[AnnotationKeyDependencies]; deps != "tasks/build.yaml,tasks/test.yaml" {
+		t.Errorf("expected dependencies annotation %q received %q", "tasks/build.yaml,tasks/test.yaml", deps)
+	}
+}
+
+// gitRef returns a task referencing path through the git resolver.
+func gitRef(path string) string {
+	return fmt.Sprintf("taskRef:\n  resolver: git\n  params:\n  - name: path\n    value: %s\n", path)
+}
+
+func TestBundleDependenciesCycle(t *testing.T) {
+	files := map[string]string{
+		"b.yaml": gitRef("c.yaml"),
+		"c.yaml": gitRef("b.yaml"),
+	}
+	read := func(path string) ([]byte, error) { return []byte(files[path]), nil }
+	_, _, err := bundleDependencies("a.yaml", []byte(gitRef("b.yaml")), "https://example.com/repo", "", "", defaultMaxDependencyDepth, read)
+	if err == nil || !strings.Contains(err.Error(), "reference cycle: a.yaml -> b.yaml -> c.yaml -> b.yaml") {
+		t.Errorf("expected reference cycle error, received %v", err)
+	}
+}
+
+func TestBundleDependenciesSharedDependency(t *testing.T) {
+	files := map[string]string{
+		"b.yaml": gitRef("d.yaml"),
+		"c.yaml": gitRef("d.yaml"),
+		"d.yaml": "kind: Task\n",
+	}
+	read := func(path string) ([]byte, error) { return []byte(files[path]), nil }
+	root := gitRef("b.yaml") + "---\n" + gitRef("c.yaml")
+	_, deps, err := bundleDependencies("a.yaml", []byte(root), "https://example.com/repo", "", "", defaultMaxDependencyDepth, read)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(deps, ",") != "b.yaml,d.yaml,c.yaml" {
+		t.Errorf("expected a file referenced twice to be bundled once, received %v", deps)
+	}
+}
+
+func TestBundleDependenciesDepthLimit(t *testing.T) {
+	read := func(path string) ([]byte, error) {
+		var n int
+		fmt.Sscanf(path, "%d.yaml", &n)
+		return []byte(gitRef(fmt.Sprintf("%d.yaml", n+1))), nil
+	}
+	_, _, err := bundleDependencies("0.yaml", []byte(gitRef("1.yaml")), "https://example.com/repo", "", "", 3, read)
+	if err == nil || !strings.Contains(err.Error(), "exceeds depth limit 3") {
+		t.Errorf("expected depth limit error, received %v", err)
+	}
+}
@@ -0,0 +1,43 @@
+package git
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/tektoncd/resolution/pkg/resolver/framework"
+)
+
+func TestResolveComputeOCIDigest(t *testing.T) {
+	content := "apiVersion: tekton.dev/v1beta1\nkind: Task\n"
+	repoDir, _ := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": content},
+	}})
+	params := map[string]string{
+		URLParam:  repoDir,
+		PathParam: "task.yaml",
+	}
+	resolver := Resolver{}
+
+	resource, err := resolver.Resolve(context.Background(), params)
+	if err != nil {
+		t.Fatalf("unexpected error resolving: %v", err)
+	}
+	if _, has := resource.Annotations()[AnnotationKeyOCIDigest]; has {
+		t.Errorf("expected no digest annotation when not configured")
+	}
+
+	ctx := framework.InjectResolverConfigToContext(context.Background(), map[string]string{
+		ConfigFieldComputeOCIDigest: "true",
+	})
+	resource, err = resolver.Resolve(ctx, params)
+	if err != nil {
+		t.Fatalf("unexpected error resolving: %v", err)
+	}
+	sum := sha256.Sum256([]byte(content))
+	expected := "sha256:" + hex.EncodeToString(sum[:])
+	if digest := resource.Annotations()[AnnotationKeyOCIDigest]; digest != expected {
+		t.Errorf("expected digest %q received %q", expected, digest)
+	}
+}
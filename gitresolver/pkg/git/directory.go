@@ -0,0 +1,141 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// defaultMaxFiles is the most files a directory resolution includes
+// when max-files isn't configured.
+const defaultMaxFiles = 1000
+
+// getMaxFiles returns the most files a directory resolution may
+// include, as configured with the max-files field in the
+// git-resolver-config configmap.
+func getMaxFiles(conf map[string]string) (int, error) {
+	maxString, ok := conf[ConfigFieldMaxFiles]
+	if !ok || maxString == "" {
+		return defaultMaxFiles, nil
+	}
+	max, err := strconv.Atoi(maxString)
+	if err != nil || max <= 0 {
+		return 0, fmt.Errorf("invalid %s %q: must be a positive integer", ConfigFieldMaxFiles, maxString)
+	}
+	return max, nil
+}
+
+// isYAMLFile returns true if name has a yaml file extension.
+func isYAMLFile(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// readDirectory returns the yaml files beneath the directory at path in
+// the given commit, concatenated in path order as a multi-document yaml
+// stream. It returns false if path isn't a directory. Directories with
+// more than maxFiles yaml files are rejected rather than truncated.
+func readDirectory(repository *git.Repository, commit, path string, maxFiles int) ([]byte, bool, error) {
+	commitObj, err := repository.CommitObject(plumbing.NewHash(commit))
+	if err != nil {
+		return nil, false, fmt.Errorf("error reading commit %q: %v", commit, err)
+	}
+	tree, err := commitObj.Tree()
+	if err != nil {
+		return nil, false, fmt.Errorf("error reading tree of commit %q: %v", commit, err)
+	}
+	if dir := strings.Trim(path, "/"); dir != "" {
+		entry, err := tree.FindEntry(dir)
+		if err != nil || entry.Mode != filemode.Dir {
+			return nil, false, nil
+		}
+		tree, err = tree.Tree(dir)
+		if err != nil {
+			return nil, false, fmt.Errorf("error reading directory %q: %v", path, err)
+		}
+	}
+
+	files := map[string]*object.File{}
+	names := []string{}
+	err = tree.Files().ForEach(func(f *object.File) error {
+		if isYAMLFile(f.Name) {
+			files[f.Name] = f
+			names = append(names, f.Name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, true, fmt.Errorf("error listing directory %q: %v", path, err)
+	}
+	if err := checkFileCount(path, len(names), maxFiles); err != nil {
+		return nil, true, err
+	}
+	sort.Strings(names)
+
+	buf := &bytes.Buffer{}
+	for i, name := range names {
+		content, err := files[name].Contents()
+		if err != nil {
+			return nil, true, fmt.Errorf("error reading file %q: %v", name, err)
+		}
+		if i > 0 {
+			buf.WriteString("---\n")
+		}
+		buf.WriteString(content)
+		if content != "" && !strings.HasSuffix(content, "\n") {
+			buf.WriteString("\n")
+		}
+	}
+	return buf.Bytes(), true, nil
+}
+
+// isDirectory returns true if path is a directory in the given commit.
+func isDirectory(repository *git.Repository, commit, path string) bool {
+	dir := strings.Trim(path, "/")
+	if dir == "" {
+		return true
+	}
+	commitObj, err := repository.CommitObject(plumbing.NewHash(commit))
+	if err != nil {
+		return false
+	}
+	tree, err := commitObj.Tree()
+	if err != nil {
+		return false
+	}
+	entry, err := tree.FindEntry(dir)
+	return err == nil && entry.Mode == filemode.Dir
+}
+
+// checkFileCount returns an error if the directory at path holds more
+// than maxFiles files.
+func checkFileCount(path string, files, maxFiles int) error {
+	if files > maxFiles {
+		return fmt.Errorf("error reading %q: directory contains %d files, exceeds limit %d", path, files, maxFiles)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/tektoncd/resolution/pkg/resolver/framework"
+)
+
+func TestResolveDirectory(t *testing.T) {
+	repoDir, _ := createTestRepo(t, []testCommit{{
+		files: map[string]string{
+			"tasks/b.yaml":        "kind: Task\nmetadata:\n  name: b",
+			"tasks/a.yaml":        "kind: Task\nmetadata:\n  name: a\n",
+			"tasks/nested/c.yml":  "kind: Task\nmetadata:\n  name: c\n",
+			"tasks/README.md":     "not yaml",
+			"pipeline/other.yaml": "kind: Pipeline\n",
+		},
+	}})
+	resolver := Resolver{}
+	resource, err := resolver.Resolve(context.Background(), map[string]string{
+		URLParam:  repoDir,
+		PathParam: "tasks",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error resolving directory: %v", err)
+	}
+	expected := "kind: Task\nmetadata:\n  name: a\n---\nkind: Task\nmetadata:\n  name: b\n---\nkind: Task\nmetadata:\n  name: c\n"
+	if got := string(resource.Data()); got != expected {
+		t.Errorf("expected concatenated directory %q, got %q", expected, got)
+	}
+}
+
+func TestResolveDirectoryMaxFiles(t *testing.T) {
+	files := map[string]string{}
+	for i := 0; i < 5; i++ {
+		files[fmt.Sprintf("tasks/task-%d.yaml", i)] = "kind: Task\n"
+	}
+	repoDir, _ := createTestRepo(t, []testCommit{{files: files}})
+
+	for _, tc := range []struct {
+		maxFiles string
+		wantErr  bool
+	}{
+		{maxFiles: "5", wantErr: false},
+		{maxFiles: "4", wantErr: true},
+	} {
+		ctx := framework.InjectResolverConfigToContext(context.Background(), map[string]string{
+			ConfigFieldMaxFiles: tc.maxFiles,
+		})
+		resolver := Resolver{}
+		_, err := resolver.Resolve(ctx, map[string]string{
+			URLParam:  repoDir,
+			PathParam: "tasks",
+		})
+		if !tc.wantErr && err != nil {
+			t.Errorf("unexpected error resolving directory with %s limit %s: %v", ConfigFieldMaxFiles, tc.maxFiles, err)
+		}
+		if tc.wantErr && (err == nil || !strings.Contains(err.Error(), "directory contains 5 files, exceeds limit 4")) {
+			t.Errorf("expected file count error with %s limit %s, got %v", ConfigFieldMaxFiles, tc.maxFiles, err)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// The text encodings that resolved files are detected as, recorded in
+// the encoding annotation.
+const (
+	EncodingUTF8    = "UTF-8"
+	EncodingUTF16LE = "UTF-16LE"
+	EncodingUTF16BE = "UTF-16BE"
+	EncodingLatin1  = "ISO-8859-1"
+)
+
+var (
+	bomUTF8    = []byte{0xef, 0xbb, 0xbf}
+	bomUTF16LE = []byte{0xff, 0xfe}
+	bomUTF16BE = []byte{0xfe, 0xff}
+)
+
+// detectEncoding returns the text encoding of content. A byte order
+// mark is trusted if there is one. Otherwise content is UTF-8 if it's
+// valid UTF-8, UTF-16 if every other byte is zero as it is for mostly
+// ascii text, and Latin-1 as a last resort since any bytes are valid
+// Latin-1.
+func detectEncoding(content []byte) string {
+	switch {
+	case bytes.HasPrefix(content, bomUTF8):
+		return EncodingUTF8
+	case bytes.HasPrefix(content, bomUTF16LE):
+		return EncodingUTF16LE
+	case bytes.HasPrefix(content, bomUTF16BE):
+		return EncodingUTF16BE
+	}
+	if len(content) >= 2 && len(content)%2 == 0 {
+		evenZeros, oddZeros := 0, 0
+		for i := 0; i < len(content); i += 2 {
+			if content[i] == 0 {
+				evenZeros++
+			}
+			if content[i+1] == 0 {
+				oddZeros++
+			}
+		}
+		pairs := len(content) / 2
+		switch {
+		case oddZeros*2 >= pairs && evenZeros == 0:
+			return EncodingUTF16LE
+		case evenZeros*2 >= pairs && oddZeros == 0:
+			return EncodingUTF16BE
+		}
+	}
+	if utf8.Valid(content) {
+		return EncodingUTF8
+	}
+	return EncodingLatin1
+}
+
+// transcodeToUTF8 returns content converted from the given encoding to
+// UTF-8 without a byte order mark.
+func transcodeToUTF8(content []byte, from string) ([]byte, error) {
+	var decoder *encoding.Decoder
+	switch from {
+	case EncodingUTF8:
+		return bytes.TrimPrefix(content, bomUTF8), nil
+	case EncodingUTF16LE:
+		decoder = unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM).NewDecoder()
+	case EncodingUTF16BE:
+		decoder = unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM).NewDecoder()
+	case EncodingLatin1:
+		decoder = charmap.ISO8859_1.NewDecoder()
+	default:
+		return nil, fmt.Errorf("unsupported encoding %q", from)
+	}
+	transcoded, err := decoder.Bytes(content)
+	if err != nil {
+		return nil, fmt.Errorf("error transcoding from %s: %w", from, err)
+	}
+	return bytes.TrimPrefix(transcoded, bomUTF8), nil
+}
+
+// decodeContent detects the encoding of content and, if transcode is
+// set, converts it to UTF-8.
+func decodeContent(content []byte, transcode bool) ([]byte, string, error) {
+	detected := detectEncoding(content)
+	if !transcode {
+		return content, detected, nil
+	}
+	transcoded, err := transcodeToUTF8(content, detected)
+	if err != nil {
+		return nil, "", err
+	}
+	return transcoded, detected, nil
+}
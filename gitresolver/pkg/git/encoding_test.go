@@ -0,0 +1,139 @@
+package git
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tektoncd/resolution/pkg/resolver/framework"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+)
+
+const encodingTestContent = "kind: Task\nmetadata:\n  name: café\n"
+
+func encodeTestContent(t *testing.T, encoder interface{ String(string) (string, error) }) string {
+	t.Helper()
+	encoded, err := encoder.String(encodingTestContent)
+	if err != nil {
+		t.Fatalf("error encoding test content: %v", err)
+	}
+	return encoded
+}
+
+func TestDetectEncoding(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		content  string
+		expected string
+	}{{
+		name:     "utf-8",
+		content:  encodingTestContent,
+		expected: EncodingUTF8,
+	}, {
+		name:     "utf-8 with bom",
+		content:  "\xef\xbb\xbf" + encodingTestContent,
+		expected: EncodingUTF8,
+	}, {
+		name:     "empty",
+		content:  "",
+		expected: EncodingUTF8,
+	}, {
+		name:     "utf-16le with bom",
+		content:  encodeTestContent(t, unicode.UTF16(unicode.LittleEndian, unicode.UseBOM).NewEncoder()),
+		expected: EncodingUTF16LE,
+	}, {
+		name:     "utf-16be with bom",
+		content:  encodeTestContent(t, unicode.UTF16(unicode.BigEndian, unicode.UseBOM).NewEncoder()),
+		expected: EncodingUTF16BE,
+	}, {
+		name:     "utf-16le without bom",
+		content:  encodeTestContent(t, unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM).NewEncoder()),
+		expected: EncodingUTF16LE,
+	}, {
+		name:     "utf-16be without bom",
+		content:  encodeTestContent(t, unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM).NewEncoder()),
+		expected: EncodingUTF16BE,
+	}, {
+		name:     "latin-1",
+		content:  encodeTestContent(t, charmap.ISO8859_1.NewEncoder()),
+		expected: EncodingLatin1,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			detected := detectEncoding([]byte(tc.content))
+			if detected != tc.expected {
+				t.Fatalf("expected %q received %q", tc.expected, detected)
+			}
+			transcoded, err := transcodeToUTF8([]byte(tc.content), detected)
+			if err != nil {
+				t.Fatalf("unexpected error transcoding: %v", err)
+			}
+			if tc.content != "" && string(transcoded) != encodingTestContent {
+				t.Errorf("expected transcoded content %q received %q", encodingTestContent, transcoded)
+			}
+		})
+	}
+}
+
+func TestResolveEncoding(t *testing.T) {
+	utf16 := encodeTestContent(t, unicode.UTF16(unicode.LittleEndian, unicode.UseBOM).NewEncoder())
+	latin1 := encodeTestContent(t, charmap.ISO8859_1.NewEncoder())
+	repoDir, _ := createTestRepo(t, []testCommit{{
+		files: map[string]string{
+			"utf8.yaml":   encodingTestContent,
+			"utf16.yaml":  utf16,
+			"latin1.yaml": latin1,
+		},
+	}})
+
+	for _, tc := range []struct {
+		path             string
+		transcode        string
+		expectedContent  string
+		expectedEncoding string
+	}{{
+		path:             "utf8.yaml",
+		transcode:        "true",
+		expectedContent:  encodingTestContent,
+		expectedEncoding: EncodingUTF8,
+	}, {
+		path:             "utf16.yaml",
+		transcode:        "true",
+		expectedContent:  encodingTestContent,
+		expectedEncoding: EncodingUTF16LE,
+	}, {
+		path:             "utf16.yaml",
+		transcode:        "false",
+		expectedContent:  utf16,
+		expectedEncoding: EncodingUTF16LE,
+	}, {
+		path:             "latin1.yaml",
+		transcode:        "true",
+		expectedContent:  encodingTestContent,
+		expectedEncoding: EncodingLatin1,
+	}, {
+		path:             "latin1.yaml",
+		transcode:        "",
+		expectedContent:  latin1,
+		expectedEncoding: EncodingLatin1,
+	}} {
+		t.Run(tc.path+" transcode "+tc.transcode, func(t *testing.T) {
+			ctx := framework.InjectResolverConfigToContext(context.Background(), map[string]string{
+				ConfigFieldTranscodeToUTF8: tc.transcode,
+			})
+			resolver := Resolver{}
+			resource, err := resolver.Resolve(ctx, map[string]string{
+				URLParam:  repoDir,
+				PathParam: tc.path,
+			})
+			if err != nil {
+				t.Fatalf("unexpected error resolving: %v", err)
+			}
+			if string(resource.Data()) != tc.expectedContent {
+				t.Errorf("expected content %q received %q", tc.expectedContent, resource.Data())
+			}
+			if encoding := resource.Annotations()[AnnotationKeyEncoding]; encoding != tc.expectedEncoding {
+				t.Errorf("expected encoding %q received %q", tc.expectedEncoding, encoding)
+			}
+		})
+	}
+}
@@ -0,0 +1,399 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/memfs"
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/storage"
+	"github.com/tektoncd/resolution/pkg/resolver/framework"
+)
+
+const (
+	// remoteName is the name given to the remote that files are
+	// fetched from.
+	remoteName = "origin"
+
+	// refSpecHead fetches only the remote's default branch.
+	refSpecHead = config.RefSpec("+HEAD:refs/remotes/origin/HEAD")
+
+	// refSpecAllBranches fetches every branch but no tags or other
+	// refs, such as pull request heads.
+	refSpecAllBranches = config.RefSpec("+refs/heads/*:refs/remotes/origin/*")
+
+	// refSpecEverything fetches every ref the remote advertises.
+	refSpecEverything = config.RefSpec("+refs/*:refs/remotes/origin/all/*")
+)
+
+// fetchedRepository is a repository retrieved from a remote along with
+// the filesystem that its worktree can be checked out to.
+type fetchedRepository struct {
+	repository *git.Repository
+	filesystem billy.Filesystem
+
+	// head is the commit that the fetched ref points to.
+	head plumbing.Hash
+
+	// ref is the candidate that was fetched, if there were any.
+	ref plumbing.ReferenceName
+
+	// cli is true if the repository was cloned with the git binary
+	// rather than go-git.
+	cli bool
+
+	// cleanup releases anything the repository holds on disk.
+	cleanup func()
+}
+
+// fetchRepository retrieves the repository at url into a new in-memory
+// filesystem, using a minimal fetch if one is configured. Objects that
+// a configured alternates repository already holds are read from it
+// rather than fetched. A nil auth leaves go-git to pick its default for
+// the url's protocol. Each ref in candidates is tried in order until
+// one exists on the remote. If go-git fails for any other reason and
+// the git cli fallback is enabled then the clone is retried with the
+// git binary, unless auth is given, which the git binary isn't. Fetches
+// using git protocol v2 or shallow-since are made with the git binary
+// from the start, falling back to go-git if it isn't installed. With
+// clone-cache-dir set, go-git fetches into a clone of the repository
+// kept on disk instead of cloning it again. With split-fetch-budget
+// set, each of these attempts is bounded by its share of the time left
+// before ctx's deadline. Clones that ctx marks as in-memory always use
+// go-git's in-memory storage, whose cleanup has nothing to do.
+func fetchRepository(ctx context.Context, url string, auth transport.AuthMethod, candidates []plumbing.ReferenceName, commit string) (*fetchedRepository, error) {
+	conf := framework.GetResolverConfigFromContext(ctx)
+	minimal, _ := strconv.ParseBool(conf[ConfigFieldMinimalFetch])
+	cliFallback, _ := strconv.ParseBool(conf[ConfigFieldGitCLIFallback])
+	cliFallback = cliFallback && auth == nil
+	if _, err := getGitProtocolVersion(conf); err != nil {
+		return nil, err
+	}
+	if _, err := getShallowSince(conf); err != nil {
+		return nil, err
+	}
+	cacheOpts, err := getCloneCacheOptions(conf)
+	if err != nil {
+		return nil, err
+	}
+	useCLI := useProtocolV2(conf, auth) || useShallowSince(conf, auth)
+	// Small clones skip the backends that write to disk.
+	if isInMemoryClone(ctx) {
+		useCLI, cacheOpts.dir = false, ""
+	}
+	// Only a minimal fetch with go-git into a new repository takes
+	// requested refspecs.
+	if len(getRefSpecsFromContext(ctx)) > 0 {
+		useCLI, cacheOpts.dir, minimal, cliFallback = false, "", true, false
+	}
+	if len(candidates) == 0 {
+		candidates = []plumbing.ReferenceName{""}
+	}
+
+	// Each candidate is planned to take one attempt, or two if the git
+	// cli fallback may follow go-git.
+	perRef := 1
+	if cliFallback {
+		perRef = 2
+	}
+	var budget *attemptBudget
+	if split, _ := strconv.ParseBool(conf[ConfigFieldSplitFetchBudget]); split {
+		budget = newAttemptBudget(ctx, len(candidates)*perRef)
+	}
+
+	for _, ref := range candidates {
+		if useCLI {
+			var fetched *fetchedRepository
+			attemptCtx, cancel := budget.start(ctx)
+			fetched, err = cloneWithCLI(attemptCtx, url, ref, commit)
+			cancel()
+			if err == nil {
+				return fetched, nil
+			}
+			if isCLIRefNotFound(err) {
+				budget.skip(perRef - 1)
+				continue
+			}
+			if !isGitNotInstalled(err) {
+				break
+			}
+			useCLI = false
+		}
+		if cacheOpts.dir != "" {
+			var fetched *fetchedRepository
+			attemptCtx, cancel := budget.start(ctx)
+			fetched, err = fetchCached(attemptCtx, cacheOpts, url, auth, ref, commit)
+			cancel()
+			if err == nil {
+				return fetched, nil
+			}
+		} else {
+			var store storage.Storer
+			store, err = newStorage(conf[ConfigFieldAlternatesDir])
+			if err != nil {
+				return nil, err
+			}
+			store = limitRepoSize(ctx, store)
+			filesystem := memfs.New()
+			var repository *git.Repository
+			var head plumbing.Hash
+			attemptCtx, cancel := budget.start(ctx)
+			// go-git only clones a single branch or tag, so other refs,
+			// like a pull request's head, are fetched on their own.
+			if minimal || ref != "" && !ref.IsBranch() && !ref.IsTag() {
+				repository, head, err = fetchMinimal(attemptCtx, store, filesystem, url, auth, ref, commit)
+			} else {
+				repository, head, err = cloneRepository(attemptCtx, store, filesystem, url, auth, ref)
+			}
+			cancel()
+			if err == nil {
+				return &fetchedRepository{
+					repository: repository,
+					filesystem: filesystem,
+					head:       head,
+					ref:        ref,
+					cleanup:    func() {},
+				}, nil
+			}
+		}
+		if isRefNotFound(err) {
+			budget.skip(perRef - 1)
+			continue
+		}
+		if cliFallback {
+			attemptCtx, cancel := budget.start(ctx)
+			fetched, cliErr := cloneWithCLI(attemptCtx, url, ref, commit)
+			cancel()
+			if cliErr != nil {
+				return nil, fmt.Errorf("%w; git cli fallback: %v", err, cliErr)
+			}
+			return fetched, nil
+		}
+		break
+	}
+	return nil, err
+}
+
+// cloneRepository performs a full clone of the repository at url, or a
+// single ref clone if ref is set, and returns the repository along with
+// the hash of the commit at its HEAD. History is limited to the clone
+// depth in ctx, if there is one.
+func cloneRepository(ctx context.Context, store storage.Storer, filesystem billy.Filesystem, url string, auth transport.AuthMethod, ref plumbing.ReferenceName) (*git.Repository, plumbing.Hash, error) {
+	cloneOpts := &git.CloneOptions{
+		URL:   url,
+		Auth:  auth,
+		Depth: getCloneDepthFromContext(ctx),
+	}
+	if ref != "" {
+		cloneOpts.SingleBranch = true
+		cloneOpts.ReferenceName = ref
+	}
+	repository, err := git.CloneContext(ctx, store, filesystem, cloneOpts)
+	if err != nil {
+		return nil, plumbing.ZeroHash, fmt.Errorf("clone error: %w", err)
+	}
+	headRef, err := resolveRepositoryRef(ctx, repository, plumbing.HEAD)
+	if err != nil {
+		return nil, plumbing.ZeroHash, fmt.Errorf("error reading repository HEAD value: %w", err)
+	}
+	head, err := peelToCommit(repository, headRef.Hash())
+	if err != nil {
+		return nil, plumbing.ZeroHash, err
+	}
+	return repository, head, nil
+}
+
+// fetchMinimal initializes an empty repository and fetches only the
+// refs needed to resolve the requested ref or commit: a single ref and
+// the tags pointing into it, the default branch if neither is given,
+// or every branch head when looking for a commit. A commit that isn't
+// reachable from any branch triggers a second fetch of all advertised
+// refs. The returned hash is the commit the fetched ref points to and
+// is zero when a commit was requested. History is limited to the clone
+// depth in ctx, if there is one.
+func fetchMinimal(ctx context.Context, store storage.Storer, filesystem billy.Filesystem, url string, auth transport.AuthMethod, ref plumbing.ReferenceName, commit string) (*git.Repository, plumbing.Hash, error) {
+	repository, err := git.Init(store, filesystem)
+	if err != nil {
+		return nil, plumbing.ZeroHash, fmt.Errorf("init error: %w", err)
+	}
+	remote, err := repository.CreateRemote(&config.RemoteConfig{
+		Name: remoteName,
+		URLs: []string{url},
+	})
+	if err != nil {
+		return nil, plumbing.ZeroHash, fmt.Errorf("error creating remote: %w", err)
+	}
+	head, err := fetchRefs(ctx, repository, remote, auth, ref, commit)
+	if err != nil {
+		return nil, plumbing.ZeroHash, err
+	}
+	return repository, head, nil
+}
+
+// fetchRefs fetches the refs that fetchMinimal needs from remote into
+// repository, which may already hold some of their objects, and returns
+// the commit the fetched ref points to, or zero if a commit was
+// requested. With neither a ref nor a commit, the branch that the
+// remote's HEAD points to is fetched and repository's HEAD is pointed
+// at it too, as a clone would, so that its name can be reported.
+// Refspecs requested in ctx are fetched in place of the ones picked for
+// ref or commit, and ref is then the local ref they fetch to.
+func fetchRefs(ctx context.Context, repository *git.Repository, remote *git.Remote, auth transport.AuthMethod, ref plumbing.ReferenceName, commit string) (plumbing.Hash, error) {
+	defaultBranch := plumbing.ReferenceName("")
+	if ref == "" && commit == "" {
+		defaultBranch = remoteDefaultBranch(ctx, remote, auth)
+		ref = defaultBranch
+	}
+	refSpecs := getRefSpecsFromContext(ctx)
+	fetchOpts := &git.FetchOptions{
+		RemoteName: remoteName,
+		Auth:       auth,
+		RefSpecs:   minimalRefSpecs(ref, commit),
+		Tags:       git.TagFollowing,
+		Depth:      getCloneDepthFromContext(ctx),
+	}
+	if len(refSpecs) > 0 {
+		fetchOpts.RefSpecs = refSpecs
+	}
+	if err := remote.FetchContext(ctx, fetchOpts); err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return plumbing.ZeroHash, fmt.Errorf("fetch error: %w", err)
+	}
+
+	if commit != "" {
+		if _, err := repository.CommitObject(plumbing.NewHash(commit)); err == nil {
+			return plumbing.ZeroHash, nil
+		}
+		// Fetching everything would defeat the requested refspecs.
+		if len(refSpecs) > 0 {
+			return plumbing.ZeroHash, fmt.Errorf("commit %s isn't reachable from the refs fetched with %q", commit, RefSpecParam)
+		}
+		fetchOpts.RefSpecs = []config.RefSpec{refSpecEverything}
+		fetchOpts.Tags = git.AllTags
+		if err := remote.FetchContext(ctx, fetchOpts); err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+			return plumbing.ZeroHash, fmt.Errorf("fetch error: %w", err)
+		}
+		return plumbing.ZeroHash, nil
+	}
+
+	refName := plumbing.NewRemoteHEADReferenceName(remoteName)
+	switch {
+	case len(refSpecs) > 0:
+		refName = ref
+	case ref.IsBranch():
+		refName = plumbing.NewRemoteReferenceName(remoteName, ref.Short())
+	case ref != "":
+		refName = ref
+	}
+	fetched, err := resolveRepositoryRef(ctx, repository, refName)
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("error reading fetched ref %q: %w", refName, err)
+	}
+	head, err := peelToCommit(repository, fetched.Hash())
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	if ref == defaultBranch {
+		// A HEAD left over from initializing the repository, or from
+		// an earlier fetch into a cached clone, would name the wrong
+		// branch, so it's detached if the remote's branch isn't known.
+		headRef := plumbing.NewHashReference(plumbing.HEAD, head)
+		if defaultBranch != "" {
+			headRef = plumbing.NewSymbolicReference(plumbing.HEAD, defaultBranch)
+		}
+		if err := repository.Storer.SetReference(headRef); err != nil {
+			return plumbing.ZeroHash, fmt.Errorf("error setting HEAD: %w", err)
+		}
+	}
+	return head, nil
+}
+
+// remoteDefaultBranch returns the branch that remote's HEAD points to,
+// or an empty name if it can't be listed or the remote doesn't say.
+func remoteDefaultBranch(ctx context.Context, remote *git.Remote, auth transport.AuthMethod) plumbing.ReferenceName {
+	refs, err := remote.ListContext(ctx, &git.ListOptions{Auth: auth})
+	if err != nil {
+		return ""
+	}
+	for _, ref := range refs {
+		if ref.Name() == plumbing.HEAD && ref.Type() == plumbing.SymbolicReference && ref.Target().IsBranch() {
+			return ref.Target()
+		}
+	}
+	return ""
+}
+
+// defaultBranchName returns the short name of the branch that
+// repository's HEAD points to, which is the remote's default branch
+// when neither a ref nor a commit was fetched. It's empty if HEAD is
+// detached.
+func defaultBranchName(repository *git.Repository) string {
+	head, err := repository.Storer.Reference(plumbing.HEAD)
+	if err != nil || head.Type() != plumbing.SymbolicReference || !head.Target().IsBranch() {
+		return ""
+	}
+	return head.Target().Short()
+}
+
+// minimalRefSpecs returns the narrowest refspecs that can satisfy a
+// request for the given ref or commit.
+func minimalRefSpecs(ref plumbing.ReferenceName, commit string) []config.RefSpec {
+	switch {
+	case ref.IsBranch():
+		return []config.RefSpec{
+			config.RefSpec(fmt.Sprintf("+%s:refs/remotes/%s/%s", ref, remoteName, ref.Short())),
+		}
+	case ref != "":
+		return []config.RefSpec{
+			config.RefSpec(fmt.Sprintf("+%s:%[1]s", ref)),
+		}
+	case commit != "":
+		return []config.RefSpec{refSpecAllBranches}
+	default:
+		return []config.RefSpec{refSpecHead}
+	}
+}
+
+// peelToCommit returns the hash of the commit that hash refers to,
+// dereferencing it first if it's an annotated tag.
+func peelToCommit(repository *git.Repository, hash plumbing.Hash) (plumbing.Hash, error) {
+	tag, err := repository.TagObject(hash)
+	if errors.Is(err, plumbing.ErrObjectNotFound) {
+		return hash, nil
+	}
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("error reading tag %q: %w", hash, err)
+	}
+	commit, err := tag.Commit()
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("error reading commit for tag %q: %w", tag.Name, err)
+	}
+	return commit.Hash, nil
+}
+
+// isRefNotFound returns true if err indicates that the ref requested
+// from a remote does not exist.
+func isRefNotFound(err error) bool {
+	return errors.Is(err, plumbing.ErrReferenceNotFound) || errors.Is(err, git.NoMatchingRefSpecError{})
+}
@@ -0,0 +1,191 @@
+package git
+
+import (
+	"context"
+	"testing"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/tektoncd/resolution/pkg/resolver/framework"
+)
+
+func TestResolveMinimalFetch(t *testing.T) {
+	repoDir, commits := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "one"},
+	}, {
+		files: map[string]string{"task.yaml": "two"},
+	}, {
+		files: map[string]string{"task.yaml": "three"},
+	}})
+	// commits[2] is only reachable from a tag after master
+	// is moved back.
+	setTestRef(t, repoDir, "refs/heads/feature", commits[1])
+	setTestRef(t, repoDir, "refs/tags/v1", commits[2])
+	setTestRef(t, repoDir, "refs/heads/master", commits[0])
+
+	for _, tc := range []struct {
+		name     string
+		params   map[string]string
+		expected string
+	}{{
+		name:     "default branch",
+		params:   map[string]string{},
+		expected: "one",
+	}, {
+		name:     "branch",
+		params:   map[string]string{BranchParam: "feature"},
+		expected: "two",
+	}, {
+		name:     "commit on a branch",
+		params:   map[string]string{CommitParam: commits[1]},
+		expected: "two",
+	}, {
+		name:     "commit off any branch",
+		params:   map[string]string{CommitParam: commits[2]},
+		expected: "three",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.params[URLParam] = repoDir
+			tc.params[PathParam] = "task.yaml"
+
+			resolver := Resolver{}
+			defaultResource, err := resolver.Resolve(context.Background(), tc.params)
+			if err != nil {
+				t.Fatalf("unexpected error resolving with default refspecs: %v", err)
+			}
+
+			ctx := framework.InjectResolverConfigToContext(context.Background(), map[string]string{
+				ConfigFieldMinimalFetch: "true",
+			})
+			minimalResource, err := resolver.Resolve(ctx, tc.params)
+			if err != nil {
+				t.Fatalf("unexpected error resolving with minimal refspecs: %v", err)
+			}
+
+			if string(minimalResource.Data()) != tc.expected {
+				t.Errorf("expected content %q received %q", tc.expected, string(minimalResource.Data()))
+			}
+			if string(defaultResource.Data()) != string(minimalResource.Data()) {
+				t.Errorf("minimal fetch content %q differs from default %q", string(minimalResource.Data()), string(defaultResource.Data()))
+			}
+			defaultCommit := defaultResource.Annotations()[AnnotationKeyCommitHash]
+			minimalCommit := minimalResource.Annotations()[AnnotationKeyCommitHash]
+			if defaultCommit != minimalCommit {
+				t.Errorf("minimal fetch commit %q differs from default %q", minimalCommit, defaultCommit)
+			}
+		})
+	}
+}
+
+func TestMinimalRefSpecs(t *testing.T) {
+	for _, tc := range []struct {
+		ref      plumbing.ReferenceName
+		commit   string
+		expected string
+	}{{
+		ref:      plumbing.NewBranchReferenceName("main"),
+		expected: "+refs/heads/main:refs/remotes/origin/main",
+	}, {
+		ref:      plumbing.NewTagReferenceName("v1.0.0"),
+		expected: "+refs/tags/v1.0.0:refs/tags/v1.0.0",
+	}, {
+		commit:   "abc123",
+		expected: "+refs/heads/*:refs/remotes/origin/*",
+	}, {
+		expected: "+HEAD:refs/remotes/origin/HEAD",
+	}} {
+		specs := minimalRefSpecs(tc.ref, tc.commit)
+		if len(specs) != 1 || specs[0].String() != tc.expected {
+			t.Errorf("ref %q commit %q: expected refspec %q received %v", tc.ref, tc.commit, tc.expected, specs)
+		}
+	}
+}
+
+// setTestDefaultBranch renames the master branch of the repo at dir to
+// name and points its HEAD at it.
+func setTestDefaultBranch(t *testing.T, dir, name string) {
+	t.Helper()
+	repo, err := git.PlainOpen(dir)
+	if err != nil {
+		t.Fatalf("error opening repo: %v", err)
+	}
+	master, err := repo.Reference(plumbing.Master, false)
+	if err != nil {
+		t.Fatalf("error reading master: %v", err)
+	}
+	branch := plumbing.NewBranchReferenceName(name)
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(branch, master.Hash())); err != nil {
+		t.Fatalf("error creating branch %q: %v", name, err)
+	}
+	if err := repo.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, branch)); err != nil {
+		t.Fatalf("error pointing HEAD at %q: %v", name, err)
+	}
+	if err := repo.Storer.RemoveReference(plumbing.Master); err != nil {
+		t.Fatalf("error removing master: %v", err)
+	}
+}
+
+func TestResolveDefaultBranch(t *testing.T) {
+	for _, branch := range []string{"main", "release/trunk-2022"} {
+		repoDir, commits := createTestRepo(t, []testCommit{{
+			files: map[string]string{"task.yaml": "default"},
+		}})
+		setTestDefaultBranch(t, repoDir, branch)
+		server := newGitHTTPServer(t, repoDir, nil)
+
+		for _, tc := range []struct {
+			name string
+			url  string
+			conf map[string]string
+		}{
+			{name: "clone", url: repoDir, conf: map[string]string{}},
+			{name: "minimal fetch", url: repoDir, conf: map[string]string{ConfigFieldMinimalFetch: "true"}},
+			{name: "clone cache", url: repoDir, conf: map[string]string{ConfigFieldCloneCacheDir: t.TempDir()}},
+			{name: "http clone", url: server.URL + "/repo", conf: map[string]string{}},
+			{name: "http minimal fetch", url: server.URL + "/repo", conf: map[string]string{ConfigFieldMinimalFetch: "true"}},
+			{name: "git cli", url: server.URL + "/repo", conf: map[string]string{ConfigFieldGitProtocolVersion: GitProtocolV2}},
+		} {
+			t.Run(branch+"/"+tc.name, func(t *testing.T) {
+				resolver := Resolver{}
+				resource, err := resolver.Resolve(framework.InjectResolverConfigToContext(context.Background(), tc.conf), map[string]string{
+					URLParam:  tc.url,
+					PathParam: "task.yaml",
+				})
+				if err != nil {
+					t.Fatalf("unexpected error resolving from the default branch: %v", err)
+				}
+				if string(resource.Data()) != "default" {
+					t.Errorf("expected content %q received %q", "default", string(resource.Data()))
+				}
+				annotations := resource.Annotations()
+				if annotations[AnnotationKeyCommitHash] != commits[0] {
+					t.Errorf("expected commit %q received %q", commits[0], annotations[AnnotationKeyCommitHash])
+				}
+				if annotations[AnnotationKeyDefaultBranch] != branch {
+					t.Errorf("expected default branch %q received %q", branch, annotations[AnnotationKeyDefaultBranch])
+				}
+			})
+		}
+	}
+}
+
+func TestResolveDefaultBranchNotRecordedForRefs(t *testing.T) {
+	repoDir, commits := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "default"},
+	}})
+	setTestDefaultBranch(t, repoDir, "main")
+
+	for _, params := range []map[string]string{
+		{URLParam: repoDir, PathParam: "task.yaml", BranchParam: "main"},
+		{URLParam: repoDir, PathParam: "task.yaml", CommitParam: commits[0]},
+	} {
+		resolver := Resolver{}
+		resource, err := resolver.Resolve(framework.InjectResolverConfigToContext(context.Background(), map[string]string{ConfigFieldMinimalFetch: "true"}), params)
+		if err != nil {
+			t.Fatalf("unexpected error resolving %v: %v", params, err)
+		}
+		if branch, ok := resource.Annotations()[AnnotationKeyDefaultBranch]; ok {
+			t.Errorf("expected no default branch for %v, received %q", params, branch)
+		}
+	}
+}
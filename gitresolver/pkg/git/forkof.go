@@ -0,0 +1,108 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+// apiRepository is the part of a repository from a GitHub compatible
+// api that describes where it was forked from. Parent is the repo it
+// was forked from directly and Source is the root of its fork network.
+type apiRepository struct {
+	FullName string         `json:"full_name"`
+	Fork     bool           `json:"fork"`
+	Parent   *apiRepository `json:"parent"`
+	Source   *apiRepository `json:"source"`
+}
+
+// checkForkOf fails unless the repo at url is one of the upstreams
+// configured with the require-fork-of field in the git-resolver-config
+// configmap, or a fork of one of them according to its host's api. No
+// check is made if no upstreams are configured.
+func checkForkOf(ctx context.Context, conf map[string]string, url string, auth transport.AuthMethod) error {
+	allowed := map[string]bool{}
+	names := []string{}
+	for _, upstream := range strings.Split(conf[ConfigFieldRequireForkOf], ",") {
+		if upstream = normalizeRepoName(upstream); upstream != "" {
+			allowed[upstream] = true
+			names = append(names, upstream)
+		}
+	}
+	if len(allowed) == 0 {
+		return nil
+	}
+
+	ep, err := transport.NewEndpoint(url)
+	if err != nil || ep.Protocol != "https" && ep.Protocol != "http" {
+		return fmt.Errorf("%s can only check http or https repositories, not %q", ConfigFieldRequireForkOf, url)
+	}
+	fullName := strings.TrimSuffix(strings.Trim(ep.Path, "/"), ".git")
+	if allowed[normalizeRepoName(ep.Host+"/"+fullName)] {
+		return nil
+	}
+
+	repository, err := lookupAPIRepository(ctx, conf, ep.Host, fullName, auth)
+	if err != nil {
+		return fmt.Errorf("error looking up the upstream of %q: %w", url, err)
+	}
+	if repository.Fork {
+		for _, upstream := range []*apiRepository{repository.Parent, repository.Source} {
+			if upstream != nil && allowed[normalizeRepoName(ep.Host+"/"+upstream.FullName)] {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("repository %q is not a fork of an allowed upstream: must be a fork of one of %s", url, strings.Join(names, ", "))
+}
+
+// lookupAPIRepository fetches the repo named fullName, like
+// tektoncd/catalog, from the api of host, or from the api configured
+// with the fork-api-url field if there is one.
+func lookupAPIRepository(ctx context.Context, conf map[string]string, host, fullName string, auth transport.AuthMethod) (*apiRepository, error) {
+	apiURL := conf[ConfigFieldForkAPIURL]
+	if apiURL == "" {
+		apiURL = githubAPIURL(host)
+	}
+	url := strings.TrimSuffix(apiURL, "/") + "/repos/" + fullName
+	body, err := getAPI(ctx, url, "application/vnd.github+json", auth)
+	if err != nil {
+		return nil, err
+	}
+	repository := &apiRepository{}
+	if err := json.Unmarshal(body, repository); err != nil {
+		return nil, fmt.Errorf("error parsing repository from %s: %w", url, err)
+	}
+	return repository, nil
+}
+
+// normalizeRepoName returns repo, like https://github.com/tektoncd/catalog.git,
+// as a lowercase host and path without a scheme or .git suffix, like
+// github.com/tektoncd/catalog, so that spellings of a repo compare equal.
+func normalizeRepoName(repo string) string {
+	repo = strings.TrimSpace(repo)
+	if i := strings.Index(repo, "://"); i >= 0 {
+		repo = repo[i+len("://"):]
+	}
+	repo = strings.TrimSuffix(strings.TrimSuffix(repo, "/"), ".git")
+	return strings.ToLower(repo)
+}
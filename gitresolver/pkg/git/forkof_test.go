@@ -0,0 +1,143 @@
+package git
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/tektoncd/resolution/pkg/resolver/framework"
+)
+
+// newForkAPIServer returns a stub of a GitHub compatible api that
+// serves the given repos by their full name.
+func newForkAPIServer(t *testing.T, repos map[string]apiRepository) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auth := r.Header.Get("Authorization"); auth != "" && auth != "Bearer token" {
+			t.Errorf("unexpected Authorization header %q", auth)
+		}
+		repo, ok := repos[strings.TrimPrefix(r.URL.Path, "/repos/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if err := json.NewEncoder(w).Encode(repo); err != nil {
+			t.Errorf("error encoding repo: %v", err)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestCheckForkOf(t *testing.T) {
+	server := newForkAPIServer(t, map[string]apiRepository{
+		"me/catalog": {
+			FullName: "me/catalog",
+			Fork:     true,
+			Parent:   &apiRepository{FullName: "tektoncd/catalog"},
+			Source:   &apiRepository{FullName: "tektoncd/catalog"},
+		},
+		"me/fork-of-fork": {
+			FullName: "me/fork-of-fork",
+			Fork:     true,
+			Parent:   &apiRepository{FullName: "someone/catalog"},
+			Source:   &apiRepository{FullName: "tektoncd/catalog"},
+		},
+		"me/lookalike": {
+			FullName: "me/lookalike",
+			Fork:     true,
+			Parent:   &apiRepository{FullName: "tektoncd-lookalike/catalog"},
+			Source:   &apiRepository{FullName: "tektoncd-lookalike/catalog"},
+		},
+		"tektoncd-lookalike/catalog": {
+			FullName: "tektoncd-lookalike/catalog",
+		},
+	})
+
+	for _, tc := range []struct {
+		name        string
+		url         string
+		requireFork string
+		expectedErr string
+	}{{
+		name:        "no check configured",
+		url:         "https://github.com/tektoncd-lookalike/catalog",
+		requireFork: "",
+	}, {
+		name:        "fork of allowed upstream",
+		url:         "https://github.com/me/catalog",
+		requireFork: "github.com/tektoncd/catalog",
+	}, {
+		name:        "fork of a fork of allowed upstream",
+		url:         "https://github.com/me/fork-of-fork.git",
+		requireFork: "github.com/tektoncd/catalog",
+	}, {
+		name:        "allowed upstream itself",
+		url:         "https://github.com/TektonCD/catalog.git",
+		requireFork: "https://github.com/tektoncd/catalog.git",
+	}, {
+		name:        "fork of another repo",
+		url:         "https://github.com/me/lookalike",
+		requireFork: "github.com/tektoncd/catalog, github.com/tektoncd/pipeline",
+		expectedErr: `repository "https://github.com/me/lookalike" is not a fork of an allowed upstream: must be a fork of one of github.com/tektoncd/catalog, github.com/tektoncd/pipeline`,
+	}, {
+		name:        "not a fork",
+		url:         "https://github.com/tektoncd-lookalike/catalog",
+		requireFork: "github.com/tektoncd/catalog",
+		expectedErr: "is not a fork of an allowed upstream",
+	}, {
+		name:        "unknown repo",
+		url:         "https://github.com/me/missing",
+		requireFork: "github.com/tektoncd/catalog",
+		expectedErr: "404 Not Found",
+	}, {
+		name:        "ssh repo",
+		url:         "git@github.com:me/catalog.git",
+		requireFork: "github.com/tektoncd/catalog",
+		expectedErr: "can only check http or https repositories",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			conf := map[string]string{
+				ConfigFieldRequireForkOf: tc.requireFork,
+				ConfigFieldForkAPIURL:    server.URL,
+			}
+			err := checkForkOf(context.Background(), conf, tc.url, &githttp.TokenAuth{Token: "token"})
+			if tc.expectedErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error checking fork: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.expectedErr) {
+				t.Errorf("expected error containing %q, received %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestResolveRequireForkOfRejectsBeforeFetching(t *testing.T) {
+	server := newForkAPIServer(t, map[string]apiRepository{
+		"me/lookalike": {
+			FullName: "me/lookalike",
+			Fork:     true,
+			Parent:   &apiRepository{FullName: "tektoncd-lookalike/catalog"},
+		},
+	})
+	ctx := framework.InjectResolverConfigToContext(context.Background(), map[string]string{
+		ConfigFieldRequireForkOf: "github.com/tektoncd/catalog",
+		ConfigFieldForkAPIURL:    server.URL,
+	})
+
+	resolver := Resolver{}
+	_, err := resolver.Resolve(ctx, map[string]string{
+		URLParam:  "https://github.com/me/lookalike",
+		PathParam: "task.yaml",
+	})
+	if err == nil || !strings.Contains(err.Error(), "is not a fork of an allowed upstream") {
+		t.Errorf("expected fork check error, received %v", err)
+	}
+}
@@ -0,0 +1,131 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"gopkg.in/yaml.v3"
+)
+
+// The formats that FormatParam can convert resolved files to. Tar
+// archives the file at PathParam, or the files beneath the directory,
+// instead of converting it.
+const (
+	FormatYAML = "yaml"
+	FormatJSON = "json"
+	FormatTar  = "tar"
+)
+
+// getFormat returns the format requested with FormatParam, if any.
+func getFormat(params map[string]string) (string, error) {
+	switch format := params[FormatParam]; format {
+	case "", FormatYAML, FormatJSON, FormatTar:
+		return format, nil
+	default:
+		return "", fmt.Errorf("invalid value for %q: %q, expected %q, %q or %q", FormatParam, format, FormatYAML, FormatJSON, FormatTar)
+	}
+}
+
+// convertFormat returns a copy of resolved with its content converted to
+// format. Json is parsed as yaml, which it is a subset of, so either can
+// be converted to the other.
+func convertFormat(resolved *ResolvedGitResource, format string) (*ResolvedGitResource, error) {
+	docs, err := decodeStructured(resolved.Content)
+	if err != nil {
+		return nil, fmt.Errorf("error converting file %q to %s: %w", resolved.Path, format, err)
+	}
+
+	converted := *resolved
+	switch format {
+	case FormatJSON:
+		converted.Content, err = encodeJSON(docs)
+		converted.ContentType = JSONContentType
+	case FormatYAML:
+		converted.Content, err = encodeYAML(docs)
+		converted.ContentType = YAMLContentType
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error converting file %q to %s: %w", resolved.Path, format, err)
+	}
+	if converted.OCIDigest != "" {
+		digest, _, err := v1.SHA256(bytes.NewReader(converted.Content))
+		if err != nil {
+			return nil, fmt.Errorf("error computing digest: %w", err)
+		}
+		converted.OCIDigest = digest.String()
+	}
+	return &converted, nil
+}
+
+// decodeStructured parses every document in content, failing unless
+// each is a mapping or a sequence.
+func decodeStructured(content []byte) ([]interface{}, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	docs := []interface{}{}
+	for {
+		var doc interface{}
+		err := decoder.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid yaml or json: %w", err)
+		}
+		switch doc.(type) {
+		case map[string]interface{}, []interface{}:
+		default:
+			return nil, fmt.Errorf("document %d is not a mapping or sequence", len(docs)+1)
+		}
+		docs = append(docs, doc)
+	}
+	if len(docs) == 0 {
+		return nil, errors.New("no yaml or json documents")
+	}
+	return docs, nil
+}
+
+// encodeJSON encodes a single document as a json value and several as a
+// json array of them.
+func encodeJSON(docs []interface{}) ([]byte, error) {
+	var v interface{} = docs
+	if len(docs) == 1 {
+		v = docs[0]
+	}
+	return json.Marshal(v)
+}
+
+// encodeYAML encodes docs as a yaml stream with two space indentation.
+func encodeYAML(docs []interface{}) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	encoder := yaml.NewEncoder(buf)
+	encoder.SetIndent(2)
+	for _, doc := range docs {
+		if err := encoder.Encode(doc); err != nil {
+			return nil, err
+		}
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
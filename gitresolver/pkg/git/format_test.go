@@ -0,0 +1,150 @@
+package git
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	resolutioncommon "github.com/tektoncd/resolution/pkg/common"
+	"gopkg.in/yaml.v3"
+)
+
+func TestResolveFormat(t *testing.T) {
+	repoDir, _ := createTestRepo(t, []testCommit{{
+		files: map[string]string{
+			"task.yaml": "kind: Task\nspec:\n  steps:\n  - name: build\n    image: golang\n",
+			"task.json": `{"kind": "Task", "spec": {"steps": [{"name": "build", "image": "golang"}]}}`,
+			"README.md": "# Tasks\n",
+		},
+	}})
+	expected := map[string]interface{}{
+		"kind": "Task",
+		"spec": map[string]interface{}{
+			"steps": []interface{}{map[string]interface{}{"name": "build", "image": "golang"}},
+		},
+	}
+
+	resolver := &Resolver{}
+	resource, err := resolver.Resolve(context.Background(), map[string]string{
+		URLParam:    repoDir,
+		PathParam:   "task.yaml",
+		FormatParam: FormatJSON,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error converting yaml to json: %v", err)
+	}
+	if ct := resource.Annotations()[resolutioncommon.AnnotationKeyContentType]; ct != JSONContentType {
+		t.Errorf("expected content type %q received %q", JSONContentType, ct)
+	}
+	var fromJSON map[string]interface{}
+	if err := json.Unmarshal(resource.Data(), &fromJSON); err != nil {
+		t.Fatalf("expected valid json, received %q: %v", string(resource.Data()), err)
+	}
+	if !reflect.DeepEqual(expected, fromJSON) {
+		t.Errorf("expected %v received %v", expected, fromJSON)
+	}
+
+	resource, err = resolver.Resolve(context.Background(), map[string]string{
+		URLParam:    repoDir,
+		PathParam:   "task.json",
+		FormatParam: FormatYAML,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error converting json to yaml: %v", err)
+	}
+	if ct := resource.Annotations()[resolutioncommon.AnnotationKeyContentType]; ct != YAMLContentType {
+		t.Errorf("expected content type %q received %q", YAMLContentType, ct)
+	}
+	var fromYAML map[string]interface{}
+	if err := yaml.Unmarshal(resource.Data(), &fromYAML); err != nil {
+		t.Fatalf("expected valid yaml, received %q: %v", string(resource.Data()), err)
+	}
+	if !reflect.DeepEqual(expected, fromYAML) {
+		t.Errorf("expected %v received %v", expected, fromYAML)
+	}
+
+	_, err = resolver.Resolve(context.Background(), map[string]string{
+		URLParam:    repoDir,
+		PathParam:   "README.md",
+		FormatParam: FormatJSON,
+	})
+	if err == nil || !strings.Contains(err.Error(), `error converting file "README.md" to json`) {
+		t.Errorf("expected conversion error for unstructured file, received %v", err)
+	}
+}
+
+func TestConvertFormatMultipleDocuments(t *testing.T) {
+	converted, err := convertFormat(&ResolvedGitResource{Content: []byte("a: 1\n---\nb: 2\n")}, FormatJSON)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(converted.Content) != `[{"a":1},{"b":2}]` {
+		t.Errorf("expected documents as a json array, received %q", string(converted.Content))
+	}
+}
+
+func TestValidateParamsFormat(t *testing.T) {
+	resolver := &Resolver{}
+	for _, tc := range []struct {
+		params   map[string]string
+		expected string
+	}{{
+		params:   map[string]string{FormatParam: "toml"},
+		expected: `invalid value for "format"`,
+	}, {
+		params:   map[string]string{FormatParam: FormatJSON, BlameParam: "true"},
+		expected: `supplied both "format" and "blame"`,
+	}} {
+		tc.params[URLParam] = "https://github.com/tektoncd/catalog.git"
+		tc.params[PathParam] = "task.yaml"
+		err := resolver.ValidateParams(context.Background(), tc.params)
+		if err == nil || !strings.Contains(err.Error(), tc.expected) {
+			t.Errorf("expected error containing %q received %v", tc.expected, err)
+		}
+	}
+}
+
+func TestResolveContentTypeFromExtension(t *testing.T) {
+	repoDir, _ := createTestRepo(t, []testCommit{{
+		files: map[string]string{
+			"pipeline.json":             `{"kind": "Pipeline"}`,
+			"task.yaml":                 "kind: Task\n",
+			"task.YML":                  "kind: Task\n",
+			"Taskfile":                  "kind: Task\n",
+			"README.txt":                "plain text\n",
+			"logo.png":                  "\x89PNG\r\n\x1a\nnot really an image",
+			"task/git-clone/0.9/a.yaml": "kind: Task\n",
+			"tasks/b.json":              `{"kind": "Task"}`,
+		},
+	}})
+
+	for _, tc := range []struct {
+		path     string
+		expected string
+	}{
+		{path: "pipeline.json", expected: JSONContentType},
+		{path: "task.yaml", expected: YAMLContentType},
+		{path: "task.YML", expected: YAMLContentType},
+		{path: "Taskfile", expected: YAMLContentType},
+		{path: "README.txt", expected: "text/plain; charset=utf-8"},
+		{path: "logo.png", expected: "image/png"},
+		{path: "task/git-clone/0.9", expected: YAMLContentType},
+		{path: "tasks/*.json", expected: YAMLContentType},
+	} {
+		t.Run(tc.path, func(t *testing.T) {
+			resolver := Resolver{}
+			resource, err := resolver.Resolve(context.Background(), map[string]string{
+				URLParam:  repoDir,
+				PathParam: tc.path,
+			})
+			if err != nil {
+				t.Fatalf("unexpected error resolving: %v", err)
+			}
+			if ct := resource.Annotations()[resolutioncommon.AnnotationKeyContentType]; ct != tc.expected {
+				t.Errorf("expected content type %q received %q", tc.expected, ct)
+			}
+		})
+	}
+}
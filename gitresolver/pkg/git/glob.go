@@ -0,0 +1,146 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-git/go-billy/v5"
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// isGlobPattern returns true if path has any of the wildcards that
+// make it a pattern rather than the path of a single file or
+// directory.
+func isGlobPattern(path string) bool {
+	return strings.ContainsAny(path, "*?[")
+}
+
+// splitGlobPattern returns the segments of pattern, relative to the
+// root of the repo and with any "." segments removed.
+func splitGlobPattern(pattern string) []string {
+	return strings.Split(path.Clean(strings.TrimPrefix(pattern, "/")), "/")
+}
+
+// validateGlobPattern returns an error if pattern is malformed.
+func validateGlobPattern(pattern string) error {
+	for _, segment := range splitGlobPattern(pattern) {
+		if _, err := path.Match(segment, ""); err != nil {
+			return fmt.Errorf("invalid %s pattern %q: %w", PathParam, pattern, err)
+		}
+	}
+	return nil
+}
+
+// matchGlob returns true if the segments of the file path name match
+// the segments of pattern. A "**" segment matches any number of
+// directories, including none, and other segments match a single name
+// as path.Match does. Names starting with a "." are only matched by a
+// segment that starts with one too, so neither "*" nor "**" reaches
+// into hidden files and directories.
+func matchGlob(pattern, name []string) bool {
+	if len(pattern) == 0 {
+		return len(name) == 0
+	}
+	if pattern[0] == "**" {
+		if matchGlob(pattern[1:], name) {
+			return true
+		}
+		return len(name) > 0 && !strings.HasPrefix(name[0], ".") && matchGlob(pattern, name[1:])
+	}
+	if len(name) == 0 {
+		return false
+	}
+	if strings.HasPrefix(name[0], ".") && !strings.HasPrefix(pattern[0], ".") {
+		return false
+	}
+	if ok, _ := path.Match(pattern[0], name[0]); !ok {
+		return false
+	}
+	return matchGlob(pattern[1:], name[1:])
+}
+
+// readGlob returns the content of the files in the given commit whose
+// paths match pattern. A single match is returned as it is, while
+// several are concatenated in path order as a multi-document yaml
+// stream. A pattern that matches nothing fails like a missing file, and
+// one that matches more than maxFiles files is rejected rather than
+// truncated.
+func readGlob(repository *git.Repository, filesystem billy.Filesystem, conf map[string]string, commit, pattern string, maxFiles int) ([]byte, error) {
+	commitObj, err := repository.CommitObject(plumbing.NewHash(commit))
+	if err != nil {
+		return nil, fmt.Errorf("error reading commit %q: %v", commit, err)
+	}
+	tree, err := commitObj.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("error reading tree of commit %q: %v", commit, err)
+	}
+	segments := splitGlobPattern(pattern)
+	names := []string{}
+	err = tree.Files().ForEach(func(f *object.File) error {
+		if matchGlob(segments, strings.Split(f.Name, "/")) {
+			names = append(names, f.Name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing files matching %q: %v", pattern, err)
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("error opening file %q: no files match the pattern: %w", pattern, object.ErrFileNotFound)
+	}
+	if len(names) > maxFiles {
+		return nil, fmt.Errorf("error reading %q: pattern matches %d files, exceeds limit %d", pattern, len(names), maxFiles)
+	}
+	sort.Strings(names)
+
+	useWorktree, _ := strconv.ParseBool(conf[ConfigFieldUseWorktreeContent])
+	read := func(name string) ([]byte, error) {
+		if err := checkSymlinks(repository, commit, name); err != nil {
+			return nil, err
+		}
+		if useWorktree {
+			return readFile(filesystem, name)
+		}
+		return readBlob(repository, commit, name)
+	}
+	if len(names) == 1 {
+		return read(names[0])
+	}
+	buf := &bytes.Buffer{}
+	for i, name := range names {
+		content, err := read(name)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			buf.WriteString("---\n")
+		}
+		buf.Write(content)
+		if len(content) > 0 && !bytes.HasSuffix(content, []byte("\n")) {
+			buf.WriteString("\n")
+		}
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,143 @@
+package git
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestResolveGlob(t *testing.T) {
+	repoDir, _ := createTestRepo(t, []testCommit{{
+		files: map[string]string{
+			"tasks/b.yaml":                    "kind: Task\nmetadata:\n  name: b",
+			"tasks/a.yaml":                    "kind: Task\nmetadata:\n  name: a\n",
+			"tasks/.hidden.yaml":              "kind: Task\nmetadata:\n  name: hidden\n",
+			"tasks/README.md":                 "not yaml",
+			"pipelines/build.yaml":            "kind: Pipeline\nmetadata:\n  name: root\n",
+			"pipelines/go/build.yaml":         "kind: Pipeline\nmetadata:\n  name: go\n",
+			"pipelines/go/test.yaml":          "kind: Pipeline\nmetadata:\n  name: test\n",
+			"pipelines/java/maven/build.yaml": "kind: Pipeline\nmetadata:\n  name: maven\n",
+			"pipelines/.draft/build.yaml":     "kind: Pipeline\nmetadata:\n  name: draft\n",
+		},
+	}})
+
+	for _, tc := range []struct {
+		name     string
+		path     string
+		expected string
+	}{{
+		name:     "single match",
+		path:     "tasks/b*.yaml",
+		expected: "kind: Task\nmetadata:\n  name: b",
+	}, {
+		name:     "multiple matches",
+		path:     "tasks/*.yaml",
+		expected: "kind: Task\nmetadata:\n  name: a\n---\nkind: Task\nmetadata:\n  name: b\n",
+	}, {
+		name:     "hidden file",
+		path:     "tasks/.*.yaml",
+		expected: "kind: Task\nmetadata:\n  name: hidden\n",
+	}, {
+		name:     "any depth",
+		path:     "pipelines/**/build.yaml",
+		expected: "kind: Pipeline\nmetadata:\n  name: root\n---\nkind: Pipeline\nmetadata:\n  name: go\n---\nkind: Pipeline\nmetadata:\n  name: maven\n",
+	}, {
+		name:     "relative to the root",
+		path:     "/./pipelines/go/*.yaml",
+		expected: "kind: Pipeline\nmetadata:\n  name: go\n---\nkind: Pipeline\nmetadata:\n  name: test\n",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			params := map[string]string{
+				URLParam:  repoDir,
+				PathParam: tc.path,
+			}
+			resolver := Resolver{}
+			if err := resolver.ValidateParams(context.Background(), params); err != nil {
+				t.Fatalf("unexpected error validating params: %v", err)
+			}
+			resource, err := resolver.Resolve(context.Background(), params)
+			if err != nil {
+				t.Fatalf("unexpected error resolving: %v", err)
+			}
+			if got := string(resource.Data()); got != tc.expected {
+				t.Errorf("expected content %q received %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestResolveGlobNoMatch(t *testing.T) {
+	repoDir, _ := createTestRepo(t, []testCommit{{
+		files: map[string]string{"tasks/a.yaml": "kind: Task\n"},
+	}})
+
+	resolver := Resolver{}
+	_, err := resolver.Resolve(context.Background(), map[string]string{
+		URLParam:  repoDir,
+		PathParam: "pipelines/*.yaml",
+	})
+	if !isFileMissing(err) || !strings.Contains(err.Error(), `"pipelines/*.yaml": no files match the pattern`) {
+		t.Errorf("expected file not found error for the pattern, received %v", err)
+	}
+}
+
+func TestMatchGlob(t *testing.T) {
+	for _, tc := range []struct {
+		pattern string
+		name    string
+		match   bool
+	}{
+		{pattern: "*.yaml", name: "task.yaml", match: true},
+		{pattern: "*.yaml", name: "tasks/task.yaml"},
+		{pattern: "*", name: ".tekton"},
+		{pattern: "**", name: "a/b/task.yaml", match: true},
+		{pattern: "**", name: ".tekton/task.yaml"},
+		{pattern: "**/task.yaml", name: "task.yaml", match: true},
+		{pattern: "**/task.yaml", name: "a/.b/task.yaml"},
+		{pattern: ".tekton/**/*.yaml", name: ".tekton/a/task.yaml", match: true},
+		{pattern: "a/**/b/*.yaml", name: "a/x/y/b/task.yaml", match: true},
+		{pattern: "a/**/b/*.yaml", name: "a/b/task.yaml", match: true},
+		{pattern: "a/**/b/*.yaml", name: "a/task.yaml"},
+		{pattern: "task-[0-9].yaml", name: "task-1.yaml", match: true},
+		{pattern: "task-?.yaml", name: "task-10.yaml"},
+	} {
+		if match := matchGlob(splitGlobPattern(tc.pattern), strings.Split(tc.name, "/")); match != tc.match {
+			t.Errorf("expected %q matching %q to be %t", tc.pattern, tc.name, tc.match)
+		}
+	}
+}
+
+func TestValidateParamsGlob(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		params      map[string]string
+		expectedErr string
+	}{{
+		name:        "malformed pattern",
+		params:      map[string]string{PathParam: "tasks/[a.yaml"},
+		expectedErr: `invalid path pattern "tasks/[a.yaml"`,
+	}, {
+		name:        "statOnly",
+		params:      map[string]string{PathParam: "tasks/*.yaml", StatOnlyParam: "true"},
+		expectedErr: `"statOnly" can't be used with a path pattern`,
+	}, {
+		name:        "tar",
+		params:      map[string]string{PathParam: "tasks/*.yaml", FormatParam: FormatTar},
+		expectedErr: `"format" "tar" can't be used with a path pattern`,
+	}, {
+		name:        "locator",
+		params:      map[string]string{LocatorParam: "github.com/tektoncd/catalog@main//tasks/*.yaml", BlameParam: "true"},
+		expectedErr: `"blame" can't be used with a path pattern`,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.params[LocatorParam] == "" {
+				tc.params[URLParam] = "https://github.com/tektoncd/catalog"
+			}
+			resolver := Resolver{}
+			err := resolver.ValidateParams(context.Background(), tc.params)
+			if err == nil || !strings.Contains(err.Error(), tc.expectedErr) {
+				t.Errorf("expected error containing %q, received %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
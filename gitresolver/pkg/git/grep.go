@@ -0,0 +1,129 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// GrepResult is the json document returned in place of a directory's
+// content when its files are searched with a pattern.
+type GrepResult struct {
+	Path    string      `json:"path"`
+	Commit  string      `json:"commit"`
+	Pattern string      `json:"pattern"`
+	Matches []GrepMatch `json:"matches"`
+}
+
+// GrepMatch is a file whose content matched a grep pattern.
+type GrepMatch struct {
+	File  string `json:"file"`
+	Lines []int  `json:"lines,omitempty"`
+}
+
+// grepDirectory searches the files beneath the directory at path as of
+// the given commit for pattern and returns the ones that match, in path
+// order, as a json-encoded resource. Binary files are skipped. As with
+// directory resolution, directories with more than maxFiles files are
+// rejected, and so are files larger than maxSize unless it's zero.
+func grepDirectory(repository *git.Repository, commit, path, pattern string, lineNumbers bool, maxFiles int, maxSize int64) (*ResolvedGitResource, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value for %q: %v", GrepParam, err)
+	}
+	commitObj, err := repository.CommitObject(plumbing.NewHash(commit))
+	if err != nil {
+		return nil, fmt.Errorf("error reading commit %q: %w", commit, err)
+	}
+	tree, err := commitObj.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("error reading tree of commit %q: %v", commit, err)
+	}
+	if dir := strings.Trim(path, "/"); dir != "" {
+		entry, err := tree.FindEntry(dir)
+		if err != nil || entry.Mode != filemode.Dir {
+			return nil, fmt.Errorf("%q requires %q to be a directory", GrepParam, path)
+		}
+		tree, err = tree.Tree(dir)
+		if err != nil {
+			return nil, fmt.Errorf("error reading directory %q: %v", path, err)
+		}
+	}
+
+	files := []*object.File{}
+	err = tree.Files().ForEach(func(f *object.File) error {
+		files = append(files, f)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing directory %q: %v", path, err)
+	}
+	if err := checkFileCount(path, len(files), maxFiles); err != nil {
+		return nil, err
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+
+	result := GrepResult{
+		Path:    path,
+		Commit:  commit,
+		Pattern: pattern,
+		Matches: []GrepMatch{},
+	}
+	for _, f := range files {
+		if binary, err := f.IsBinary(); err != nil || binary {
+			continue
+		}
+		if maxSize > 0 && f.Size > maxSize {
+			return nil, fmt.Errorf("file %q exceeds max size %d bytes", f.Name, maxSize)
+		}
+		content, err := f.Contents()
+		if err != nil {
+			return nil, fmt.Errorf("error reading file %q: %v", f.Name, err)
+		}
+		if !re.MatchString(content) {
+			continue
+		}
+		match := GrepMatch{File: f.Name}
+		if lineNumbers {
+			for i, line := range strings.Split(content, "\n") {
+				if re.MatchString(line) {
+					match.Lines = append(match.Lines, i+1)
+				}
+			}
+		}
+		result.Matches = append(result.Matches, match)
+	}
+
+	content, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("error serializing grep results for %q: %w", path, err)
+	}
+	return &ResolvedGitResource{
+		Commit:      commit,
+		Content:     content,
+		ContentType: JSONContentType,
+	}, nil
+}
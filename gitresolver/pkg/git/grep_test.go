@@ -0,0 +1,125 @@
+package git
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	resolutioncommon "github.com/tektoncd/resolution/pkg/common"
+	"github.com/tektoncd/resolution/pkg/resolver/framework"
+)
+
+func TestResolveGrep(t *testing.T) {
+	repoDir, commits := createTestRepo(t, []testCommit{{
+		files: map[string]string{
+			"tasks/build.yaml":       "kind: Task\nsteps:\n- image: golang\n- image: golang:1.18\n",
+			"tasks/lint.yaml":        "kind: Task\nsteps:\n- image: alpine\n",
+			"tasks/nested/test.yaml": "kind: Task\n# uses golang too\n",
+			"pipeline/pipeline.yaml": "kind: Pipeline\n# golang\n",
+			"tasks/binary/blob.bin":  "golang\x00\x01",
+		},
+	}})
+
+	for _, tc := range []struct {
+		name        string
+		lineNumbers string
+		expected    []GrepMatch
+	}{{
+		name: "files",
+		expected: []GrepMatch{
+			{File: "build.yaml"},
+			{File: "nested/test.yaml"},
+		},
+	}, {
+		name:        "line numbers",
+		lineNumbers: "true",
+		expected: []GrepMatch{
+			{File: "build.yaml", Lines: []int{3, 4}},
+			{File: "nested/test.yaml", Lines: []int{2}},
+		},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			resolver := Resolver{}
+			resource, err := resolver.Resolve(context.Background(), map[string]string{
+				URLParam:             repoDir,
+				PathParam:            "tasks",
+				GrepParam:            "golang",
+				GrepLineNumbersParam: tc.lineNumbers,
+			})
+			if err != nil {
+				t.Fatalf("unexpected error resolving grep: %v", err)
+			}
+			if ct := resource.Annotations()[resolutioncommon.AnnotationKeyContentType]; ct != JSONContentType {
+				t.Errorf("expected content-type %q received %q", JSONContentType, ct)
+			}
+			result := GrepResult{}
+			if err := json.Unmarshal(resource.Data(), &result); err != nil {
+				t.Fatalf("error parsing grep result: %v", err)
+			}
+			if result.Commit != commits[0] || result.Pattern != "golang" {
+				t.Errorf("unexpected grep result header %+v", result)
+			}
+			if !reflect.DeepEqual(result.Matches, tc.expected) {
+				t.Errorf("expected matches %+v received %+v", tc.expected, result.Matches)
+			}
+		})
+	}
+}
+
+func TestResolveGrepLimits(t *testing.T) {
+	repoDir, _ := createTestRepo(t, []testCommit{{
+		files: map[string]string{
+			"tasks/a.yaml": "kind: Task\n",
+			"tasks/b.yaml": "kind: Task\nmetadata:\n  name: a-longer-task\n",
+		},
+	}})
+	params := map[string]string{
+		URLParam:  repoDir,
+		PathParam: "tasks",
+		GrepParam: "Task",
+	}
+
+	for _, tc := range []struct {
+		conf    map[string]string
+		message string
+	}{
+		{conf: map[string]string{ConfigFieldMaxFiles: "1"}, message: "directory contains 2 files, exceeds limit 1"},
+		{conf: map[string]string{ConfigFieldMaxSize: "20"}, message: "exceeds max size 20 bytes"},
+	} {
+		ctx := framework.InjectResolverConfigToContext(context.Background(), tc.conf)
+		resolver := Resolver{}
+		_, err := resolver.Resolve(ctx, params)
+		if err == nil || !strings.Contains(err.Error(), tc.message) {
+			t.Errorf("expected error containing %q with config %v, got %v", tc.message, tc.conf, err)
+		}
+	}
+}
+
+func TestResolveGrepRequiresDirectory(t *testing.T) {
+	repoDir, _ := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "kind: Task\n"},
+	}})
+	resolver := Resolver{}
+	if _, err := resolver.Resolve(context.Background(), map[string]string{
+		URLParam:  repoDir,
+		PathParam: "task.yaml",
+		GrepParam: "Task",
+	}); err == nil {
+		t.Fatalf("expected error grepping a file")
+	}
+}
+
+func TestValidateParamsGrep(t *testing.T) {
+	resolver := Resolver{}
+	for _, params := range []map[string]string{
+		{URLParam: "foo", PathParam: "bar", GrepParam: "("},
+		{URLParam: "foo", PathParam: "bar", GrepParam: "Task", BlameParam: "true"},
+		{URLParam: "foo", PathParam: "bar", GrepParam: "Task", GrepLineNumbersParam: "sometimes"},
+	} {
+		if err := resolver.ValidateParams(context.Background(), params); err == nil {
+			t.Errorf("expected error validating %v", params)
+		}
+	}
+}
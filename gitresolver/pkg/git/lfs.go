@@ -0,0 +1,268 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	git "github.com/go-git/go-git/v5"
+	formatcfg "github.com/go-git/go-git/v5/plumbing/format/config"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	resolutioncommon "github.com/tektoncd/resolution/pkg/common"
+)
+
+// lfsPointerVersion starts the first line of every git lfs pointer
+// file.
+const lfsPointerVersion = "version https://git-lfs"
+
+// maxLFSPointerSize is the largest that git lfs pointer files are
+// allowed to be.
+const maxLFSPointerSize = 1024
+
+// lfsMediaType is the media type of git lfs batch api requests and
+// responses.
+const lfsMediaType = "application/vnd.git-lfs+json"
+
+// lfsPointer is the object that a git lfs pointer file stands in for.
+type lfsPointer struct {
+	oid  string
+	size int64
+}
+
+// parseLFSPointer returns the object that content points to if content
+// is a git lfs pointer file: a version line, then sorted key value
+// lines including a sha256 oid and a size.
+func parseLFSPointer(content []byte) (lfsPointer, bool) {
+	if len(content) > maxLFSPointerSize || !bytes.HasPrefix(content, []byte(lfsPointerVersion)) {
+		return lfsPointer{}, false
+	}
+	pointer := lfsPointer{size: -1}
+	lines := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	for _, line := range lines[1:] {
+		key, value, ok := strings.Cut(line, " ")
+		if !ok {
+			return lfsPointer{}, false
+		}
+		switch key {
+		case "oid":
+			hash := strings.TrimPrefix(value, "sha256:")
+			if _, err := hex.DecodeString(hash); hash == value || err != nil || len(hash) != 2*sha256.Size {
+				return lfsPointer{}, false
+			}
+			pointer.oid = hash
+		case "size":
+			size, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || size < 0 {
+				return lfsPointer{}, false
+			}
+			pointer.size = size
+		}
+	}
+	if pointer.oid == "" || pointer.size < 0 {
+		return lfsPointer{}, false
+	}
+	return pointer, true
+}
+
+// fetchLFS returns true unless params opt out of replacing git lfs
+// pointer files with the objects they point to.
+func fetchLFS(params map[string]string) bool {
+	v, ok := params[LFSParam]
+	if !ok {
+		return true
+	}
+	fetch, _ := strconv.ParseBool(v)
+	return fetch
+}
+
+// lfsEndpoint returns the url of the git lfs api that serves the
+// objects of the repo at repoURL, read at commit from repository: the
+// lfs.url in its .lfsconfig if it has one, or else the info/lfs path
+// beneath the repo's url. Repos cloned over ssh are assumed to serve
+// the api on the same host over https.
+func lfsEndpoint(repository *git.Repository, commit, repoURL string) (string, error) {
+	if lfsConfig, err := readBlob(repository, commit, ".lfsconfig"); err == nil {
+		cfg := formatcfg.New()
+		if err := formatcfg.NewDecoder(bytes.NewReader(lfsConfig)).Decode(cfg); err != nil {
+			return "", fmt.Errorf("error parsing .lfsconfig: %w", err)
+		}
+		if endpoint := cfg.Section("lfs").Option("url"); endpoint != "" {
+			return endpoint, nil
+		}
+	} else if !isFileMissing(err) {
+		return "", err
+	}
+
+	ep, err := transport.NewEndpoint(repoURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid repo url %q: %w", repoURL, err)
+	}
+	u := url.URL{Scheme: ep.Protocol, Host: ep.Host, Path: strings.TrimSuffix(ep.Path, "/")}
+	switch ep.Protocol {
+	case "http", "https":
+		if ep.Port != 0 {
+			u.Host += ":" + strconv.Itoa(ep.Port)
+		}
+	case "ssh":
+		u.Scheme = "https"
+	default:
+		return "", fmt.Errorf("git lfs objects can't be fetched for %s repo %q", ep.Protocol, sanitizeURL(repoURL))
+	}
+	if !strings.HasPrefix(u.Path, "/") {
+		u.Path = "/" + u.Path
+	}
+	if !strings.HasSuffix(u.Path, ".git") {
+		u.Path += ".git"
+	}
+	u.Path += "/info/lfs"
+	return u.String(), nil
+}
+
+// lfsBatchResponse is the reply of a git lfs batch api request.
+type lfsBatchResponse struct {
+	Objects []struct {
+		OID     string `json:"oid"`
+		Actions struct {
+			Download *struct {
+				Href   string            `json:"href"`
+				Header map[string]string `json:"header"`
+			} `json:"download"`
+		} `json:"actions"`
+		Error *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	} `json:"objects"`
+}
+
+// fetchLFSObject downloads the object that pointer points to from the
+// git lfs api at endpoint, using the basic transfer adapter. The batch
+// request is authenticated with auth, if it's set, and the download
+// with whatever headers the api returns for it. The object is checked
+// against the pointer's oid and size.
+func fetchLFSObject(ctx context.Context, endpoint string, auth transport.AuthMethod, pointer lfsPointer) ([]byte, error) {
+	batch, err := json.Marshal(map[string]interface{}{
+		"operation": "download",
+		"transfers": []string{"basic"},
+		"objects":   []map[string]interface{}{{"oid": pointer.oid, "size": pointer.size}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(endpoint, "/")+"/objects/batch", bytes.NewReader(batch))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", lfsMediaType)
+	req.Header.Set("Content-Type", lfsMediaType)
+	if auth, ok := auth.(githttp.AuthMethod); ok {
+		auth.SetAuth(req)
+	}
+	body, err := doLFSRequest(req, 1<<20)
+	if err != nil {
+		return nil, err
+	}
+	batchResp := lfsBatchResponse{}
+	if err := json.Unmarshal(body, &batchResp); err != nil {
+		return nil, fmt.Errorf("error parsing response from %s: %w", req.URL.Redacted(), err)
+	}
+	if len(batchResp.Objects) != 1 || batchResp.Objects[0].OID != pointer.oid {
+		return nil, fmt.Errorf("git lfs object %s is missing from the response from %s", pointer.oid, req.URL.Redacted())
+	}
+	object := batchResp.Objects[0]
+	if object.Error != nil {
+		return nil, fmt.Errorf("error fetching git lfs object %s: %d %s", pointer.oid, object.Error.Code, object.Error.Message)
+	}
+	if object.Actions.Download == nil {
+		return nil, fmt.Errorf("git lfs object %s has no download action", pointer.oid)
+	}
+
+	req, err = http.NewRequestWithContext(ctx, http.MethodGet, object.Actions.Download.Href, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid download url for git lfs object %s: %w", pointer.oid, err)
+	}
+	for key, value := range object.Actions.Download.Header {
+		req.Header.Set(key, value)
+	}
+	content, err := doLFSRequest(req, pointer.size+1)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(content)
+	if int64(len(content)) != pointer.size || hex.EncodeToString(sum[:]) != pointer.oid {
+		return nil, fmt.Errorf("git lfs object %s downloaded from %s doesn't match its pointer", pointer.oid, req.URL.Redacted())
+	}
+	return content, nil
+}
+
+// doLFSRequest sends req and returns up to limit bytes of its body,
+// failing unless the response is successful.
+func doLFSRequest(req *http.Request, limit int64) ([]byte, error) {
+	resp, err := apiClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status from %s: %s", req.URL.Redacted(), resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit))
+	if err != nil {
+		return nil, fmt.Errorf("error reading response from %s: %w", req.URL.Redacted(), err)
+	}
+	return body, nil
+}
+
+// resolveLFSPointer returns content, read from target, or the object it
+// points to if it's a git lfs pointer file and params don't opt out.
+// The object is looked up with the lfs endpoint of target's repo, which
+// is only sent auth if it's on the same host as the repo at repoURL
+// that auth is for, and must fit in maxSize.
+func resolveLFSPointer(ctx context.Context, params map[string]string, target submodulePath, repoURL string, auth transport.AuthMethod, maxSize int64, content []byte) ([]byte, error) {
+	pointer, ok := parseLFSPointer(content)
+	if !ok || !fetchLFS(params) {
+		return content, nil
+	}
+	if maxSize > 0 && pointer.size > maxSize {
+		return nil, resolutioncommon.NewError(resolutioncommon.ReasonResourceTooLarge, fmt.Errorf("git lfs object %s of file %q is %d bytes, exceeds max size %d bytes", pointer.oid, target.path, pointer.size, maxSize))
+	}
+	endpoint, err := lfsEndpoint(target.repository, target.commit, target.url)
+	if err != nil {
+		return nil, err
+	}
+	if repoHost(endpoint) != repoHost(repoURL) {
+		auth = nil
+	}
+	_, span := startSpan(ctx, "git.lfs", attrURL.String(sanitizeURL(endpoint)), attrPath.String(target.path))
+	content, err = fetchLFSObject(ctx, endpoint, auth, pointer)
+	endSpan(span, err)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching git lfs object of file %q: %w", target.path, err)
+	}
+	return content, nil
+}
@@ -0,0 +1,234 @@
+package git
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	git "github.com/go-git/go-git/v5"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	resolutioncommon "github.com/tektoncd/resolution/pkg/common"
+	"github.com/tektoncd/resolution/pkg/resolver/framework"
+)
+
+// stubLFSServer serves the given objects, keyed by content, through the
+// git lfs batch api, recording the Authorization of batch requests.
+type stubLFSServer struct {
+	*httptest.Server
+	objects       map[string][]byte
+	authorization string
+}
+
+func newStubLFSServer(t *testing.T, contents ...string) *stubLFSServer {
+	t.Helper()
+	s := &stubLFSServer{objects: map[string][]byte{}}
+	for _, content := range contents {
+		s.objects[lfsOID(content)] = []byte(content)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/info/lfs/objects/batch", func(w http.ResponseWriter, r *http.Request) {
+		s.authorization = r.Header.Get("Authorization")
+		if r.Method != http.MethodPost || r.Header.Get("Accept") != lfsMediaType {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		req := struct {
+			Objects []struct {
+				OID string `json:"oid"`
+			} `json:"objects"`
+		}{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		objects := []interface{}{}
+		for _, o := range req.Objects {
+			if _, ok := s.objects[o.OID]; !ok {
+				objects = append(objects, map[string]interface{}{"oid": o.OID, "error": map[string]interface{}{"code": 404, "message": "Object does not exist"}})
+				continue
+			}
+			objects = append(objects, map[string]interface{}{"oid": o.OID, "actions": map[string]interface{}{"download": map[string]interface{}{
+				"href":   s.URL + "/objects/" + o.OID,
+				"header": map[string]string{"X-Download-Token": "download"},
+			}}})
+		}
+		w.Header().Set("Content-Type", lfsMediaType)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"objects": objects})
+	})
+	mux.HandleFunc("/objects/", func(w http.ResponseWriter, r *http.Request) {
+		content, ok := s.objects[strings.TrimPrefix(r.URL.Path, "/objects/")]
+		if !ok || r.Header.Get("X-Download-Token") != "download" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(content)
+	})
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+func lfsOID(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func lfsPointerFile(content string) string {
+	return fmt.Sprintf("version https://git-lfs.github.com/spec/v1\noid sha256:%s\nsize %d\n", lfsOID(content), len(content))
+}
+
+func TestParseLFSPointer(t *testing.T) {
+	oid := lfsOID("content")
+	for _, tc := range []struct {
+		name     string
+		content  string
+		expected bool
+	}{
+		{name: "pointer", content: lfsPointerFile("content"), expected: true},
+		{name: "extension keys", content: "version https://git-lfs.github.com/spec/v1\next-0-foo sha256:" + oid + "\noid sha256:" + oid + "\nsize 7\n", expected: true},
+		{name: "yaml", content: "apiVersion: tekton.dev/v1beta1\nkind: Task\n"},
+		{name: "no oid", content: "version https://git-lfs.github.com/spec/v1\nsize 7\n"},
+		{name: "no size", content: "version https://git-lfs.github.com/spec/v1\noid sha256:" + oid + "\n"},
+		{name: "bad oid", content: "version https://git-lfs.github.com/spec/v1\noid sha256:abc\nsize 7\n"},
+		{name: "other hash", content: "version https://git-lfs.github.com/spec/v1\noid sha1:" + oid[:40] + "\nsize 7\n"},
+		{name: "too large", content: lfsPointerFile("content") + strings.Repeat("x", maxLFSPointerSize)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			pointer, ok := parseLFSPointer([]byte(tc.content))
+			if ok != tc.expected {
+				t.Fatalf("expected pointer %t, received %t", tc.expected, ok)
+			}
+			if ok && (pointer.oid != oid || pointer.size != 7) {
+				t.Errorf("expected oid %s and size 7, received %+v", oid, pointer)
+			}
+		})
+	}
+}
+
+func TestResolveLFSPointer(t *testing.T) {
+	const content = "apiVersion: tekton.dev/v1beta1\nkind: Task\n"
+	server := newStubLFSServer(t, content)
+	repoDir, _ := createTestRepo(t, []testCommit{{
+		files: map[string]string{
+			".lfsconfig":   fmt.Sprintf("[lfs]\n\turl = %s/info/lfs\n", server.URL),
+			"task.yaml":    lfsPointerFile(content),
+			"missing.yaml": lfsPointerFile("missing"),
+			"plain.yaml":   "plain",
+		},
+	}})
+
+	for _, tc := range []struct {
+		name     string
+		conf     map[string]string
+		params   map[string]string
+		expected string
+		err      string
+	}{{
+		name:     "pointer",
+		params:   map[string]string{PathParam: "task.yaml"},
+		expected: content,
+	}, {
+		name:     "opt out",
+		params:   map[string]string{PathParam: "task.yaml", LFSParam: "false"},
+		expected: lfsPointerFile(content),
+	}, {
+		name:     "not a pointer",
+		params:   map[string]string{PathParam: "plain.yaml"},
+		expected: "plain",
+	}, {
+		name:   "missing object",
+		params: map[string]string{PathParam: "missing.yaml"},
+		err:    "404 Object does not exist",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			params := map[string]string{URLParam: repoDir}
+			for k, v := range tc.params {
+				params[k] = v
+			}
+			resolver := Resolver{}
+			resource, err := resolver.Resolve(framework.InjectResolverConfigToContext(context.Background(), tc.conf), params)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("expected error containing %q, received %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error resolving file: %v", err)
+			}
+			if string(resource.Data()) != tc.expected {
+				t.Errorf("expected content %q, received %q", tc.expected, string(resource.Data()))
+			}
+		})
+	}
+}
+
+func TestResolveLFSPointerTooLarge(t *testing.T) {
+	// The pointer fits in the max size but the object doesn't, so it
+	// isn't fetched.
+	repoDir, _ := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": lfsPointerFile(strings.Repeat("x", 1000))},
+	}})
+	resolver := Resolver{}
+	_, err := resolver.Resolve(framework.InjectResolverConfigToContext(context.Background(), map[string]string{ConfigFieldMaxSize: "500"}), map[string]string{URLParam: repoDir, PathParam: "task.yaml"})
+	if err == nil || !strings.Contains(err.Error(), "is 1000 bytes, exceeds max size 500 bytes") {
+		t.Fatalf("expected the object to be too large, received %v", err)
+	}
+	if reason, _ := resolutioncommon.ReasonError(err); reason != resolutioncommon.ReasonResourceTooLarge {
+		t.Errorf("expected reason %q, received %q from %v", resolutioncommon.ReasonResourceTooLarge, reason, err)
+	}
+}
+
+func TestFetchLFSObject(t *testing.T) {
+	server := newStubLFSServer(t, "content")
+	pointer, _ := parseLFSPointer([]byte(lfsPointerFile("content")))
+
+	content, err := fetchLFSObject(context.Background(), server.URL+"/info/lfs", &githttp.BasicAuth{Username: "git", Password: "token"}, pointer)
+	if err != nil {
+		t.Fatalf("unexpected error fetching object: %v", err)
+	}
+	if string(content) != "content" {
+		t.Errorf("expected the object's content, received %q", string(content))
+	}
+	if server.authorization == "" {
+		t.Errorf("expected the batch request to be authenticated")
+	}
+
+	// An object that doesn't match its pointer is rejected.
+	server.objects[pointer.oid] = []byte("altered")
+	if _, err := fetchLFSObject(context.Background(), server.URL+"/info/lfs", nil, pointer); err == nil || !strings.Contains(err.Error(), "doesn't match its pointer") {
+		t.Errorf("expected a mismatched object to be rejected, received %v", err)
+	}
+}
+
+func TestLFSEndpoint(t *testing.T) {
+	repoDir, commits := createTestRepo(t, []testCommit{{files: map[string]string{"task.yaml": "task"}}})
+	repository, err := git.PlainOpen(repoDir)
+	if err != nil {
+		t.Fatalf("error opening repo: %v", err)
+	}
+	for _, tc := range []struct {
+		url      string
+		expected string
+	}{
+		{url: "https://github.com/tektoncd/catalog", expected: "https://github.com/tektoncd/catalog.git/info/lfs"},
+		{url: "https://github.com/tektoncd/catalog.git", expected: "https://github.com/tektoncd/catalog.git/info/lfs"},
+		{url: "http://git.example.com:8080/team/repo/", expected: "http://git.example.com:8080/team/repo.git/info/lfs"},
+		{url: "git@github.com:tektoncd/catalog.git", expected: "https://github.com/tektoncd/catalog.git/info/lfs"},
+		{url: "ssh://git@github.com:2222/tektoncd/catalog", expected: "https://github.com/tektoncd/catalog.git/info/lfs"},
+	} {
+		endpoint, err := lfsEndpoint(repository, commits[0], tc.url)
+		if err != nil {
+			t.Fatalf("unexpected error for %s: %v", tc.url, err)
+		}
+		if endpoint != tc.expected {
+			t.Errorf("expected endpoint %q for %s, received %q", tc.expected, tc.url, endpoint)
+		}
+	}
+}
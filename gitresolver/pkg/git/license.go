@@ -0,0 +1,124 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// licenseFileNames are the names, without extension and compared case
+// insensitively, of files at the root of a repo that hold its license.
+var licenseFileNames = []string{"license", "licence", "copying"}
+
+// spdxIdentifierPattern matches an explicit SPDX-License-Identifier tag.
+var spdxIdentifierPattern = regexp.MustCompile(`SPDX-License-Identifier:\s*([A-Za-z0-9.+-]+)`)
+
+// licenseSignature maps phrases that all appear in a license's text to
+// its SPDX identifier. More specific signatures are listed first.
+type licenseSignature struct {
+	id      string
+	phrases []string
+}
+
+var licenseSignatures = []licenseSignature{
+	{id: "Apache-2.0", phrases: []string{"apache license", "version 2.0"}},
+	{id: "MPL-2.0", phrases: []string{"mozilla public license", "2.0"}},
+	{id: "AGPL-3.0", phrases: []string{"gnu affero general public license", "version 3"}},
+	{id: "LGPL-3.0", phrases: []string{"gnu lesser general public license", "version 3"}},
+	{id: "LGPL-2.1", phrases: []string{"gnu lesser general public license", "version 2.1"}},
+	{id: "GPL-3.0", phrases: []string{"gnu general public license", "version 3"}},
+	{id: "GPL-2.0", phrases: []string{"gnu general public license", "version 2"}},
+	{id: "MIT", phrases: []string{"permission is hereby granted, free of charge"}},
+	{id: "ISC", phrases: []string{"permission to use, copy, modify, and/or distribute this software for any purpose"}},
+	{id: "BSD-3-Clause", phrases: []string{"redistribution and use in source and binary forms", "neither the name"}},
+	{id: "BSD-2-Clause", phrases: []string{"redistribution and use in source and binary forms"}},
+	{id: "Unlicense", phrases: []string{"this is free and unencumbered software released into the public domain"}},
+}
+
+// detectLicense looks for a license file at the root of the repository
+// as of the given commit and returns a best-effort SPDX identifier for
+// it. An empty string is returned if no license file is found or its
+// text isn't recognized.
+func detectLicense(repository *git.Repository, commit string) (string, error) {
+	commitObj, err := repository.CommitObject(plumbing.NewHash(commit))
+	if err != nil {
+		return "", fmt.Errorf("error reading commit %q: %w", commit, err)
+	}
+	tree, err := commitObj.Tree()
+	if err != nil {
+		return "", fmt.Errorf("error reading tree of commit %q: %w", commit, err)
+	}
+	for _, entry := range tree.Entries {
+		if !entry.Mode.IsFile() || !isLicenseFileName(entry.Name) {
+			continue
+		}
+		file, err := tree.File(entry.Name)
+		if err != nil {
+			return "", fmt.Errorf("error opening license file %q: %w", entry.Name, err)
+		}
+		text, err := file.Contents()
+		if err != nil {
+			return "", fmt.Errorf("error reading license file %q: %w", entry.Name, err)
+		}
+		if id := identifyLicense(text); id != "" {
+			return id, nil
+		}
+	}
+	return "", nil
+}
+
+// isLicenseFileName returns true for names like LICENSE, LICENSE.md or
+// COPYING.txt.
+func isLicenseFileName(name string) bool {
+	base := strings.ToLower(name)
+	if dot := strings.Index(base, "."); dot >= 0 {
+		base = base[:dot]
+	}
+	for _, n := range licenseFileNames {
+		if base == n {
+			return true
+		}
+	}
+	return false
+}
+
+// identifyLicense returns the SPDX identifier of the license text, or
+// an empty string if it isn't recognized.
+func identifyLicense(text string) string {
+	if match := spdxIdentifierPattern.FindStringSubmatch(text); match != nil {
+		return match[1]
+	}
+	normalized := strings.ToLower(strings.Join(strings.Fields(text), " "))
+	for _, sig := range licenseSignatures {
+		matched := true
+		for _, phrase := range sig.phrases {
+			if !strings.Contains(normalized, phrase) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return sig.id
+		}
+	}
+	return ""
+}
@@ -0,0 +1,74 @@
+package git
+
+import (
+	"context"
+	"testing"
+)
+
+const mitLicense = `MIT License
+
+Copyright (c) 2022 Example
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction.
+`
+
+func TestResolveIncludeLicense(t *testing.T) {
+	repoDir, _ := createTestRepo(t, []testCommit{{
+		files: map[string]string{
+			"LICENSE":   mitLicense,
+			"task.yaml": "kind: Task",
+		},
+	}})
+	resolver := Resolver{}
+	resource, err := resolver.Resolve(context.Background(), map[string]string{
+		URLParam:            repoDir,
+		PathParam:           "task.yaml",
+		IncludeLicenseParam: "true",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error resolving: %v", err)
+	}
+	if license := resource.Annotations()[AnnotationKeyLicense]; license != "MIT" {
+		t.Errorf("expected license %q received %q", "MIT", license)
+	}
+	if string(resource.Data()) != "kind: Task" {
+		t.Errorf("expected requested file content, received %q", string(resource.Data()))
+	}
+}
+
+func TestResolveIncludeLicenseMissing(t *testing.T) {
+	repoDir, _ := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "kind: Task"},
+	}})
+	resolver := Resolver{}
+	resource, err := resolver.Resolve(context.Background(), map[string]string{
+		URLParam:            repoDir,
+		PathParam:           "task.yaml",
+		IncludeLicenseParam: "true",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error resolving: %v", err)
+	}
+	if _, has := resource.Annotations()[AnnotationKeyLicense]; has {
+		t.Errorf("expected no license annotation for a repo without a license")
+	}
+}
+
+func TestIdentifyLicense(t *testing.T) {
+	for _, tc := range []struct {
+		text     string
+		expected string
+	}{
+		{text: mitLicense, expected: "MIT"},
+		{text: "Apache License\n  Version 2.0, January 2004", expected: "Apache-2.0"},
+		{text: "GNU GENERAL PUBLIC LICENSE\nVersion 3, 29 June 2007", expected: "GPL-3.0"},
+		{text: "// SPDX-License-Identifier: BSD-3-Clause", expected: "BSD-3-Clause"},
+		{text: "All rights reserved.", expected: ""},
+	} {
+		if id := identifyLicense(tc.text); id != tc.expected {
+			t.Errorf("expected %q received %q for %q", tc.expected, id, tc.text)
+		}
+	}
+}
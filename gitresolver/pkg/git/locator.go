@@ -0,0 +1,92 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// locator is a combined address of a repo, an optional ref and a path,
+// written as <repo>[@<ref>]//<path>. For example:
+// github.com/tektoncd/catalog@v1.2.3//task/git-clone/0.9/git-clone.yaml
+type locator struct {
+	URL  string
+	Ref  string
+	Path string
+}
+
+// parseLocator splits a locator string into its repo url, ref and path.
+// Repos given without a scheme are assumed to be https unless they use
+// scp-like ssh syntax such as git@github.com:org/repo.
+func parseLocator(s string) (*locator, error) {
+	scheme, rest := "", s
+	if i := strings.Index(s, "://"); i >= 0 {
+		scheme, rest = s[:i+len("://")], s[i+len("://"):]
+	}
+
+	sep := strings.Index(rest, "//")
+	if sep < 0 {
+		return nil, fmt.Errorf("invalid %s %q: expected <repo>[@<ref>]//<path>", LocatorParam, s)
+	}
+	repo, path := rest[:sep], rest[sep+len("//"):]
+	if path == "" {
+		return nil, fmt.Errorf("invalid %s %q: missing path", LocatorParam, s)
+	}
+
+	ref := ""
+	if at := strings.LastIndex(repo, "@"); at >= 0 && at > strings.LastIndexAny(repo, "/:") {
+		repo, ref = repo[:at], repo[at+1:]
+		if ref == "" {
+			return nil, fmt.Errorf("invalid %s %q: empty ref after @", LocatorParam, s)
+		}
+	}
+	if repo == "" {
+		return nil, fmt.Errorf("invalid %s %q: missing repo", LocatorParam, s)
+	}
+
+	url := scheme + repo
+	if scheme == "" && !isSCPLike(repo) {
+		url = "https://" + repo
+	}
+
+	return &locator{
+		URL:  url,
+		Ref:  ref,
+		Path: path,
+	}, nil
+}
+
+// isSCPLike returns true if repo is written in scp-like ssh syntax, with
+// a user and host separated from the path by a colon.
+func isSCPLike(repo string) bool {
+	host := repo
+	if slash := strings.Index(repo, "/"); slash >= 0 {
+		host = repo[:slash]
+	}
+	return strings.Contains(host, "@") && strings.Contains(host, ":")
+}
+
+// isFullCommitHash returns true if s is a full 40 character hex sha.
+func isFullCommitHash(s string) bool {
+	if len(s) != 40 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
@@ -0,0 +1,128 @@
+package git
+
+import (
+	"context"
+	"testing"
+)
+
+func TestParseLocator(t *testing.T) {
+	for _, tc := range []struct {
+		locator  string
+		expected *locator
+	}{{
+		locator:  "github.com/tektoncd/catalog@v1.2.3//task/git-clone/0.9/git-clone.yaml",
+		expected: &locator{URL: "https://github.com/tektoncd/catalog", Ref: "v1.2.3", Path: "task/git-clone/0.9/git-clone.yaml"},
+	}, {
+		locator:  "github.com/tektoncd/catalog//task/git-clone/0.9/git-clone.yaml",
+		expected: &locator{URL: "https://github.com/tektoncd/catalog", Path: "task/git-clone/0.9/git-clone.yaml"},
+	}, {
+		locator:  "https://github.com/tektoncd/catalog.git@main//task.yaml",
+		expected: &locator{URL: "https://github.com/tektoncd/catalog.git", Ref: "main", Path: "task.yaml"},
+	}, {
+		locator:  "git@github.com:tektoncd/catalog@aeb957601cf41c012be462827053a21a420befca//task.yaml",
+		expected: &locator{URL: "git@github.com:tektoncd/catalog", Ref: "aeb957601cf41c012be462827053a21a420befca", Path: "task.yaml"},
+	}, {
+		locator:  "git@github.com:tektoncd/catalog//task.yaml",
+		expected: &locator{URL: "git@github.com:tektoncd/catalog", Path: "task.yaml"},
+	}, {
+		locator:  "file:///tmp/repo@feature/x//dir/task.yaml",
+		expected: &locator{URL: "file:///tmp/repo@feature/x", Path: "dir/task.yaml"},
+	}, {
+		locator:  "file:///tmp/repo@release-1//dir/task.yaml",
+		expected: &locator{URL: "file:///tmp/repo", Ref: "release-1", Path: "dir/task.yaml"},
+	}} {
+		t.Run(tc.locator, func(t *testing.T) {
+			loc, err := parseLocator(tc.locator)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if *loc != *tc.expected {
+				t.Errorf("expected %+v received %+v", *tc.expected, *loc)
+			}
+		})
+	}
+}
+
+func TestParseLocatorInvalid(t *testing.T) {
+	for _, tc := range []string{
+		"github.com/tektoncd/catalog",
+		"github.com/tektoncd/catalog@v1.2.3",
+		"github.com/tektoncd/catalog@v1.2.3//",
+		"github.com/tektoncd/catalog@//task.yaml",
+		"//task.yaml",
+		"https://@v1//task.yaml",
+	} {
+		t.Run(tc, func(t *testing.T) {
+			if _, err := parseLocator(tc); err == nil {
+				t.Errorf("expected error parsing %q", tc)
+			}
+		})
+	}
+}
+
+func TestValidateParamsLocator(t *testing.T) {
+	resolver := Resolver{}
+	if err := resolver.ValidateParams(context.Background(), map[string]string{
+		LocatorParam: "github.com/tektoncd/catalog@v1.2.3//task.yaml",
+	}); err != nil {
+		t.Fatalf("unexpected error validating locator: %v", err)
+	}
+
+	if err := resolver.ValidateParams(context.Background(), map[string]string{
+		LocatorParam: "github.com/tektoncd/catalog@v1.2.3",
+	}); err == nil {
+		t.Fatalf("expected error validating malformed locator")
+	}
+
+	for _, p := range []string{URLParam, PathParam, CommitParam, BranchParam} {
+		params := map[string]string{
+			LocatorParam: "github.com/tektoncd/catalog@v1.2.3//task.yaml",
+			p:            "foo",
+		}
+		if err := resolver.ValidateParams(context.Background(), params); err == nil {
+			t.Errorf("expected error for locator combined with %q", p)
+		}
+	}
+}
+
+func TestResolveLocator(t *testing.T) {
+	repoDir, commits := createTestRepo(t, []testCommit{{
+		files: map[string]string{"dir/task.yaml": "one"},
+	}, {
+		files: map[string]string{"dir/task.yaml": "two"},
+	}, {
+		files: map[string]string{"dir/task.yaml": "three"},
+	}})
+	setTestRef(t, repoDir, "refs/tags/v1.0.0", commits[0])
+	setTestRef(t, repoDir, "refs/heads/feature", commits[1])
+
+	for _, tc := range []struct {
+		ref      string
+		expected string
+	}{{
+		ref:      "",
+		expected: "three",
+	}, {
+		ref:      "@v1.0.0",
+		expected: "one",
+	}, {
+		ref:      "@feature",
+		expected: "two",
+	}, {
+		ref:      "@" + commits[1],
+		expected: "two",
+	}} {
+		t.Run(tc.ref, func(t *testing.T) {
+			resolver := Resolver{}
+			resource, err := resolver.Resolve(context.Background(), map[string]string{
+				LocatorParam: "file://" + repoDir + tc.ref + "//dir/task.yaml",
+			})
+			if err != nil {
+				t.Fatalf("unexpected error resolving: %v", err)
+			}
+			if string(resource.Data()) != tc.expected {
+				t.Errorf("expected content %q received %q", tc.expected, string(resource.Data()))
+			}
+		})
+	}
+}
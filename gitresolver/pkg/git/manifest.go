@@ -0,0 +1,115 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	resolutioncommon "github.com/tektoncd/resolution/pkg/common"
+)
+
+// ManifestContentType is the content type of a resolved file wrapped in
+// a manifest of its provenance.
+const ManifestContentType string = "application/vnd.tekton.resolution+json"
+
+// The ways that wrap-with-manifest can include the resolved file in
+// the manifest.
+const (
+	// ManifestContentInline embeds the resolved file in the manifest.
+	ManifestContentInline = "inline"
+
+	// ManifestContentDigest only records the resolved file's digest.
+	ManifestContentDigest = "digest"
+)
+
+// Manifest is the json document returned in place of a resolved file
+// when wrap-with-manifest is configured. It carries the file, or its
+// digest, along with everything known about where it came from.
+type Manifest struct {
+	Source      ManifestSource `json:"source"`
+	ResolvedAt  time.Time      `json:"resolvedAt"`
+	ContentType string         `json:"contentType"`
+	Digest      string         `json:"digest"`
+
+	// Content is the resolved file, base64 encoded in json, if it was
+	// inlined.
+	Content []byte `json:"content,omitempty"`
+
+	// Annotations are those of the resolved file, which record its
+	// signatures, overlays and other metadata.
+	Annotations map[string]string `json:"annotations"`
+}
+
+// ManifestSource records where a file wrapped in a Manifest was
+// resolved from.
+type ManifestSource struct {
+	URL     string `json:"url"`
+	Ref     string `json:"ref,omitempty"`
+	RefType string `json:"refType,omitempty"`
+	Commit  string `json:"commit"`
+	Path    string `json:"path"`
+}
+
+// wrapWithManifest returns a copy of resolved whose content is a
+// Manifest describing it, resolved at the given time. mode is how the
+// manifest includes the file: ManifestContentInline or
+// ManifestContentDigest.
+func wrapWithManifest(resolved *ResolvedGitResource, mode string, resolvedAt time.Time) (*ResolvedGitResource, error) {
+	if mode != ManifestContentInline && mode != ManifestContentDigest {
+		return nil, fmt.Errorf("invalid %s %q: must be %q or %q", ConfigFieldWrapWithManifest, mode, ManifestContentInline, ManifestContentDigest)
+	}
+	digest, _, err := v1.SHA256(bytes.NewReader(resolved.Content))
+	if err != nil {
+		return nil, fmt.Errorf("error computing digest: %w", err)
+	}
+	annotations := resolved.Annotations()
+	manifest := Manifest{
+		Source: ManifestSource{
+			URL:     sanitizeURL(resolved.URL),
+			Ref:     resolved.Ref,
+			RefType: resolved.RefType,
+			Commit:  resolved.Commit,
+			Path:    resolved.Path,
+		},
+		ResolvedAt:  resolvedAt.UTC(),
+		ContentType: annotations[resolutioncommon.AnnotationKeyContentType],
+		Digest:      digest.String(),
+		Annotations: annotations,
+	}
+	if mode == ManifestContentInline {
+		manifest.Content = resolved.Content
+	}
+
+	wrapped := *resolved
+	wrapped.Content, err = json.Marshal(manifest)
+	if err != nil {
+		return nil, fmt.Errorf("error serializing manifest for %q: %w", resolved.Path, err)
+	}
+	wrapped.ContentType = ManifestContentType
+	if wrapped.OCIDigest != "" {
+		digest, _, err := v1.SHA256(bytes.NewReader(wrapped.Content))
+		if err != nil {
+			return nil, fmt.Errorf("error computing digest: %w", err)
+		}
+		wrapped.OCIDigest = digest.String()
+	}
+	return &wrapped, nil
+}
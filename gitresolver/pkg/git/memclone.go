@@ -0,0 +1,70 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+)
+
+// defaultInMemoryCloneMaxDepth is the deepest clone that in-memory-clone
+// keeps in memory when in-memory-clone-max-depth isn't configured.
+const defaultInMemoryCloneMaxDepth = 10
+
+type inMemoryCloneKey struct{}
+
+// withInMemoryClone returns a copy of ctx whose clones are made with
+// go-git into memory, rather than with the git binary or into the clone
+// cache on disk.
+func withInMemoryClone(ctx context.Context) context.Context {
+	return context.WithValue(ctx, inMemoryCloneKey{}, true)
+}
+
+// isInMemoryClone returns true if clones made with ctx are kept in
+// memory.
+func isInMemoryClone(ctx context.Context) bool {
+	inMemory, _ := ctx.Value(inMemoryCloneKey{}).(bool)
+	return inMemory
+}
+
+// getInMemoryCloneMaxDepth returns the deepest clone that's kept in
+// memory, as configured with the in-memory-clone-max-depth field in the
+// git-resolver-config configmap.
+func getInMemoryCloneMaxDepth(conf map[string]string) (int, error) {
+	depthString, ok := conf[ConfigFieldInMemoryCloneMaxDepth]
+	if !ok || depthString == "" {
+		return defaultInMemoryCloneMaxDepth, nil
+	}
+	depth, err := strconv.Atoi(depthString)
+	if err != nil || depth <= 0 {
+		return 0, fmt.Errorf("invalid %s %q: must be a positive integer", ConfigFieldInMemoryCloneMaxDepth, depthString)
+	}
+	return depth, nil
+}
+
+// useInMemoryClone returns true if in-memory-clone is configured and the
+// clone that ctx makes for params is expected to be small: a shallow
+// clone no deeper than maxDepth that doesn't follow submodules, which
+// fetch a repo of their own for each one.
+func useInMemoryClone(ctx context.Context, conf, params map[string]string, maxDepth int) bool {
+	if enabled, _ := strconv.ParseBool(conf[ConfigFieldInMemoryClone]); !enabled {
+		return false
+	}
+	depth := getCloneDepthFromContext(ctx)
+	return depth > 0 && depth <= maxDepth && !followSubmodules(conf, params)
+}
@@ -0,0 +1,111 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/tektoncd/resolution/pkg/resolver/framework"
+)
+
+func TestUseInMemoryClone(t *testing.T) {
+	enabled := map[string]string{ConfigFieldInMemoryClone: "true"}
+	for _, tc := range []struct {
+		name     string
+		conf     map[string]string
+		params   map[string]string
+		depth    int
+		expected bool
+	}{
+		{name: "shallow", conf: enabled, depth: 1, expected: true},
+		{name: "at max depth", conf: enabled, depth: defaultInMemoryCloneMaxDepth, expected: true},
+		{name: "disabled", conf: map[string]string{}, depth: 1},
+		{name: "full history", conf: enabled},
+		{name: "too deep", conf: enabled, depth: defaultInMemoryCloneMaxDepth + 1},
+		{name: "submodules", conf: enabled, params: map[string]string{SubmodulesParam: "true"}, depth: 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := withCloneDepth(context.Background(), tc.depth)
+			if inMemory := useInMemoryClone(ctx, tc.conf, tc.params, defaultInMemoryCloneMaxDepth); inMemory != tc.expected {
+				t.Errorf("expected in-memory clone %t, received %t", tc.expected, inMemory)
+			}
+		})
+	}
+}
+
+func TestFetchRepositoryInMemory(t *testing.T) {
+	repoDir, _ := createTestRepo(t, []testCommit{{files: map[string]string{"task.yaml": "task"}}})
+	// The git binary clones to disk for protocol v2 unless the clone is
+	// kept in memory.
+	ctx := framework.InjectResolverConfigToContext(context.Background(), map[string]string{ConfigFieldGitProtocolVersion: GitProtocolV2})
+	ctx = withCloneDepth(ctx, 1)
+
+	for _, tc := range []struct {
+		name        string
+		ctx         context.Context
+		expectedCLI bool
+	}{
+		{name: "on disk", ctx: ctx, expectedCLI: true},
+		{name: "in memory", ctx: withInMemoryClone(ctx)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			fetched, err := fetchRepository(tc.ctx, repoDir, nil, nil, "")
+			if err != nil {
+				t.Fatalf("unexpected error fetching: %v", err)
+			}
+			defer fetched.cleanup()
+			if fetched.cli != tc.expectedCLI {
+				t.Errorf("expected cli clone %t, received %t", tc.expectedCLI, fetched.cli)
+			}
+		})
+	}
+}
+
+func TestResolveInMemoryCloneMatchesOnDisk(t *testing.T) {
+	repoDir, _ := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "one", "pipeline.yaml": "pipeline"},
+	}, {
+		files: map[string]string{"task.yaml": "two"},
+	}})
+	onDisk := map[string]string{ConfigFieldGitProtocolVersion: GitProtocolV2, ConfigFieldCloneDepth: "1"}
+	inMemory := map[string]string{ConfigFieldGitProtocolVersion: GitProtocolV2, ConfigFieldCloneDepth: "1", ConfigFieldInMemoryClone: "true"}
+
+	for _, path := range []string{"task.yaml", "pipeline.yaml"} {
+		results := map[string]string{}
+		for name, conf := range map[string]map[string]string{"on disk": onDisk, "in memory": inMemory} {
+			resolver := Resolver{}
+			resource, err := resolver.Resolve(framework.InjectResolverConfigToContext(context.Background(), conf), map[string]string{URLParam: repoDir, PathParam: path})
+			if err != nil {
+				t.Fatalf("unexpected error resolving %s %s: %v", name, path, err)
+			}
+			results[name] = fmt.Sprintf("%s@%s", resource.Data(), resource.Annotations()[AnnotationKeyCommitHash])
+		}
+		if results["on disk"] != results["in memory"] {
+			t.Errorf("expected the same result for %s on disk and in memory, received %q and %q", path, results["on disk"], results["in memory"])
+		}
+	}
+}
+
+func BenchmarkResolveSmallFile(b *testing.B) {
+	repoDir, _ := createTestRepo(b, []testCommit{{
+		files: map[string]string{"task.yaml": "apiVersion: tekton.dev/v1beta1\nkind: Task\n"},
+	}})
+	for _, bc := range []struct {
+		name string
+		conf map[string]string
+	}{
+		{name: "on disk", conf: map[string]string{ConfigFieldGitProtocolVersion: GitProtocolV2, ConfigFieldCloneDepth: "1"}},
+		{name: "in memory", conf: map[string]string{ConfigFieldGitProtocolVersion: GitProtocolV2, ConfigFieldCloneDepth: "1", ConfigFieldInMemoryClone: "true"}},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			ctx := framework.InjectResolverConfigToContext(context.Background(), bc.conf)
+			params := map[string]string{URLParam: repoDir, PathParam: "task.yaml"}
+			for i := 0; i < b.N; i++ {
+				resolver := Resolver{}
+				if _, err := resolver.Resolve(ctx, params); err != nil {
+					b.Fatalf("unexpected error resolving: %v", err)
+				}
+			}
+		})
+	}
+}
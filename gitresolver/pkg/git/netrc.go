@@ -0,0 +1,182 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/system"
+)
+
+// netrcSecretKey is the key in the secret named by netrc-secret that
+// holds the netrc file.
+const netrcSecretKey = ".netrc"
+
+// netrcMachine is the login and password of a machine entry in a netrc
+// file. The default entry has no name.
+type netrcMachine struct {
+	name     string
+	login    string
+	password string
+}
+
+// parseNetrc returns the machine entries in a netrc file, in the order
+// they're listed, with the default entry, if any, last. Macro
+// definitions and accounts are skipped, as are comments from # to the
+// end of a line.
+func parseNetrc(data []byte, source string) ([]netrcMachine, error) {
+	var tokens []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	inMacro := false
+	for scanner.Scan() {
+		line := scanner.Text()
+		if inMacro {
+			// A macro definition runs until the next empty line.
+			inMacro = strings.TrimSpace(line) != ""
+			continue
+		}
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		for _, field := range strings.Fields(line) {
+			tokens = append(tokens, field)
+			if field == "macdef" {
+				inMacro = true
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading netrc %s: %w", source, err)
+	}
+
+	var machines []netrcMachine
+	var defaultMachine *netrcMachine
+	var current *netrcMachine
+	for i := 0; i < len(tokens); i++ {
+		switch token := tokens[i]; token {
+		case "default":
+			if defaultMachine != nil {
+				return nil, fmt.Errorf("netrc %s has more than one default entry", source)
+			}
+			defaultMachine = &netrcMachine{}
+			current = defaultMachine
+		case "machine", "login", "password", "account", "macdef":
+			if i+1 == len(tokens) {
+				return nil, fmt.Errorf("netrc %s has no value for %q", source, token)
+			}
+			i++
+			switch token {
+			case "machine":
+				machines = append(machines, netrcMachine{name: tokens[i]})
+				current = &machines[len(machines)-1]
+			case "login", "password":
+				if current == nil {
+					return nil, fmt.Errorf("netrc %s has %q outside of a machine entry", source, token)
+				}
+				if token == "login" {
+					current.login = tokens[i]
+				} else {
+					current.password = tokens[i]
+				}
+			}
+		default:
+			// The token isn't named since it may be part of a password.
+			return nil, fmt.Errorf("netrc %s has an unknown token", source)
+		}
+		if current != nil && current != defaultMachine && defaultMachine != nil {
+			return nil, fmt.Errorf("netrc %s has a machine entry after the default entry", source)
+		}
+	}
+	if defaultMachine != nil {
+		machines = append(machines, *defaultMachine)
+	}
+	return machines, nil
+}
+
+// lookupNetrc returns the first entry for host in machines, falling
+// back to the default entry, or nil if neither is there.
+func lookupNetrc(machines []netrcMachine, host string) *netrcMachine {
+	for i, machine := range machines {
+		if machine.name == "" || strings.EqualFold(machine.name, host) {
+			return &machines[i]
+		}
+	}
+	return nil
+}
+
+// readNetrc returns the netrc file at netrc, or in the secret in the
+// resolver's namespace named by netrc-secret, and where it was read
+// from, or nil if neither is configured. The file is read on every
+// resolution so that rotated credentials are picked up straight away.
+func (r *Resolver) readNetrc(ctx context.Context, conf map[string]string) ([]byte, string, error) {
+	path, name := conf[ConfigFieldNetrc], conf[ConfigFieldNetrcSecret]
+	switch {
+	case path != "" && name != "":
+		return nil, "", fmt.Errorf("%s and %s can't both be set", ConfigFieldNetrc, ConfigFieldNetrcSecret)
+	case path != "":
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, "", fmt.Errorf("error reading netrc: %w", err)
+		}
+		return data, path, nil
+	case name != "":
+		namespace := system.Namespace()
+		secret, err := r.kubeClientSet.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, "", fmt.Errorf("error reading netrc secret %s/%s: %w", namespace, name, err)
+		}
+		data, ok := secret.Data[netrcSecretKey]
+		if !ok {
+			return nil, "", fmt.Errorf("netrc secret %s/%s has no %q key", namespace, name, netrcSecretKey)
+		}
+		return data, "secret " + namespace + "/" + name, nil
+	}
+	return nil, "", nil
+}
+
+// netrcAuth returns http basic auth with the login and password of the
+// entry for the host of the repo at url in the configured netrc file,
+// or nil if there is no netrc file, no entry for the host or url isn't
+// http or https, so that the repo is fetched anonymously. Errors name
+// the file but never include its content.
+func (r *Resolver) netrcAuth(ctx context.Context, conf map[string]string, url string) (transport.AuthMethod, error) {
+	data, source, err := r.readNetrc(ctx, conf)
+	if err != nil || data == nil {
+		return nil, err
+	}
+	ep, err := transport.NewEndpoint(url)
+	if err != nil || ep.Protocol != "https" && ep.Protocol != "http" {
+		return nil, nil
+	}
+	machines, err := parseNetrc(data, source)
+	if err != nil {
+		return nil, err
+	}
+	machine := lookupNetrc(machines, ep.Host)
+	if machine == nil {
+		return nil, nil
+	}
+	return &githttp.BasicAuth{Username: machine.login, Password: machine.password}, nil
+}
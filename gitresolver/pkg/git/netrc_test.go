@@ -0,0 +1,210 @@
+package git
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tektoncd/resolution/pkg/resolver/framework"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"knative.dev/pkg/system"
+)
+
+const testNetrc = `# team credentials
+machine github.com login gh-user password gh-pass
+machine gitlab.example.com
+  login gl-user
+  password gl-pass
+  account ignored
+
+macdef init
+  cd /tmp
+  machine macro.example.com login nope
+
+machine 127.0.0.1 login ip-user password ip-pass
+machine localhost login local-user password local-pass
+`
+
+func TestParseNetrc(t *testing.T) {
+	machines, err := parseNetrc([]byte(testNetrc+"default login anonymous password guest\n"), "test")
+	if err != nil {
+		t.Fatalf("unexpected error parsing netrc: %v", err)
+	}
+	for _, tc := range []struct {
+		host     string
+		expected *netrcMachine
+	}{{
+		host:     "github.com",
+		expected: &netrcMachine{name: "github.com", login: "gh-user", password: "gh-pass"},
+	}, {
+		host:     "GitLab.Example.com",
+		expected: &netrcMachine{name: "gitlab.example.com", login: "gl-user", password: "gl-pass"},
+	}, {
+		host:     "localhost",
+		expected: &netrcMachine{name: "localhost", login: "local-user", password: "local-pass"},
+	}, {
+		host:     "macro.example.com",
+		expected: &netrcMachine{login: "anonymous", password: "guest"},
+	}} {
+		t.Run(tc.host, func(t *testing.T) {
+			if machine := lookupNetrc(machines, tc.host); !reflect.DeepEqual(machine, tc.expected) {
+				t.Errorf("expected entry %+v received %+v", tc.expected, machine)
+			}
+		})
+	}
+
+	machines, err = parseNetrc([]byte(testNetrc), "test")
+	if err != nil {
+		t.Fatalf("unexpected error parsing netrc: %v", err)
+	}
+	if machine := lookupNetrc(machines, "bitbucket.org"); machine != nil {
+		t.Errorf("expected no entry without a default, received %+v", machine)
+	}
+}
+
+func TestParseNetrcInvalid(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		netrc       string
+		expectedErr string
+	}{{
+		name:        "missing value",
+		netrc:       "machine github.com login",
+		expectedErr: `netrc test has no value for "login"`,
+	}, {
+		name:        "login outside of a machine",
+		netrc:       "login gh-user",
+		expectedErr: `netrc test has "login" outside of a machine entry`,
+	}, {
+		name:        "unknown token",
+		netrc:       "machine github.com login gh-user password gh pass",
+		expectedErr: "netrc test has an unknown token",
+	}, {
+		name:        "machine after default",
+		netrc:       "default login anonymous\nmachine github.com login gh-user",
+		expectedErr: "netrc test has a machine entry after the default entry",
+	}, {
+		name:        "two defaults",
+		netrc:       "default login a\ndefault login b",
+		expectedErr: "netrc test has more than one default entry",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := parseNetrc([]byte(tc.netrc), "test")
+			if err == nil || err.Error() != tc.expectedErr {
+				t.Fatalf("expected error %q, received %v", tc.expectedErr, err)
+			}
+			if strings.Contains(err.Error(), "pass") {
+				t.Errorf("expected the password to be left out of errors, received %v", err)
+			}
+		})
+	}
+}
+
+func TestResolveNetrc(t *testing.T) {
+	t.Setenv(system.NamespaceEnvKey, "tekton-remote-resolution")
+	repoDir, _ := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "kind: Task"},
+	}})
+	// The same server is reached as both localhost and 127.0.0.1 and
+	// expects each host's own credentials.
+	server := newGitHTTPServer(t, repoDir, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			expected := "ip-user"
+			if strings.HasPrefix(r.Host, "localhost:") {
+				expected = "local-user"
+			}
+			if username, password, ok := r.BasicAuth(); !ok || username != expected || password != strings.TrimSuffix(expected, "-user")+"-pass" {
+				w.Header().Set("WWW-Authenticate", `Basic realm="git"`)
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+	localhostURL := strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+	netrcFile := filepath.Join(t.TempDir(), ".netrc")
+	if err := os.WriteFile(netrcFile, []byte(testNetrc), 0o600); err != nil {
+		t.Fatalf("error writing netrc: %v", err)
+	}
+	otherHostsFile := filepath.Join(t.TempDir(), ".netrc")
+	if err := os.WriteFile(otherHostsFile, []byte("machine github.com login gh-user password gh-pass\n"), 0o600); err != nil {
+		t.Fatalf("error writing netrc: %v", err)
+	}
+	kubeClient := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "git-netrc", Namespace: "tekton-remote-resolution"},
+		Data:       map[string][]byte{".netrc": []byte(testNetrc)},
+	}, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "wrong-key", Namespace: "tekton-remote-resolution"},
+		Data:       map[string][]byte{"netrc": []byte(testNetrc)},
+	})
+
+	for _, tc := range []struct {
+		name        string
+		conf        map[string]string
+		url         string
+		expectedErr string
+	}{{
+		name: "netrc file",
+		conf: map[string]string{ConfigFieldNetrc: netrcFile},
+		url:  server.URL,
+	}, {
+		name: "netrc file for another host",
+		conf: map[string]string{ConfigFieldNetrc: netrcFile},
+		url:  localhostURL,
+	}, {
+		name: "netrc secret",
+		conf: map[string]string{ConfigFieldNetrcSecret: "git-netrc"},
+		url:  localhostURL,
+	}, {
+		name:        "no entry for host",
+		conf:        map[string]string{ConfigFieldNetrc: otherHostsFile},
+		url:         server.URL,
+		expectedErr: "authentication required",
+	}, {
+		name:        "without netrc",
+		conf:        map[string]string{},
+		url:         server.URL,
+		expectedErr: "authentication required",
+	}, {
+		name:        "missing netrc file",
+		conf:        map[string]string{ConfigFieldNetrc: filepath.Join(t.TempDir(), ".netrc")},
+		url:         server.URL,
+		expectedErr: "error reading netrc",
+	}, {
+		name:        "secret without key",
+		conf:        map[string]string{ConfigFieldNetrcSecret: "wrong-key"},
+		url:         server.URL,
+		expectedErr: `netrc secret tekton-remote-resolution/wrong-key has no ".netrc" key`,
+	}, {
+		name:        "both file and secret",
+		conf:        map[string]string{ConfigFieldNetrc: netrcFile, ConfigFieldNetrcSecret: "git-netrc"},
+		url:         server.URL,
+		expectedErr: "netrc and netrc-secret can't both be set",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			resolver := &Resolver{kubeClientSet: kubeClient}
+			resource, err := resolver.Resolve(framework.InjectResolverConfigToContext(context.Background(), tc.conf), map[string]string{
+				URLParam:  tc.url + "/repo",
+				PathParam: "task.yaml",
+			})
+			if tc.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectedErr) {
+					t.Fatalf("expected error containing %q, received %v", tc.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error resolving with netrc: %v", err)
+			}
+			if string(resource.Data()) != "kind: Task" {
+				t.Errorf("expected content %q received %q", "kind: Task", resource.Data())
+			}
+		})
+	}
+}
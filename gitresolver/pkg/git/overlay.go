@@ -0,0 +1,172 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// MergeStrategyDeepMerge recursively merges overlay mappings into
+	// the base file's, with overlay values winning wherever both set
+	// the same key to something other than a mapping.
+	MergeStrategyDeepMerge = "deep-merge"
+
+	// MergeStrategyReplace replaces each top-level key of the base
+	// file with the overlay's value for it, if it has one.
+	MergeStrategyReplace = "replace"
+)
+
+// overlayOnlyParams are the params that only describe the overlay
+// file. They're dropped when resolving it so it isn't overlaid again.
+var overlayOnlyParams = []string{
+	OverlayURLParam,
+	OverlayPathParam,
+	OverlayCommitParam,
+	OverlayBranchParam,
+	MergeStrategyParam,
+}
+
+// getMergeStrategy returns the merge strategy requested with the
+// mergeStrategy param, defaulting to deep-merge.
+func getMergeStrategy(params map[string]string) (string, error) {
+	switch strategy := params[MergeStrategyParam]; strategy {
+	case "":
+		return MergeStrategyDeepMerge, nil
+	case MergeStrategyDeepMerge, MergeStrategyReplace:
+		return strategy, nil
+	default:
+		return "", fmt.Errorf("invalid value for %q: %q must be one of %q, %q", MergeStrategyParam, strategy, MergeStrategyDeepMerge, MergeStrategyReplace)
+	}
+}
+
+// overlayParams returns the params to resolve the overlay file with,
+// fetching from baseURL unless another repo is given. Params that don't
+// locate a file, like renderValues, carry over from the base request.
+func overlayParams(params map[string]string, baseURL string) map[string]string {
+	overlay := map[string]string{}
+	for k, v := range params {
+		overlay[k] = v
+	}
+	// The base file's ref, and the date it was resolved as of, don't
+	// apply to the overlay, which is picked by its own params.
+	for _, p := range overlayOnlyParams {
+		delete(overlay, p)
+	}
+	for _, p := range append(refSelectingParams, LocatorParam, DateParam) {
+		delete(overlay, p)
+	}
+	overlay[URLParam] = baseURL
+	if url := params[OverlayURLParam]; url != "" {
+		overlay[URLParam] = url
+	}
+	overlay[PathParam] = params[OverlayPathParam]
+	if commit := params[OverlayCommitParam]; commit != "" {
+		overlay[CommitParam] = commit
+	}
+	if branch := params[OverlayBranchParam]; branch != "" {
+		overlay[BranchParam] = branch
+	}
+	return overlay
+}
+
+// resolveOverlay resolves the overlay file described by params and
+// merges it over base. The result records where both came from. base
+// may be shared with the cache so it's copied rather than modified.
+func (r *Resolver) resolveOverlay(ctx context.Context, params map[string]string, base *ResolvedGitResource) (*ResolvedGitResource, error) {
+	strategy, err := getMergeStrategy(params)
+	if err != nil {
+		return nil, err
+	}
+	overlay, err := r.resolve(ctx, overlayParams(params, base.URL))
+	if err != nil {
+		return nil, fmt.Errorf("error resolving overlay: %w", err)
+	}
+	content, err := mergeYAML(base.Content, overlay.Content, strategy)
+	if err != nil {
+		return nil, fmt.Errorf("error merging overlay %q over %q: %w", overlay.Path, base.Path, err)
+	}
+
+	merged := *base
+	merged.Content = content
+	merged.OverlayURL, merged.OverlayPath, merged.OverlayCommit = overlay.URL, overlay.Path, overlay.Commit
+	if merged.OCIDigest != "" {
+		digest, _, err := v1.SHA256(bytes.NewReader(content))
+		if err != nil {
+			return nil, fmt.Errorf("error computing digest: %w", err)
+		}
+		merged.OCIDigest = digest.String()
+	}
+	return &merged, nil
+}
+
+// mergeYAML merges the single yaml document in overlay over the one in
+// base using the given strategy and returns the result with sorted keys.
+func mergeYAML(base, overlay []byte, strategy string) ([]byte, error) {
+	var baseDoc, overlayDoc map[string]interface{}
+	if err := yaml.Unmarshal(base, &baseDoc); err != nil {
+		return nil, fmt.Errorf("base is not a yaml mapping: %w", err)
+	}
+	if err := yaml.Unmarshal(overlay, &overlayDoc); err != nil {
+		return nil, fmt.Errorf("overlay is not a yaml mapping: %w", err)
+	}
+	if baseDoc == nil {
+		baseDoc = map[string]interface{}{}
+	}
+
+	var merged map[string]interface{}
+	if strategy == MergeStrategyReplace {
+		merged = baseDoc
+		for k, v := range overlayDoc {
+			merged[k] = v
+		}
+	} else {
+		merged = deepMerge(baseDoc, overlayDoc)
+	}
+
+	buf := &bytes.Buffer{}
+	encoder := yaml.NewEncoder(buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(merged); err != nil {
+		return nil, fmt.Errorf("error encoding yaml: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("error encoding yaml: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// deepMerge merges overlay into base, recursing into mappings that both
+// set and otherwise taking overlay's value. Sequences are replaced
+// rather than appended to.
+func deepMerge(base, overlay map[string]interface{}) map[string]interface{} {
+	for k, overlayValue := range overlay {
+		baseMap, baseIsMap := base[k].(map[string]interface{})
+		overlayMap, overlayIsMap := overlayValue.(map[string]interface{})
+		if baseIsMap && overlayIsMap {
+			base[k] = deepMerge(baseMap, overlayMap)
+			continue
+		}
+		base[k] = overlayValue
+	}
+	return base
+}
@@ -0,0 +1,200 @@
+package git
+
+import (
+	"context"
+	"testing"
+)
+
+func TestResolveOverlay(t *testing.T) {
+	baseRepo, baseCommits := createTestRepo(t, []testCommit{{
+		files: map[string]string{"config.yaml": `kind: Config
+metadata:
+  name: base
+  labels:
+    team: build
+spec:
+  replicas: 1
+  args:
+  - --verbose
+`},
+	}})
+	overlayRepo, overlayCommits := createTestRepo(t, []testCommit{{
+		files: map[string]string{"prod.yaml": `metadata:
+  labels:
+    env: prod
+spec:
+  replicas: 3
+  args:
+  - --quiet
+`},
+	}})
+
+	for _, tc := range []struct {
+		strategy string
+		expected string
+	}{{
+		strategy: MergeStrategyDeepMerge,
+		expected: `kind: Config
+metadata:
+  labels:
+    env: prod
+    team: build
+  name: base
+spec:
+  args:
+    - --quiet
+  replicas: 3
+`,
+	}, {
+		strategy: MergeStrategyReplace,
+		expected: `kind: Config
+metadata:
+  labels:
+    env: prod
+spec:
+  args:
+    - --quiet
+  replicas: 3
+`,
+	}} {
+		t.Run(tc.strategy, func(t *testing.T) {
+			resolver := Resolver{}
+			resource, err := resolver.Resolve(context.Background(), map[string]string{
+				URLParam:           baseRepo,
+				PathParam:          "config.yaml",
+				OverlayURLParam:    overlayRepo,
+				OverlayPathParam:   "prod.yaml",
+				MergeStrategyParam: tc.strategy,
+			})
+			if err != nil {
+				t.Fatalf("unexpected error resolving overlay: %v", err)
+			}
+			if got := string(resource.Data()); got != tc.expected {
+				t.Errorf("expected merged content:\n%s\nreceived:\n%s", tc.expected, got)
+			}
+			annotations := resource.Annotations()
+			for key, want := range map[string]string{
+				AnnotationKeyCommitHash:    baseCommits[0],
+				AnnotationKeyOverlayURL:    "file://" + overlayRepo,
+				AnnotationKeyOverlayPath:   "prod.yaml",
+				AnnotationKeyOverlayCommit: overlayCommits[0],
+			} {
+				if annotations[key] != want {
+					t.Errorf("expected annotation %s=%q received %q", key, want, annotations[key])
+				}
+			}
+		})
+	}
+}
+
+func TestResolveOverlaySameRepo(t *testing.T) {
+	repoDir, _ := createTestRepo(t, []testCommit{{
+		files: map[string]string{
+			"base.yaml":    "a: 1\nb: 2\n",
+			"overlay.yaml": "b: 3\n",
+		},
+	}})
+	resolver := Resolver{}
+	resource, err := resolver.Resolve(context.Background(), map[string]string{
+		URLParam:         repoDir,
+		PathParam:        "base.yaml",
+		OverlayPathParam: "overlay.yaml",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error resolving overlay: %v", err)
+	}
+	if got, want := string(resource.Data()), "a: 1\nb: 3\n"; got != want {
+		t.Errorf("expected merged content %q received %q", want, got)
+	}
+}
+
+func TestResolveOverlayBaseRef(t *testing.T) {
+	baseRepo, baseCommits := createTestRepo(t, []testCommit{{
+		files: map[string]string{"base.yaml": "a: 1\nb: 2\n"},
+	}})
+	setTestRef(t, baseRepo, "refs/tags/v1", baseCommits[0])
+	setTestRef(t, baseRepo, "refs/pull/7/head", baseCommits[0])
+	overlayRepo, overlayCommits := createTestRepo(t, []testCommit{{
+		files: map[string]string{"overlay.yaml": "b: 3\n"},
+	}, {
+		files: map[string]string{"overlay.yaml": "b: 4\n"},
+	}})
+	setTestRef(t, overlayRepo, "refs/heads/prod", overlayCommits[0])
+
+	for _, tc := range []struct {
+		name     string
+		params   map[string]string
+		expected string
+	}{{
+		name:     "revision",
+		params:   map[string]string{RevisionParam: "v1"},
+		expected: "a: 1\nb: 4\n",
+	}, {
+		name:     "pull request",
+		params:   map[string]string{PullRequestParam: "7"},
+		expected: "a: 1\nb: 4\n",
+	}, {
+		name:     "revision with an overlay branch",
+		params:   map[string]string{RevisionParam: "v1", OverlayBranchParam: "prod"},
+		expected: "a: 1\nb: 3\n",
+	}, {
+		name:     "pull request with an overlay branch",
+		params:   map[string]string{PullRequestParam: "7", OverlayBranchParam: "prod"},
+		expected: "a: 1\nb: 3\n",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			params := map[string]string{
+				URLParam:         baseRepo,
+				PathParam:        "base.yaml",
+				OverlayURLParam:  overlayRepo,
+				OverlayPathParam: "overlay.yaml",
+			}
+			for k, v := range tc.params {
+				params[k] = v
+			}
+			resolver := Resolver{}
+			resource, err := resolver.Resolve(context.Background(), params)
+			if err != nil {
+				t.Fatalf("unexpected error resolving overlay: %v", err)
+			}
+			if got := string(resource.Data()); got != tc.expected {
+				t.Errorf("expected merged content %q received %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestResolveOverlayNotAMapping(t *testing.T) {
+	repoDir, _ := createTestRepo(t, []testCommit{{
+		files: map[string]string{
+			"base.yaml":    "a: 1\n",
+			"overlay.yaml": "- not a mapping\n",
+		},
+	}})
+	resolver := Resolver{}
+	if _, err := resolver.Resolve(context.Background(), map[string]string{
+		URLParam:         repoDir,
+		PathParam:        "base.yaml",
+		OverlayPathParam: "overlay.yaml",
+	}); err == nil {
+		t.Fatalf("expected error merging a sequence over a mapping")
+	}
+}
+
+func TestValidateParamsOverlay(t *testing.T) {
+	resolver := Resolver{}
+	valid := map[string]string{URLParam: "foo", PathParam: "bar", OverlayPathParam: "baz", MergeStrategyParam: MergeStrategyReplace}
+	if err := resolver.ValidateParams(context.Background(), valid); err != nil {
+		t.Errorf("unexpected error validating overlay params: %v", err)
+	}
+	for _, params := range []map[string]string{
+		{URLParam: "foo", PathParam: "bar", OverlayPathParam: "baz", MergeStrategyParam: "append"},
+		{URLParam: "foo", PathParam: "bar", OverlayPathParam: "baz", OverlayCommitParam: "abc", OverlayBranchParam: "main"},
+		{URLParam: "foo", PathParam: "bar", OverlayPathParam: "baz", BlameParam: "true"},
+		{URLParam: "foo", PathParam: "bar", OverlayURLParam: "quux"},
+	} {
+		if err := resolver.ValidateParams(context.Background(), params); err == nil {
+			t.Errorf("expected error validating %v", params)
+		}
+	}
+}
@@ -25,5 +25,161 @@ const PathParam string = "path"
 // CommitParam is the commit hash that a file should be fetched from
 const CommitParam string = "commit"
 
+// RevisionParam is a branch, tag or commit hash, or abbreviation of
+// one, that a file should be fetched from. A branch is preferred over
+// a tag of the same name, and either over a commit
+const RevisionParam string = "revision"
+
 // BranchParam is the git branch that a file should be fetched from
 const BranchParam string = "branch"
+
+// TagParam is the git tag that a file should be fetched from
+const TagParam string = "tag"
+
+// BlameParam, when set to "true", returns per-line blame information
+// for the file at PathParam instead of its content
+const BlameParam string = "blame"
+
+// LocatorParam is a single combined address of the form
+// <repo>[@<ref>]//<path> that can be used in place of the url, path,
+// branch and commit params
+const LocatorParam string = "locator"
+
+// AllowEmptyParam, when set to "true", accepts an empty file as a
+// successful resolution even if empty content is otherwise rejected
+const AllowEmptyParam string = "allowEmpty"
+
+// RenderValuesParam is the name of a ConfigMap in the request's
+// namespace whose data is used as values when rendering templates
+const RenderValuesParam string = "renderValues"
+
+// IncludeLicenseParam, when set to "true", records the SPDX identifier
+// of the repository's license in an annotation
+const IncludeLicenseParam string = "includeLicense"
+
+// GrepParam is a regular expression that, combined with a directory
+// PathParam, returns the files beneath it whose content matches as
+// json instead of their content
+const GrepParam string = "grep"
+
+// GrepLineNumbersParam, when set to "true", includes the numbers of
+// the matching lines of each file reported for GrepParam
+const GrepLineNumbersParam string = "grepLineNumbers"
+
+// OnMissingParam controls what is returned when the file at PathParam
+// doesn't exist: "fail" to return an error, "empty" for empty content,
+// or any other value to return that value as the content
+const OnMissingParam string = "onMissing"
+
+// OverlayURLParam is the git repo url of an overlay file that is merged
+// over the file at PathParam. It defaults to URLParam
+const OverlayURLParam string = "overlayUrl"
+
+// OverlayPathParam is the path into the overlay repo of a yaml file to
+// merge over the file at PathParam
+const OverlayPathParam string = "overlayPath"
+
+// OverlayCommitParam is the commit hash that the overlay file should be
+// fetched from
+const OverlayCommitParam string = "overlayCommit"
+
+// OverlayBranchParam is the git branch that the overlay file should be
+// fetched from
+const OverlayBranchParam string = "overlayBranch"
+
+// MergeStrategyParam is how the overlay file is merged over the file
+// at PathParam: "deep-merge" or "replace"
+const MergeStrategyParam string = "mergeStrategy"
+
+// VerifySignatureParam, when set to "true", fails the resolution unless
+// the file at PathParam has a detached signature alongside it, with a
+// .sig suffix, made by one of the configured trusted keys
+const VerifySignatureParam string = "verifySignature"
+
+// FormatParam converts the resolved file to "yaml" or "json" before it
+// is returned
+const FormatParam string = "format"
+
+// IncludeDependenciesParam, when set to "true", bundles the files in
+// the same repo that the file at PathParam references through the git
+// resolver along with it
+const IncludeDependenciesParam string = "includeDependencies"
+
+// ContainingRefsParam, when set to "true", returns the branches and
+// tags that the commit at CommitParam is reachable from as json instead
+// of the content of a file
+const ContainingRefsParam string = "containingRefs"
+
+// CommitMetadataParam, when set to "true", returns the metadata of the
+// resolved commit, like its tree, parents, author and committer, as
+// json instead of the content of a file
+const CommitMetadataParam string = "commitMetadata"
+
+// CatalogRefParam is the short name of an entry in the configured
+// catalog, like "git-clone@0.9", that is resolved in place of a
+// LocatorParam
+const CatalogRefParam string = "catalogRef"
+
+// StatOnlyParam, when set to "true", returns the type, mode, hash and
+// size of the entry at PathParam as json instead of its content
+const StatOnlyParam string = "statOnly"
+
+// TokenSecretParam is the name of a Secret in the request's namespace
+// holding a personal access token that is sent, with http basic auth,
+// to the https git server at URLParam
+const TokenSecretParam string = "tokenSecret"
+
+// TokenSecretKeyParam is the key in the Secret named by
+// TokenSecretParam that holds the token. It defaults to "token"
+const TokenSecretKeyParam string = "tokenSecretKey"
+
+// DepthParam is the number of commits of history that a branch or tag
+// is cloned with. It can't be combined with a commit, which a shallow
+// clone may not reach
+const DepthParam string = "depth"
+
+// FetchModeParam is how the file at PathParam is fetched: "clone", or
+// "api" to read it from GitHub's contents api without a clone when
+// the repo is on a GitHub host
+const FetchModeParam string = "fetchMode"
+
+// RequireSignatureParam, when set to "true", fails the resolution
+// unless the resolved commit is signed by one of the keys in the
+// Secret named by TrustedKeysSecretParam
+const RequireSignatureParam string = "requireSignature"
+
+// TrustedKeysSecretParam is the name of a Secret in the request's
+// namespace listing the pgp keys that RequireSignatureParam checks the
+// resolved commit against
+const TrustedKeysSecretParam string = "trustedKeysSecret"
+
+// PullRequestParam is the number of a pull request whose head commit
+// the file at PathParam is fetched from, instead of a branch, tag or
+// commit
+const PullRequestParam string = "pullRequest"
+
+// SubmodulesParam, when set to "true", reads a PathParam that leads
+// into a submodule from that submodule at the commit the repo records
+// for it. It overrides the submodules config field
+const SubmodulesParam string = "submodules"
+
+// DateParam is an RFC 3339 time. The file at PathParam is fetched from
+// the latest commit on the chosen branch, tag or revision, or the
+// default branch, that was committed at or before it
+const DateParam string = "date"
+
+// LFSParam, when set to "false", returns a git lfs pointer file at
+// PathParam as it's committed instead of the object it points to
+const LFSParam string = "lfs"
+
+// RefSpecParam is a comma separated list of git refspecs, like
+// refs/merge-requests/42/head or
+// +refs/merge-requests/*/head:refs/remotes/origin/mr/*, that are fetched
+// in place of a branch, tag or the default branch. The file at
+// PathParam is read from the ref that the first of them fetches to, or
+// from CommitParam if the first has a wildcard
+const RefSpecParam string = "refspec"
+
+// refSelectingParams are the params that pick which ref or commit of a
+// repo a file is resolved from.
+var refSelectingParams = []string{CommitParam, BranchParam, TagParam, PullRequestParam, RevisionParam, RefSpecParam}
@@ -0,0 +1,125 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	resolutioncommon "github.com/tektoncd/resolution/pkg/common"
+)
+
+// ReasonInvalidPath is the reason that resolutions fail with when the
+// requested path, or a symlink along it, leads outside the repository.
+const ReasonInvalidPath = "InvalidPath"
+
+// maxSymlinkHops is the most symlinks that are followed while checking
+// where a path leads, matching the limit most kernels apply.
+const maxSymlinkHops = 40
+
+// validateRepoPath returns an error if path, once cleaned, would lead
+// outside the root of the repository. A leading "/" refers to the root
+// rather than to the resolver's own filesystem.
+func validateRepoPath(p string) error {
+	if escapesRoot(strings.TrimPrefix(p, "/")) {
+		return resolutioncommon.NewError(ReasonInvalidPath, fmt.Errorf("path %q leads outside the repository", p))
+	}
+	return nil
+}
+
+// escapesRoot returns true if the relative path p climbs above the
+// directory it's relative to.
+func escapesRoot(p string) bool {
+	cleaned := path.Clean(p)
+	return cleaned == ".." || strings.HasPrefix(cleaned, "../")
+}
+
+// checkSymlinks returns an error if following the symlinks committed
+// along p, in the tree of commit, would lead outside the repository.
+// Blobs are read from the tree without following symlinks, but files
+// in a worktree on disk may be, so a link to something like
+// /etc/hostname has to be refused before the file is opened. Parts of
+// p that don't exist are left for the read itself to report.
+func checkSymlinks(repository *git.Repository, commit, p string) error {
+	if err := validateRepoPath(p); err != nil {
+		return err
+	}
+	commitObj, err := repository.CommitObject(plumbing.NewHash(commit))
+	if err != nil {
+		return fmt.Errorf("error reading commit %q: %v", commit, err)
+	}
+	tree, err := commitObj.Tree()
+	if err != nil {
+		return fmt.Errorf("error reading tree of commit %q: %v", commit, err)
+	}
+
+	remaining := strings.Split(path.Clean(strings.TrimPrefix(p, "/")), "/")
+	current := ""
+	for hops := 0; len(remaining) > 0; {
+		next := path.Join(current, remaining[0])
+		remaining = remaining[1:]
+		entry, err := tree.FindEntry(next)
+		if err != nil {
+			return nil
+		}
+		if entry.Mode != filemode.Symlink {
+			current = next
+			continue
+		}
+		if hops++; hops > maxSymlinkHops {
+			return resolutioncommon.NewError(ReasonInvalidPath, fmt.Errorf("path %q has too many levels of symlinks", p))
+		}
+		target, err := readSymlinkTarget(repository, entry.Hash)
+		if err != nil {
+			return fmt.Errorf("error reading symlink %q: %v", next, err)
+		}
+		if path.IsAbs(target) || escapesRoot(path.Join(current, target)) {
+			return resolutioncommon.NewError(ReasonInvalidPath, fmt.Errorf("path %q is a symlink to %q outside the repository", next, target))
+		}
+		// The target is resolved relative to the directory holding the
+		// link, and what's left of p is looked up beneath it.
+		if resolved := path.Join(current, target); resolved != "." {
+			remaining = append(strings.Split(resolved, "/"), remaining...)
+		}
+		current = ""
+	}
+	return nil
+}
+
+// readSymlinkTarget returns the path that the symlink blob with the
+// given hash points to.
+func readSymlinkTarget(repository *git.Repository, hash plumbing.Hash) (string, error) {
+	blob, err := repository.BlobObject(hash)
+	if err != nil {
+		return "", err
+	}
+	r, err := blob.Reader()
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	target, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	return string(target), nil
+}
@@ -0,0 +1,153 @@
+package git
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	resolutioncommon "github.com/tektoncd/resolution/pkg/common"
+	"github.com/tektoncd/resolution/pkg/resolver/framework"
+)
+
+// commitTestSymlinks adds symlinks, named by their path in the repo and
+// mapped to their target, to the repo at repoDir in a new commit.
+func commitTestSymlinks(t *testing.T, repoDir string, links map[string]string) {
+	t.Helper()
+	repo, err := git.PlainOpen(repoDir)
+	if err != nil {
+		t.Fatalf("error opening repo: %v", err)
+	}
+	w, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("error getting worktree: %v", err)
+	}
+	for name, target := range links {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(repoDir, name)), 0755); err != nil {
+			t.Fatalf("error creating directory for symlink %q: %v", name, err)
+		}
+		if err := os.Symlink(target, filepath.Join(repoDir, name)); err != nil {
+			t.Fatalf("error creating symlink %q: %v", name, err)
+		}
+		if _, err := w.Add(name); err != nil {
+			t.Fatalf("error adding symlink %q: %v", name, err)
+		}
+	}
+	if _, err := w.Commit("symlinks", &git.CommitOptions{Author: &object.Signature{Name: "test", When: time.Now()}}); err != nil {
+		t.Fatalf("error committing symlinks: %v", err)
+	}
+}
+
+func TestValidateParamsPathTraversal(t *testing.T) {
+	resolver := Resolver{}
+	for _, tc := range []struct {
+		params    map[string]string
+		expectErr bool
+	}{
+		{params: map[string]string{URLParam: "/tmp/repo", PathParam: "tasks/../task.yaml"}},
+		{params: map[string]string{URLParam: "/tmp/repo", PathParam: "/task.yaml"}},
+		{params: map[string]string{URLParam: "/tmp/repo", PathParam: "../../etc/passwd"}, expectErr: true},
+		{params: map[string]string{URLParam: "/tmp/repo", PathParam: "tasks/../../task.yaml"}, expectErr: true},
+		{params: map[string]string{URLParam: "/tmp/repo", PathParam: "/../etc/passwd"}, expectErr: true},
+		{params: map[string]string{URLParam: "/tmp/repo", PathParam: "../*.yaml"}, expectErr: true},
+		{params: map[string]string{LocatorParam: "/tmp/repo//../etc/passwd"}, expectErr: true},
+		{params: map[string]string{URLParam: "/tmp/repo", PathParam: "task.yaml", OverlayPathParam: "../overlay.yaml"}, expectErr: true},
+	} {
+		err := resolver.ValidateParams(context.Background(), tc.params)
+		if !tc.expectErr {
+			if err != nil {
+				t.Errorf("unexpected error validating %v: %v", tc.params, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), "leads outside the repository") {
+			t.Errorf("expected params %v to be rejected, received %v", tc.params, err)
+			continue
+		}
+		if reason, _ := resolutioncommon.ReasonError(err); reason != ReasonInvalidPath {
+			t.Errorf("expected reason %q, received %q", ReasonInvalidPath, reason)
+		}
+	}
+}
+
+func TestResolveSymlinks(t *testing.T) {
+	repoDir, _ := createTestRepo(t, []testCommit{{
+		files: map[string]string{
+			"task.yaml":       "kind: Task\n",
+			"tasks/step.yaml": "kind: Step\n",
+		},
+	}})
+	commitTestSymlinks(t, repoDir, map[string]string{
+		"hostname":          "/etc/hostname",
+		"parent":            "../outside.yaml",
+		"nested/up":         "../../outside.yaml",
+		"inside.yaml":       "task.yaml",
+		"escape-dir":        "/etc",
+		"chain/first":       "../chain/second",
+		"chain/second":      "../hostname",
+		"nested/task.yaml":  "../task.yaml",
+		"nested/root-alias": "..",
+	})
+
+	for _, tc := range []struct {
+		path    string
+		message string
+	}{
+		{path: "hostname", message: `path "hostname" is a symlink to`},
+		{path: "parent", message: `path "parent" is a symlink to "../outside.yaml" outside the repository`},
+		{path: "nested/up", message: `path "nested/up" is a symlink to "../../outside.yaml" outside the repository`},
+		{path: "escape-dir/hostname", message: `path "escape-dir" is a symlink to`},
+		{path: "chain/first", message: `path "hostname" is a symlink to`},
+		{path: "nested/root-alias/hostname", message: `path "hostname" is a symlink to`},
+		{path: "/etc/hostname", message: `error opening file "/etc/hostname"`},
+		{path: "inside.yaml"},
+		{path: "nested/task.yaml"},
+	} {
+		for _, worktree := range []string{"false", "true"} {
+			t.Run(tc.path+"/worktree="+worktree, func(t *testing.T) {
+				ctx := framework.InjectResolverConfigToContext(context.Background(), map[string]string{
+					ConfigFieldUseWorktreeContent: worktree,
+				})
+				resolver := Resolver{}
+				_, err := resolver.Resolve(ctx, map[string]string{
+					URLParam:  repoDir,
+					PathParam: tc.path,
+				})
+				if tc.message == "" {
+					if err != nil {
+						t.Fatalf("unexpected error resolving symlink inside the repo: %v", err)
+					}
+					return
+				}
+				if err == nil || !strings.Contains(err.Error(), tc.message) {
+					t.Fatalf("expected error containing %q, received %v", tc.message, err)
+				}
+				if strings.Contains(tc.message, "symlink") {
+					if reason, _ := resolutioncommon.ReasonError(err); reason != ReasonInvalidPath {
+						t.Errorf("expected reason %q, received %q", ReasonInvalidPath, reason)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestResolveGlobSkipsEscapingSymlinks(t *testing.T) {
+	repoDir, _ := createTestRepo(t, []testCommit{{
+		files: map[string]string{"tasks/a.yaml": "kind: Task\n"},
+	}})
+	commitTestSymlinks(t, repoDir, map[string]string{"tasks/b.yaml": "/etc/hostname"})
+
+	resolver := Resolver{}
+	_, err := resolver.Resolve(context.Background(), map[string]string{
+		URLParam:  repoDir,
+		PathParam: "tasks/*.yaml",
+	})
+	if err == nil || !strings.Contains(err.Error(), `path "tasks/b.yaml" is a symlink to`) {
+		t.Fatalf("expected the escaping symlink to be refused, received %v", err)
+	}
+}
@@ -0,0 +1,162 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+
+	lru "github.com/hashicorp/golang-lru"
+	resolutioncommon "github.com/tektoncd/resolution/pkg/common"
+	"github.com/tektoncd/resolution/pkg/resolver/framework"
+)
+
+// AnnotationKeyRepin is the annotation on a ResolutionRequest that,
+// when set to "true", resolves its ref's current commit and pins that
+// in place of the commit pinned before.
+const AnnotationKeyRepin = "git.resolution.tekton.dev/repin"
+
+// AnnotationKeyPinnedCommit is the annotation on a ResolutionRequest
+// that gives the commit its ref is pinned to, like the commit recorded
+// in the status of an earlier request for the ref. It takes precedence
+// over the commit the resolver remembers, which is forgotten when the
+// resolver restarts.
+const AnnotationKeyPinnedCommit = "git.resolution.tekton.dev/pinned-commit"
+
+// defaultMaxPins is the most refs that a pinStore remembers the pinned
+// commits of when its size isn't set.
+const defaultMaxPins = 4096
+
+// pinStore holds the commit that each ref was first resolved to when
+// pin-on-first-resolve is configured. Pins are kept per requesting
+// namespace, so that one team repinning a ref doesn't move it for
+// others. The least recently used are forgotten once size, or
+// defaultMaxPins, refs are pinned. The zero value is ready to use.
+type pinStore struct {
+	mu      sync.Mutex
+	size    int
+	entries *lru.Cache
+}
+
+// get returns the commit pinned for key, if there is one.
+func (s *pinStore) get(key string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.entries == nil {
+		return "", false
+	}
+	commit, ok := s.entries.Get(key)
+	if !ok {
+		return "", false
+	}
+	return commit.(string), true
+}
+
+// set pins key to commit, forgetting the least recently used pin if
+// the store is full.
+func (s *pinStore) set(key, commit string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.entries == nil {
+		size := s.size
+		if size <= 0 {
+			size = defaultMaxPins
+		}
+		entries, err := lru.New(size)
+		if err != nil {
+			return
+		}
+		s.entries = entries
+	}
+	s.entries.Add(key, commit)
+}
+
+// pinnedRef is a request for a ref that pinning applies to.
+type pinnedRef struct {
+	key string
+	ref string
+}
+
+// pinRef returns the ref that params resolve to be pinned, if
+// pin-on-first-resolve is configured and params name a branch, a tag, a
+// locator ref that isn't a commit, or the default branch. If the ref
+// is pinned by the request's pinned-commit annotation or was pinned by
+// an earlier resolution, and isn't being repinned, the returned params
+// resolve the pinned commit in its place.
+func (r *Resolver) pinRef(ctx context.Context, params map[string]string) (map[string]string, *pinnedRef, error) {
+	conf := framework.GetResolverConfigFromContext(ctx)
+	if pin, _ := strconv.ParseBool(conf[ConfigFieldPinOnFirstResolve]); !pin || params[CommitParam] != "" || params[DateParam] != "" || params[RefSpecParam] != "" || isReport(params) {
+		return params, nil, nil
+	}
+	url, ref, path := params[URLParam], params[BranchParam], params[PathParam]
+	if tag := params[TagParam]; tag != "" {
+		ref = tag
+	}
+	if revision := params[RevisionParam]; revision != "" {
+		if isFullCommitHash(revision) {
+			return params, nil, nil
+		}
+		ref = revision
+	}
+	if locatorString := params[LocatorParam]; locatorString != "" {
+		loc, err := parseLocator(locatorString)
+		if err != nil {
+			return nil, nil, err
+		}
+		if isFullCommitHash(loc.Ref) {
+			return params, nil, nil
+		}
+		url, ref, path = loc.URL, loc.Ref, loc.Path
+	}
+
+	pinned := &pinnedRef{
+		key: resolutioncommon.RequestNamespace(ctx) + "\x00" + canonicalRepoURL(url) + "\x00" + ref,
+		ref: ref,
+	}
+	annotations := resolutioncommon.RequestAnnotations(ctx)
+	if repin, _ := strconv.ParseBool(annotations[AnnotationKeyRepin]); repin {
+		return params, pinned, nil
+	}
+	commit, ok := annotations[AnnotationKeyPinnedCommit]
+	if ok && !isFullCommitHash(commit) {
+		return nil, nil, fmt.Errorf("invalid %s annotation %q: must be a full commit hash", AnnotationKeyPinnedCommit, commit)
+	}
+	if !ok {
+		commit, ok = r.pins.get(pinned.key)
+	}
+	if !ok {
+		return params, pinned, nil
+	}
+	atCommit := make(map[string]string, len(params))
+	for k, v := range params {
+		if k != LocatorParam && k != BranchParam && k != TagParam && k != RevisionParam {
+			atCommit[k] = v
+		}
+	}
+	atCommit[URLParam], atCommit[PathParam], atCommit[CommitParam] = url, path, commit
+	return atCommit, pinned, nil
+}
+
+// recordPin pins the ref to the commit it was resolved to and marks
+// resolved as pinned, so that the commit is recorded in the request's
+// status along with the pinned annotation.
+func (r *Resolver) recordPin(pinned *pinnedRef, resolved *ResolvedGitResource) {
+	r.pins.set(pinned.key, resolved.Commit)
+	resolved.Ref, resolved.Pinned = pinned.ref, true
+}
@@ -0,0 +1,165 @@
+package git
+
+import (
+	"context"
+	"testing"
+
+	resolutioncommon "github.com/tektoncd/resolution/pkg/common"
+	"github.com/tektoncd/resolution/pkg/resolver/framework"
+)
+
+func TestResolvePinOnFirstResolve(t *testing.T) {
+	repoDir, commits := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "first"},
+	}, {
+		files: map[string]string{"task.yaml": "second"},
+	}, {
+		files: map[string]string{"task.yaml": "third"},
+	}})
+	setTestRef(t, repoDir, "refs/heads/feature", commits[0])
+
+	resolver := &Resolver{}
+	resolve := func(t *testing.T, namespace string, annotations map[string]string, params map[string]string, expectedCommit, expectedContent string) {
+		t.Helper()
+		ctx := framework.InjectResolverConfigToContext(context.Background(), map[string]string{
+			ConfigFieldPinOnFirstResolve: "true",
+		})
+		ctx = resolutioncommon.InjectRequestNamespace(ctx, namespace)
+		ctx = resolutioncommon.InjectRequestAnnotations(ctx, annotations)
+		resource, err := resolver.Resolve(ctx, params)
+		if err != nil {
+			t.Fatalf("unexpected error resolving: %v", err)
+		}
+		if string(resource.Data()) != expectedContent {
+			t.Errorf("expected content %q received %q", expectedContent, resource.Data())
+		}
+		resolved := resource.Annotations()
+		if resolved[AnnotationKeyCommitHash] != expectedCommit {
+			t.Errorf("expected commit %q received %q", expectedCommit, resolved[AnnotationKeyCommitHash])
+		}
+		if resolved[AnnotationKeyPinned] != "true" {
+			t.Errorf("expected %s annotation to be true, received %q", AnnotationKeyPinned, resolved[AnnotationKeyPinned])
+		}
+	}
+	branchParams := map[string]string{
+		URLParam:    repoDir,
+		BranchParam: "feature",
+		PathParam:   "task.yaml",
+	}
+	locatorParams := map[string]string{
+		LocatorParam: "file://" + repoDir + "@feature//task.yaml",
+	}
+
+	resolve(t, "team-a", nil, branchParams, commits[0], "first")
+	setTestRef(t, repoDir, "refs/heads/feature", commits[1])
+	resolve(t, "team-a", nil, branchParams, commits[0], "first")
+
+	// Other namespaces, and the file:// url that the locator gives, are
+	// pinned separately to where the branch is now.
+	resolve(t, "team-b", nil, branchParams, commits[1], "second")
+	resolve(t, "team-a", nil, locatorParams, commits[1], "second")
+
+	setTestRef(t, repoDir, "refs/heads/feature", commits[2])
+	resolve(t, "team-a", map[string]string{AnnotationKeyRepin: "true"}, branchParams, commits[2], "third")
+	resolve(t, "team-a", nil, branchParams, commits[2], "third")
+	resolve(t, "team-b", nil, branchParams, commits[1], "second")
+	resolve(t, "team-a", nil, locatorParams, commits[1], "second")
+}
+
+func TestResolvePinOnFirstResolveDisabled(t *testing.T) {
+	repoDir, commits := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "first"},
+	}, {
+		files: map[string]string{"task.yaml": "second"},
+	}})
+	setTestRef(t, repoDir, "refs/heads/feature", commits[0])
+
+	resolver := &Resolver{}
+	params := map[string]string{
+		URLParam:    repoDir,
+		BranchParam: "feature",
+		PathParam:   "task.yaml",
+	}
+	if _, err := resolver.Resolve(context.Background(), params); err != nil {
+		t.Fatalf("unexpected error resolving: %v", err)
+	}
+	setTestRef(t, repoDir, "refs/heads/feature", commits[1])
+	resource, err := resolver.Resolve(context.Background(), params)
+	if err != nil {
+		t.Fatalf("unexpected error resolving: %v", err)
+	}
+	if string(resource.Data()) != "second" {
+		t.Errorf("expected the branch's new tip to be resolved, received %q", resource.Data())
+	}
+	if pinned, ok := resource.Annotations()[AnnotationKeyPinned]; ok {
+		t.Errorf("expected no %s annotation, received %q", AnnotationKeyPinned, pinned)
+	}
+}
+
+func TestResolvePinnedCommitAnnotation(t *testing.T) {
+	repoDir, commits := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "first"},
+	}, {
+		files: map[string]string{"task.yaml": "second"},
+	}})
+	setTestRef(t, repoDir, "refs/heads/feature", commits[1])
+	params := map[string]string{
+		URLParam:    repoDir,
+		BranchParam: "feature",
+		PathParam:   "task.yaml",
+	}
+	ctx := framework.InjectResolverConfigToContext(context.Background(), map[string]string{
+		ConfigFieldPinOnFirstResolve: "true",
+	})
+
+	// A new resolver, as after a restart, remembers no pins but
+	// resolves the commit a request is annotated with.
+	resolver := &Resolver{}
+	resource, err := resolver.Resolve(resolutioncommon.InjectRequestAnnotations(ctx, map[string]string{
+		AnnotationKeyPinnedCommit: commits[0],
+	}), params)
+	if err != nil {
+		t.Fatalf("unexpected error resolving: %v", err)
+	}
+	if string(resource.Data()) != "first" || resource.Annotations()[AnnotationKeyCommitHash] != commits[0] {
+		t.Errorf("expected the annotated commit %q to be resolved, received %q at %q", commits[0], resource.Data(), resource.Annotations()[AnnotationKeyCommitHash])
+	}
+	if resource.Annotations()[AnnotationKeyPinned] != "true" {
+		t.Errorf("expected %s annotation to be true, received %q", AnnotationKeyPinned, resource.Annotations()[AnnotationKeyPinned])
+	}
+
+	resource, err = resolver.Resolve(resolutioncommon.InjectRequestAnnotations(ctx, map[string]string{
+		AnnotationKeyPinnedCommit: commits[0],
+		AnnotationKeyRepin:        "true",
+	}), params)
+	if err != nil {
+		t.Fatalf("unexpected error resolving: %v", err)
+	}
+	if string(resource.Data()) != "second" {
+		t.Errorf("expected repinning to resolve the branch's tip, received %q", resource.Data())
+	}
+
+	if _, err := resolver.Resolve(resolutioncommon.InjectRequestAnnotations(ctx, map[string]string{
+		AnnotationKeyPinnedCommit: "feature",
+	}), params); err == nil {
+		t.Errorf("expected error for a %s annotation that isn't a commit", AnnotationKeyPinnedCommit)
+	}
+}
+
+func TestPinStoreEvictsLeastRecentlyUsed(t *testing.T) {
+	store := &pinStore{size: 2}
+	store.set("a", "1")
+	store.set("b", "2")
+	if _, ok := store.get("a"); !ok {
+		t.Fatalf("expected pin for a")
+	}
+	store.set("c", "3")
+	if _, ok := store.get("b"); ok {
+		t.Errorf("expected the least recently used pin to be forgotten")
+	}
+	for key, expected := range map[string]string{"a": "1", "c": "3"} {
+		if commit, ok := store.get(key); !ok || commit != expected {
+			t.Errorf("expected pin %s=%s, received %q", key, expected, commit)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// defaultPlaceholderPattern matches ${...} style template placeholders.
+const defaultPlaceholderPattern = `\$\{[^}]*\}`
+
+// getPlaceholderPattern returns the pattern that unresolved template
+// placeholders are found with, as configured with the
+// placeholder-pattern field in the git-resolver-config configmap.
+func getPlaceholderPattern(conf map[string]string) (*regexp.Regexp, error) {
+	pattern := conf[ConfigFieldPlaceholderPattern]
+	if pattern == "" {
+		pattern = defaultPlaceholderPattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s %q: %w", ConfigFieldPlaceholderPattern, pattern, err)
+	}
+	return re, nil
+}
+
+// checkPlaceholders fails if reject-unresolved-placeholders is enabled
+// and the resolved content still contains a template placeholder, e.g.
+// because the file was meant to be rendered.
+func checkPlaceholders(resolved *ResolvedGitResource, conf map[string]string) error {
+	if reject, _ := strconv.ParseBool(conf[ConfigFieldRejectUnresolvedPlaceholders]); !reject {
+		return nil
+	}
+	re, err := getPlaceholderPattern(conf)
+	if err != nil {
+		return err
+	}
+	if match := re.Find(resolved.Content); match != nil {
+		return fmt.Errorf("resolved content contains unresolved placeholders: found %q in %q", match, resolved.Path)
+	}
+	return nil
+}
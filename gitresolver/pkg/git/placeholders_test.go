@@ -0,0 +1,108 @@
+package git
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/tektoncd/resolution/pkg/resolver/framework"
+)
+
+func TestResolveRejectUnresolvedPlaceholders(t *testing.T) {
+	repoDir, _ := createTestRepo(t, []testCommit{{
+		files: map[string]string{
+			"placeholders.yaml": "image: ${IMAGE}\n",
+			"braces.yaml":       "image: {{ .Image }}\n",
+			"resolved.yaml":     "image: golang\nscript: echo $(params.name)\n",
+		},
+	}})
+
+	for _, tc := range []struct {
+		name      string
+		path      string
+		conf      map[string]string
+		expectErr bool
+	}{{
+		name:      "placeholder",
+		path:      "placeholders.yaml",
+		conf:      map[string]string{ConfigFieldRejectUnresolvedPlaceholders: "true"},
+		expectErr: true,
+	}, {
+		name: "no placeholder",
+		path: "resolved.yaml",
+		conf: map[string]string{ConfigFieldRejectUnresolvedPlaceholders: "true"},
+	}, {
+		name: "disabled",
+		path: "placeholders.yaml",
+		conf: map[string]string{},
+	}, {
+		name: "custom pattern",
+		path: "braces.yaml",
+		conf: map[string]string{
+			ConfigFieldRejectUnresolvedPlaceholders: "true",
+			ConfigFieldPlaceholderPattern:           `\{\{[^}]*\}\}`,
+		},
+		expectErr: true,
+	}, {
+		name: "custom pattern ignores default placeholders",
+		path: "placeholders.yaml",
+		conf: map[string]string{
+			ConfigFieldRejectUnresolvedPlaceholders: "true",
+			ConfigFieldPlaceholderPattern:           `\{\{[^}]*\}\}`,
+		},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			resolver := &Resolver{}
+			ctx := framework.InjectResolverConfigToContext(context.Background(), tc.conf)
+			_, err := resolver.Resolve(ctx, map[string]string{
+				URLParam:  repoDir,
+				PathParam: tc.path,
+			})
+			if tc.expectErr {
+				if err == nil || !strings.Contains(err.Error(), "resolved content contains unresolved placeholders") {
+					t.Errorf("expected unresolved placeholders error, received %v", err)
+				}
+			} else if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestResolveRejectUnresolvedPlaceholdersAfterRender(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		rendered  string
+		expectErr bool
+	}{{
+		name:     "rendered",
+		rendered: "greeting: hello",
+	}, {
+		name:      "left unrendered",
+		rendered:  "greeting: ${greeting}",
+		expectErr: true,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			stubYTT(t, "echo '"+tc.rendered+"'\n")
+			resolver, ctx, params := setupRenderTest(t)
+			ctx = framework.InjectResolverConfigToContext(ctx, map[string]string{
+				ConfigFieldRenderer:                     RendererYTT,
+				ConfigFieldRejectUnresolvedPlaceholders: "true",
+			})
+			_, err := resolver.Resolve(ctx, params)
+			if tc.expectErr {
+				if err == nil || !strings.Contains(err.Error(), "resolved content contains unresolved placeholders") {
+					t.Errorf("expected unresolved placeholders error, received %v", err)
+				}
+			} else if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestGetPlaceholderPatternInvalid(t *testing.T) {
+	if _, err := getPlaceholderPattern(map[string]string{ConfigFieldPlaceholderPattern: "("}); err == nil {
+		t.Errorf("expected error for invalid placeholder pattern")
+	}
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/tektoncd/resolution/pkg/resolver/framework"
+	"golang.org/x/net/http/httpproxy"
+)
+
+// checkProxyConfig returns an error if http-proxy is set to something
+// other than the url of an http, https or socks5 proxy.
+func checkProxyConfig(conf map[string]string) error {
+	proxy := conf[ConfigFieldHTTPProxy]
+	if proxy == "" {
+		return nil
+	}
+	u, err := url.Parse(proxy)
+	if err != nil || u.Host == "" || u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "socks5" {
+		return fmt.Errorf("invalid %s %q: must be an http, https or socks5 url", ConfigFieldHTTPProxy, proxy)
+	}
+	return nil
+}
+
+// requestProxy picks the proxy for req. With http-proxy or no-proxy in
+// the resolver's config, they take the place of the HTTP_PROXY and
+// HTTPS_PROXY or NO_PROXY environment variables respectively. Otherwise
+// proxyFunc picks it.
+func requestProxy(req *http.Request) (*url.URL, error) {
+	conf := framework.GetResolverConfigFromContext(req.Context())
+	if conf[ConfigFieldHTTPProxy] == "" && conf[ConfigFieldNoProxy] == "" {
+		return proxyFunc(req)
+	}
+	return proxyConfig(conf).ProxyFunc()(req.URL)
+}
+
+// proxyConfig returns the proxy settings from the environment with any
+// from the resolver's config in their place.
+func proxyConfig(conf map[string]string) *httpproxy.Config {
+	proxyConf := httpproxy.FromEnvironment()
+	if proxy := conf[ConfigFieldHTTPProxy]; proxy != "" {
+		proxyConf.HTTPProxy, proxyConf.HTTPSProxy = proxy, proxy
+	}
+	if noProxy := conf[ConfigFieldNoProxy]; noProxy != "" {
+		proxyConf.NoProxy = noProxy
+	}
+	return proxyConf
+}
+
+// proxyEnv returns the environment variables that point the git binary
+// at the proxies from the resolver's config, if any. Both cases are
+// set since curl reads http_proxy only in lower case and prefers the
+// lower case form of the others.
+func proxyEnv(conf map[string]string) []string {
+	if conf[ConfigFieldHTTPProxy] == "" && conf[ConfigFieldNoProxy] == "" {
+		return nil
+	}
+	proxyConf := proxyConfig(conf)
+	env := []string{}
+	for name, value := range map[string]string{
+		"http_proxy":  proxyConf.HTTPProxy,
+		"https_proxy": proxyConf.HTTPSProxy,
+		"no_proxy":    proxyConf.NoProxy,
+	} {
+		env = append(env, name+"="+value, strings.ToUpper(name)+"="+value)
+	}
+	return env
+}
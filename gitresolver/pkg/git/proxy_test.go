@@ -0,0 +1,201 @@
+package git
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/tektoncd/resolution/pkg/resolver/framework"
+)
+
+// newStubProxy returns the url of an http proxy that forwards every
+// request it's sent to target, along with the number of requests it has
+// forwarded.
+func newStubProxy(t *testing.T, target string) (string, *int32) {
+	t.Helper()
+	targetURL, err := url.Parse(target)
+	if err != nil {
+		t.Fatalf("error parsing proxy target: %v", err)
+	}
+	var proxied int32
+	forward := httputil.NewSingleHostReverseProxy(targetURL)
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&proxied, 1)
+		forward.ServeHTTP(w, r)
+	}))
+	t.Cleanup(proxy.Close)
+	return proxy.URL, &proxied
+}
+
+func TestResolveThroughConfiguredProxy(t *testing.T) {
+	installTransports()
+	repoDir, _ := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "via proxy"},
+	}})
+	gitServer := newGitHTTPServer(t, repoDir, nil)
+	proxyURL, proxied := newStubProxy(t, gitServer.URL)
+
+	for _, tc := range []struct {
+		name        string
+		url         string
+		noProxy     string
+		expectProxy bool
+	}{{
+		name:        "external host",
+		url:         "http://git.example.com/repo",
+		noProxy:     "git.internal.invalid",
+		expectProxy: true,
+	}, {
+		name:    "excluded host",
+		url:     "http://git.internal.invalid/repo",
+		noProxy: "git.internal.invalid",
+	}, {
+		name:    "excluded domain",
+		url:     "http://git.internal.invalid/repo",
+		noProxy: ".internal.invalid",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			atomic.StoreInt32(proxied, 0)
+			ctx := framework.InjectResolverConfigToContext(context.Background(), map[string]string{
+				ConfigFieldHTTPProxy:      proxyURL,
+				ConfigFieldNoProxy:        tc.noProxy,
+				ConfigFieldConnectTimeout: "1s",
+			})
+			resolver := Resolver{}
+			resource, err := resolver.Resolve(ctx, map[string]string{
+				URLParam:  tc.url,
+				PathParam: "task.yaml",
+			})
+			if !tc.expectProxy {
+				// The excluded host doesn't exist, so only a request
+				// sent through the proxy could succeed.
+				if err == nil {
+					t.Fatalf("expected resolving from an excluded host to bypass the proxy")
+				}
+				if got := atomic.LoadInt32(proxied); got != 0 {
+					t.Errorf("expected no requests through the proxy, received %d", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error resolving through proxy: %v", err)
+			}
+			if string(resource.Data()) != "via proxy" {
+				t.Errorf("expected content %q received %q", "via proxy", string(resource.Data()))
+			}
+			if atomic.LoadInt32(proxied) == 0 {
+				t.Errorf("expected requests to be sent through the proxy")
+			}
+		})
+	}
+}
+
+func TestRequestProxy(t *testing.T) {
+	t.Setenv("HTTP_PROXY", "http://env-proxy:3128")
+	t.Setenv("HTTPS_PROXY", "http://env-proxy:3128")
+	t.Setenv("NO_PROXY", "env-internal.example.com")
+	original := proxyFunc
+	proxyFunc = func(*http.Request) (*url.URL, error) { return url.Parse("http://default-proxy:3128") }
+	defer func() { proxyFunc = original }()
+
+	for _, tc := range []struct {
+		name     string
+		conf     map[string]string
+		url      string
+		expected string
+	}{{
+		name:     "unconfigured",
+		conf:     map[string]string{},
+		url:      "https://github.com/tektoncd/catalog",
+		expected: "http://default-proxy:3128",
+	}, {
+		name:     "configured proxy",
+		conf:     map[string]string{ConfigFieldHTTPProxy: "http://proxy.example.com:8080"},
+		url:      "https://github.com/tektoncd/catalog",
+		expected: "http://proxy.example.com:8080",
+	}, {
+		name:     "configured proxy for http",
+		conf:     map[string]string{ConfigFieldHTTPProxy: "http://proxy.example.com:8080"},
+		url:      "http://git.example.com/repo",
+		expected: "http://proxy.example.com:8080",
+	}, {
+		name: "configured no proxy",
+		conf: map[string]string{ConfigFieldHTTPProxy: "http://proxy.example.com:8080", ConfigFieldNoProxy: "git.example.com"},
+		url:  "https://git.example.com/repo",
+	}, {
+		name: "configured no proxy domain",
+		conf: map[string]string{ConfigFieldHTTPProxy: "http://proxy.example.com:8080", ConfigFieldNoProxy: ".example.com"},
+		url:  "https://git.example.com/repo",
+	}, {
+		name:     "configured no proxy with environment proxy",
+		conf:     map[string]string{ConfigFieldNoProxy: "git.example.com"},
+		url:      "https://github.com/tektoncd/catalog",
+		expected: "http://env-proxy:3128",
+	}, {
+		name:     "configured no proxy replaces environment",
+		conf:     map[string]string{ConfigFieldNoProxy: "git.example.com"},
+		url:      "https://env-internal.example.com/repo",
+		expected: "http://env-proxy:3128",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequestWithContext(framework.InjectResolverConfigToContext(context.Background(), tc.conf), http.MethodGet, tc.url, nil)
+			if err != nil {
+				t.Fatalf("error creating request: %v", err)
+			}
+			proxy, err := requestProxy(req)
+			if err != nil {
+				t.Fatalf("unexpected error picking proxy: %v", err)
+			}
+			got := ""
+			if proxy != nil {
+				got = proxy.String()
+			}
+			if got != tc.expected {
+				t.Errorf("expected proxy %q received %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestCheckProxyConfig(t *testing.T) {
+	for proxy, valid := range map[string]bool{
+		"":                              true,
+		"http://proxy.example.com:3128": true,
+		"socks5://proxy.example.com":    true,
+		"proxy.example.com:3128":        false,
+		"ftp://proxy.example.com":       false,
+	} {
+		err := checkProxyConfig(map[string]string{ConfigFieldHTTPProxy: proxy})
+		if valid && err != nil {
+			t.Errorf("unexpected error for %q: %v", proxy, err)
+		}
+		if !valid && (err == nil || !strings.Contains(err.Error(), "invalid http-proxy")) {
+			t.Errorf("expected invalid http-proxy error for %q, received %v", proxy, err)
+		}
+	}
+}
+
+func TestProxyEnv(t *testing.T) {
+	if env := proxyEnv(map[string]string{}); env != nil {
+		t.Errorf("expected no proxy environment when unconfigured, received %v", env)
+	}
+	env := strings.Join(proxyEnv(map[string]string{
+		ConfigFieldHTTPProxy: "http://proxy.example.com:3128",
+		ConfigFieldNoProxy:   "git.internal.example.com",
+	}), " ")
+	for _, expected := range []string{
+		"http_proxy=http://proxy.example.com:3128",
+		"HTTPS_PROXY=http://proxy.example.com:3128",
+		"no_proxy=git.internal.example.com",
+		"NO_PROXY=git.internal.example.com",
+	} {
+		if !strings.Contains(env, expected) {
+			t.Errorf("expected %s in the git binary's environment, received %s", expected, env)
+		}
+	}
+}
@@ -0,0 +1,263 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/system"
+)
+
+// knownHostsKey is the key of the jump host secret that holds the
+// known_hosts entries used to verify the jump host and git servers.
+const knownHostsKey = "known_hosts"
+
+// jumpDialer opens connections to other hosts from a jump host.
+// *ssh.Client satisfies it.
+type jumpDialer interface {
+	Dial(network, addr string) (net.Conn, error)
+	Close() error
+}
+
+// dialJumpHost connects to the jump host at addr. It is a variable so
+// that tests can stub out the ssh connection.
+var dialJumpHost = func(addr string, config *ssh.ClientConfig) (jumpDialer, error) {
+	return ssh.Dial("tcp", addr, config)
+}
+
+// proxyJump routes ssh urls through the jump host configured with
+// ssh-proxy-jump. It returns the url to fetch from, pointing at a local
+// end of a tunnel to the git server, along with the auth to use and a
+// func that closes the tunnel. Urls are returned unchanged if no jump
+// host is configured or they don't use ssh.
+func (r *Resolver) proxyJump(ctx context.Context, url string, conf map[string]string) (string, transport.AuthMethod, func(), error) {
+	jump := conf[ConfigFieldSSHProxyJump]
+	if jump == "" {
+		return url, nil, func() {}, nil
+	}
+	ep, err := transport.NewEndpoint(url)
+	if err != nil || ep.Protocol != "ssh" {
+		return url, nil, func() {}, nil
+	}
+
+	jumpUser, jumpAddr, err := parseJumpHost(jump)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	signer, hostKeyCallback, err := r.jumpHostCredentials(ctx, conf)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	jumpConfig := &ssh.ClientConfig{
+		User:            jumpUser,
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         getConnectTimeout(ctx),
+	}
+	auth := &gitssh.PublicKeys{
+		User:   ep.User,
+		Signer: signer,
+		HostKeyCallbackHelper: gitssh.HostKeyCallbackHelper{
+			HostKeyCallback: hostKeyCallback,
+		},
+	}
+
+	port := ep.Port
+	if port <= 0 {
+		port = gitssh.DefaultPort
+	}
+	target := net.JoinHostPort(ep.Host, strconv.Itoa(port))
+
+	dialer, err := dialJumpHost(jumpAddr, jumpConfig)
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("error connecting to ssh jump host %q: %w", jumpAddr, err)
+	}
+	tunnel, err := openTunnel(dialer, target)
+	if err != nil {
+		_ = dialer.Close()
+		return "", nil, nil, err
+	}
+
+	host, tunnelPort, _ := net.SplitHostPort(tunnel.addr())
+	ep.Host = host
+	ep.Port, _ = strconv.Atoi(tunnelPort)
+	return ep.String(), &tunnelAuth{AuthMethod: auth, target: target}, tunnel.close, nil
+}
+
+// jumpHostCredentials reads the private key and known_hosts from the
+// secret named by ssh-proxy-jump-secret in the resolver's namespace.
+// They are used for both the jump host and the git server behind it.
+func (r *Resolver) jumpHostCredentials(ctx context.Context, conf map[string]string) (ssh.Signer, ssh.HostKeyCallback, error) {
+	name := conf[ConfigFieldSSHProxyJumpSecret]
+	if name == "" {
+		return nil, nil, fmt.Errorf("%s requires %s to be set", ConfigFieldSSHProxyJump, ConfigFieldSSHProxyJumpSecret)
+	}
+	namespace := system.Namespace()
+	secret, err := r.kubeClientSet.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("error reading ssh jump host secret %s/%s: %w", namespace, name, err)
+	}
+	key, ok := secret.Data[corev1.SSHAuthPrivateKey]
+	if !ok {
+		return nil, nil, fmt.Errorf("ssh jump host secret %s/%s has no %q key", namespace, name, corev1.SSHAuthPrivateKey)
+	}
+	hosts, ok := secret.Data[knownHostsKey]
+	if !ok {
+		return nil, nil, fmt.Errorf("ssh jump host secret %s/%s has no %q key", namespace, name, knownHostsKey)
+	}
+	signer, err := ssh.ParsePrivateKey(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error parsing ssh jump host private key: %w", err)
+	}
+	hostKeyCallback, err := knownHostsCallback(hosts)
+	if err != nil {
+		return nil, nil, err
+	}
+	return signer, hostKeyCallback, nil
+}
+
+// knownHostsCallback returns a host key callback that accepts the hosts
+// listed in the given known_hosts data.
+func knownHostsCallback(hosts []byte) (ssh.HostKeyCallback, error) {
+	f, err := os.CreateTemp("", "known_hosts-")
+	if err != nil {
+		return nil, fmt.Errorf("error writing known_hosts: %w", err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	if _, err := f.Write(hosts); err != nil {
+		return nil, fmt.Errorf("error writing known_hosts: %w", err)
+	}
+	callback, err := knownhosts.New(f.Name())
+	if err != nil {
+		return nil, fmt.Errorf("error parsing known_hosts: %w", err)
+	}
+	return callback, nil
+}
+
+// parseJumpHost splits a jump host written as user@host[:port] into its
+// user and address, defaulting to the standard ssh port.
+func parseJumpHost(jump string) (string, string, error) {
+	at := strings.LastIndex(jump, "@")
+	if at <= 0 || at == len(jump)-1 {
+		return "", "", fmt.Errorf("invalid %s %q: expected user@host[:port]", ConfigFieldSSHProxyJump, jump)
+	}
+	user, hostPort := jump[:at], jump[at+1:]
+	if _, _, err := net.SplitHostPort(hostPort); err != nil {
+		hostPort = net.JoinHostPort(hostPort, strconv.Itoa(gitssh.DefaultPort))
+	}
+	return user, hostPort, nil
+}
+
+// tunnelAuth wraps the auth for a git server reached through a tunnel
+// so that the server's host key is checked against its own address
+// rather than the local end of the tunnel.
+type tunnelAuth struct {
+	gitssh.AuthMethod
+	target string
+}
+
+// ClientConfig returns the wrapped auth's ssh client config with its
+// host key callback pointed at the tunnel's target.
+func (a *tunnelAuth) ClientConfig() (*ssh.ClientConfig, error) {
+	config, err := a.AuthMethod.ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	if callback := config.HostKeyCallback; callback != nil {
+		config.HostKeyCallback = func(_ string, remote net.Addr, key ssh.PublicKey) error {
+			return callback(a.target, remote, key)
+		}
+	}
+	return config, nil
+}
+
+// sshTunnel forwards connections accepted on a local listener to a
+// target address through a jump host.
+type sshTunnel struct {
+	listener net.Listener
+	dialer   jumpDialer
+	target   string
+
+	closeOnce sync.Once
+}
+
+// openTunnel starts forwarding connections from a local port to target
+// through the given jump host.
+func openTunnel(dialer jumpDialer, target string) (*sshTunnel, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("error opening ssh tunnel: %w", err)
+	}
+	t := &sshTunnel{listener: listener, dialer: dialer, target: target}
+	go t.serve()
+	return t, nil
+}
+
+func (t *sshTunnel) addr() string {
+	return t.listener.Addr().String()
+}
+
+func (t *sshTunnel) serve() {
+	for {
+		local, err := t.listener.Accept()
+		if err != nil {
+			return
+		}
+		go t.forward(local)
+	}
+}
+
+func (t *sshTunnel) forward(local net.Conn) {
+	defer local.Close()
+	remote, err := t.dialer.Dial("tcp", t.target)
+	if err != nil {
+		return
+	}
+	defer remote.Close()
+	done := make(chan struct{}, 2)
+	go func() {
+		_, _ = io.Copy(remote, local)
+		done <- struct{}{}
+	}()
+	go func() {
+		_, _ = io.Copy(local, remote)
+		done <- struct{}{}
+	}()
+	<-done
+}
+
+// close stops accepting connections and disconnects from the jump host.
+func (t *sshTunnel) close() {
+	t.closeOnce.Do(func() {
+		_ = t.listener.Close()
+		_ = t.dialer.Close()
+	})
+}
@@ -0,0 +1,262 @@
+package git
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/tektoncd/resolution/pkg/resolver/framework"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"knative.dev/pkg/system"
+)
+
+// stubJumpDialer records the addresses it is asked to dial and hands
+// back the result of its dial func.
+type stubJumpDialer struct {
+	mu     sync.Mutex
+	dialed []string
+	closed bool
+	dial   func() (net.Conn, error)
+}
+
+func (d *stubJumpDialer) Dial(network, addr string) (net.Conn, error) {
+	d.mu.Lock()
+	d.dialed = append(d.dialed, addr)
+	d.mu.Unlock()
+	return d.dial()
+}
+
+func (d *stubJumpDialer) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.closed = true
+	return nil
+}
+
+func (d *stubJumpDialer) dialedAddrs() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]string{}, d.dialed...)
+}
+
+// setupProxyJumpTest stubs out the jump host connection and returns a
+// resolver whose fake kube client holds the jump host secret, along with
+// config enabling the jump host. The address and config that the jump
+// host was dialed with are written to the returned pointers.
+func setupProxyJumpTest(t *testing.T, dialer *stubJumpDialer) (*Resolver, map[string]string, *string, **ssh.ClientConfig) {
+	t.Helper()
+	t.Setenv(system.NamespaceEnvKey, "tekton-remote-resolution")
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("error generating key: %v", err)
+	}
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("error marshalling key: %v", err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatalf("error creating signer: %v", err)
+	}
+	resolver := &Resolver{
+		kubeClientSet: fake.NewSimpleClientset(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "bastion", Namespace: "tekton-remote-resolution"},
+			Data: map[string][]byte{
+				corev1.SSHAuthPrivateKey: pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}),
+				knownHostsKey:            []byte(knownhosts.Line([]string{"bastion.example.com"}, signer.PublicKey()) + "\n"),
+			},
+		}),
+	}
+	conf := map[string]string{
+		ConfigFieldSSHProxyJump:       "jumper@bastion.example.com",
+		ConfigFieldSSHProxyJumpSecret: "bastion",
+	}
+
+	var dialedAddr string
+	var dialedConfig *ssh.ClientConfig
+	original := dialJumpHost
+	dialJumpHost = func(addr string, config *ssh.ClientConfig) (jumpDialer, error) {
+		dialedAddr, dialedConfig = addr, config
+		return dialer, nil
+	}
+	t.Cleanup(func() { dialJumpHost = original })
+	return resolver, conf, &dialedAddr, &dialedConfig
+}
+
+func TestProxyJumpRoutesThroughJumpHost(t *testing.T) {
+	server, client := net.Pipe()
+	go func() {
+		defer server.Close()
+		buf := make([]byte, 4)
+		if _, err := io.ReadFull(server, buf); err == nil {
+			_, _ = server.Write(buf)
+		}
+	}()
+	dialer := &stubJumpDialer{dial: func() (net.Conn, error) { return client, nil }}
+	resolver, conf, dialedAddr, dialedConfig := setupProxyJumpTest(t, dialer)
+
+	url, auth, closeTunnel, err := resolver.proxyJump(context.Background(), "git@git.example.com:org/repo.git", conf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if *dialedAddr != "bastion.example.com:22" {
+		t.Errorf("expected jump host to be dialed at %q received %q", "bastion.example.com:22", *dialedAddr)
+	}
+	if (*dialedConfig).User != "jumper" {
+		t.Errorf("expected jump host user %q received %q", "jumper", (*dialedConfig).User)
+	}
+	if auth == nil {
+		t.Errorf("expected auth for the git server")
+	}
+	if !strings.HasPrefix(url, "ssh://git@127.0.0.1:") || !strings.HasSuffix(url, "/org/repo.git") {
+		t.Fatalf("expected url pointing at local tunnel, received %q", url)
+	}
+
+	addr := strings.TrimSuffix(strings.TrimPrefix(url, "ssh://git@"), "/org/repo.git")
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("error dialing tunnel: %v", err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte("ping")); err != nil {
+		t.Fatalf("error writing to tunnel: %v", err)
+	}
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		t.Fatalf("error reading from tunnel: %v", err)
+	}
+	if string(buf) != "ping" {
+		t.Errorf("expected data to be forwarded through tunnel, received %q", string(buf))
+	}
+	if dialed := dialer.dialedAddrs(); len(dialed) != 1 || dialed[0] != "git.example.com:22" {
+		t.Errorf("expected jump host to dial git server, received %v", dialed)
+	}
+
+	closeTunnel()
+	if !dialer.closed {
+		t.Errorf("expected jump host connection to be closed")
+	}
+}
+
+func TestResolveThroughJumpHost(t *testing.T) {
+	dialer := &stubJumpDialer{dial: func() (net.Conn, error) { return nil, errors.New("connection refused") }}
+	resolver, conf, dialedAddr, _ := setupProxyJumpTest(t, dialer)
+	ctx := framework.InjectResolverConfigToContext(context.Background(), conf)
+
+	_, err := resolver.Resolve(ctx, map[string]string{
+		URLParam:  "ssh://git@git.example.com:2222/org/repo.git",
+		PathParam: "task.yaml",
+	})
+	if err == nil {
+		t.Fatalf("expected error from unreachable git server")
+	}
+	if *dialedAddr != "bastion.example.com:22" {
+		t.Errorf("expected jump host to be dialed, received %q", *dialedAddr)
+	}
+	if dialed := dialer.dialedAddrs(); len(dialed) == 0 || dialed[0] != "git.example.com:2222" {
+		t.Errorf("expected git server to be dialed through jump host, received %v", dialed)
+	}
+}
+
+func TestResolveThroughJumpHostCloneCache(t *testing.T) {
+	installTransports()
+	repoDir, _ := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "kind: Task"},
+	}})
+	server := newSSHGitServer(t)
+	dialer := &stubJumpDialer{dial: func() (net.Conn, error) { return net.Dial("tcp", server.addr) }}
+	resolver, conf, _, _ := setupProxyJumpTest(t, dialer)
+	// The git server behind the jump host is trusted with the same
+	// known_hosts as the jump host.
+	secrets := resolver.kubeClientSet.CoreV1().Secrets("tekton-remote-resolution")
+	secret, err := secrets.Get(context.Background(), "bastion", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("error reading jump host secret: %v", err)
+	}
+	secret.Data[knownHostsKey] = append(secret.Data[knownHostsKey], knownhosts.Line([]string{"git.example.com:2222"}, server.hostKey)+"\n"...)
+	if _, err := secrets.Update(context.Background(), secret, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("error updating jump host secret: %v", err)
+	}
+	cacheDir := t.TempDir()
+	conf[ConfigFieldCloneCacheDir] = cacheDir
+	ctx := framework.InjectResolverConfigToContext(context.Background(), conf)
+
+	for i := 0; i < 2; i++ {
+		resource, err := resolver.Resolve(ctx, map[string]string{
+			URLParam:  "ssh://git@git.example.com:2222" + repoDir,
+			PathParam: "task.yaml",
+		})
+		if err != nil {
+			t.Fatalf("unexpected error resolving through jump host: %v", err)
+		}
+		if string(resource.Data()) != "kind: Task" {
+			t.Errorf("expected content %q received %q", "kind: Task", string(resource.Data()))
+		}
+	}
+	entries, err := os.ReadDir(cacheDir)
+	if err != nil {
+		t.Fatalf("error reading clone cache: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected resolutions through new tunnels to share one cached clone, found %d", len(entries))
+	}
+}
+
+func TestProxyJumpIgnoresNonSSH(t *testing.T) {
+	dialer := &stubJumpDialer{}
+	resolver, conf, dialedAddr, _ := setupProxyJumpTest(t, dialer)
+	url, auth, _, err := resolver.proxyJump(context.Background(), "https://github.com/tektoncd/resolution", conf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if url != "https://github.com/tektoncd/resolution" || auth != nil || *dialedAddr != "" {
+		t.Errorf("expected https url to be left alone, received %q", url)
+	}
+}
+
+func TestProxyJumpMissingSecret(t *testing.T) {
+	resolver, conf, _, _ := setupProxyJumpTest(t, &stubJumpDialer{})
+	delete(conf, ConfigFieldSSHProxyJumpSecret)
+	if _, _, _, err := resolver.proxyJump(context.Background(), "git@git.example.com:org/repo.git", conf); err == nil {
+		t.Errorf("expected error when jump host secret isn't configured")
+	}
+}
+
+func TestParseJumpHost(t *testing.T) {
+	for _, tc := range []struct {
+		jump string
+		user string
+		addr string
+	}{
+		{jump: "jumper@bastion", user: "jumper", addr: "bastion:22"},
+		{jump: "jumper@bastion:2022", user: "jumper", addr: "bastion:2022"},
+	} {
+		user, addr, err := parseJumpHost(tc.jump)
+		if err != nil {
+			t.Fatalf("unexpected error parsing %q: %v", tc.jump, err)
+		}
+		if user != tc.user || addr != tc.addr {
+			t.Errorf("expected %q %q received %q %q", tc.user, tc.addr, user, addr)
+		}
+	}
+	for _, jump := range []string{"bastion", "@bastion", "jumper@"} {
+		if _, _, err := parseJumpHost(jump); err == nil {
+			t.Errorf("expected error parsing %q", jump)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// pullRequestNumber is the placeholder in pull-request-ref that a pull
+// request's number is substituted into.
+const pullRequestNumber = "{number}"
+
+// defaultPullRequestRef is the ref that GitHub keeps the head of each
+// pull request at. GitLab's equivalent for merge requests is
+// refs/merge-requests/{number}/head.
+const defaultPullRequestRef = "refs/pull/" + pullRequestNumber + "/head"
+
+// validatePullRequest returns an error unless s is a pull request
+// number.
+func validatePullRequest(s string) error {
+	if n, err := strconv.Atoi(s); err != nil || n <= 0 {
+		return fmt.Errorf("invalid value for %q: %q is not a pull request number", PullRequestParam, s)
+	}
+	return nil
+}
+
+// pullRequestRef returns the ref that the head of pull request number
+// is fetched from, following pull-request-ref if it's configured.
+func pullRequestRef(conf map[string]string, number string) (plumbing.ReferenceName, error) {
+	if err := validatePullRequest(number); err != nil {
+		return "", err
+	}
+	pattern := defaultPullRequestRef
+	if v := conf[ConfigFieldPullRequestRef]; v != "" {
+		pattern = v
+	}
+	if !strings.HasPrefix(pattern, "refs/") || !strings.Contains(pattern, pullRequestNumber) {
+		return "", fmt.Errorf("invalid %s %q: must be a ref under refs/ containing %s", ConfigFieldPullRequestRef, pattern, pullRequestNumber)
+	}
+	return plumbing.ReferenceName(strings.ReplaceAll(pattern, pullRequestNumber, number)), nil
+}
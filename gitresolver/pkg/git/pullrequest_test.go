@@ -0,0 +1,135 @@
+package git
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/tektoncd/resolution/pkg/resolver/framework"
+)
+
+func TestResolvePullRequest(t *testing.T) {
+	repoDir, commits := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "merged"},
+	}, {
+		files: map[string]string{"task.yaml": "proposed"},
+	}})
+	// commits[1] is only reachable from the pull request refs after
+	// master is moved back.
+	setTestRef(t, repoDir, "refs/pull/1/head", commits[1])
+	setTestRef(t, repoDir, "refs/merge-requests/1/head", commits[1])
+	setTestRef(t, repoDir, "refs/heads/master", commits[0])
+	server := newGitHTTPServer(t, repoDir, nil)
+
+	for _, tc := range []struct {
+		name string
+		url  string
+		conf map[string]string
+	}{
+		{name: "clone", url: repoDir, conf: map[string]string{}},
+		{name: "minimal fetch", url: repoDir, conf: map[string]string{ConfigFieldMinimalFetch: "true"}},
+		{name: "clone cache", url: repoDir, conf: map[string]string{ConfigFieldCloneCacheDir: t.TempDir()}},
+		{name: "http clone", url: server.URL + "/repo", conf: map[string]string{}},
+		{name: "git cli", url: server.URL + "/repo", conf: map[string]string{ConfigFieldGitProtocolVersion: GitProtocolV2}},
+		{name: "merge request ref", url: repoDir, conf: map[string]string{ConfigFieldPullRequestRef: "refs/merge-requests/{number}/head"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := framework.InjectResolverConfigToContext(context.Background(), tc.conf)
+			params := map[string]string{
+				URLParam:         tc.url,
+				PathParam:        "task.yaml",
+				PullRequestParam: "1",
+			}
+			resolver := Resolver{}
+			if err := resolver.ValidateParams(ctx, params); err != nil {
+				t.Fatalf("unexpected error validating params: %v", err)
+			}
+			resource, err := resolver.Resolve(ctx, params)
+			if err != nil {
+				t.Fatalf("unexpected error resolving pull request: %v", err)
+			}
+			if string(resource.Data()) != "proposed" {
+				t.Errorf("expected content %q received %q", "proposed", string(resource.Data()))
+			}
+			if commit := resource.Annotations()[AnnotationKeyCommitHash]; commit != commits[1] {
+				t.Errorf("expected commit %q received %q", commits[1], commit)
+			}
+		})
+	}
+}
+
+func TestResolveMissingPullRequest(t *testing.T) {
+	repoDir, _ := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "merged"},
+	}})
+
+	resolver := Resolver{}
+	_, err := resolver.Resolve(context.Background(), map[string]string{
+		URLParam:         repoDir,
+		PathParam:        "task.yaml",
+		PullRequestParam: "2",
+	})
+	if err == nil || !isNotFound(err) {
+		t.Fatalf("expected a not found error for a missing pull request, received %v", err)
+	}
+}
+
+func TestValidateParamsPullRequest(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		params    map[string]string
+		conf      map[string]string
+		expectErr string
+	}{{
+		name:   "number",
+		params: map[string]string{PullRequestParam: "42"},
+	}, {
+		name:      "not a number",
+		params:    map[string]string{PullRequestParam: "feature"},
+		expectErr: "is not a pull request number",
+	}, {
+		name:      "zero",
+		params:    map[string]string{PullRequestParam: "0"},
+		expectErr: "is not a pull request number",
+	}, {
+		name:      "with branch",
+		params:    map[string]string{PullRequestParam: "42", BranchParam: "main"},
+		expectErr: `supplied both "branch" and "pullRequest"`,
+	}, {
+		name:      "with commit",
+		params:    map[string]string{PullRequestParam: "42", CommitParam: "abc1234"},
+		expectErr: `supplied both "commit" and "pullRequest"`,
+	}, {
+		name:      "with tag",
+		params:    map[string]string{PullRequestParam: "42", TagParam: "v1"},
+		expectErr: `supplied both "tag" and "pullRequest"`,
+	}, {
+		name:      "ref without number",
+		params:    map[string]string{PullRequestParam: "42"},
+		conf:      map[string]string{ConfigFieldPullRequestRef: "refs/pull/head"},
+		expectErr: "invalid pull-request-ref",
+	}, {
+		name:      "ref outside refs",
+		params:    map[string]string{PullRequestParam: "42"},
+		conf:      map[string]string{ConfigFieldPullRequestRef: "pull/{number}/head"},
+		expectErr: "invalid pull-request-ref",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			params := map[string]string{URLParam: "https://github.com/tektoncd/catalog", PathParam: "task.yaml"}
+			for k, v := range tc.params {
+				params[k] = v
+			}
+			resolver := Resolver{}
+			err := resolver.ValidateParams(framework.InjectResolverConfigToContext(context.Background(), tc.conf), params)
+			if tc.expectErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error validating params: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.expectErr) {
+				t.Fatalf("expected error containing %q, received %v", tc.expectErr, err)
+			}
+		})
+	}
+}
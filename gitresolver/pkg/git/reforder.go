@@ -0,0 +1,96 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"fmt"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+const (
+	// TagOrderBranchesFirst resolves a name that's both a branch and a
+	// tag to the branch.
+	TagOrderBranchesFirst = "branches-first"
+
+	// TagOrderTagsFirst resolves a name that's both a branch and a tag
+	// to the tag.
+	TagOrderTagsFirst = "tags-first"
+)
+
+// The kinds of ref recorded in the ref-type annotation.
+const (
+	RefTypeBranch         = "branch"
+	RefTypeLightweightTag = "lightweight-tag"
+	RefTypeAnnotatedTag   = "annotated-tag"
+	RefTypeCommit         = "commit"
+)
+
+// getTagResolutionOrder returns which of a branch and a tag sharing a
+// name is resolved, as configured with the tag-resolution-order field
+// in the git-resolver-config configmap. Branches win by default.
+func getTagResolutionOrder(conf map[string]string) (string, error) {
+	switch order := conf[ConfigFieldTagResolutionOrder]; order {
+	case "", TagOrderBranchesFirst:
+		return TagOrderBranchesFirst, nil
+	case TagOrderTagsFirst:
+		return order, nil
+	default:
+		return "", fmt.Errorf("invalid %s %q: must be one of %q, %q", ConfigFieldTagResolutionOrder, order, TagOrderBranchesFirst, TagOrderTagsFirst)
+	}
+}
+
+// refCandidates returns the refs that name could refer to, in the order
+// they're tried.
+func refCandidates(name, order string) []plumbing.ReferenceName {
+	branch, tag := plumbing.NewBranchReferenceName(name), plumbing.NewTagReferenceName(name)
+	if order == TagOrderTagsFirst {
+		return []plumbing.ReferenceName{tag, branch}
+	}
+	return []plumbing.ReferenceName{branch, tag}
+}
+
+// refType returns the kind of ref that name is given the hash it points
+// to and the commit that hash peels to. Only annotated tags point to
+// something other than a commit. Refs that are neither a branch nor a
+// tag have no type.
+func refType(name plumbing.ReferenceName, hash plumbing.Hash, commit string) string {
+	switch {
+	case name.IsBranch():
+		return RefTypeBranch
+	case !name.IsTag():
+		return ""
+	case hash.String() == commit:
+		return RefTypeLightweightTag
+	default:
+		return RefTypeAnnotatedTag
+	}
+}
+
+// fetchedRefType returns the kind of ref that was fetched into
+// repository as name.
+func fetchedRefType(repository *git.Repository, name plumbing.ReferenceName, commit string) (string, error) {
+	if !name.IsTag() {
+		return refType(name, plumbing.ZeroHash, commit), nil
+	}
+	ref, err := repository.Reference(name, true)
+	if err != nil {
+		return "", fmt.Errorf("error reading fetched ref %q: %w", name, err)
+	}
+	return refType(name, ref.Hash(), commit), nil
+}
@@ -0,0 +1,229 @@
+package git
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/tektoncd/resolution/pkg/resolver/framework"
+)
+
+// createCollidingRefsRepo makes a repo where "v1" is both a branch and
+// a lightweight tag, and "v2" is both a branch and an annotated tag,
+// each pointing to a different commit.
+func createCollidingRefsRepo(t *testing.T) string {
+	t.Helper()
+	repoDir, commits := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "v1 branch"},
+	}, {
+		files: map[string]string{"task.yaml": "v1 tag"},
+	}, {
+		files: map[string]string{"task.yaml": "v2 branch"},
+	}, {
+		files: map[string]string{"task.yaml": "v2 tag"},
+	}})
+	setTestRef(t, repoDir, "refs/heads/v1", commits[0])
+	setTestRef(t, repoDir, "refs/tags/v1", commits[1])
+	setTestRef(t, repoDir, "refs/heads/v2", commits[2])
+	repo, err := git.PlainOpen(repoDir)
+	if err != nil {
+		t.Fatalf("error opening repo: %v", err)
+	}
+	if _, err := repo.CreateTag("v2", plumbing.NewHash(commits[3]), &git.CreateTagOptions{
+		Tagger:  &object.Signature{Name: "Someone", Email: "someone@example.com", When: time.Now()},
+		Message: "v2",
+	}); err != nil {
+		t.Fatalf("error creating annotated tag: %v", err)
+	}
+	return repoDir
+}
+
+func TestResolveTagResolutionOrder(t *testing.T) {
+	repoDir := createCollidingRefsRepo(t)
+
+	for _, fetch := range []struct {
+		name string
+		conf map[string]string
+	}{{
+		name: "clone",
+		conf: map[string]string{},
+	}, {
+		name: "minimal fetch",
+		conf: map[string]string{ConfigFieldMinimalFetch: "true"},
+	}, {
+		name: "git cli",
+		conf: map[string]string{ConfigFieldGitProtocolVersion: GitProtocolV2},
+	}, {
+		name: "cache",
+		conf: map[string]string{ConfigFieldCacheSize: "10"},
+	}} {
+		for _, tc := range []struct {
+			order           string
+			ref             string
+			expectedContent string
+			expectedType    string
+		}{{
+			order:           "",
+			ref:             "v1",
+			expectedContent: "v1 branch",
+			expectedType:    RefTypeBranch,
+		}, {
+			order:           TagOrderBranchesFirst,
+			ref:             "v2",
+			expectedContent: "v2 branch",
+			expectedType:    RefTypeBranch,
+		}, {
+			order:           TagOrderTagsFirst,
+			ref:             "v1",
+			expectedContent: "v1 tag",
+			expectedType:    RefTypeLightweightTag,
+		}, {
+			order:           TagOrderTagsFirst,
+			ref:             "v2",
+			expectedContent: "v2 tag",
+			expectedType:    RefTypeAnnotatedTag,
+		}} {
+			t.Run(fetch.name+"/"+tc.order+"/"+tc.ref, func(t *testing.T) {
+				conf := map[string]string{ConfigFieldTagResolutionOrder: tc.order}
+				for k, v := range fetch.conf {
+					conf[k] = v
+				}
+				ctx := framework.InjectResolverConfigToContext(context.Background(), conf)
+				resolver := Resolver{}
+				// Resolving twice checks both sides of the cache.
+				for i := 0; i < 2; i++ {
+					resource, err := resolver.Resolve(ctx, map[string]string{
+						LocatorParam: "file://" + repoDir + "@" + tc.ref + "//task.yaml",
+					})
+					if err != nil {
+						t.Fatalf("unexpected error resolving: %v", err)
+					}
+					if string(resource.Data()) != tc.expectedContent {
+						t.Errorf("expected content %q received %q", tc.expectedContent, resource.Data())
+					}
+					if refType := resource.Annotations()[AnnotationKeyRefType]; refType != tc.expectedType {
+						t.Errorf("expected ref type %q received %q", tc.expectedType, refType)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestResolveRefTypeForCommit(t *testing.T) {
+	repoDir, commits := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "one"},
+	}})
+	resolver := Resolver{}
+	resource, err := resolver.Resolve(context.Background(), map[string]string{
+		URLParam:    repoDir,
+		CommitParam: commits[0],
+		PathParam:   "task.yaml",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error resolving: %v", err)
+	}
+	if refType := resource.Annotations()[AnnotationKeyRefType]; refType != RefTypeCommit {
+		t.Errorf("expected ref type %q when resolving a commit, received %q", RefTypeCommit, refType)
+	}
+}
+
+func TestGetTagResolutionOrderInvalid(t *testing.T) {
+	_, err := getTagResolutionOrder(map[string]string{ConfigFieldTagResolutionOrder: "annotated-first"})
+	if err == nil || !strings.Contains(err.Error(), ConfigFieldTagResolutionOrder) {
+		t.Errorf("expected invalid order error, received %v", err)
+	}
+}
+
+func TestResolveTagParam(t *testing.T) {
+	repoDir := createCollidingRefsRepo(t)
+	repo, err := git.PlainOpen(repoDir)
+	if err != nil {
+		t.Fatalf("error opening repo: %v", err)
+	}
+	for _, fetch := range []struct {
+		name string
+		conf map[string]string
+	}{{
+		name: "clone",
+	}, {
+		name: "cache",
+		conf: map[string]string{ConfigFieldCacheSize: "10"},
+	}} {
+		for _, tc := range []struct {
+			tag             string
+			expectedContent string
+			expectedType    string
+		}{{
+			tag:             "v1",
+			expectedContent: "v1 tag",
+			expectedType:    RefTypeLightweightTag,
+		}, {
+			tag:             "v2",
+			expectedContent: "v2 tag",
+			expectedType:    RefTypeAnnotatedTag,
+		}} {
+			t.Run(fetch.name+"/"+tc.tag, func(t *testing.T) {
+				expectedCommit, err := repo.ResolveRevision(plumbing.Revision("refs/tags/" + tc.tag))
+				if err != nil {
+					t.Fatalf("error resolving tag: %v", err)
+				}
+				// Branches of the same name are never picked, whatever
+				// the tag resolution order.
+				ctx := framework.InjectResolverConfigToContext(context.Background(), fetch.conf)
+				resolver := Resolver{}
+				for i := 0; i < 2; i++ {
+					resource, err := resolver.Resolve(ctx, map[string]string{
+						URLParam:  repoDir,
+						TagParam:  tc.tag,
+						PathParam: "task.yaml",
+					})
+					if err != nil {
+						t.Fatalf("unexpected error resolving: %v", err)
+					}
+					if string(resource.Data()) != tc.expectedContent {
+						t.Errorf("expected content %q received %q", tc.expectedContent, resource.Data())
+					}
+					if commit := resource.Annotations()[AnnotationKeyCommitHash]; commit != expectedCommit.String() {
+						t.Errorf("expected commit %q received %q", expectedCommit, commit)
+					}
+					if refType := resource.Annotations()[AnnotationKeyRefType]; refType != tc.expectedType {
+						t.Errorf("expected ref type %q received %q", tc.expectedType, refType)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestResolveTagParamMissing(t *testing.T) {
+	repoDir := createCollidingRefsRepo(t)
+	resolver := Resolver{}
+	_, err := resolver.Resolve(context.Background(), map[string]string{
+		URLParam:  repoDir,
+		TagParam:  "v3",
+		PathParam: "task.yaml",
+	})
+	if !isNotFound(err) {
+		t.Errorf("expected ref not found error for missing tag, received %v", err)
+	}
+}
+
+func TestValidateParamsTag(t *testing.T) {
+	for _, conflict := range []string{CommitParam, BranchParam} {
+		resolver := Resolver{}
+		err := resolver.ValidateParams(context.Background(), map[string]string{
+			URLParam:  "https://github.com/tektoncd/catalog",
+			PathParam: "task.yaml",
+			TagParam:  "v1.4.2",
+			conflict:  "main",
+		})
+		if err == nil || !strings.Contains(err.Error(), `supplied both "`+conflict+`" and "tag"`) {
+			t.Errorf("expected error supplying both %s and tag, received %v", conflict, err)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// parseRefSpecs returns the comma separated refspecs given with the
+// refspec param. Each is <src>[:<dst>], optionally led by a +, where
+// both sides are refs under refs/ with at most one * between them. A
+// refspec without a <dst> fetches <src> to a ref of the same name.
+func parseRefSpecs(value string) ([]config.RefSpec, error) {
+	specs := []config.RefSpec{}
+	for _, spec := range strings.Split(value, ",") {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			return nil, fmt.Errorf("invalid value for %q: %q has an empty refspec", RefSpecParam, value)
+		}
+		src, dst, hasDst := strings.Cut(strings.TrimPrefix(spec, "+"), ":")
+		if !hasDst {
+			dst = src
+		}
+		for _, name := range []string{src, dst} {
+			if !strings.HasPrefix(name, "refs/") || strings.ContainsAny(name, " \t~^?[\\") || strings.Contains(name, "..") || strings.HasSuffix(name, "/") {
+				return nil, fmt.Errorf("invalid value for %q: %q must map a ref under refs/ to one under refs/", RefSpecParam, spec)
+			}
+		}
+		refSpec := config.RefSpec("+" + src + ":" + dst)
+		if err := refSpec.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid value for %q: %q: %v", RefSpecParam, spec, err)
+		}
+		specs = append(specs, refSpec)
+	}
+	return specs, nil
+}
+
+// refSpecTarget returns the local ref that the first of specs fetches
+// to, which the file is read from unless a commit is requested. It's
+// empty if the first refspec has a wildcard, which fetches any number
+// of refs.
+func refSpecTarget(specs []config.RefSpec) plumbing.ReferenceName {
+	if len(specs) == 0 || specs[0].IsWildcard() {
+		return ""
+	}
+	return specs[0].Dst("")
+}
+
+type refSpecsKey struct{}
+
+// withRefSpecs returns a copy of ctx whose fetches use specs in place
+// of the refspecs that would otherwise be picked for the requested ref
+// or commit.
+func withRefSpecs(ctx context.Context, specs []config.RefSpec) context.Context {
+	return context.WithValue(ctx, refSpecsKey{}, specs)
+}
+
+// getRefSpecsFromContext returns the refspecs that fetches with ctx
+// use, if any were requested.
+func getRefSpecsFromContext(ctx context.Context) []config.RefSpec {
+	specs, _ := ctx.Value(refSpecsKey{}).([]config.RefSpec)
+	return specs
+}
@@ -0,0 +1,174 @@
+package git
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/tektoncd/resolution/pkg/resolver/framework"
+)
+
+func TestResolveRefSpec(t *testing.T) {
+	repoDir, commits := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "merged"},
+	}, {
+		files: map[string]string{"task.yaml": "proposed"},
+	}, {
+		files: map[string]string{"task.yaml": "other"},
+	}})
+	// commits[1] and commits[2] are only reachable from the merge
+	// request refs after master is moved back.
+	setTestRef(t, repoDir, "refs/merge-requests/1/head", commits[1])
+	setTestRef(t, repoDir, "refs/merge-requests/2/head", commits[2])
+	setTestRef(t, repoDir, "refs/heads/master", commits[0])
+	server := newGitHTTPServer(t, repoDir, nil)
+
+	for _, tc := range []struct {
+		name     string
+		url      string
+		conf     map[string]string
+		params   map[string]string
+		expected string
+		commit   string
+	}{{
+		name:     "ref",
+		url:      repoDir,
+		params:   map[string]string{RefSpecParam: "refs/merge-requests/1/head"},
+		expected: "proposed",
+		commit:   commits[1],
+	}, {
+		name:     "ref with destination",
+		url:      server.URL + "/repo",
+		params:   map[string]string{RefSpecParam: "+refs/merge-requests/2/head:refs/remotes/origin/mr/2"},
+		expected: "other",
+		commit:   commits[2],
+	}, {
+		name:     "wildcard with commit",
+		url:      repoDir,
+		params:   map[string]string{RefSpecParam: "+refs/merge-requests/*/head:refs/remotes/origin/mr/*", CommitParam: commits[1]},
+		expected: "proposed",
+		commit:   commits[1],
+	}, {
+		name:     "several refspecs",
+		url:      repoDir,
+		params:   map[string]string{RefSpecParam: "refs/merge-requests/2/head, refs/heads/master"},
+		expected: "other",
+		commit:   commits[2],
+	}, {
+		// The git binary and the clone cache don't take refspecs, so
+		// they're skipped.
+		name:     "git cli and clone cache configured",
+		url:      server.URL + "/repo",
+		conf:     map[string]string{ConfigFieldGitProtocolVersion: GitProtocolV2, ConfigFieldCloneCacheDir: t.TempDir()},
+		params:   map[string]string{RefSpecParam: "refs/merge-requests/1/head"},
+		expected: "proposed",
+		commit:   commits[1],
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := framework.InjectResolverConfigToContext(context.Background(), tc.conf)
+			params := map[string]string{
+				URLParam:  tc.url,
+				PathParam: "task.yaml",
+			}
+			for k, v := range tc.params {
+				params[k] = v
+			}
+			resolver := Resolver{}
+			if err := resolver.ValidateParams(ctx, params); err != nil {
+				t.Fatalf("unexpected error validating params: %v", err)
+			}
+			resource, err := resolver.Resolve(ctx, params)
+			if err != nil {
+				t.Fatalf("unexpected error resolving refspec: %v", err)
+			}
+			if string(resource.Data()) != tc.expected {
+				t.Errorf("expected content %q received %q", tc.expected, string(resource.Data()))
+			}
+			if commit := resource.Annotations()[AnnotationKeyCommitHash]; commit != tc.commit {
+				t.Errorf("expected commit %q received %q", tc.commit, commit)
+			}
+		})
+	}
+}
+
+func TestResolveRefSpecCommitNotFetched(t *testing.T) {
+	repoDir, commits := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "merged"},
+	}, {
+		files: map[string]string{"task.yaml": "proposed"},
+	}})
+	setTestRef(t, repoDir, "refs/heads/master", commits[0])
+	setTestRef(t, repoDir, "refs/heads/feature", commits[1])
+
+	_, err := (&Resolver{}).Resolve(context.Background(), map[string]string{
+		URLParam:     repoDir,
+		PathParam:    "task.yaml",
+		RefSpecParam: "+refs/merge-requests/*/head:refs/remotes/origin/mr/*",
+		CommitParam:  commits[1],
+	})
+	if err == nil || !strings.Contains(err.Error(), RefSpecParam) {
+		t.Fatalf("expected a commit outside the refspecs not to be fetched, got %v", err)
+	}
+}
+
+func TestValidateParamsRefSpec(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		params      map[string]string
+		expectedErr string
+	}{{
+		name:   "ref",
+		params: map[string]string{RefSpecParam: "refs/merge-requests/1/head"},
+	}, {
+		name:   "wildcard with commit",
+		params: map[string]string{RefSpecParam: "refs/pull/*/head:refs/remotes/origin/pr/*", CommitParam: "aeb957601cf41c012be462827053a21a420befca"},
+	}, {
+		name:        "wildcard without commit",
+		params:      map[string]string{RefSpecParam: "refs/pull/*/head:refs/remotes/origin/pr/*"},
+		expectedErr: `is needed to pick which commit to read`,
+	}, {
+		name:        "mismatched wildcards",
+		params:      map[string]string{RefSpecParam: "refs/pull/*/head:refs/remotes/origin/pr"},
+		expectedErr: "mismatched number of wildcards",
+	}, {
+		name:        "too many separators",
+		params:      map[string]string{RefSpecParam: "refs/a:refs/b:refs/c"},
+		expectedErr: "separators are wrong",
+	}, {
+		name:        "not a ref",
+		params:      map[string]string{RefSpecParam: "main"},
+		expectedErr: "must map a ref under refs/",
+	}, {
+		name:        "empty refspec",
+		params:      map[string]string{RefSpecParam: "refs/heads/main,"},
+		expectedErr: "has an empty refspec",
+	}, {
+		name:        "with branch",
+		params:      map[string]string{RefSpecParam: "refs/merge-requests/1/head", BranchParam: "main"},
+		expectedErr: `supplied both "branch" and "refspec"`,
+	}, {
+		name:        "with revision",
+		params:      map[string]string{RefSpecParam: "refs/merge-requests/1/head", RevisionParam: "main"},
+		expectedErr: `supplied both "revision" and "refspec"`,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			params := map[string]string{
+				URLParam:  "https://github.com/tektoncd/catalog.git",
+				PathParam: "task.yaml",
+			}
+			for k, v := range tc.params {
+				params[k] = v
+			}
+			err := (&Resolver{}).ValidateParams(context.Background(), params)
+			if tc.expectedErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.expectedErr) {
+				t.Fatalf("expected error containing %q, got %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	resolutioncommon "github.com/tektoncd/resolution/pkg/common"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// RendererNone returns resolved content as-is.
+	RendererNone = "none"
+
+	// RendererYTT renders resolved content as a ytt template.
+	RendererYTT = "ytt"
+)
+
+// yttBinary is the ytt executable used to render templates. It's a
+// variable so that tests can substitute a stub.
+var yttBinary = "ytt"
+
+// getRenderer returns the templating engine configured with the
+// renderer field in the git-resolver-config configmap.
+func getRenderer(conf map[string]string) (string, error) {
+	switch renderer := conf[ConfigFieldRenderer]; renderer {
+	case "", RendererNone:
+		return RendererNone, nil
+	case RendererYTT:
+		return renderer, nil
+	default:
+		return "", fmt.Errorf("invalid %s %q: must be one of %q, %q", ConfigFieldRenderer, renderer, RendererNone, RendererYTT)
+	}
+}
+
+// renderValues returns the data values to render a template with. They
+// are read from the configmap named by the renderValues param in the
+// namespace the request originates from.
+func (r *Resolver) renderValues(ctx context.Context, params map[string]string) (map[string]string, error) {
+	name := params[RenderValuesParam]
+	if name == "" {
+		return map[string]string{}, nil
+	}
+	namespace := resolutioncommon.RequestNamespace(ctx)
+	cm, err := r.kubeClientSet.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error reading render values from configmap %s/%s: %w", namespace, name, err)
+	}
+	return cm.Data, nil
+}
+
+// render passes content through the given templating engine. The file's
+// base name is preserved since engines use the extension to tell
+// templates apart from plain files.
+func render(ctx context.Context, renderer string, path string, content []byte, values map[string]string) ([]byte, error) {
+	if renderer != RendererYTT {
+		return content, nil
+	}
+
+	dir, err := os.MkdirTemp("", "git-resolver-render-")
+	if err != nil {
+		return nil, fmt.Errorf("error creating render directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	templatePath := filepath.Join(dir, filepath.Base(path))
+	if err := os.WriteFile(templatePath, content, 0600); err != nil {
+		return nil, fmt.Errorf("error writing template: %w", err)
+	}
+
+	valuesBytes, err := yaml.Marshal(values)
+	if err != nil {
+		return nil, fmt.Errorf("error serializing render values: %w", err)
+	}
+	// The values file is given a name of its own so that it can't
+	// replace a template of the same name.
+	valuesFile, err := os.CreateTemp(dir, "data-values-*.yml")
+	if err != nil {
+		return nil, fmt.Errorf("error writing render values: %w", err)
+	}
+	valuesPath := valuesFile.Name()
+	_, err = valuesFile.Write(valuesBytes)
+	if closeErr := valuesFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error writing render values: %w", err)
+	}
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	// #nosec G204 -- the binary is fixed and arguments are file paths we created.
+	cmd := exec.CommandContext(ctx, yttBinary, "-f", templatePath, "--data-values-file", valuesPath)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("error rendering %q with %s: %v: %s", path, renderer, err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,144 @@
+package git
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	resolutioncommon "github.com/tektoncd/resolution/pkg/common"
+	"github.com/tektoncd/resolution/pkg/resolver/framework"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const yttTemplate = `#@ load("@ytt:data", "data")
+greeting: #@ data.values.greeting
+`
+
+// setupRenderTest creates a repo holding a ytt template and a resolver
+// with a fake kube client holding a configmap of render values in the
+// "default" namespace.
+func setupRenderTest(t *testing.T) (*Resolver, context.Context, map[string]string) {
+	t.Helper()
+	repoDir, _ := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": yttTemplate},
+	}})
+	resolver := &Resolver{
+		kubeClientSet: fake.NewSimpleClientset(&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "values", Namespace: "default"},
+			Data:       map[string]string{"greeting": "hello"},
+		}),
+	}
+	ctx := resolutioncommon.InjectRequestNamespace(context.Background(), "default")
+	ctx = framework.InjectResolverConfigToContext(ctx, map[string]string{
+		ConfigFieldRenderer: RendererYTT,
+	})
+	params := map[string]string{
+		URLParam:          repoDir,
+		PathParam:         "task.yaml",
+		RenderValuesParam: "values",
+	}
+	return resolver, ctx, params
+}
+
+// stubYTT replaces the ytt binary with a shell script for the duration
+// of a test.
+func stubYTT(t *testing.T, script string) {
+	t.Helper()
+	stub := filepath.Join(t.TempDir(), "ytt")
+	if err := os.WriteFile(stub, []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatalf("error writing ytt stub: %v", err)
+	}
+	original := yttBinary
+	yttBinary = stub
+	t.Cleanup(func() { yttBinary = original })
+}
+
+func TestResolveRenderYTT(t *testing.T) {
+	if _, err := exec.LookPath(yttBinary); err != nil {
+		t.Skip("ytt is not installed")
+	}
+	resolver, ctx, params := setupRenderTest(t)
+	resource, err := resolver.Resolve(ctx, params)
+	if err != nil {
+		t.Fatalf("unexpected error rendering: %v", err)
+	}
+	if expected := "greeting: hello\n"; string(resource.Data()) != expected {
+		t.Errorf("expected rendered content %q received %q", expected, string(resource.Data()))
+	}
+}
+
+func TestResolveRenderPassesTemplateAndValues(t *testing.T) {
+	// The stub echoes the template and data values files it was given.
+	stubYTT(t, `[ "$1" = "-f" ] && [ "$3" = "--data-values-file" ] || exit 2
+cat "$2" "$4"
+`)
+	resolver, ctx, params := setupRenderTest(t)
+	resource, err := resolver.Resolve(ctx, params)
+	if err != nil {
+		t.Fatalf("unexpected error rendering: %v", err)
+	}
+	if expected := yttTemplate + "greeting: hello\n"; string(resource.Data()) != expected {
+		t.Errorf("expected rendered content %q received %q", expected, string(resource.Data()))
+	}
+}
+
+func TestResolveRenderTemplateNamedLikeValues(t *testing.T) {
+	stubYTT(t, `[ "$1" = "-f" ] && [ "$3" = "--data-values-file" ] || exit 2
+cat "$2" "$4"
+`)
+	resolver, ctx, params := setupRenderTest(t)
+	repoDir, _ := createTestRepo(t, []testCommit{{
+		files: map[string]string{"values.yml": yttTemplate},
+	}})
+	params[URLParam], params[PathParam] = repoDir, "values.yml"
+	resource, err := resolver.Resolve(ctx, params)
+	if err != nil {
+		t.Fatalf("unexpected error rendering: %v", err)
+	}
+	if expected := yttTemplate + "greeting: hello\n"; string(resource.Data()) != expected {
+		t.Errorf("expected rendered content %q received %q", expected, string(resource.Data()))
+	}
+}
+
+func TestResolveRenderError(t *testing.T) {
+	stubYTT(t, `echo "ytt: Error: undefined: greeting" >&2
+exit 1
+`)
+	resolver, ctx, params := setupRenderTest(t)
+	_, err := resolver.Resolve(ctx, params)
+	if err == nil {
+		t.Fatalf("expected error rendering invalid template")
+	}
+	if !strings.Contains(err.Error(), "undefined: greeting") {
+		t.Errorf("expected error to include renderer diagnostics, received %q", err.Error())
+	}
+}
+
+func TestGetRenderer(t *testing.T) {
+	for _, tc := range []struct {
+		value     string
+		expected  string
+		expectErr bool
+	}{
+		{value: "", expected: RendererNone},
+		{value: "none", expected: RendererNone},
+		{value: "ytt", expected: RendererYTT},
+		{value: "jinja", expectErr: true},
+	} {
+		renderer, err := getRenderer(map[string]string{ConfigFieldRenderer: tc.value})
+		if tc.expectErr {
+			if err == nil {
+				t.Errorf("expected error for renderer %q", tc.value)
+			}
+			continue
+		}
+		if err != nil || renderer != tc.expected {
+			t.Errorf("renderer %q: expected %q received %q (err %v)", tc.value, tc.expected, renderer, err)
+		}
+	}
+}
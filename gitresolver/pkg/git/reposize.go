@@ -0,0 +1,159 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/storage"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// repoSizeLimit is the most a clone of a repository may fetch.
+type repoSizeLimit struct {
+	// max is the limit in bytes. Zero means clones aren't limited.
+	max int64
+
+	// host is the host of the repository's url, set when max comes
+	// from per-host-max-repo-size rather than max-repo-size.
+	host string
+
+	// quantity is max as it was configured, for error messages.
+	quantity string
+}
+
+// exceeded returns the error for a clone that fetched more than the
+// limit.
+func (l repoSizeLimit) exceeded() error {
+	if l.host != "" {
+		return fmt.Errorf("repository exceeds the %s clone size limit for host %q set by %s", l.quantity, l.host, ConfigFieldPerHostMaxRepoSize)
+	}
+	return fmt.Errorf("repository exceeds the %s clone size limit set by %s", l.quantity, ConfigFieldMaxRepoSize)
+}
+
+// getRepoSizeLimit returns the most a clone of a repository on host may
+// fetch, as configured for the host with the per-host-max-repo-size
+// field in the git-resolver-config configmap, a comma separated list of
+// host=quantity pairs, or else with max-repo-size.
+func getRepoSizeLimit(conf map[string]string, host string) (repoSizeLimit, error) {
+	for _, entry := range strings.Split(conf[ConfigFieldPerHostMaxRepoSize], ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		entryHost, sizeString, ok := strings.Cut(entry, "=")
+		entryHost, sizeString = strings.TrimSpace(entryHost), strings.TrimSpace(sizeString)
+		if !ok || entryHost == "" || sizeString == "" {
+			return repoSizeLimit{}, fmt.Errorf("invalid %s entry %q: must be host=quantity", ConfigFieldPerHostMaxRepoSize, entry)
+		}
+		size, err := resource.ParseQuantity(sizeString)
+		if err != nil {
+			return repoSizeLimit{}, fmt.Errorf("invalid %s size %q for host %q: %w", ConfigFieldPerHostMaxRepoSize, sizeString, entryHost, err)
+		}
+		if host != "" && strings.EqualFold(entryHost, host) {
+			return repoSizeLimit{max: size.Value(), host: host, quantity: sizeString}, nil
+		}
+	}
+	sizeString := conf[ConfigFieldMaxRepoSize]
+	if sizeString == "" {
+		return repoSizeLimit{}, nil
+	}
+	size, err := resource.ParseQuantity(sizeString)
+	if err != nil {
+		return repoSizeLimit{}, fmt.Errorf("invalid %s %q: %w", ConfigFieldMaxRepoSize, sizeString, err)
+	}
+	return repoSizeLimit{max: size.Value(), quantity: sizeString}, nil
+}
+
+type repoSizeLimitKey struct{}
+
+// withRepoSizeLimit returns a copy of ctx that the clones made with it
+// are limited by. The limit is worked out from the url as requested,
+// since the url that's cloned may be a tunnel to it.
+func withRepoSizeLimit(ctx context.Context, limit repoSizeLimit) context.Context {
+	return context.WithValue(ctx, repoSizeLimitKey{}, limit)
+}
+
+// getRepoSizeLimitFromContext returns the limit that clones made with
+// ctx are subject to.
+func getRepoSizeLimitFromContext(ctx context.Context) repoSizeLimit {
+	limit, _ := ctx.Value(repoSizeLimitKey{}).(repoSizeLimit)
+	return limit
+}
+
+// sizeLimitedStorage is a storer that fails once the objects written to
+// it exceed a limit, so that a clone of too large a repository is
+// stopped partway through rather than held in memory in full. Sizes
+// are of the objects as stored, before compression.
+type sizeLimitedStorage struct {
+	storage.Storer
+	limit repoSizeLimit
+	size  int64
+}
+
+// limitRepoSize wraps store so that it's limited by the repo size
+// limit in ctx, if there is one.
+func limitRepoSize(ctx context.Context, store storage.Storer) storage.Storer {
+	limit := getRepoSizeLimitFromContext(ctx)
+	if limit.max <= 0 {
+		return store
+	}
+	return &sizeLimitedStorage{Storer: store, limit: limit}
+}
+
+// SetEncodedObject stores obj unless it takes the total stored past
+// the limit.
+func (s *sizeLimitedStorage) SetEncodedObject(obj plumbing.EncodedObject) (plumbing.Hash, error) {
+	s.size += obj.Size()
+	if s.size > s.limit.max {
+		return plumbing.ZeroHash, s.limit.exceeded()
+	}
+	return s.Storer.SetEncodedObject(obj)
+}
+
+// checkClonedSize returns an error if the objects in a repository
+// cloned with the git binary into dir exceed the repo size limit in
+// ctx. Objects are measured as stored on disk, compressed.
+func checkClonedSize(ctx context.Context, dir string) error {
+	limit := getRepoSizeLimitFromContext(ctx)
+	if limit.max <= 0 {
+		return nil
+	}
+	var size int64
+	err := filepath.WalkDir(filepath.Join(dir, ".git", "objects"), func(_ string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error measuring cloned repository: %w", err)
+	}
+	if size > limit.max {
+		return limit.exceeded()
+	}
+	return nil
+}
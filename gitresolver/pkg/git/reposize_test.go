@@ -0,0 +1,122 @@
+package git
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/tektoncd/resolution/pkg/resolver/framework"
+)
+
+// createLargeTestRepo makes a repo with a task and a 64KiB file that
+// makes cloning it exceed small size limits.
+func createLargeTestRepo(t *testing.T) string {
+	t.Helper()
+	repoDir, _ := createTestRepo(t, []testCommit{{
+		files: map[string]string{
+			"task.yaml": "kind: Task",
+			"large.txt": strings.Repeat("0123456789abcdef", 4096),
+		},
+	}})
+	return repoDir
+}
+
+func TestResolvePerHostMaxRepoSize(t *testing.T) {
+	repoDir := createLargeTestRepo(t)
+	// The same server is reached through two hosts so that each can be
+	// given its own limit.
+	serverURL, err := url.Parse(newGitHTTPServer(t, repoDir, nil).URL)
+	if err != nil {
+		t.Fatalf("error parsing server url: %v", err)
+	}
+	ipURL := "http://127.0.0.1:" + serverURL.Port() + "/repo"
+	localhostURL := "http://localhost:" + serverURL.Port() + "/repo"
+
+	for _, tc := range []struct {
+		name        string
+		conf        map[string]string
+		url         string
+		expectedErr string
+	}{{
+		name: "host under its limit",
+		conf: map[string]string{
+			ConfigFieldPerHostMaxRepoSize: "127.0.0.1=1Ki, localhost=1Mi",
+		},
+		url: localhostURL,
+	}, {
+		name: "host over its limit",
+		conf: map[string]string{
+			ConfigFieldPerHostMaxRepoSize: "127.0.0.1=1Ki, localhost=1Mi",
+		},
+		url:         ipURL,
+		expectedErr: `exceeds the 1Ki clone size limit for host "127.0.0.1"`,
+	}, {
+		name: "host limit overrides global limit",
+		conf: map[string]string{
+			ConfigFieldMaxRepoSize:        "1Ki",
+			ConfigFieldPerHostMaxRepoSize: "localhost=1Mi",
+		},
+		url: localhostURL,
+	}, {
+		name: "global limit applies to other hosts",
+		conf: map[string]string{
+			ConfigFieldMaxRepoSize:        "1Ki",
+			ConfigFieldPerHostMaxRepoSize: "localhost=1Mi",
+		},
+		url:         ipURL,
+		expectedErr: "exceeds the 1Ki clone size limit set by " + ConfigFieldMaxRepoSize,
+	}, {
+		name: "minimal fetch over its limit",
+		conf: map[string]string{
+			ConfigFieldMinimalFetch:       "true",
+			ConfigFieldPerHostMaxRepoSize: "127.0.0.1=1Ki",
+		},
+		url:         ipURL,
+		expectedErr: `for host "127.0.0.1"`,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := framework.InjectResolverConfigToContext(context.Background(), tc.conf)
+			resolver := Resolver{}
+			resource, err := resolver.Resolve(ctx, map[string]string{
+				URLParam:  tc.url,
+				PathParam: "task.yaml",
+			})
+			if tc.expectedErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error resolving: %v", err)
+				}
+				if string(resource.Data()) != "kind: Task" {
+					t.Errorf("expected task content, received %q", resource.Data())
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tc.expectedErr) {
+				t.Errorf("expected error containing %q, received %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestResolveMaxRepoSizeWithCLI(t *testing.T) {
+	repoDir := createLargeTestRepo(t)
+	ctx := framework.InjectResolverConfigToContext(context.Background(), map[string]string{
+		ConfigFieldGitProtocolVersion: "2",
+		ConfigFieldMaxRepoSize:        "1Ki",
+	})
+	resolver := Resolver{}
+	_, err := resolver.Resolve(ctx, map[string]string{
+		URLParam:  "file://" + repoDir,
+		PathParam: "task.yaml",
+	})
+	if err == nil || !strings.Contains(err.Error(), "exceeds the 1Ki clone size limit") {
+		t.Errorf("expected clone size limit error, received %v", err)
+	}
+}
+
+func TestGetRepoSizeLimitInvalid(t *testing.T) {
+	for _, value := range []string{"github.com", "github.com=lots", "=1Mi"} {
+		_, err := getRepoSizeLimit(map[string]string{ConfigFieldPerHostMaxRepoSize: value}, "github.com")
+		if err == nil || !strings.Contains(err.Error(), ConfigFieldPerHostMaxRepoSize) {
+			t.Errorf("expected invalid %s error for %q, received %v", ConfigFieldPerHostMaxRepoSize, value, err)
+		}
+	}
+}
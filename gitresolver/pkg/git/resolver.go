@@ -19,17 +19,33 @@ package git
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
-	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5"
 	git "github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
-	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/tektoncd/resolution/pkg/apis/resolution/v1alpha1"
 	resolutioncommon "github.com/tektoncd/resolution/pkg/common"
 	"github.com/tektoncd/resolution/pkg/resolver/framework"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/utils/clock"
+	kubeclient "knative.dev/pkg/client/injection/kube/client"
+	"knative.dev/pkg/logging"
 )
 
 // LabelValueGitResolverType is the value to use for the
@@ -43,14 +59,67 @@ const GitResolverName string = "Git"
 // YAMLContentType is the content type to use when returning yaml
 const YAMLContentType string = "application/x-yaml"
 
+// JSONContentType is the content type to use when returning json
+const JSONContentType string = "application/json"
+
 var _ framework.Resolver = &Resolver{}
 
 // Resolver implements a framework.Resolver that can fetch files from git.
-type Resolver struct{}
+type Resolver struct {
+	kubeClientSet kubernetes.Interface
+
+	// cache holds resolved files when cache-size is configured.
+	cache resultCache
+
+	// refs holds the hashes of moving refs when ref-cache-ttl is
+	// configured.
+	refs refCache
+
+	// misses holds requests that failed because their file or ref
+	// doesn't exist when negative-cache-ttl is configured.
+	misses missCache
+
+	// tokens holds the access token used when auth-mode is
+	// workload-identity.
+	tokens tokenSource
+
+	// clock is used to age cached refs and tokens and can be
+	// overridden for tests. The real clock is used when it is nil.
+	clock clock.PassiveClock
+
+	// schemes translates urls with custom schemes and can be
+	// overridden for tests. DefaultSchemeRegistry is used when it is
+	// nil.
+	schemes *SchemeRegistry
+
+	// pins holds the commits that refs were first resolved to when
+	// pin-on-first-resolve is configured.
+	pins pinStore
+
+	// clones bounds how many clones are made at once when
+	// max-concurrent-clones is configured.
+	clones cloneLimiter
+}
 
 // Initialize performs any setup required by the gitresolver.
 func (r *Resolver) Initialize(ctx context.Context) error {
-	return nil
+	r.kubeClientSet = kubeclient.Get(ctx)
+	installTransports()
+	return registerCloneMetricViews()
+}
+
+func (r *Resolver) schemeRegistry() *SchemeRegistry {
+	if r.schemes == nil {
+		return DefaultSchemeRegistry
+	}
+	return r.schemes
+}
+
+func (r *Resolver) now() time.Time {
+	if r.clock == nil {
+		return time.Now()
+	}
+	return r.clock.Now()
 }
 
 // GetName returns the string name that the gitresolver should be
@@ -69,11 +138,99 @@ func (r *Resolver) GetSelector(_ context.Context) map[string]string {
 
 // ValidateParams returns an error if the given parameter map is not
 // valid for a resource request targeting the gitresolver.
-func (r *Resolver) ValidateParams(_ context.Context, params map[string]string) error {
+func (r *Resolver) ValidateParams(ctx context.Context, params map[string]string) error {
+	params = withConfigDefaults(framework.GetResolverConfigFromContext(ctx), params)
 	required := []string{
 		URLParam,
 		PathParam,
 	}
+	if metadata, _ := strconv.ParseBool(params[CommitMetadataParam]); metadata {
+		for _, p := range []string{OverlayPathParam, FormatParam} {
+			if params[p] != "" {
+				return fmt.Errorf("supplied both %q and %q", CommitMetadataParam, p)
+			}
+		}
+		for _, p := range []string{BlameParam, GrepParam, IncludeDependenciesParam, ContainingRefsParam} {
+			if v := params[p]; v != "" && v != "false" {
+				return fmt.Errorf("supplied both %q and %q", CommitMetadataParam, p)
+			}
+		}
+		required = []string{URLParam}
+	}
+	if stat, _ := strconv.ParseBool(params[StatOnlyParam]); stat {
+		for _, p := range []string{OverlayPathParam, FormatParam} {
+			if params[p] != "" {
+				return fmt.Errorf("supplied both %q and %q", StatOnlyParam, p)
+			}
+		}
+		for _, p := range []string{BlameParam, GrepParam, IncludeDependenciesParam, ContainingRefsParam, CommitMetadataParam} {
+			if v := params[p]; v != "" && v != "false" {
+				return fmt.Errorf("supplied both %q and %q", StatOnlyParam, p)
+			}
+		}
+	}
+	if containing, _ := strconv.ParseBool(params[ContainingRefsParam]); containing {
+		for _, p := range []string{LocatorParam, BranchParam, TagParam, PullRequestParam, RevisionParam, RefSpecParam, OverlayPathParam, FormatParam} {
+			if params[p] != "" {
+				return fmt.Errorf("supplied both %q and %q", ContainingRefsParam, p)
+			}
+		}
+		for _, p := range []string{BlameParam, GrepParam, IncludeDependenciesParam} {
+			if v := params[p]; v != "" && v != "false" {
+				return fmt.Errorf("supplied both %q and %q", ContainingRefsParam, p)
+			}
+		}
+		required = []string{URLParam, CommitParam}
+	}
+	if params[CatalogRefParam] != "" {
+		for _, p := range []string{LocatorParam, URLParam, PathParam, CommitParam, BranchParam, TagParam, PullRequestParam, RevisionParam, RefSpecParam} {
+			if params[p] != "" {
+				return fmt.Errorf("supplied both %q and %q", CatalogRefParam, p)
+			}
+		}
+		required = nil
+	}
+	repoURL, commit, path := params[URLParam], params[CommitParam], params[PathParam]
+	if locator := params[LocatorParam]; locator != "" {
+		for _, p := range []string{URLParam, PathParam, CommitParam, BranchParam, TagParam, PullRequestParam, RevisionParam, RefSpecParam} {
+			if params[p] != "" {
+				return fmt.Errorf("supplied both %q and %q", LocatorParam, p)
+			}
+		}
+		loc, err := parseLocator(locator)
+		if err != nil {
+			return err
+		}
+		repoURL, path = loc.URL, loc.Path
+		if isFullCommitHash(loc.Ref) {
+			commit = loc.Ref
+		}
+		required = nil
+	}
+	for _, p := range []string{path, params[OverlayPathParam]} {
+		if err := validateRepoPath(p); err != nil {
+			return err
+		}
+	}
+	// Urls with a custom scheme are checked once they're translated,
+	// when the repo is resolved.
+	conf := framework.GetResolverConfigFromContext(ctx)
+	for _, u := range []string{repoURL, params[OverlayURLParam]} {
+		if u != "" && !r.schemeRegistry().isCustom(u) {
+			if err := checkAllowedURL(conf, u); err != nil {
+				return err
+			}
+		}
+	}
+	if params[TokenSecretParam] != "" {
+		// Tokens are only ever sent over http, never to be handed to
+		// an ssh server or read by some other scheme's handler.
+		if ep, err := transport.NewEndpoint(repoURL); repoURL != "" && (err != nil || ep.Protocol != "https" && ep.Protocol != "http") {
+			return fmt.Errorf("%q requires an http or https url", TokenSecretParam)
+		}
+	} else if params[TokenSecretKeyParam] != "" {
+		return fmt.Errorf("%q requires %q", TokenSecretKeyParam, TokenSecretParam)
+	}
 	missing := []string{}
 	if params == nil {
 		missing = required
@@ -89,70 +246,1060 @@ func (r *Resolver) ValidateParams(_ context.Context, params map[string]string) e
 		return fmt.Errorf("missing %v", strings.Join(missing, ", "))
 	}
 
-	if params[CommitParam] != "" && params[BranchParam] != "" {
+	if params[CommitParam] != "" && params[BranchParam] != "" && !enforceCommitOnBranch(framework.GetResolverConfigFromContext(ctx)) {
 		return fmt.Errorf("supplied both %q and %q", CommitParam, BranchParam)
 	}
+	if _, err := getFetchMode(framework.GetResolverConfigFromContext(ctx), params); err != nil {
+		return err
+	}
+	if params[DepthParam] != "" {
+		if _, err := getCloneDepth(nil, params); err != nil {
+			return err
+		}
+		if commit != "" {
+			return fmt.Errorf("%q can't be used to fetch commit %s: a shallow clone may not reach it, so commits are always fetched with all history", DepthParam, commit)
+		}
+	}
+	if params[TagParam] != "" {
+		for _, p := range []string{CommitParam, BranchParam} {
+			if params[p] != "" {
+				return fmt.Errorf("supplied both %q and %q", p, TagParam)
+			}
+		}
+	}
+	if number := params[PullRequestParam]; number != "" {
+		for _, p := range []string{CommitParam, BranchParam, TagParam} {
+			if params[p] != "" {
+				return fmt.Errorf("supplied both %q and %q", p, PullRequestParam)
+			}
+		}
+		if _, err := pullRequestRef(conf, number); err != nil {
+			return err
+		}
+	}
+	if params[DateParam] != "" {
+		if commit != "" {
+			return fmt.Errorf("supplied both %q and %q", CommitParam, DateParam)
+		}
+		if _, err := getDate(params); err != nil {
+			return err
+		}
+	}
+	if params[RevisionParam] != "" {
+		for _, p := range []string{CommitParam, BranchParam, TagParam, PullRequestParam} {
+			if params[p] != "" {
+				return fmt.Errorf("supplied both %q and %q", p, RevisionParam)
+			}
+		}
+	}
+	if value := params[RefSpecParam]; value != "" {
+		for _, p := range []string{BranchParam, TagParam, PullRequestParam, RevisionParam} {
+			if params[p] != "" {
+				return fmt.Errorf("supplied both %q and %q", p, RefSpecParam)
+			}
+		}
+		specs, err := parseRefSpecs(value)
+		if err != nil {
+			return err
+		}
+		if refSpecTarget(specs) == "" && commit == "" {
+			return fmt.Errorf("%q %q fetches any number of refs, so %q is needed to pick which commit to read", RefSpecParam, specs[0], CommitParam)
+		}
+	}
+	for _, p := range []string{CommitParam, OverlayCommitParam} {
+		if v := params[p]; v != "" {
+			if err := validateCommitHash(p, v); err != nil {
+				return err
+			}
+		}
+	}
+
+	if isGlobPattern(path) {
+		if err := validateGlobPattern(path); err != nil {
+			return err
+		}
+		// These read exactly the path they're given, or what it refers
+		// to, so they have no meaning for a set of files.
+		for _, p := range []string{BlameParam, GrepParam, StatOnlyParam, IncludeDependenciesParam, VerifySignatureParam} {
+			if v := params[p]; v != "" && v != "false" {
+				return fmt.Errorf("%q can't be used with a %s pattern", p, PathParam)
+			}
+		}
+		if params[FormatParam] == FormatTar {
+			return fmt.Errorf("%q %q can't be used with a %s pattern", FormatParam, FormatTar, PathParam)
+		}
+	}
+
+	if params[OverlayPathParam] != "" {
+		if params[OverlayCommitParam] != "" && params[OverlayBranchParam] != "" {
+			return fmt.Errorf("supplied both %q and %q", OverlayCommitParam, OverlayBranchParam)
+		}
+		for _, p := range []string{BlameParam, GrepParam} {
+			if v := params[p]; v != "" && v != "false" {
+				return fmt.Errorf("supplied both %q and %q", OverlayPathParam, p)
+			}
+		}
+		if _, err := getMergeStrategy(params); err != nil {
+			return err
+		}
+	} else {
+		for _, p := range []string{OverlayURLParam, OverlayCommitParam, OverlayBranchParam, MergeStrategyParam} {
+			if params[p] != "" {
+				return fmt.Errorf("%q requires %q", p, OverlayPathParam)
+			}
+		}
+	}
+
+	if include, _ := strconv.ParseBool(params[IncludeDependenciesParam]); include {
+		for _, p := range []string{BlameParam, GrepParam} {
+			if v := params[p]; v != "" && v != "false" {
+				return fmt.Errorf("supplied both %q and %q", IncludeDependenciesParam, p)
+			}
+		}
+	}
+
+	if params[FormatParam] != "" {
+		for _, p := range []string{BlameParam, GrepParam} {
+			if v := params[p]; v != "" && v != "false" {
+				return fmt.Errorf("supplied both %q and %q", FormatParam, p)
+			}
+		}
+		if _, err := getFormat(params); err != nil {
+			return err
+		}
+		if params[FormatParam] == FormatTar {
+			if params[OverlayPathParam] != "" {
+				return fmt.Errorf("supplied both %q and %q", FormatParam, OverlayPathParam)
+			}
+			if include, _ := strconv.ParseBool(params[IncludeDependenciesParam]); include {
+				return fmt.Errorf("supplied both %q and %q", FormatParam, IncludeDependenciesParam)
+			}
+		}
+	}
+
+	if submodules, _ := strconv.ParseBool(params[SubmodulesParam]); submodules {
+		// Only files are read through submodules; reports on the
+		// repo are about its own history.
+		for _, p := range []string{BlameParam, GrepParam, StatOnlyParam, CommitMetadataParam, ContainingRefsParam} {
+			if v := params[p]; v != "" && v != "false" {
+				return fmt.Errorf("supplied both %q and %q", SubmodulesParam, p)
+			}
+		}
+		if params[FormatParam] == FormatTar {
+			return fmt.Errorf("supplied both %q and %q %q", SubmodulesParam, FormatParam, FormatTar)
+		}
+	}
+
+	if pattern := params[GrepParam]; pattern != "" {
+		if blame, _ := strconv.ParseBool(params[BlameParam]); blame {
+			return fmt.Errorf("supplied both %q and %q", GrepParam, BlameParam)
+		}
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid value for %q: %v", GrepParam, err)
+		}
+	}
+
+	for _, p := range []string{BlameParam, AllowEmptyParam, IncludeLicenseParam, GrepLineNumbersParam, VerifySignatureParam, IncludeDependenciesParam, ContainingRefsParam, CommitMetadataParam, StatOnlyParam, RequireSignatureParam, SubmodulesParam, LFSParam} {
+		if v, has := params[p]; has {
+			if _, err := strconv.ParseBool(v); err != nil {
+				return fmt.Errorf("invalid value for %q: %q", p, v)
+			}
+		}
+	}
 
-	// TODO(sbwsg): validate repo url is well-formed, git:// or https://
-	// TODO(sbwsg): validate path is valid relative path
+	if require, _ := strconv.ParseBool(params[RequireSignatureParam]); require {
+		if params[TrustedKeysSecretParam] == "" {
+			return fmt.Errorf("%q requires %q", RequireSignatureParam, TrustedKeysSecretParam)
+		}
+	} else if params[TrustedKeysSecretParam] != "" {
+		return fmt.Errorf("%q requires %q", TrustedKeysSecretParam, RequireSignatureParam)
+	}
 
 	return nil
 }
 
 // Resolve performs the work of fetching a file from git given a map of
 // parameters.
-func (r *Resolver) Resolve(_ context.Context, params map[string]string) (framework.ResolvedResource, error) {
+func (r *Resolver) Resolve(ctx context.Context, params map[string]string) (framework.ResolvedResource, error) {
+	ctx, span := startSpan(ctx, "git.resolve")
+	params, err := expandCatalogRef(framework.GetResolverConfigFromContext(ctx), params)
+	if err == nil {
+		params = withConfigDefaults(framework.GetResolverConfigFromContext(ctx), params)
+	}
+	var pinned *pinnedRef
+	if err == nil {
+		params, pinned, err = r.pinRef(ctx, params)
+	}
+	var resolved *ResolvedGitResource
+	if err == nil {
+		resolved, err = r.resolve(ctx, params)
+	}
+	if err == nil && pinned != nil {
+		r.recordPin(pinned, resolved)
+	}
+	if err == nil && params[OverlayPathParam] != "" {
+		resolved, err = r.resolveOverlay(ctx, params, resolved)
+	}
+	if format := params[FormatParam]; err == nil && format != "" && format != FormatTar {
+		resolved, err = convertFormat(resolved, format)
+	}
+	if err == nil && !isReport(params) {
+		err = checkPlaceholders(resolved, framework.GetResolverConfigFromContext(ctx))
+	}
+	// Files are wrapped last so that the manifest describes them as
+	// they would otherwise have been returned.
+	if mode := framework.GetResolverConfigFromContext(ctx)[ConfigFieldWrapWithManifest]; err == nil && mode != "" {
+		resolved, err = wrapWithManifest(resolved, mode, r.now())
+	}
+	// Attestations are made over the content exactly as it's returned.
+	if secret := framework.GetResolverConfigFromContext(ctx)[ConfigFieldAttestationKeySecret]; err == nil && secret != "" {
+		resolved, err = r.attest(ctx, secret, resolved, r.now())
+	}
+	if err == nil {
+		err = checkResolvedSize(ctx, resolved)
+	}
+	// Errors often quote the url, which may hold credentials.
+	err = resolutioncommon.RedactError(err)
+	endSpan(span, err)
+	if err != nil {
+		return nil, err
+	}
+	return resolved, nil
+}
+
+// checkResolvedSize returns an error if the content of resolved, as it
+// would be written, is larger than the configured max-size. Content
+// that the reconciler compresses is checked at its compressed size so
+// that a large file that compresses well isn't turned away before it
+// gets the chance.
+func checkResolvedSize(ctx context.Context, resolved *ResolvedGitResource) error {
+	maxSize, err := getMaxSize(ctx)
+	if err != nil {
+		return err
+	}
+	size := int64(len(resolved.Content))
+	if maxSize <= 0 || size <= maxSize {
+		return nil
+	}
+	if threshold := framework.GetCompressionThresholdFromContext(ctx); threshold > 0 && size > int64(threshold) {
+		compressed, err := framework.CompressedSize(resolved.Content)
+		if err != nil {
+			return err
+		}
+		if int64(compressed) <= maxSize {
+			return nil
+		}
+		return resolutioncommon.NewError(resolutioncommon.ReasonResourceTooLarge, fmt.Errorf("resolved file %q is %d bytes compressed, exceeds max size %d bytes", resolved.Path, compressed, maxSize))
+	}
+	return resolutioncommon.NewError(resolutioncommon.ReasonResourceTooLarge, fmt.Errorf("resolved file %q is %d bytes, exceeds max size %d bytes", resolved.Path, size, maxSize))
+}
+
+// isReport returns true if params request a report on the repo, like
+// blame or grep results, rather than a file. Reports don't return the
+// files they're about, so their placeholders don't need resolving.
+func isReport(params map[string]string) bool {
+	blame, _ := strconv.ParseBool(params[BlameParam])
+	containing, _ := strconv.ParseBool(params[ContainingRefsParam])
+	metadata, _ := strconv.ParseBool(params[CommitMetadataParam])
+	stat, _ := strconv.ParseBool(params[StatOnlyParam])
+	return blame || containing || metadata || stat || params[GrepParam] != ""
+}
+
+// resolve fetches the file described by params, recording the clone,
+// checkout and read as spans under the one in ctx.
+func (r *Resolver) resolve(ctx context.Context, params map[string]string) (_ *ResolvedGitResource, err error) {
 	repo := params[URLParam]
 	commit := params[CommitParam]
 	branch := params[BranchParam]
 	path := params[PathParam]
-	cloneOpts := &git.CloneOptions{
-		URL: repo,
-	}
-	filesystem := memfs.New()
+	conf := framework.GetResolverConfigFromContext(ctx)
+
+	candidates := []plumbing.ReferenceName{}
 	if branch != "" {
-		cloneOpts.SingleBranch = true
-		cloneOpts.ReferenceName = plumbing.NewBranchReferenceName(branch)
+		candidates = append(candidates, plumbing.NewBranchReferenceName(branch))
+	}
+	ref := branch
+	if tag := params[TagParam]; tag != "" {
+		candidates = append(candidates, plumbing.NewTagReferenceName(tag))
+		ref = tag
+	}
+	if number := params[PullRequestParam]; number != "" {
+		pullRequest, err := pullRequestRef(conf, number)
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, pullRequest)
+		ref = pullRequest.String()
+	}
+	if locatorString := params[LocatorParam]; locatorString != "" {
+		loc, err := parseLocator(locatorString)
+		if err != nil {
+			return nil, err
+		}
+		repo, path = loc.URL, loc.Path
+		switch {
+		case isFullCommitHash(loc.Ref):
+			commit = loc.Ref
+		case loc.Ref != "":
+			order, err := getTagResolutionOrder(conf)
+			if err != nil {
+				return nil, err
+			}
+			ref = loc.Ref
+			candidates = append(candidates, refCandidates(loc.Ref, order)...)
+		}
+	}
+	// A revision is tried as a branch and then a tag, and only taken
+	// to be a commit if it could be one and neither exists.
+	revision := params[RevisionParam]
+	maybeCommit := revision != "" && validateCommitHash(RevisionParam, revision) == nil
+	if revision != "" {
+		order, err := getTagResolutionOrder(conf)
+		if err != nil {
+			return nil, err
+		}
+		ref = revision
+		candidates = append(candidates, refCandidates(revision, order)...)
+	}
+	// Refspecs replace the ones picked for a ref, and the file is read
+	// from where the first one fetches to.
+	if value := params[RefSpecParam]; value != "" {
+		specs, err := parseRefSpecs(value)
+		if err != nil {
+			return nil, err
+		}
+		if target := refSpecTarget(specs); target != "" {
+			candidates = append(candidates, target)
+			ref = target.String()
+		}
+		ctx = withRefSpecs(ctx, specs)
+	}
+	if err := validateRepoPath(path); err != nil {
+		return nil, err
+	}
+
+	if err := checkProxyConfig(conf); err != nil {
+		return nil, err
+	}
+	ctx, err = r.withProxyAuth(ctx, conf)
+	if err != nil {
+		return nil, err
+	}
+	ctx, err = r.withClientCert(ctx, conf)
+	if err != nil {
+		return nil, err
+	}
+	ctx, err = r.withCABundle(ctx, conf)
+	if err != nil {
+		return nil, err
+	}
+	sizeLimit, err := getRepoSizeLimit(conf, repoHost(repo))
+	if err != nil {
+		return nil, err
+	}
+	ctx = withRepoSizeLimit(ctx, sizeLimit)
+	retries, err := getRetryPolicy(conf)
+	if err != nil {
+		return nil, err
+	}
+	maxClones, err := getMaxConcurrentClones(conf)
+	if err != nil {
+		return nil, err
+	}
+	depth, err := getCloneDepth(conf, params)
+	if err != nil {
+		return nil, err
+	}
+	inMemoryMaxDepth, err := getInMemoryCloneMaxDepth(conf)
+	if err != nil {
+		return nil, err
+	}
+	date, err := getDate(params)
+	if err != nil {
+		return nil, err
+	}
+	// A shallow clone may not reach an arbitrary commit, so commits are
+	// always fetched with all of their history.
+	if commit == "" && !maybeCommit {
+		ctx = withCloneDepth(ctx, depth)
+	}
+
+	// The url as given is reported back in the result, while clones use
+	// the url with the configured .git suffix handling applied and
+	// caches use a form without the suffix so that both spellings of a
+	// repo share entries.
+	suffixMode, err := getGitSuffixMode(conf)
+	if err != nil {
+		return nil, err
+	}
+	remoteURL, cacheURL := repo, canonicalRepoURL(repo)
+
+	// Requests for a file or ref that doesn't exist are briefly failed
+	// without fetching again. Servers report private repos that a token
+	// can't read as not found, so misses for requests with a token
+	// secret from their own namespace aren't cached.
+	missTTL, err := getNegativeCacheTTL(conf)
+	if err != nil {
+		return nil, err
+	}
+	if missTTL > 0 && params[TokenSecretParam] == "" {
+		missKey := missCacheKey(cacheURL, commit, candidates, path, params, conf)
+		if missErr := r.misses.get(r.now(), missKey); missErr != nil {
+			return nil, missErr
+		}
+		defer func() {
+			if isNotFound(err) {
+				r.misses.add(r.now(), missTTL, missKey, err)
+			}
+		}()
+	}
+	// Urls with a custom scheme are cloned through the url, and with
+	// the auth, that their handler translates them to.
+	repo, schemeAuth, err := r.schemeRegistry().Translate(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkAllowedURL(conf, repo); err != nil {
+		return nil, err
+	}
+	repo, auth, closeTunnel, err := r.proxyJump(ctx, cloneURL(repo, suffixMode), conf)
+	if err != nil {
+		return nil, err
+	}
+	defer closeTunnel()
+	if auth == nil {
+		auth = schemeAuth
+	}
+	if auth == nil {
+		auth, err = r.tokenSecretAuth(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if auth == nil {
+		auth, err = r.netrcAuth(ctx, conf, repo)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if auth == nil {
+		auth, err = r.httpAuth(ctx, conf, repo)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// Repos are checked against the required upstreams before anything
+	// is fetched from them.
+	if err := checkForkOf(ctx, conf, remoteURL, auth); err != nil {
+		return nil, err
+	}
+	if maybeCommit {
+		auth, err = r.withTokenRetry(ctx, conf, repo, auth, func(auth transport.AuthMethod) error {
+			return withRetries(ctx, retries, func() (err error) {
+				_, err = lsRemote(ctx, repo, auth, candidates)
+				return err
+			})
+		})
+		switch {
+		case errors.Is(err, plumbing.ErrReferenceNotFound) && !date.IsZero():
+			return nil, fmt.Errorf("supplied both a commit %q and %q", RevisionParam, DateParam)
+		case errors.Is(err, plumbing.ErrReferenceNotFound):
+			commit, ref, candidates = revision, "", nil
+		case err != nil:
+			return nil, err
+		}
+	}
+	if containing, _ := strconv.ParseBool(params[ContainingRefsParam]); containing {
+		resolved, err := r.resolveContainingRefs(ctx, conf, remoteURL, repo, auth, commit)
+		if err != nil {
+			return nil, err
+		}
+		resolved.URL, resolved.Path = remoteURL, path
+		return resolved, nil
+	}
+
+	// Results are cached by the revision they were read from, so a
+	// request for a branch is looked up by the commit at its tip.
+	// Rendered files are never cached since their values can change.
+	renderer, err := getRenderer(conf)
+	if err != nil {
+		return nil, err
+	}
+	cacheSize, err := getCacheSize(conf)
+	if err != nil {
+		return nil, err
+	}
+	// A commit requested along with a branch is checked against the
+	// branch's current tip, and signed commits against the keys that
+	// are trusted now, so neither can be served from the cache.
+	checkOnBranch := commit != "" && branch != "" && enforceCommitOnBranch(conf)
+	requireSignature, _ := strconv.ParseBool(params[RequireSignatureParam])
+	checkSignature := conf[ConfigFieldTrustedKeys] != "" || requireSignature
+	// An abbreviated commit is only expanded against the clone, so it
+	// isn't looked up in the cache or the contents api either, where a
+	// prefix that has since become ambiguous could still match.
+	abbreviated := commit != "" && !isFullCommitHash(commit)
+	// A file as of a date isn't read from the ref's tip, which the
+	// cache would be looked up by. Files read with a token secret from
+	// the request's namespace aren't cached either, so that requests
+	// from another namespace can't be served them without the token.
+	var cacheKey string
+	if cacheSize > 0 && renderer == RendererNone && !checkOnBranch && !checkSignature && !abbreviated && date.IsZero() && params[TokenSecretParam] == "" {
+		revision := commit
+		var resolvedRef *plumbing.Reference
+		if revision == "" {
+			auth, err = r.withTokenRetry(ctx, conf, repo, auth, func(auth transport.AuthMethod) error {
+				return withRetries(ctx, retries, func() (err error) {
+					resolvedRef, err = r.resolveRevision(ctx, conf, cacheURL, repo, auth, candidates)
+					return err
+				})
+			})
+			if err != nil {
+				return nil, err
+			}
+			revision = resolvedRef.Hash().String()
+		}
+		cacheKey = resultCacheKey(cacheURL, revision, path, params, conf)
+		if cached, ok := r.cache.get(cacheSize, cacheKey); ok {
+			// Requests for different refs share an entry when
+			// they point to the same revision.
+			hit := *cached
+			hit.URL, hit.Ref, hit.RefType = remoteURL, ref, RefTypeCommit
+			if resolvedRef != nil {
+				hit.RefType = refType(resolvedRef.Name(), resolvedRef.Hash(), hit.Commit)
+			}
+			trace.SpanFromContext(ctx).SetAttributes(attrHost.String(repoHost(remoteURL)), attrRef.String(ref), attrCommit.String(hit.Commit), attrCacheHit.Bool(true))
+			return &hit, nil
+		}
+	}
+
+	// Plain files on GitHub hosts can be read through the contents api
+	// without a clone. Any problem with the api, or a file it can't
+	// serve, falls back to cloning.
+	fetchMode, err := getFetchMode(conf, params)
+	if err != nil {
+		return nil, err
+	}
+	if apiURL, fullName, ok := contentsAPIRepo(conf, remoteURL); ok && fetchMode == FetchModeAPI && !checkOnBranch && !abbreviated && canUseContentsAPI(conf, params, path) {
+		_, apiSpan := startSpan(ctx, "git.api", attrHost.String(repoHost(remoteURL)), attrURL.String(sanitizeURL(remoteURL)), attrRef.String(ref))
+		content, apiCommit, err := fetchFromContentsAPI(ctx, apiURL, fullName, auth, candidates, commit, path)
+		// Git lfs objects are fetched with the repo's .lfsconfig,
+		// which needs a clone.
+		if _, isPointer := parseLFSPointer(content); err == nil && isPointer && fetchLFS(params) {
+			err = fmt.Errorf("file %q is a git lfs pointer", path)
+		}
+		endSpan(apiSpan, err)
+		if err == nil {
+			trace.SpanFromContext(ctx).SetAttributes(attrHost.String(repoHost(remoteURL)), attrRef.String(ref), attrCommit.String(apiCommit))
+			transcode, _ := strconv.ParseBool(conf[ConfigFieldTranscodeToUTF8])
+			var encoding string
+			content, encoding, err = decodeContent(content, transcode)
+			if err != nil {
+				return nil, fmt.Errorf("error decoding file %q: %w", path, err)
+			}
+			resolved, err := r.processContent(ctx, conf, params, path, content, renderer, false)
+			if err != nil {
+				return nil, err
+			}
+			resolved.Commit, resolved.Encoding = apiCommit, encoding
+			resolved.URL, resolved.Ref, resolved.Path = remoteURL, ref, path
+			if commit != "" {
+				resolved.RefType = RefTypeCommit
+			}
+			if err := r.finishResolved(conf, resolved, cacheSize, cacheKey); err != nil {
+				return nil, err
+			}
+			return resolved, nil
+		}
+		logging.FromContext(ctx).Infof("falling back to cloning %s: %v", remoteURL, err)
+	}
+
+	fetchCommit := commit
+	if checkOnBranch {
+		// Fetch the branch rather than the commit so that its tip is
+		// known and commits off the branch aren't fetched at all.
+		fetchCommit = ""
+	}
+	cloneCtx, cloneSpan := startSpan(ctx, "git.clone", attrHost.String(repoHost(remoteURL)), attrURL.String(sanitizeURL(remoteURL)), attrRef.String(ref))
+	// Blame, checking that a commit is on a branch and finding the
+	// commit as of a date walk history past the resolved commit, so
+	// they can't use a shallow clone.
+	if blame, _ := strconv.ParseBool(params[BlameParam]); blame || checkOnBranch || !date.IsZero() {
+		cloneCtx = withFullHistory(cloneCtx)
+	}
+	if useInMemoryClone(cloneCtx, conf, params, inMemoryMaxDepth) {
+		cloneCtx = withInMemoryClone(cloneCtx)
 	}
-	repository, err := git.Clone(memory.NewStorage(), filesystem, cloneOpts)
+	cloneCtx = withCloneCacheURL(cloneCtx, cacheURL)
+	cloneCtx, fetchedBytes := withFetchedBytesCounter(cloneCtx)
+	var fetched *fetchedRepository
+	_, err = r.withTokenRetry(cloneCtx, conf, repo, auth, func(auth transport.AuthMethod) error {
+		return withRetries(cloneCtx, retries, func() (err error) {
+			release, err := r.clones.acquire(cloneCtx, maxClones)
+			if err != nil {
+				return err
+			}
+			defer release()
+			fetched, err = fetchRepository(cloneCtx, repo, auth, candidates, fetchCommit)
+			return err
+		})
+	})
+	cloneSpan.SetAttributes(attrFetchedBytes.Int64(atomic.LoadInt64(fetchedBytes)))
+	endSpan(cloneSpan, err)
 	if err != nil {
-		return nil, fmt.Errorf("clone error: %w", err)
+		return nil, err
+	}
+	defer fetched.cleanup()
+	repository, filesystem := fetched.repository, fetched.filesystem
+	if abbreviated {
+		if commit, err = expandCommitHash(repository, commit); err != nil {
+			return nil, err
+		}
+	}
+	if checkOnBranch {
+		if err := verifyCommitOnBranch(repository, commit, branch, fetched.head); err != nil {
+			return nil, err
+		}
+	}
+	resolvedRefType, defaultBranch := RefTypeCommit, ""
+	if commit == "" && fetched.ref == "" {
+		defaultBranch = defaultBranchName(repository)
 	}
 	if commit == "" {
-		headRef, err := repository.Head()
+		commit = fetched.head.String()
+		if resolvedRefType, err = fetchedRefType(repository, fetched.ref, commit); err != nil {
+			return nil, err
+		}
+		if !date.IsZero() {
+			if commit, err = commitAsOf(repository, commit, date); err != nil {
+				return nil, err
+			}
+		}
+	}
+	trace.SpanFromContext(ctx).SetAttributes(attrHost.String(repoHost(remoteURL)), attrRef.String(ref), attrCommit.String(commit))
+
+	_, checkoutSpan := startSpan(ctx, "git.checkout", attrCommit.String(commit))
+	err = checkoutCommit(repository, commit)
+	endSpan(checkoutSpan, err)
+	if err != nil {
+		return nil, err
+	}
+
+	// The commit must be signed by a key the resolver trusts, if any
+	// are configured, and by one the request trusts if it asks, before
+	// anything is read from it.
+	commitSigner := ""
+	if conf[ConfigFieldTrustedKeys] != "" {
+		keys, err := r.trustedCommitKeys(ctx, conf)
+		if err != nil {
+			return nil, err
+		}
+		if commitSigner, err = verifyCommitSignature(repository, commit, keys); err != nil {
+			return nil, err
+		}
+	}
+	if requireSignature {
+		keys, err := r.requestTrustedKeys(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+		if commitSigner, err = verifyCommitSignature(repository, commit, keys); err != nil {
+			return nil, err
+		}
+	}
+
+	var resolved *ResolvedGitResource
+	if metadata, _ := strconv.ParseBool(params[CommitMetadataParam]); metadata {
+		_, readSpan := startSpan(ctx, "git.read", attrCommit.String(commit))
+		resolved, err = commitMetadata(repository, commit, commitSigner)
+		endSpan(readSpan, err)
+		if err != nil {
+			return nil, err
+		}
+	} else if stat, _ := strconv.ParseBool(params[StatOnlyParam]); stat {
+		_, readSpan := startSpan(ctx, "git.read", attrPath.String(path))
+		resolved, err = statPath(repository, commit, path)
+		endSpan(readSpan, err)
+		if err != nil {
+			return nil, err
+		}
+	} else if params[FormatParam] == FormatTar {
+		maxFiles, err := getMaxFiles(conf)
+		if err != nil {
+			return nil, err
+		}
+		opts, err := getArchiveOptions(conf)
+		if err != nil {
+			return nil, err
+		}
+		_, readSpan := startSpan(ctx, "git.read", attrPath.String(path))
+		resolved, err = archivePath(repository, commit, path, maxFiles, opts)
+		if err == nil {
+			readSpan.SetAttributes(attrBytes.Int(len(resolved.Content)))
+		}
+		endSpan(readSpan, err)
+		if err != nil {
+			return nil, err
+		}
+	} else if blame, _ := strconv.ParseBool(params[BlameParam]); blame {
+		maxSize, err := getMaxSize(ctx)
+		if err != nil {
+			return nil, err
+		}
+		_, readSpan := startSpan(ctx, "git.read", attrPath.String(path))
+		resolved, err = blameFile(repository, commit, path, maxSize)
+		if err == nil {
+			readSpan.SetAttributes(attrBytes.Int(len(resolved.Content)))
+		}
+		endSpan(readSpan, err)
+		if err != nil {
+			return nil, err
+		}
+	} else if pattern := params[GrepParam]; pattern != "" {
+		maxSize, err := getMaxSize(ctx)
+		if err != nil {
+			return nil, err
+		}
+		maxFiles, err := getMaxFiles(conf)
+		if err != nil {
+			return nil, err
+		}
+		lineNumbers, _ := strconv.ParseBool(params[GrepLineNumbersParam])
+		_, readSpan := startSpan(ctx, "git.read", attrPath.String(path))
+		resolved, err = grepDirectory(repository, commit, path, pattern, lineNumbers, maxFiles, maxSize)
+		if err == nil {
+			readSpan.SetAttributes(attrBytes.Int(len(resolved.Content)))
+		}
+		endSpan(readSpan, err)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		// A path that leads into a submodule is read from the
+		// submodule, at the commit recorded for it.
+		target := submodulePath{url: repo, repository: repository, filesystem: filesystem, commit: commit, path: path}
+		if followSubmodules(conf, params) {
+			maxDepth, err := getMaxSubmoduleDepth(conf)
+			if err != nil {
+				return nil, err
+			}
+			if target, err = enterSubmodules(ctx, conf, target, auth, maxDepth); err != nil {
+				return nil, err
+			}
+		}
+		var content []byte
+		_, readSpan := startSpan(ctx, "git.read", attrPath.String(path))
+		content, err = readPath(target.repository, target.filesystem, conf, target.commit, target.path)
+		readSpan.SetAttributes(attrBytes.Int(len(content)))
+		endSpan(readSpan, err)
+		fromDefault := false
+		if isFileMissing(err) {
+			content, fromDefault, err = onMissing(params, err)
+		}
+		if err != nil {
+			return nil, err
+		}
+		crossCheck, err := getCrossCheckMode(conf)
+		if err != nil {
+			return nil, err
+		}
+		backendMismatch := ""
+		// The git binary reads the parent repo alone, so files read
+		// from submodules aren't cross-checked.
+		if crossCheck != "" && !fromDefault && target.repository == repository {
+			backendMismatch, err = crossCheckBackends(ctx, crossCheck, repo, auth, fetched, commit, path, content)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if !fromDefault {
+			maxSize, err := getMaxSize(ctx)
+			if err != nil {
+				return nil, err
+			}
+			if content, err = resolveLFSPointer(ctx, params, target, repo, auth, maxSize, content); err != nil {
+				return nil, err
+			}
+		}
+		signatureKey := ""
+		if verify, _ := strconv.ParseBool(params[VerifySignatureParam]); verify {
+			keys, err := trustedSigningKeys(conf)
+			if err != nil {
+				return nil, err
+			}
+			signatureKey, err = verifyDetachedSignature(target.repository, target.commit, target.path, content, keys)
+			if err != nil {
+				return nil, err
+			}
+		}
+		// Signatures are made over the bytes as committed, so content
+		// is only transcoded once they're verified.
+		transcode, _ := strconv.ParseBool(conf[ConfigFieldTranscodeToUTF8])
+		var encoding string
+		content, encoding, err = decodeContent(content, transcode)
+		if err != nil {
+			return nil, fmt.Errorf("error decoding file %q: %w", path, err)
+		}
+		var dependencies []string
+		if include, _ := strconv.ParseBool(params[IncludeDependenciesParam]); include && !fromDefault {
+			maxDepth, err := getMaxDependencyDepth(conf)
+			if err != nil {
+				return nil, err
+			}
+			content, dependencies, err = bundleDependencies(path, content, remoteURL, commit, ref, maxDepth, func(dep string) ([]byte, error) {
+				depContent, err := readPath(repository, filesystem, conf, commit, dep)
+				if err != nil || !transcode {
+					return depContent, err
+				}
+				depContent, _, err = decodeContent(depContent, transcode)
+				if err != nil {
+					return nil, fmt.Errorf("error decoding file %q: %w", dep, err)
+				}
+				return depContent, nil
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+		resolved, err = r.processContent(ctx, conf, params, path, content, renderer, fromDefault)
+		if err != nil {
+			return nil, err
+		}
+		resolved.Commit, resolved.Encoding = commit, encoding
+		resolved.BackendMismatch, resolved.SignatureKey, resolved.Dependencies = backendMismatch, signatureKey, dependencies
+		// Directories and patterns resolve to yaml streams whatever
+		// their names look like.
+		if isGlobPattern(path) || isDirectory(target.repository, target.commit, target.path) {
+			resolved.ContentType = YAMLContentType
+		}
+	}
+
+	if includeLicense, _ := strconv.ParseBool(params[IncludeLicenseParam]); includeLicense {
+		resolved.License, err = detectLicense(repository, commit)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if resolved.Provenance, err = readCommitProvenance(repository, commit); err != nil {
+		return nil, err
+	}
+	if resolved.ShortCommit, err = shortCommitHash(repository, commit); err != nil {
+		return nil, err
+	}
+
+	resolved.URL, resolved.Ref, resolved.Path = remoteURL, ref, path
+	resolved.RefType, resolved.DefaultBranch = resolvedRefType, defaultBranch
+	resolved.CommitSigner = commitSigner
+	if err := r.finishResolved(conf, resolved, cacheSize, cacheKey); err != nil {
+		return nil, err
+	}
+	return resolved, nil
+}
+
+// processContent applies the checks and transformations that a file's
+// content goes through once it's been read: rejecting empty files if
+// configured, rendering, stripping comments and canonicalizing yaml.
+func (r *Resolver) processContent(ctx context.Context, conf, params map[string]string, path string, content []byte, renderer string, fromDefault bool) (*ResolvedGitResource, error) {
+	if len(content) == 0 && !fromDefault {
+		allowEmpty, _ := strconv.ParseBool(params[AllowEmptyParam])
+		rejectEmpty, _ := strconv.ParseBool(conf[ConfigFieldRejectEmpty])
+		if rejectEmpty && !allowEmpty {
+			return nil, fmt.Errorf("file %q is empty; set %q to accept empty content", path, AllowEmptyParam)
+		}
+	}
+	if renderer != RendererNone {
+		values, err := r.renderValues(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+		content, err = render(ctx, renderer, path, content, values)
+		if err != nil {
+			return nil, err
+		}
+	}
+	commentsStripped := false
+	if strip, _ := strconv.ParseBool(conf[ConfigFieldStripComments]); strip {
+		content, commentsStripped = stripComments(content), true
+	}
+	canonicalized := false
+	if canonicalize, _ := strconv.ParseBool(conf[ConfigFieldCanonicalizeYAML]); canonicalize {
+		canonical, err := canonicalizeYAML(content)
+		skipInvalid, _ := strconv.ParseBool(conf[ConfigFieldCanonicalizeYAMLSkipInvalid])
+		switch {
+		case err == nil:
+			content, canonicalized = canonical, true
+		case !skipInvalid:
+			return nil, fmt.Errorf("error canonicalizing file %q: %w", path, err)
+		}
+	}
+	return &ResolvedGitResource{
+		Content:          content,
+		Canonicalized:    canonicalized,
+		CommentsStripped: commentsStripped,
+		FromDefault:      fromDefault,
+	}, nil
+}
+
+// finishResolved computes resolved's digest, if configured, and caches
+// it under cacheKey, if there is one.
+func (r *Resolver) finishResolved(conf map[string]string, resolved *ResolvedGitResource, cacheSize int, cacheKey string) error {
+	if computeDigest, _ := strconv.ParseBool(conf[ConfigFieldComputeOCIDigest]); computeDigest {
+		digest, _, err := v1.SHA256(bytes.NewReader(resolved.Content))
 		if err != nil {
-			return nil, fmt.Errorf("error reading repository HEAD value: %w", err)
+			return fmt.Errorf("error computing digest: %w", err)
 		}
-		commit = headRef.Hash().String()
+		resolved.OCIDigest = digest.String()
 	}
+	if cacheKey != "" {
+		r.cache.add(cacheSize, cacheKey, resolved)
+	}
+	return nil
+}
 
+// checkoutCommit checks out the given commit into the repository's
+// worktree. The worktree is always freshly created, so the check for
+// local changes is skipped: go-git's in-memory filesystem doesn't
+// report absolute symlinks as they were written, which would otherwise
+// fail the checkout of any repo holding one.
+func checkoutCommit(repository *git.Repository, commit string) error {
 	w, err := repository.Worktree()
 	if err != nil {
-		return nil, fmt.Errorf("worktree error: %v", err)
+		return fmt.Errorf("worktree error: %v", err)
 	}
-
 	err = w.Checkout(&git.CheckoutOptions{
-		Hash: plumbing.NewHash(commit),
+		Hash:  plumbing.NewHash(commit),
+		Force: true,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("checkout error: %v", err)
+		return fmt.Errorf("checkout error: %v", err)
+	}
+	return nil
+}
+
+// Values of the onMissing param with special meanings. Any other value
+// is returned as the content of a missing file.
+const (
+	OnMissingFail  = "fail"
+	OnMissingEmpty = "empty"
+)
+
+// isFileMissing returns true if err means the requested file doesn't
+// exist at the resolved commit.
+func isFileMissing(err error) bool {
+	return errors.Is(err, object.ErrFileNotFound) || errors.Is(err, os.ErrNotExist)
+}
+
+// onMissing returns the content to resolve in place of a missing file
+// according to the onMissing param, and whether it is a default. The
+// given error is returned unchanged when the param is unset or "fail".
+func onMissing(params map[string]string, missingErr error) ([]byte, bool, error) {
+	switch mode, ok := params[OnMissingParam]; {
+	case !ok || mode == OnMissingFail:
+		return nil, false, missingErr
+	case mode == OnMissingEmpty:
+		return []byte{}, true, nil
+	default:
+		return []byte(mode), true, nil
+	}
+}
+
+// readPath returns the content of the file at path, or the yaml files
+// beneath it concatenated together if it's a directory, or the files
+// matching it if it's a glob pattern.
+func readPath(repository *git.Repository, filesystem billy.Filesystem, conf map[string]string, commit, path string) ([]byte, error) {
+	maxFiles, err := getMaxFiles(conf)
+	if err != nil {
+		return nil, err
+	}
+	if isGlobPattern(path) {
+		return readGlob(repository, filesystem, conf, commit, path, maxFiles)
+	}
+	if err := checkSymlinks(repository, commit, path); err != nil {
+		return nil, err
+	}
+	content, isDir, err := readDirectory(repository, commit, path, maxFiles)
+	if err != nil || isDir {
+		return content, err
 	}
+	if useWorktree, _ := strconv.ParseBool(conf[ConfigFieldUseWorktreeContent]); useWorktree {
+		return readFile(filesystem, path)
+	}
+	return readBlob(repository, commit, path)
+}
 
+// readFile returns the full content of the file at path.
+func readFile(filesystem billy.Filesystem, path string) ([]byte, error) {
 	f, err := filesystem.Open(path)
 	if err != nil {
-		return nil, fmt.Errorf("error opening file %q: %v", path, err)
+		return nil, fmt.Errorf("error opening file %q: %w", path, err)
 	}
+	defer f.Close()
 
 	buf := &bytes.Buffer{}
 	_, err = io.Copy(buf, f)
 	if err != nil {
 		return nil, fmt.Errorf("error reading file %q: %v", path, err)
 	}
+	return buf.Bytes(), nil
+}
 
-	return &ResolvedGitResource{
-		Commit:  commit,
-		Content: buf.Bytes(),
-	}, nil
+// enforceCommitOnBranch returns true if requests supplying both a
+// commit and a branch are allowed, and checked, as configured with the
+// enforce-commit-on-branch field in the git-resolver-config configmap.
+func enforceCommitOnBranch(conf map[string]string) bool {
+	enforce, _ := strconv.ParseBool(conf[ConfigFieldEnforceCommitOnBranch])
+	return enforce
+}
+
+// verifyCommitOnBranch returns an error unless commit is the given
+// branch's tip or one of its ancestors.
+func verifyCommitOnBranch(repository *git.Repository, commit, branch string, tip plumbing.Hash) error {
+	c, err := repository.CommitObject(plumbing.NewHash(commit))
+	if errors.Is(err, plumbing.ErrObjectNotFound) {
+		return fmt.Errorf("commit %s is not on branch %q", commit, branch)
+	}
+	if err != nil {
+		return fmt.Errorf("error reading commit %s: %w", commit, err)
+	}
+	tipCommit, err := repository.CommitObject(tip)
+	if err != nil {
+		return fmt.Errorf("error reading tip of branch %q: %w", branch, err)
+	}
+	onBranch, err := c.IsAncestor(tipCommit)
+	if err != nil {
+		return fmt.Errorf("error checking commit %s is on branch %q: %w", commit, branch, err)
+	}
+	if !onBranch {
+		return fmt.Errorf("commit %s is not on branch %q", commit, branch)
+	}
+	return nil
+}
+
+// readBlob returns the content of the file at path as stored in the
+// repository at the given commit. Unlike readFile the bytes are not
+// subject to checkout filters like core.autocrlf, so they are the same
+// regardless of where the repo was checked out.
+func readBlob(repository *git.Repository, commit, path string) ([]byte, error) {
+	commitObj, err := repository.CommitObject(plumbing.NewHash(commit))
+	if err != nil {
+		return nil, fmt.Errorf("error reading commit %q: %v", commit, err)
+	}
+	file, err := commitObj.File(strings.TrimPrefix(path, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("error opening file %q: %w", path, err)
+	}
+	r, err := file.Reader()
+	if err != nil {
+		return nil, fmt.Errorf("error opening file %q: %v", path, err)
+	}
+	defer r.Close()
+
+	buf := &bytes.Buffer{}
+	_, err = io.Copy(buf, r)
+	if err != nil {
+		return nil, fmt.Errorf("error reading file %q: %v", path, err)
+	}
+	return buf.Bytes(), nil
 }
 
 var _ framework.ConfigWatcher = &Resolver{}
@@ -178,25 +1325,247 @@ func (r *Resolver) GetResolutionTimeout(ctx context.Context, defaultTimeout time
 	return defaultTimeout
 }
 
+// defaultMaxSize is the maximum number of bytes that a resolved file
+// may contain when max-size isn't configured. It leaves room for the
+// base64 encoding of the content in the ResolutionRequest's status
+// under etcd's object size limit.
+const defaultMaxSize = 1024 * 1024
+
+// getMaxSize returns the maximum number of bytes that a file resolved
+// from git may contain, as configured with the max-size field in the
+// git-resolver-config configmap. Zero means no limit is enforced.
+func getMaxSize(ctx context.Context) (int64, error) {
+	conf := framework.GetResolverConfigFromContext(ctx)
+	sizeString, ok := conf[ConfigFieldMaxSize]
+	if !ok || sizeString == "" {
+		return defaultMaxSize, nil
+	}
+	size, err := resource.ParseQuantity(sizeString)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q: %w", ConfigFieldMaxSize, sizeString, err)
+	}
+	return size.Value(), nil
+}
+
 // ResolvedGitResource implements framework.ResolvedResource and returns
 // the resolved file []byte data and an annotation map for any metadata.
 type ResolvedGitResource struct {
 	Commit  string
 	Content []byte
+
+	// ShortCommit is the shortest unambiguous abbreviation of Commit in
+	// the cloned repo. It's left empty when the file was read without
+	// cloning the repo.
+	ShortCommit string
+
+	// URL, Ref and Path record where Content was resolved from. Ref
+	// is empty when a commit or the default branch was requested.
+	URL  string
+	Ref  string
+	Path string
+
+	// DefaultBranch is the name of the remote's default branch when
+	// neither a ref nor a commit was requested and it was resolved from
+	// that.
+	DefaultBranch string
+
+	// RefType is the kind of ref that Ref resolved to, one of
+	// "branch", "lightweight-tag" or "annotated-tag", or "commit" when
+	// a commit was requested. It's empty when the default branch was
+	// resolved, or a ref through the contents api.
+	RefType string
+
+	// ContentType overrides the content type detected from Path's
+	// extension when set.
+	ContentType string
+
+	// Files are the entries of Content when it's a tar archive of a
+	// directory or file at Path.
+	Files []framework.ResolvedFile
+
+	// Encoding is the text encoding that the resolved file was detected
+	// as being committed in, before any transcoding to UTF-8.
+	Encoding string
+
+	// OCIDigest is the sha256 digest of Content as an OCI blob, if
+	// one was computed.
+	OCIDigest string
+
+	// Provenance is who made the resolved commit and when. It's left
+	// empty when the file was read without cloning the repo.
+	Provenance commitProvenance
+
+	// License is the SPDX identifier of the repository's license, if
+	// one was requested and detected.
+	License string
+
+	// Canonicalized is true if Content was re-emitted in canonical
+	// yaml form.
+	Canonicalized bool
+
+	// CommentsStripped is true if comments and blank lines were removed
+	// from Content.
+	CommentsStripped bool
+
+	// BackendMismatch describes how the content resolved by go-git and
+	// the git binary differed, if cross-check-backends is "warn".
+	BackendMismatch string
+
+	// Attestation is a json DSSE envelope of a signed in-toto statement
+	// of where Content was resolved from, if attestation-key-secret is
+	// configured.
+	Attestation string
+
+	// Pinned is true if Commit is the one that Ref was pinned to when
+	// it was first resolved, with pin-on-first-resolve configured.
+	Pinned bool
+
+	// FromDefault is true if the requested file was missing and Content
+	// is the default given by the onMissing param.
+	FromDefault bool
+
+	// Dependencies are the paths of the files bundled after the one at
+	// Path when includeDependencies is set.
+	Dependencies []string
+
+	// CommitSigner is the id of the trusted key that Commit was signed
+	// with, if trusted-keys-secret is configured.
+	CommitSigner string
+
+	// SignatureKey is the id of the trusted key whose detached signature
+	// of the file was verified, if verification was requested.
+	SignatureKey string
+
+	// OverlayURL, OverlayPath and OverlayCommit record where an overlay
+	// file merged into Content was resolved from, if one was requested.
+	OverlayURL    string
+	OverlayPath   string
+	OverlayCommit string
 }
 
-var _ framework.ResolvedResource = &ResolvedGitResource{}
+var _ framework.ResolvedResourceWithMetadata = &ResolvedGitResource{}
 
 // Data returns the bytes of the file resolved from git.
 func (r *ResolvedGitResource) Data() []byte {
 	return r.Content
 }
 
+var _ framework.ResolvedResourceWithFiles = &ResolvedGitResource{}
+
+// ResolvedFiles returns the files that Content archives, or nil if it
+// isn't an archive of them, like once it's been wrapped in a manifest.
+func (r *ResolvedGitResource) ResolvedFiles() []framework.ResolvedFile {
+	if r.ContentType != TarContentType {
+		return nil
+	}
+	return r.Files
+}
+
+// detectContentType returns the content type of the file at path:
+// json or yaml going by its extension, yaml if it has none, or else
+// whatever its content is sniffed as.
+func detectContentType(path string, content []byte) string {
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		return JSONContentType
+	case "", ".yaml", ".yml":
+		return YAMLContentType
+	default:
+		return http.DetectContentType(content)
+	}
+}
+
 // Annotations returns the metadata that accompanies the file fetched
 // from git.
 func (r *ResolvedGitResource) Annotations() map[string]string {
-	return map[string]string{
+	contentType := r.ContentType
+	if contentType == "" {
+		contentType = detectContentType(r.Path, r.Content)
+	}
+	annotations := map[string]string{
 		AnnotationKeyCommitHash:                   r.Commit,
-		resolutioncommon.AnnotationKeyContentType: YAMLContentType,
+		resolutioncommon.AnnotationKeyContentType: contentType,
+	}
+	if r.ShortCommit != "" {
+		annotations[AnnotationKeyCommitShortHash] = r.ShortCommit
+	} else if r.Commit != "" {
+		annotations[AnnotationKeyCommitShortHash] = shortHashOf(r.Commit)
+	}
+	if r.URL != "" {
+		annotations[AnnotationKeyURL] = sanitizeURL(r.URL)
+	}
+	if r.Ref != "" {
+		annotations[AnnotationKeyRef] = r.Ref
+	}
+	if r.RefType != "" {
+		annotations[AnnotationKeyRefType] = r.RefType
+	}
+	if r.DefaultBranch != "" {
+		annotations[AnnotationKeyDefaultBranch] = r.DefaultBranch
+	}
+	if r.Encoding != "" {
+		annotations[AnnotationKeyEncoding] = r.Encoding
+	}
+	if r.OCIDigest != "" {
+		annotations[AnnotationKeyOCIDigest] = r.OCIDigest
+	}
+	if r.License != "" {
+		annotations[AnnotationKeyLicense] = r.License
+	}
+	if r.Canonicalized {
+		annotations[AnnotationKeyCanonicalized] = "true"
+	}
+	if r.CommentsStripped {
+		annotations[AnnotationKeyCommentsStripped] = "true"
+	}
+	if r.BackendMismatch != "" {
+		annotations[AnnotationKeyBackendMismatch] = r.BackendMismatch
+	}
+	if r.Attestation != "" {
+		annotations[AnnotationKeyAttestation] = r.Attestation
+	}
+	if r.Pinned {
+		annotations[AnnotationKeyPinned] = "true"
+	}
+	if r.FromDefault {
+		annotations[AnnotationKeyResolvedFrom] = "default"
+	}
+	if len(r.Dependencies) > 0 {
+		annotations[AnnotationKeyDependencies] = strings.Join(r.Dependencies, ",")
+	}
+	if r.CommitSigner != "" {
+		annotations[AnnotationKeyCommitSigner] = r.CommitSigner
+	}
+	if r.SignatureKey != "" {
+		annotations[AnnotationKeySignatureKey] = r.SignatureKey
+	}
+	if !r.Provenance.CommittedAt.IsZero() {
+		annotations[AnnotationKeyCommitAuthorName] = r.Provenance.AuthorName
+		annotations[AnnotationKeyCommitAuthorEmail] = r.Provenance.AuthorEmail
+		annotations[AnnotationKeyCommitDate] = r.Provenance.CommittedAt.Format(time.RFC3339)
+		annotations[AnnotationKeyCommitSubject] = r.Provenance.Subject
+	}
+	if r.OverlayPath != "" {
+		annotations[AnnotationKeyOverlayURL] = sanitizeURL(r.OverlayURL)
+		annotations[AnnotationKeyOverlayPath] = r.OverlayPath
+		annotations[AnnotationKeyOverlayCommit] = r.OverlayCommit
+	}
+	return annotations
+}
+
+// Metadata returns the provenance of the file fetched from git.
+func (r *ResolvedGitResource) Metadata() *v1alpha1.ResolvedMetadata {
+	digest := r.OCIDigest
+	if digest == "" {
+		if hash, _, err := v1.SHA256(bytes.NewReader(r.Content)); err == nil {
+			digest = hash.String()
+		}
+	}
+	return &v1alpha1.ResolvedMetadata{
+		Repo:   sanitizeURL(r.URL),
+		Ref:    r.Ref,
+		Commit: r.Commit,
+		Path:   r.Path,
+		Digest: digest,
 	}
 }
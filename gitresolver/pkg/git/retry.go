@@ -0,0 +1,153 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	resolutioncommon "github.com/tektoncd/resolution/pkg/common"
+	"knative.dev/pkg/logging"
+)
+
+// defaultBackoff is how long the first retry waits when retry-backoff
+// isn't configured.
+const defaultBackoff = time.Second
+
+// retryPolicy is how many times, and how patiently, transient failures
+// of operations against a remote are retried.
+type retryPolicy struct {
+	maxRetries int
+	backoff    time.Duration
+}
+
+// getRetryPolicy returns the retry policy configured with the
+// max-retries and retry-backoff fields in the git-resolver-config
+// configmap. Failures aren't retried unless max-retries is set.
+func getRetryPolicy(conf map[string]string) (retryPolicy, error) {
+	policy := retryPolicy{backoff: defaultBackoff}
+	if retriesString := conf[ConfigFieldMaxRetries]; retriesString != "" {
+		retries, err := strconv.Atoi(retriesString)
+		if err != nil || retries < 0 {
+			return retryPolicy{}, fmt.Errorf("invalid %s %q: must be a non-negative integer", ConfigFieldMaxRetries, retriesString)
+		}
+		policy.maxRetries = retries
+	}
+	backoff, err := getDuration(conf, ConfigFieldBackoff)
+	if err != nil {
+		return retryPolicy{}, err
+	}
+	if backoff < 0 {
+		return retryPolicy{}, fmt.Errorf("invalid %s %q: must not be negative", ConfigFieldBackoff, conf[ConfigFieldBackoff])
+	}
+	if backoff > 0 {
+		policy.backoff = backoff
+	}
+	return policy, nil
+}
+
+// withRetries calls fn, calling it again after an exponentially growing
+// wait each time it fails with a transient error, until it succeeds or
+// the policy's retries run out. Retrying stops early, returning the
+// last error, if ctx is done or its deadline would pass during the
+// wait. A transient error that's still failing is marked retryable so
+// that the request can be resolved again later.
+func withRetries(ctx context.Context, policy retryPolicy, fn func() error) error {
+	wait := policy.backoff
+	err := fn()
+	for retry := 1; retry <= policy.maxRetries && isTransientError(err) && ctx.Err() == nil; retry++ {
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			break
+		}
+		logging.FromContext(ctx).Infof("retrying in %s, %d of %d: %v", wait, retry, policy.maxRetries, err)
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		wait *= 2
+		err = fn()
+	}
+	if isTransientError(err) && ctx.Err() == nil {
+		return resolutioncommon.NewRetryableError(err)
+	}
+	return err
+}
+
+// cliTransientMessages are the parts of the git binary's error messages
+// that show a failure was down to the network or the server rather than
+// the request.
+var cliTransientMessages = []string{
+	"Could not resolve host",
+	"Connection refused",
+	"Connection reset",
+	"Connection timed out",
+	"Operation timed out",
+	"The remote end hung up unexpectedly",
+	"early EOF",
+	"RPC failed",
+	"returned error: 5",
+}
+
+// isTransientError returns true if err is the kind of failure that may
+// not happen again, like a dns lookup or connection failing or a server
+// error, so the operation is worth retrying. Missing refs and files,
+// missing repos and rejected credentials are never retried.
+func isTransientError(err error) bool {
+	if err == nil || isNotFound(err) || isAuthRejected(err) || errors.Is(err, context.Canceled) ||
+		errors.Is(err, transport.ErrRepositoryNotFound) || errors.Is(err, transport.ErrEmptyRemoteRepository) {
+		return false
+	}
+	// go-git's http transport reports unexpected failures in an error
+	// that doesn't unwrap.
+	var unexpected *plumbing.UnexpectedError
+	if errors.As(err, &unexpected) {
+		return isTransientError(unexpected.Err)
+	}
+	var httpErr *githttp.Err
+	if errors.As(err, &httpErr) {
+		code := httpErr.StatusCode()
+		return code >= http.StatusInternalServerError || code == http.StatusTooManyRequests || code == http.StatusRequestTimeout
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+	for _, message := range cliTransientMessages {
+		if strings.Contains(err.Error(), message) {
+			return true
+		}
+	}
+	return false
+}
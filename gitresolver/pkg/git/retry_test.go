@@ -0,0 +1,218 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	resolutioncommon "github.com/tektoncd/resolution/pkg/common"
+	"github.com/tektoncd/resolution/pkg/resolver/framework"
+)
+
+// newFlakyGitServer serves the repo at repoDir over http, failing the
+// first failures ref advertisements with the given status. It returns
+// the repo's url and a count of the advertisements requested.
+func newFlakyGitServer(t *testing.T, repoDir string, failures int32, status int) (string, *int32) {
+	t.Helper()
+	var requested int32
+	server := newGitHTTPServer(t, repoDir, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if strings.HasSuffix(r.URL.Path, "/info/refs") {
+				if atomic.AddInt32(&requested, 1) <= failures {
+					http.Error(w, "flaky", status)
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+	return server.URL + "/repo", &requested
+}
+
+func TestResolveRetriesTransientErrors(t *testing.T) {
+	repoDir, _ := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "kind: Task"},
+	}})
+
+	for _, tc := range []struct {
+		name      string
+		failures  int32
+		status    int
+		conf      map[string]string
+		attempts  int32
+		expectErr string
+		retryable bool
+	}{{
+		name:     "recovers",
+		failures: 2,
+		status:   http.StatusBadGateway,
+		conf:     map[string]string{ConfigFieldMaxRetries: "2", ConfigFieldBackoff: "1ms"},
+		attempts: 3,
+	}, {
+		name:     "recovers with minimal fetch",
+		failures: 1,
+		status:   http.StatusServiceUnavailable,
+		conf:     map[string]string{ConfigFieldMaxRetries: "1", ConfigFieldBackoff: "1ms", ConfigFieldMinimalFetch: "true"},
+		attempts: 2,
+	}, {
+		name:      "retries run out",
+		failures:  3,
+		status:    http.StatusInternalServerError,
+		conf:      map[string]string{ConfigFieldMaxRetries: "2", ConfigFieldBackoff: "1ms"},
+		attempts:  3,
+		expectErr: "status code: 500",
+		retryable: true,
+	}, {
+		name:      "not retried by default",
+		failures:  1,
+		status:    http.StatusBadGateway,
+		conf:      map[string]string{},
+		attempts:  1,
+		expectErr: "status code: 502",
+		retryable: true,
+	}, {
+		name:      "missing repo",
+		failures:  1,
+		status:    http.StatusNotFound,
+		conf:      map[string]string{ConfigFieldMaxRetries: "2", ConfigFieldBackoff: "1ms"},
+		attempts:  1,
+		expectErr: transport.ErrRepositoryNotFound.Error(),
+	}, {
+		name:      "auth failure",
+		failures:  1,
+		status:    http.StatusUnauthorized,
+		conf:      map[string]string{ConfigFieldMaxRetries: "2", ConfigFieldBackoff: "1ms"},
+		attempts:  1,
+		expectErr: transport.ErrAuthenticationRequired.Error(),
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			repoURL, requested := newFlakyGitServer(t, repoDir, tc.failures, tc.status)
+			resolver := Resolver{}
+			resource, err := resolver.Resolve(framework.InjectResolverConfigToContext(context.Background(), tc.conf), map[string]string{
+				URLParam:  repoURL,
+				PathParam: "task.yaml",
+			})
+			if tc.expectErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error resolving: %v", err)
+				}
+				if string(resource.Data()) != "kind: Task" {
+					t.Errorf("expected content %q received %q", "kind: Task", string(resource.Data()))
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tc.expectErr) {
+				t.Fatalf("expected error containing %q, received %v", tc.expectErr, err)
+			} else if resolutioncommon.IsRetryable(err) != tc.retryable {
+				t.Errorf("expected error to be retryable %t, received %v", tc.retryable, err)
+			}
+			if got := atomic.LoadInt32(requested); got != tc.attempts {
+				t.Errorf("expected %d attempts, received %d", tc.attempts, got)
+			}
+		})
+	}
+}
+
+func TestResolveRetriesRespectDeadline(t *testing.T) {
+	repoDir, _ := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "kind: Task"},
+	}})
+	repoURL, requested := newFlakyGitServer(t, repoDir, 10, http.StatusBadGateway)
+
+	ctx := framework.InjectResolverConfigToContext(context.Background(), map[string]string{
+		ConfigFieldMaxRetries: "5",
+		ConfigFieldBackoff:    "100ms",
+	})
+	ctx, cancel := context.WithTimeout(ctx, 250*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	resolver := Resolver{}
+	_, err := resolver.Resolve(ctx, map[string]string{
+		URLParam:  repoURL,
+		PathParam: "task.yaml",
+	})
+	if err == nil || !strings.Contains(err.Error(), "status code: 502") {
+		t.Fatalf("expected the last transient error, received %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 250*time.Millisecond {
+		t.Errorf("expected retries to stop before the deadline, took %s", elapsed)
+	}
+	// Waits of 100ms then 200ms: the second would pass the deadline.
+	if got := atomic.LoadInt32(requested); got != 2 {
+		t.Errorf("expected 2 attempts before the deadline, received %d", got)
+	}
+}
+
+func TestGetRetryPolicy(t *testing.T) {
+	for _, tc := range []struct {
+		conf     map[string]string
+		expected retryPolicy
+		message  string
+	}{
+		{conf: map[string]string{}, expected: retryPolicy{backoff: defaultBackoff}},
+		{conf: map[string]string{ConfigFieldMaxRetries: "3", ConfigFieldBackoff: "250ms"}, expected: retryPolicy{maxRetries: 3, backoff: 250 * time.Millisecond}},
+		{conf: map[string]string{ConfigFieldMaxRetries: "-1"}, message: `invalid max-retries "-1"`},
+		{conf: map[string]string{ConfigFieldMaxRetries: "many"}, message: `invalid max-retries "many"`},
+		{conf: map[string]string{ConfigFieldBackoff: "soon"}, message: `invalid retry-backoff "soon"`},
+		{conf: map[string]string{ConfigFieldBackoff: "-1s"}, message: `invalid retry-backoff "-1s"`},
+	} {
+		policy, err := getRetryPolicy(tc.conf)
+		if tc.message != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.message) {
+				t.Errorf("expected error containing %q for %v, received %v", tc.message, tc.conf, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error for %v: %v", tc.conf, err)
+		} else if policy != tc.expected {
+			t.Errorf("expected policy %+v for %v, received %+v", tc.expected, tc.conf, policy)
+		}
+	}
+}
+
+func TestIsTransientError(t *testing.T) {
+	httpErr := func(code int) error {
+		return plumbing.NewUnexpectedError(&githttp.Err{Response: &http.Response{
+			StatusCode: code,
+			Request:    &http.Request{URL: &url.URL{Scheme: "https", Host: "example.com", Path: "/repo"}},
+		}})
+	}
+	for _, tc := range []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{name: "nil", err: nil},
+		{name: "dns", err: fmt.Errorf("clone error: %w", &net.DNSError{Err: "no such host", Name: "example.com"}), expected: true},
+		{name: "connection refused", err: fmt.Errorf("clone error: %w", &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}), expected: true},
+		{name: "unexpected eof", err: fmt.Errorf("fetch error: %w", io.ErrUnexpectedEOF), expected: true},
+		{name: "timeout", err: fmt.Errorf("clone error: %w", context.DeadlineExceeded), expected: true},
+		{name: "server error", err: fmt.Errorf("clone error: %w", httpErr(http.StatusBadGateway)), expected: true},
+		{name: "rate limited", err: fmt.Errorf("clone error: %w", httpErr(http.StatusTooManyRequests)), expected: true},
+		{name: "client error", err: fmt.Errorf("clone error: %w", httpErr(http.StatusBadRequest))},
+		{name: "wrapped dial error", err: plumbing.NewUnexpectedError(&net.OpError{Op: "dial", Err: syscall.ECONNRESET}), expected: true},
+		{name: "git cli", err: errors.New("git clone: exit status 128: fatal: unable to access 'https://example.com/': Could not resolve host: example.com"), expected: true},
+		{name: "canceled", err: fmt.Errorf("clone error: %w", context.Canceled)},
+		{name: "ref not found", err: fmt.Errorf("clone error: %w", plumbing.ErrReferenceNotFound)},
+		{name: "git cli ref not found", err: errors.New("git clone: exit status 128: fatal: Remote branch nope not found in upstream origin")},
+		{name: "repo not found", err: fmt.Errorf("clone error: %w", transport.ErrRepositoryNotFound)},
+		{name: "auth required", err: fmt.Errorf("clone error: %w", transport.ErrAuthenticationRequired)},
+		{name: "auth failed", err: fmt.Errorf("clone error: %w", transport.ErrAuthorizationFailed)},
+		{name: "other", err: errors.New("something else")},
+	} {
+		if got := isTransientError(tc.err); got != tc.expected {
+			t.Errorf("%s: expected transient %t, received %t for %v", tc.name, tc.expected, got, tc.err)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package git
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tektoncd/resolution/pkg/resolver/framework"
+)
+
+func TestResolveRevision(t *testing.T) {
+	repoDir, commits := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "commit"},
+	}, {
+		files: map[string]string{"task.yaml": "branch"},
+	}, {
+		files: map[string]string{"task.yaml": "tag"},
+	}, {
+		files: map[string]string{"task.yaml": "hex branch"},
+	}, {
+		files: map[string]string{"task.yaml": "head"},
+	}})
+	setTestRef(t, repoDir, "refs/heads/feature", commits[1])
+	setTestRef(t, repoDir, "refs/tags/feature", commits[2])
+	setTestRef(t, repoDir, "refs/tags/v1", commits[2])
+	// A branch named like an abbreviation of a commit is picked over
+	// the commit.
+	setTestRef(t, repoDir, "refs/heads/"+commits[0][:8], commits[3])
+
+	for _, fetch := range []struct {
+		name string
+		conf map[string]string
+	}{
+		{name: "clone", conf: map[string]string{}},
+		{name: "minimal fetch", conf: map[string]string{ConfigFieldMinimalFetch: "true"}},
+		{name: "cache", conf: map[string]string{ConfigFieldCacheSize: "10"}},
+	} {
+		for _, tc := range []struct {
+			name            string
+			revision        string
+			expectedContent string
+			expectedCommit  string
+			expectedType    string
+		}{{
+			name:            "branch",
+			revision:        "feature",
+			expectedContent: "branch",
+			expectedCommit:  commits[1],
+			expectedType:    RefTypeBranch,
+		}, {
+			name:            "tag",
+			revision:        "v1",
+			expectedContent: "tag",
+			expectedCommit:  commits[2],
+			expectedType:    RefTypeLightweightTag,
+		}, {
+			name:            "commit",
+			revision:        commits[0],
+			expectedContent: "commit",
+			expectedCommit:  commits[0],
+			expectedType:    RefTypeCommit,
+		}, {
+			name:            "abbreviated commit",
+			revision:        commits[0][:7],
+			expectedContent: "commit",
+			expectedCommit:  commits[0],
+			expectedType:    RefTypeCommit,
+		}, {
+			name:            "branch named like a commit",
+			revision:        commits[0][:8],
+			expectedContent: "hex branch",
+			expectedCommit:  commits[3],
+			expectedType:    RefTypeBranch,
+		}} {
+			t.Run(fetch.name+"/"+tc.name, func(t *testing.T) {
+				resolver := Resolver{}
+				resource, err := resolver.Resolve(framework.InjectResolverConfigToContext(context.Background(), fetch.conf), map[string]string{
+					URLParam:      repoDir,
+					PathParam:     "task.yaml",
+					RevisionParam: tc.revision,
+				})
+				if err != nil {
+					t.Fatalf("unexpected error resolving revision %q: %v", tc.revision, err)
+				}
+				if string(resource.Data()) != tc.expectedContent {
+					t.Errorf("expected content %q, received %q", tc.expectedContent, string(resource.Data()))
+				}
+				annotations := resource.Annotations()
+				if annotations[AnnotationKeyCommitHash] != tc.expectedCommit {
+					t.Errorf("expected commit %s, received %q", tc.expectedCommit, annotations[AnnotationKeyCommitHash])
+				}
+				if annotations[AnnotationKeyRefType] != tc.expectedType {
+					t.Errorf("expected ref type %q, received %q", tc.expectedType, annotations[AnnotationKeyRefType])
+				}
+			})
+		}
+	}
+}
+
+func TestResolveMissingRevision(t *testing.T) {
+	repoDir, _ := createTestRepo(t, []testCommit{{files: map[string]string{"task.yaml": "task"}}})
+	for _, revision := range []string{"missing", "0000000"} {
+		resolver := Resolver{}
+		_, err := resolver.Resolve(framework.InjectResolverConfigToContext(context.Background(), map[string]string{}), map[string]string{
+			URLParam:      repoDir,
+			PathParam:     "task.yaml",
+			RevisionParam: revision,
+		})
+		if err == nil {
+			t.Errorf("expected revision %q not to be found", revision)
+		}
+	}
+}
+
+func TestValidateParamsRevision(t *testing.T) {
+	for _, p := range []string{CommitParam, BranchParam, TagParam, PullRequestParam, LocatorParam} {
+		params := map[string]string{URLParam: "https://github.com/tektoncd/catalog", PathParam: "task.yaml", RevisionParam: "main", p: "1"}
+		if p == CommitParam {
+			params[p] = "aeb957601cf41c012be462827053a21a420befca"
+		}
+		if err := (&Resolver{}).ValidateParams(context.Background(), params); err == nil {
+			t.Errorf("expected %q to be rejected alongside %q", p, RevisionParam)
+		}
+	}
+	if err := (&Resolver{}).ValidateParams(context.Background(), map[string]string{URLParam: "https://github.com/tektoncd/catalog", PathParam: "task.yaml", RevisionParam: "main"}); err != nil {
+		t.Errorf("unexpected error validating %q: %v", RevisionParam, err)
+	}
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+// builtinSchemes are the url schemes that go-git clones from itself.
+var builtinSchemes = []string{"http", "https", "ssh", "git", "file"}
+
+// SchemeHandler translates a repo url with a custom scheme, such as one
+// exposed by a bridge from another version control system, into a url
+// that can be cloned. It may also return the auth to clone it with,
+// which is used in place of the resolver's own http auth. A nil auth
+// leaves auth to the resolver as for any other url.
+type SchemeHandler func(ctx context.Context, url string) (string, transport.AuthMethod, error)
+
+// SchemeRegistry maps url schemes to the handlers that translate them.
+// It is safe for concurrent use.
+type SchemeRegistry struct {
+	mu       sync.RWMutex
+	handlers map[string]SchemeHandler
+}
+
+// DefaultSchemeRegistry is the registry that resolvers translate urls
+// with. Handlers for custom schemes should be registered with it before
+// the resolver's controller is started.
+var DefaultSchemeRegistry = NewSchemeRegistry()
+
+// NewSchemeRegistry returns a registry that handles the built-in
+// schemes by cloning their urls as given.
+func NewSchemeRegistry() *SchemeRegistry {
+	registry := &SchemeRegistry{handlers: map[string]SchemeHandler{}}
+	for _, scheme := range builtinSchemes {
+		registry.handlers[scheme] = nil
+	}
+	return registry
+}
+
+// RegisterSchemeHandler registers handler in DefaultSchemeRegistry for
+// urls with the given scheme.
+func RegisterSchemeHandler(scheme string, handler SchemeHandler) error {
+	return DefaultSchemeRegistry.Register(scheme, handler)
+}
+
+// Register makes handler translate urls with the given scheme, like
+// "p4git" for "p4git://depot/project", replacing any handler already
+// registered for it. The built-in schemes can't be replaced.
+func (s *SchemeRegistry) Register(scheme string, handler SchemeHandler) error {
+	scheme = strings.ToLower(scheme)
+	if scheme == "" || handler == nil {
+		return fmt.Errorf("a url scheme handler needs a scheme and a handler")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if handler, ok := s.handlers[scheme]; ok && handler == nil {
+		return fmt.Errorf("url scheme %q is built in and can't be replaced", scheme)
+	}
+	s.handlers[scheme] = handler
+	return nil
+}
+
+// Translate returns the url to clone for the repo at url, and the auth
+// to clone it with if its scheme's handler gives one. Urls with a
+// built-in or unregistered scheme, or with none like scp style ssh urls
+// and local paths, are returned as given.
+func (s *SchemeRegistry) Translate(ctx context.Context, url string) (string, transport.AuthMethod, error) {
+	scheme, _, ok := strings.Cut(url, "://")
+	if !ok {
+		return url, nil, nil
+	}
+	scheme = strings.ToLower(scheme)
+	s.mu.RLock()
+	handler := s.handlers[scheme]
+	s.mu.RUnlock()
+	if handler == nil {
+		return url, nil, nil
+	}
+	translated, auth, err := handler(ctx, url)
+	if err != nil {
+		return "", nil, fmt.Errorf("error translating %s url %q: %w", scheme, url, err)
+	}
+	if translatedScheme, _, ok := strings.Cut(translated, "://"); ok && !s.isBuiltin(translatedScheme) {
+		return "", nil, fmt.Errorf("%s url %q was translated to %q, which doesn't have a built-in scheme", scheme, url, translated)
+	}
+	return translated, auth, nil
+}
+
+// isCustom returns true if url has a scheme with a registered handler
+// that Translate passes it to.
+func (s *SchemeRegistry) isCustom(url string) bool {
+	scheme, _, ok := strings.Cut(url, "://")
+	if !ok {
+		return false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.handlers[strings.ToLower(scheme)] != nil
+}
+
+// isBuiltin returns true if scheme is one that go-git clones itself.
+func (s *SchemeRegistry) isBuiltin(scheme string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	handler, ok := s.handlers[strings.ToLower(scheme)]
+	return ok && handler == nil
+}
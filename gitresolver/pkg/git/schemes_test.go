@@ -0,0 +1,88 @@
+package git
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+func TestResolveCustomScheme(t *testing.T) {
+	repoDir, commits := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "kind: Task"},
+	}})
+	// The server only accepts the credentials that the handler gives,
+	// so resolving succeeds only if both its url and auth are used.
+	server := newGitHTTPServer(t, repoDir, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if user, pass, ok := r.BasicAuth(); !ok || user != "bridge" || pass != "secret" {
+				w.Header().Set("WWW-Authenticate", `Basic realm="bridge"`)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+
+	registry := NewSchemeRegistry()
+	translated := []string{}
+	if err := registry.Register("p4git", func(_ context.Context, url string) (string, transport.AuthMethod, error) {
+		translated = append(translated, url)
+		depot := strings.TrimPrefix(url, "p4git://")
+		if depot != "depot/project" {
+			t.Errorf("unexpected depot %q", depot)
+		}
+		return server.URL + "/repo", &githttp.BasicAuth{Username: "bridge", Password: "secret"}, nil
+	}); err != nil {
+		t.Fatalf("unexpected error registering scheme handler: %v", err)
+	}
+
+	resolver := Resolver{schemes: registry}
+	resource, err := resolver.Resolve(context.Background(), map[string]string{
+		URLParam:  "p4git://depot/project",
+		PathParam: "task.yaml",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error resolving: %v", err)
+	}
+	if string(resource.Data()) != "kind: Task" {
+		t.Errorf("expected content %q received %q", "kind: Task", resource.Data())
+	}
+	if commit := resource.Annotations()[AnnotationKeyCommitHash]; commit != commits[0] {
+		t.Errorf("expected commit %q received %q", commits[0], commit)
+	}
+	if url := resource.(*ResolvedGitResource).URL; url != "p4git://depot/project" {
+		t.Errorf("expected the url to be reported as requested, received %q", url)
+	}
+	if len(translated) != 1 {
+		t.Errorf("expected the url to be translated once, translated %v", translated)
+	}
+}
+
+func TestSchemeRegistry(t *testing.T) {
+	registry := NewSchemeRegistry()
+	passthrough := func(_ context.Context, url string) (string, transport.AuthMethod, error) {
+		return url, nil, nil
+	}
+	for _, scheme := range builtinSchemes {
+		if err := registry.Register(scheme, passthrough); err == nil {
+			t.Errorf("expected an error replacing built-in scheme %q", scheme)
+		}
+	}
+	if err := registry.Register("loop", passthrough); err != nil {
+		t.Fatalf("unexpected error registering scheme handler: %v", err)
+	}
+
+	for _, url := range []string{"https://github.com/tektoncd/catalog", "git@github.com:tektoncd/catalog.git", "/var/repos/catalog", "unregistered://host/repo"} {
+		translated, auth, err := registry.Translate(context.Background(), url)
+		if err != nil || translated != url || auth != nil {
+			t.Errorf("expected %q to be returned as given, received %q, %v, %v", url, translated, auth, err)
+		}
+	}
+	if _, _, err := registry.Translate(context.Background(), "loop://host/repo"); err == nil || !strings.Contains(err.Error(), "built-in scheme") {
+		t.Errorf("expected an error translating to a custom scheme, received %v", err)
+	}
+}
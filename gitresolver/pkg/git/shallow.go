@@ -0,0 +1,119 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/tektoncd/resolution/pkg/resolver/framework"
+)
+
+// shallowSinceDateLayout is the layout of a shallow-since date given
+// without a time, which is taken as midnight UTC.
+const shallowSinceDateLayout = "2006-01-02"
+
+// getShallowSince returns the date that clones only fetch history
+// since, as configured with the shallow-since field in the
+// git-resolver-config configmap, as an RFC 3339 time or a date. The
+// zero time means clones fetch all history.
+func getShallowSince(conf map[string]string) (time.Time, error) {
+	since := conf[ConfigFieldShallowSince]
+	if since == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, since); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(shallowSinceDateLayout, since)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s %q: must be an RFC 3339 time or a date like %q", ConfigFieldShallowSince, since, shallowSinceDateLayout)
+	}
+	return t, nil
+}
+
+// useShallowSince returns true if clones should only fetch history
+// since the configured date. go-git can't make these requests, so they
+// are made with the git binary, which doesn't have the credentials
+// that go-git authenticates with.
+func useShallowSince(conf map[string]string, auth transport.AuthMethod) bool {
+	return conf[ConfigFieldShallowSince] != "" && auth == nil
+}
+
+// getCloneDepth returns the number of commits of history that a branch
+// or tag is cloned with, from the depth param or else the clone-depth
+// field in the git-resolver-config configmap. Zero means all history.
+func getCloneDepth(conf, params map[string]string) (int, error) {
+	depthString, name := params[DepthParam], DepthParam
+	if depthString == "" {
+		depthString, name = conf[ConfigFieldCloneDepth], ConfigFieldCloneDepth
+	}
+	if depthString == "" {
+		return 0, nil
+	}
+	depth, err := strconv.Atoi(depthString)
+	if err != nil || depth <= 0 {
+		return 0, fmt.Errorf("invalid %s %q: must be a positive integer", name, depthString)
+	}
+	return depth, nil
+}
+
+type cloneDepthKey struct{}
+
+// withCloneDepth returns a copy of ctx whose clones fetch only depth
+// commits of history. Zero fetches all history.
+func withCloneDepth(ctx context.Context, depth int) context.Context {
+	return context.WithValue(ctx, cloneDepthKey{}, depth)
+}
+
+// getCloneDepthFromContext returns the number of commits of history
+// that clones made with ctx fetch, or zero for all of it.
+func getCloneDepthFromContext(ctx context.Context) int {
+	depth, _ := ctx.Value(cloneDepthKey{}).(int)
+	return depth
+}
+
+// withFullHistory returns a copy of ctx whose clones fetch all of a
+// repository's history, for resolutions that walk past the commit they
+// resolve, like blame.
+func withFullHistory(ctx context.Context) context.Context {
+	if getCloneDepthFromContext(ctx) > 0 {
+		ctx = withCloneDepth(ctx, 0)
+	}
+	conf := framework.GetResolverConfigFromContext(ctx)
+	if conf[ConfigFieldShallowSince] == "" {
+		return ctx
+	}
+	full := make(map[string]string, len(conf))
+	for k, v := range conf {
+		if k != ConfigFieldShallowSince {
+			full[k] = v
+		}
+	}
+	return framework.InjectResolverConfigToContext(ctx, full)
+}
+
+// isShallowSinceEmpty returns true if err is from the git binary
+// refusing a shallow-since clone because the requested ref has no
+// commits since the date.
+func isShallowSinceEmpty(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "no commits selected for shallow requests")
+}
@@ -0,0 +1,333 @@
+package git
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/tektoncd/resolution/pkg/resolver/framework"
+)
+
+// createDatedTestRepo makes a repo with a commit on the first of each
+// of January, February and March 2020, with a feature branch and a tag
+// at the first.
+func createDatedTestRepo(t *testing.T) (string, []string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	repoDir, commits := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "january"},
+		when:  time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC),
+	}, {
+		files: map[string]string{"task.yaml": "february"},
+		when:  time.Date(2020, time.February, 1, 0, 0, 0, 0, time.UTC),
+	}, {
+		files: map[string]string{"task.yaml": "march"},
+		when:  time.Date(2020, time.March, 1, 0, 0, 0, 0, time.UTC),
+	}})
+	setTestRef(t, repoDir, "refs/heads/feature", commits[0])
+	setTestRef(t, repoDir, "refs/tags/v1", commits[0])
+	return repoDir, commits
+}
+
+func TestFetchRepositoryShallowSince(t *testing.T) {
+	repoDir, commits := createDatedTestRepo(t)
+	ctx := framework.InjectResolverConfigToContext(context.Background(), map[string]string{
+		ConfigFieldShallowSince: "2020-01-15",
+	})
+
+	fetched, err := fetchRepository(ctx, "file://"+repoDir, nil, nil, "")
+	if err != nil {
+		t.Fatalf("unexpected error fetching: %v", err)
+	}
+	defer fetched.cleanup()
+	if fetched.head.String() != commits[2] {
+		t.Errorf("expected head %q received %q", commits[2], fetched.head)
+	}
+	shallow, err := fetched.repository.Storer.Shallow()
+	if err != nil {
+		t.Fatalf("error reading shallow commits: %v", err)
+	}
+	if len(shallow) != 1 || shallow[0].String() != commits[1] {
+		t.Errorf("expected history to stop at %q, shallow commits are %v", commits[1], shallow)
+	}
+	if _, err := fetched.repository.CommitObject(plumbing.NewHash(commits[0])); err == nil {
+		t.Errorf("expected commit before the shallow-since date not to be fetched")
+	}
+}
+
+func TestResolveShallowSince(t *testing.T) {
+	repoDir, commits := createDatedTestRepo(t)
+	ctx := framework.InjectResolverConfigToContext(context.Background(), map[string]string{
+		ConfigFieldShallowSince: "2020-01-15T00:00:00Z",
+	})
+
+	for _, tc := range []struct {
+		name     string
+		params   map[string]string
+		expected string
+	}{{
+		name:     "default branch",
+		params:   map[string]string{},
+		expected: "march",
+	}, {
+		name:     "recent commit",
+		params:   map[string]string{CommitParam: commits[1]},
+		expected: "february",
+	}, {
+		name:     "commit before the boundary",
+		params:   map[string]string{CommitParam: commits[0]},
+		expected: "january",
+	}, {
+		name:     "branch tip before the boundary",
+		params:   map[string]string{BranchParam: "feature"},
+		expected: "january",
+	}, {
+		name:     "tag before the boundary",
+		params:   map[string]string{LocatorParam: "file://" + repoDir + "@v1//task.yaml"},
+		expected: "january",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.params[LocatorParam] == "" {
+				tc.params[URLParam] = "file://" + repoDir
+				tc.params[PathParam] = "task.yaml"
+			}
+			resolver := Resolver{}
+			resource, err := resolver.Resolve(ctx, tc.params)
+			if err != nil {
+				t.Fatalf("unexpected error resolving: %v", err)
+			}
+			if string(resource.Data()) != tc.expected {
+				t.Errorf("expected content %q received %q", tc.expected, resource.Data())
+			}
+		})
+	}
+}
+
+func TestResolveShallowSinceBlameUsesFullHistory(t *testing.T) {
+	repoDir, commits := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "line one\n"},
+		when:  time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC),
+	}, {
+		files: map[string]string{"task.yaml": "line one\nline two\n"},
+		when:  time.Date(2020, time.March, 1, 0, 0, 0, 0, time.UTC),
+	}})
+	ctx := framework.InjectResolverConfigToContext(context.Background(), map[string]string{
+		ConfigFieldShallowSince: "2020-02-15",
+	})
+
+	resolver := Resolver{}
+	resource, err := resolver.Resolve(ctx, map[string]string{
+		URLParam:   "file://" + repoDir,
+		PathParam:  "task.yaml",
+		BlameParam: "true",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error resolving blame: %v", err)
+	}
+	result := BlameResult{}
+	if err := json.Unmarshal(resource.Data(), &result); err != nil {
+		t.Fatalf("error parsing blame result: %v", err)
+	}
+	if len(result.Lines) != 2 || result.Lines[0].Commit != commits[0] || result.Lines[1].Commit != commits[1] {
+		t.Errorf("expected lines to be blamed on %q and %q, received %+v", commits[0], commits[1], result.Lines)
+	}
+}
+
+func TestGetShallowSinceInvalid(t *testing.T) {
+	_, err := getShallowSince(map[string]string{ConfigFieldShallowSince: "last week"})
+	if err == nil || !strings.Contains(err.Error(), ConfigFieldShallowSince) {
+		t.Errorf("expected invalid shallow-since error, received %v", err)
+	}
+}
+
+// createLongHistoryRepo makes a repo whose task.yaml is rewritten with
+// incompressible content by each of n commits.
+func createLongHistoryRepo(t *testing.T, n int) (string, []string) {
+	t.Helper()
+	history := make([]testCommit, 0, n)
+	for i := 0; i < n; i++ {
+		content := make([]byte, 4096)
+		if _, err := rand.Read(content); err != nil {
+			t.Fatalf("error generating content: %v", err)
+		}
+		history = append(history, testCommit{
+			files: map[string]string{"task.yaml": fmt.Sprintf("revision: %d\ndata: %x\n", i, content)},
+		})
+	}
+	return createTestRepo(t, history)
+}
+
+func TestFetchRepositoryCloneDepth(t *testing.T) {
+	repoDir, commits := createLongHistoryRepo(t, 3)
+	for _, tc := range []struct {
+		name string
+		conf map[string]string
+	}{{
+		name: "clone",
+	}, {
+		name: "minimal",
+		conf: map[string]string{ConfigFieldMinimalFetch: "true"},
+	}, {
+		name: "git cli",
+		conf: map[string]string{ConfigFieldGitProtocolVersion: GitProtocolV2},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := exec.LookPath("git"); err != nil && tc.conf[ConfigFieldGitProtocolVersion] != "" {
+				t.Skip("git is not installed")
+			}
+			ctx := framework.InjectResolverConfigToContext(context.Background(), tc.conf)
+			ctx = withCloneDepth(ctx, 1)
+			fetched, err := fetchRepository(ctx, "file://"+repoDir, nil, nil, "")
+			if err != nil {
+				t.Fatalf("unexpected error fetching: %v", err)
+			}
+			defer fetched.cleanup()
+			if fetched.head.String() != commits[2] {
+				t.Errorf("expected head %q received %q", commits[2], fetched.head)
+			}
+			shallow, err := fetched.repository.Storer.Shallow()
+			if err != nil {
+				t.Fatalf("error reading shallow commits: %v", err)
+			}
+			if len(shallow) != 1 || shallow[0].String() != commits[2] {
+				t.Errorf("expected history to stop at %q, shallow commits are %v", commits[2], shallow)
+			}
+			if _, err := fetched.repository.CommitObject(plumbing.NewHash(commits[1])); err == nil {
+				t.Errorf("expected commits past the clone depth not to be fetched")
+			}
+		})
+	}
+}
+
+func TestResolveCloneDepthTransfersLess(t *testing.T) {
+	repoDir, commits := createLongHistoryRepo(t, 30)
+	var transferred int64
+	server := newGitHTTPServer(t, repoDir, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			counter := &countingResponseWriter{ResponseWriter: w}
+			next.ServeHTTP(counter, r)
+			atomic.AddInt64(&transferred, counter.n)
+		})
+	})
+	resolve := func(t *testing.T, params map[string]string) int64 {
+		t.Helper()
+		atomic.StoreInt64(&transferred, 0)
+		resolver := Resolver{}
+		if err := resolver.ValidateParams(context.Background(), params); err != nil {
+			t.Fatalf("unexpected error validating params: %v", err)
+		}
+		resource, err := resolver.Resolve(context.Background(), params)
+		if err != nil {
+			t.Fatalf("unexpected error resolving: %v", err)
+		}
+		if commit := resource.Annotations()[AnnotationKeyCommitHash]; commit != commits[29] {
+			t.Errorf("expected commit %q received %q", commits[29], commit)
+		}
+		if !strings.HasPrefix(string(resource.Data()), "revision: 29\n") {
+			t.Errorf("expected the latest revision, received %.20q", resource.Data())
+		}
+		return atomic.LoadInt64(&transferred)
+	}
+
+	full := resolve(t, map[string]string{
+		URLParam:  server.URL + "/repo",
+		PathParam: "task.yaml",
+	})
+	shallow := resolve(t, map[string]string{
+		URLParam:   server.URL + "/repo",
+		PathParam:  "task.yaml",
+		DepthParam: "1",
+	})
+	if shallow*10 > full {
+		t.Errorf("expected a depth 1 clone to transfer a fraction of the %d bytes a full clone does, transferred %d", full, shallow)
+	}
+}
+
+// countingResponseWriter counts the bytes of a response's body.
+type countingResponseWriter struct {
+	http.ResponseWriter
+	n int64
+}
+
+func (w *countingResponseWriter) Write(p []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(p)
+	w.n += int64(n)
+	return n, err
+}
+
+func TestResolveCloneDepthCommitFetchesFullHistory(t *testing.T) {
+	repoDir, commits := createLongHistoryRepo(t, 3)
+	ctx := framework.InjectResolverConfigToContext(context.Background(), map[string]string{
+		ConfigFieldCloneDepth: "1",
+	})
+	resolver := Resolver{}
+	resource, err := resolver.Resolve(ctx, map[string]string{
+		URLParam:    repoDir,
+		CommitParam: commits[0],
+		PathParam:   "task.yaml",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error resolving: %v", err)
+	}
+	if !strings.HasPrefix(string(resource.Data()), "revision: 0\n") {
+		t.Errorf("expected the first revision, received %.20q", resource.Data())
+	}
+}
+
+func TestValidateParamsDepth(t *testing.T) {
+	commit := strings.Repeat("a", 40)
+	for _, tc := range []struct {
+		name        string
+		params      map[string]string
+		expectedErr string
+	}{{
+		name: "commit",
+		params: map[string]string{
+			URLParam:    "https://github.com/tektoncd/catalog",
+			PathParam:   "task.yaml",
+			CommitParam: commit,
+			DepthParam:  "1",
+		},
+		expectedErr: `"depth" can't be used to fetch commit ` + commit,
+	}, {
+		name: "locator commit",
+		params: map[string]string{
+			LocatorParam: "github.com/tektoncd/catalog@" + commit + "//task.yaml",
+			DepthParam:   "1",
+		},
+		expectedErr: `"depth" can't be used to fetch commit ` + commit,
+	}, {
+		name: "not positive",
+		params: map[string]string{
+			URLParam:   "https://github.com/tektoncd/catalog",
+			PathParam:  "task.yaml",
+			DepthParam: "0",
+		},
+		expectedErr: `invalid depth "0": must be a positive integer`,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			resolver := Resolver{}
+			err := resolver.ValidateParams(context.Background(), tc.params)
+			if err == nil || !strings.Contains(err.Error(), tc.expectedErr) {
+				t.Errorf("expected error containing %q, received %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestGetCloneDepthInvalid(t *testing.T) {
+	_, err := getCloneDepth(map[string]string{ConfigFieldCloneDepth: "shallow"}, nil)
+	if err == nil || !strings.Contains(err.Error(), ConfigFieldCloneDepth) {
+		t.Errorf("expected invalid clone depth error, received %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	git "github.com/go-git/go-git/v5"
+)
+
+// signatureSuffix is appended to the path of a file to find its
+// detached signature.
+const signatureSuffix = ".sig"
+
+// armoredSignaturePrefix starts an ascii armored pgp signature.
+const armoredSignaturePrefix = "-----BEGIN PGP SIGNATURE-----"
+
+// trustedSigningKeys parses the armored pgp public keys configured with
+// the trusted-signing-keys field in the git-resolver-config configmap.
+func trustedSigningKeys(conf map[string]string) (openpgp.EntityList, error) {
+	armored := conf[ConfigFieldTrustedSigningKeys]
+	if strings.TrimSpace(armored) == "" {
+		return nil, fmt.Errorf("%q requires %s to be configured", VerifySignatureParam, ConfigFieldTrustedSigningKeys)
+	}
+	keys, err := openpgp.ReadArmoredKeyRing(strings.NewReader(armored))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", ConfigFieldTrustedSigningKeys, err)
+	}
+	return keys, nil
+}
+
+// verifyDetachedSignature checks content, read from path at the given
+// commit, against the detached pgp signature stored next to it in a
+// file with a .sig suffix. Signatures may be binary or ascii armored.
+// It returns the id of the trusted key that made the signature.
+func verifyDetachedSignature(repository *git.Repository, commit, path string, content []byte, keys openpgp.EntityList) (string, error) {
+	sigPath := path + signatureSuffix
+	sig, err := readBlob(repository, commit, sigPath)
+	if isFileMissing(err) {
+		return "", fmt.Errorf("file %q has no signature at %q", path, sigPath)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	var signer *openpgp.Entity
+	if bytes.HasPrefix(bytes.TrimSpace(sig), []byte(armoredSignaturePrefix)) {
+		signer, err = openpgp.CheckArmoredDetachedSignature(keys, bytes.NewReader(content), bytes.NewReader(sig), nil)
+	} else {
+		signer, err = openpgp.CheckDetachedSignature(keys, bytes.NewReader(content), bytes.NewReader(sig), nil)
+	}
+	if err != nil {
+		return "", fmt.Errorf("invalid signature %q for file %q: %w", sigPath, path, err)
+	}
+	return signer.PrimaryKey.KeyIdString(), nil
+}
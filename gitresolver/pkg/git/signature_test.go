@@ -0,0 +1,121 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/tektoncd/resolution/pkg/resolver/framework"
+)
+
+// newSigningKey generates a pgp key and returns it along with its
+// armored public key.
+func newSigningKey(t *testing.T) (*openpgp.Entity, string) {
+	t.Helper()
+	entity, err := openpgp.NewEntity("test", "", "test@example.com", &packet.Config{Algorithm: packet.PubKeyAlgoEdDSA})
+	if err != nil {
+		t.Fatalf("error generating signing key: %v", err)
+	}
+	var public bytes.Buffer
+	w, err := armor.Encode(&public, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatalf("error armoring public key: %v", err)
+	}
+	if err := entity.Serialize(w); err != nil {
+		t.Fatalf("error serializing public key: %v", err)
+	}
+	w.Close()
+	return entity, public.String()
+}
+
+func sign(t *testing.T, signer *openpgp.Entity, content string) string {
+	t.Helper()
+	var sig bytes.Buffer
+	if err := openpgp.ArmoredDetachSign(&sig, signer, strings.NewReader(content), nil); err != nil {
+		t.Fatalf("error signing content: %v", err)
+	}
+	return sig.String()
+}
+
+func TestResolveVerifySignature(t *testing.T) {
+	signer, publicKey := newSigningKey(t)
+	untrusted, _ := newSigningKey(t)
+	content := "kind: Task"
+
+	for _, tc := range []struct {
+		name          string
+		files         map[string]string
+		expectedError string
+	}{{
+		name: "signed",
+		files: map[string]string{
+			"task.yaml":     content,
+			"task.yaml.sig": sign(t, signer, content),
+		},
+	}, {
+		name: "tampered",
+		files: map[string]string{
+			"task.yaml":     "kind: Pipeline",
+			"task.yaml.sig": sign(t, signer, content),
+		},
+		expectedError: `invalid signature "task.yaml.sig"`,
+	}, {
+		name: "untrusted key",
+		files: map[string]string{
+			"task.yaml":     content,
+			"task.yaml.sig": sign(t, untrusted, content),
+		},
+		expectedError: `invalid signature "task.yaml.sig"`,
+	}, {
+		name:          "missing signature",
+		files:         map[string]string{"task.yaml": content},
+		expectedError: `has no signature at "task.yaml.sig"`,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			repoDir, _ := createTestRepo(t, []testCommit{{files: tc.files}})
+			ctx := framework.InjectResolverConfigToContext(context.Background(), map[string]string{
+				ConfigFieldTrustedSigningKeys: publicKey,
+			})
+			resolver := &Resolver{}
+			resource, err := resolver.Resolve(ctx, map[string]string{
+				URLParam:             repoDir,
+				PathParam:            "task.yaml",
+				VerifySignatureParam: "true",
+			})
+			if tc.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectedError) {
+					t.Fatalf("expected error containing %q received %v", tc.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error resolving signed file: %v", err)
+			}
+			if string(resource.Data()) != content {
+				t.Errorf("expected content %q received %q", content, string(resource.Data()))
+			}
+			if key := resource.Annotations()[AnnotationKeySignatureKey]; key != signer.PrimaryKey.KeyIdString() {
+				t.Errorf("expected signature key %q received %q", signer.PrimaryKey.KeyIdString(), key)
+			}
+		})
+	}
+}
+
+func TestResolveVerifySignatureWithoutKeys(t *testing.T) {
+	repoDir, _ := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "kind: Task"},
+	}})
+	resolver := &Resolver{}
+	_, err := resolver.Resolve(framework.InjectResolverConfigToContext(context.Background(), map[string]string{}), map[string]string{
+		URLParam:             repoDir,
+		PathParam:            "task.yaml",
+		VerifySignatureParam: "true",
+	})
+	if err == nil || !strings.Contains(err.Error(), ConfigFieldTrustedSigningKeys) {
+		t.Fatalf("expected error about missing %s received %v", ConfigFieldTrustedSigningKeys, err)
+	}
+}
@@ -0,0 +1,439 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/format/pktline"
+	"github.com/go-git/go-git/v5/plumbing/protocol/packp"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"github.com/go-git/go-git/v5/utils/ioutil"
+	"github.com/tektoncd/resolution/pkg/resolver/framework"
+	"golang.org/x/crypto/ssh"
+)
+
+// sshConnections holds the ssh connections shared by resolutions.
+var sshConnections = &sshPool{conns: map[string]*pooledSSHConn{}}
+
+// getSSHIdleTimeout returns how long an idle ssh connection is kept
+// open for reuse, as configured with the ssh-idle-timeout field in the
+// git-resolver-config configmap. Zero means connections aren't reused.
+func getSSHIdleTimeout(ctx context.Context) time.Duration {
+	conf := framework.GetResolverConfigFromContext(ctx)
+	if timeoutString, ok := conf[ConfigFieldSSHIdleTimeout]; ok {
+		timeout, err := time.ParseDuration(timeoutString)
+		if err == nil {
+			return timeout
+		}
+	}
+	return 0
+}
+
+// sshTransport fetches over ssh, sharing one connection per host and
+// credentials between resolutions when ssh-idle-timeout is configured.
+// Otherwise, and for pushes, it hands off to go-git's ssh client.
+type sshTransport struct{}
+
+// NewUploadPackSession implements transport.Transport. Whether to reuse
+// a connection depends on the resolver's config, which is only known
+// once the session is first used with a context, so connecting is
+// deferred until then.
+func (sshTransport) NewUploadPackSession(ep *transport.Endpoint, auth transport.AuthMethod) (transport.UploadPackSession, error) {
+	return &sshUploadPackSession{endpoint: ep, auth: auth}, nil
+}
+
+// NewReceivePackSession implements transport.Transport.
+func (sshTransport) NewReceivePackSession(ep *transport.Endpoint, auth transport.AuthMethod) (transport.ReceivePackSession, error) {
+	return gitssh.DefaultClient.NewReceivePackSession(ep, auth)
+}
+
+// sshUploadPackSession connects to the server on first use, through the
+// pool or go-git's ssh client.
+type sshUploadPackSession struct {
+	endpoint *transport.Endpoint
+	auth     transport.AuthMethod
+	session  transport.UploadPackSession
+}
+
+func (s *sshUploadPackSession) connect(ctx context.Context) error {
+	if s.session != nil {
+		return nil
+	}
+	idleTimeout := getSSHIdleTimeout(ctx)
+	if idleTimeout <= 0 {
+		session, err := gitssh.DefaultClient.NewUploadPackSession(s.endpoint, s.auth)
+		if err != nil {
+			return err
+		}
+		s.session = session
+		return nil
+	}
+
+	auth, ok := s.auth.(gitssh.AuthMethod)
+	if s.auth == nil {
+		var err error
+		if auth, err = gitssh.DefaultAuthBuilder(s.endpoint.User); err != nil {
+			return err
+		}
+	} else if !ok {
+		return transport.ErrInvalidAuthMethod
+	}
+	key, ok := sshPoolKey(s.endpoint, auth)
+	if !ok {
+		session, err := gitssh.DefaultClient.NewUploadPackSession(s.endpoint, auth)
+		if err != nil {
+			return err
+		}
+		s.session = session
+		return nil
+	}
+	config, err := auth.ClientConfig()
+	if err != nil {
+		return err
+	}
+	session, err := newPooledSSHSession(ctx, sshConnections, key, sshAddr(s.endpoint), config, s.endpoint, idleTimeout)
+	if err != nil {
+		return err
+	}
+	s.session = session
+	return nil
+}
+
+func (s *sshUploadPackSession) AdvertisedReferences() (*packp.AdvRefs, error) {
+	return s.AdvertisedReferencesContext(context.TODO())
+}
+
+func (s *sshUploadPackSession) AdvertisedReferencesContext(ctx context.Context) (*packp.AdvRefs, error) {
+	if err := s.connect(ctx); err != nil {
+		return nil, err
+	}
+	return s.session.AdvertisedReferencesContext(ctx)
+}
+
+func (s *sshUploadPackSession) UploadPack(ctx context.Context, req *packp.UploadPackRequest) (*packp.UploadPackResponse, error) {
+	if err := s.connect(ctx); err != nil {
+		return nil, err
+	}
+	return s.session.UploadPack(ctx, req)
+}
+
+func (s *sshUploadPackSession) Close() error {
+	if s.session == nil {
+		return nil
+	}
+	return s.session.Close()
+}
+
+// sshPoolKey identifies the connections that fetches from ep with auth
+// may share. Only auth whose identity can be compared is pooled, so
+// connections are never shared between different credentials. This
+// excludes connections tunnelled through a jump host, which only live
+// as long as a single resolution's tunnel.
+func sshPoolKey(ep *transport.Endpoint, auth gitssh.AuthMethod) (string, bool) {
+	var user, identity string
+	switch a := auth.(type) {
+	case *gitssh.PublicKeys:
+		user, identity = a.User, ssh.FingerprintSHA256(a.Signer.PublicKey())
+	case *gitssh.PublicKeysCallback:
+		// Built by gitssh.DefaultAuthBuilder to use the ssh agent.
+		user, identity = a.User, "agent"
+	default:
+		return "", false
+	}
+	return fmt.Sprintf("%s@%s/%s", user, sshAddr(ep), identity), true
+}
+
+// sshAddr returns the host and port to connect to for ep, defaulting to
+// the standard ssh port.
+func sshAddr(ep *transport.Endpoint) string {
+	port := ep.Port
+	if port <= 0 {
+		port = gitssh.DefaultPort
+	}
+	return net.JoinHostPort(ep.Host, strconv.Itoa(port))
+}
+
+// sshPool holds ssh connections to git servers so that resolutions can
+// share them instead of reconnecting for every fetch. Each fetch opens
+// its own session on a shared connection. Connections are closed once
+// they've been idle for the ssh-idle-timeout of the last resolution to
+// use them.
+type sshPool struct {
+	mu    sync.Mutex
+	conns map[string]*pooledSSHConn
+}
+
+type pooledSSHConn struct {
+	client *ssh.Client
+	users  int
+	idle   *time.Timer
+}
+
+// get returns the pooled connection for key, dialing addr if there
+// isn't one. The connection must be released once it's no longer used.
+func (p *sshPool) get(ctx context.Context, key, addr string, config *ssh.ClientConfig) (*pooledSSHConn, error) {
+	p.mu.Lock()
+	if conn, ok := p.conns[key]; ok {
+		conn.acquire()
+		p.mu.Unlock()
+		return conn, nil
+	}
+	p.mu.Unlock()
+
+	client, err := dialSSH(ctx, addr, config)
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if conn, ok := p.conns[key]; ok {
+		// Another resolution connected while this one was dialing.
+		_ = client.Close()
+		conn.acquire()
+		return conn, nil
+	}
+	conn := &pooledSSHConn{client: client, users: 1}
+	p.conns[key] = conn
+	return conn, nil
+}
+
+func (c *pooledSSHConn) acquire() {
+	c.users++
+	if c.idle != nil {
+		c.idle.Stop()
+		c.idle = nil
+	}
+}
+
+// release marks conn as no longer used by one resolution, closing it
+// after idleTimeout if nothing else uses it in the meantime.
+func (p *sshPool) release(key string, conn *pooledSSHConn, idleTimeout time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	conn.users--
+	if conn.users > 0 || p.conns[key] != conn {
+		return
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(idleTimeout, func() {
+		p.mu.Lock()
+		if conn.idle != timer || p.conns[key] != conn {
+			p.mu.Unlock()
+			return
+		}
+		delete(p.conns, key)
+		p.mu.Unlock()
+		_ = conn.client.Close()
+	})
+	conn.idle = timer
+}
+
+// discard removes a broken connection from the pool so that the next
+// fetch dials a new one.
+func (p *sshPool) discard(key string, conn *pooledSSHConn) {
+	p.mu.Lock()
+	if p.conns[key] == conn {
+		delete(p.conns, key)
+	}
+	p.mu.Unlock()
+	_ = conn.client.Close()
+}
+
+// dialSSH connects to the ssh server at addr, giving up on the handshake
+// when ctx's deadline passes.
+func dialSSH(ctx context.Context, addr string, config *ssh.ClientConfig) (*ssh.Client, error) {
+	conn, err := dialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	c, chans, reqs, err := ssh.NewClientConn(conn, addr, config)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	_ = conn.SetDeadline(time.Time{})
+	return ssh.NewClient(c, chans, reqs), nil
+}
+
+// pooledSSHSession runs git-upload-pack in a session on a pooled
+// connection.
+type pooledSSHSession struct {
+	pool        *sshPool
+	key         string
+	conn        *pooledSSHConn
+	idleTimeout time.Duration
+
+	session *ssh.Session
+	stdin   io.WriteCloser
+	stdout  io.Reader
+	stderr  lockedBuffer
+
+	advRefs   *packp.AdvRefs
+	packRun   bool
+	closeOnce sync.Once
+}
+
+func newPooledSSHSession(ctx context.Context, pool *sshPool, key, addr string, config *ssh.ClientConfig, ep *transport.Endpoint, idleTimeout time.Duration) (*pooledSSHSession, error) {
+	conn, err := pool.get(ctx, key, addr, config)
+	if err != nil {
+		return nil, err
+	}
+	session, err := conn.client.NewSession()
+	if err != nil {
+		// The server may have closed the connection while it was idle.
+		pool.discard(key, conn)
+		if conn, err = pool.get(ctx, key, addr, config); err != nil {
+			return nil, err
+		}
+		if session, err = conn.client.NewSession(); err != nil {
+			pool.discard(key, conn)
+			return nil, err
+		}
+	}
+
+	s := &pooledSSHSession{pool: pool, key: key, conn: conn, idleTimeout: idleTimeout, session: session}
+	session.Stderr = &s.stderr
+	if s.stdin, err = session.StdinPipe(); err == nil {
+		if s.stdout, err = session.StdoutPipe(); err == nil {
+			err = session.Start(fmt.Sprintf("%s '%s'", transport.UploadPackServiceName, ep.Path))
+		}
+	}
+	if err != nil {
+		_ = session.Close()
+		pool.release(key, conn, idleTimeout)
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *pooledSSHSession) AdvertisedReferences() (*packp.AdvRefs, error) {
+	return s.AdvertisedReferencesContext(context.TODO())
+}
+
+func (s *pooledSSHSession) AdvertisedReferencesContext(ctx context.Context) (*packp.AdvRefs, error) {
+	if s.advRefs != nil {
+		return s.advRefs, nil
+	}
+	ar := packp.NewAdvRefs()
+	if err := ar.Decode(s.reader(ctx)); err != nil {
+		switch err {
+		case packp.ErrEmptyInput:
+			// The server writes errors such as a missing repository to
+			// stderr and exits without output.
+			_ = s.session.Wait()
+			return nil, fmt.Errorf("error listing refs: %s", strings.TrimSpace(s.stderr.String()))
+		case packp.ErrEmptyAdvRefs:
+			return nil, transport.ErrEmptyRemoteRepository
+		}
+		return nil, err
+	}
+	if ar.IsEmpty() {
+		return nil, transport.ErrEmptyRemoteRepository
+	}
+	transport.FilterUnsupportedCapabilities(ar.Capabilities)
+	s.advRefs = ar
+	return ar, nil
+}
+
+func (s *pooledSSHSession) UploadPack(ctx context.Context, req *packp.UploadPackRequest) (*packp.UploadPackResponse, error) {
+	if req.IsEmpty() && len(req.Shallows) == 0 {
+		return nil, transport.ErrEmptyUploadPackRequest
+	}
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	if _, err := s.AdvertisedReferencesContext(ctx); err != nil {
+		return nil, err
+	}
+
+	s.packRun = true
+	w := ioutil.NewWriteCloserOnError(ioutil.NewContextWriteCloser(ctx, s.stdin), s.onError)
+	if err := req.UploadRequest.Encode(w); err != nil {
+		return nil, fmt.Errorf("error sending upload-pack request: %w", err)
+	}
+	if err := req.UploadHaves.Encode(w, true); err != nil {
+		return nil, fmt.Errorf("error sending upload-pack haves: %w", err)
+	}
+	if err := pktline.NewEncoder(w).Encodef("done\n"); err != nil {
+		return nil, fmt.Errorf("error sending upload-pack done: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("error sending upload-pack request: %w", err)
+	}
+
+	res := packp.NewUploadPackResponse(req)
+	if err := res.Decode(ioutil.NewReadCloser(s.reader(ctx), s)); err != nil {
+		return nil, fmt.Errorf("error decoding upload-pack response: %w", err)
+	}
+	return res, nil
+}
+
+// reader returns the session's stdout, closing the session if reading
+// fails or ctx is done first.
+func (s *pooledSSHSession) reader(ctx context.Context) io.Reader {
+	return ioutil.NewReaderOnError(ioutil.NewContextReader(ctx, s.stdout), s.onError)
+}
+
+func (s *pooledSSHSession) onError(error) {
+	_ = s.Close()
+}
+
+// Close ends the session and hands its connection back to the pool.
+func (s *pooledSSHSession) Close() error {
+	s.closeOnce.Do(func() {
+		if !s.packRun {
+			// Ask the server to exit cleanly instead of sending a pack.
+			_, _ = s.stdin.Write(pktline.FlushPkt)
+		}
+		_ = s.stdin.Close()
+		_ = s.session.Close()
+		s.pool.release(s.key, s.conn, s.idleTimeout)
+	})
+	return nil
+}
+
+// lockedBuffer is a bytes.Buffer that is safe to write to from the ssh
+// session's stderr copier while being read.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
@@ -0,0 +1,239 @@
+package git
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"net"
+	"os/exec"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"github.com/tektoncd/resolution/pkg/resolver/framework"
+	"golang.org/x/crypto/ssh"
+)
+
+// sshGitServer is a local ssh server that runs git-upload-pack for the
+// repos on disk, counting the connections made to it.
+type sshGitServer struct {
+	addr     string
+	hostKey  ssh.PublicKey
+	accepted int32
+	open     int32
+}
+
+func newSSHGitServer(t *testing.T) *sshGitServer {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("error generating host key: %v", err)
+	}
+	hostKey, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatalf("error creating host key signer: %v", err)
+	}
+	config := &ssh.ServerConfig{NoClientAuth: true}
+	config.AddHostKey(hostKey)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("error listening: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	server := &sshGitServer{addr: listener.Addr().String(), hostKey: hostKey.PublicKey()}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			atomic.AddInt32(&server.accepted, 1)
+			go server.serve(conn, config)
+		}
+	}()
+	return server
+}
+
+func (s *sshGitServer) serve(conn net.Conn, config *ssh.ServerConfig) {
+	defer conn.Close()
+	serverConn, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	atomic.AddInt32(&s.open, 1)
+	defer atomic.AddInt32(&s.open, -1)
+	go ssh.DiscardRequests(reqs)
+	for newChannel := range chans {
+		if newChannel.ChannelType() != "session" {
+			_ = newChannel.Reject(ssh.UnknownChannelType, "unsupported channel type")
+			continue
+		}
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			continue
+		}
+		go serveUploadPack(channel, requests)
+	}
+	_ = serverConn.Wait()
+}
+
+// serveUploadPack runs the git-upload-pack command of an exec request.
+func serveUploadPack(channel ssh.Channel, requests <-chan *ssh.Request) {
+	defer channel.Close()
+	for req := range requests {
+		var exec struct{ Command string }
+		if req.Type != "exec" || ssh.Unmarshal(req.Payload, &exec) != nil || !strings.HasPrefix(exec.Command, "git-upload-pack ") {
+			if req.WantReply {
+				_ = req.Reply(false, nil)
+			}
+			continue
+		}
+		_ = req.Reply(true, nil)
+		path := strings.Trim(strings.TrimPrefix(exec.Command, "git-upload-pack "), "'")
+		status := runUploadPack(channel, path)
+		_, _ = channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{status}))
+		return
+	}
+}
+
+func runUploadPack(channel ssh.Channel, path string) uint32 {
+	cmd := exec.Command("git-upload-pack", path)
+	cmd.Stdout = channel
+	cmd.Stderr = channel.Stderr()
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return 1
+	}
+	if err := cmd.Start(); err != nil {
+		return 1
+	}
+	go func() {
+		_, _ = io.Copy(stdin, channel)
+		stdin.Close()
+	}()
+	if err := cmd.Wait(); err != nil {
+		return 1
+	}
+	return 0
+}
+
+// useTestSSHAuth makes fetches without explicit auth use a fixed key
+// instead of the ssh agent.
+func useTestSSHAuth(t *testing.T) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("error generating client key: %v", err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatalf("error creating client key signer: %v", err)
+	}
+	original := gitssh.DefaultAuthBuilder
+	gitssh.DefaultAuthBuilder = func(user string) (gitssh.AuthMethod, error) {
+		return &gitssh.PublicKeys{
+			User:   user,
+			Signer: signer,
+			HostKeyCallbackHelper: gitssh.HostKeyCallbackHelper{
+				HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+			},
+		}, nil
+	}
+	t.Cleanup(func() {
+		gitssh.DefaultAuthBuilder = original
+		closeSSHConnections()
+	})
+}
+
+func closeSSHConnections() {
+	sshConnections.mu.Lock()
+	defer sshConnections.mu.Unlock()
+	for key, conn := range sshConnections.conns {
+		_ = conn.client.Close()
+		delete(sshConnections.conns, key)
+	}
+}
+
+func TestResolveSSHConnectionReuse(t *testing.T) {
+	installTransports()
+	repoDir, _ := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "kind: Task"},
+	}})
+
+	for _, tc := range []struct {
+		name        string
+		idleTimeout string
+		expected    int32
+	}{{
+		name:     "disabled",
+		expected: 2,
+	}, {
+		name:        "enabled",
+		idleTimeout: "1m",
+		expected:    1,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			useTestSSHAuth(t)
+			server := newSSHGitServer(t)
+			ctx := framework.InjectResolverConfigToContext(context.Background(), map[string]string{
+				ConfigFieldSSHIdleTimeout: tc.idleTimeout,
+			})
+			resolver := &Resolver{}
+			for i := 0; i < 2; i++ {
+				resource, err := resolver.Resolve(ctx, map[string]string{
+					URLParam:  fmt.Sprintf("ssh://git@%s%s", server.addr, repoDir),
+					PathParam: "task.yaml",
+				})
+				if err != nil {
+					t.Fatalf("unexpected error resolving over ssh: %v", err)
+				}
+				if string(resource.Data()) != "kind: Task" {
+					t.Errorf("expected content %q received %q", "kind: Task", string(resource.Data()))
+				}
+			}
+			if n := atomic.LoadInt32(&server.accepted); n != tc.expected {
+				t.Errorf("expected %d ssh connections, received %d", tc.expected, n)
+			}
+		})
+	}
+}
+
+func TestResolveSSHIdleConnectionReaped(t *testing.T) {
+	installTransports()
+	useTestSSHAuth(t)
+	repoDir, _ := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "kind: Task"},
+	}})
+	server := newSSHGitServer(t)
+	ctx := framework.InjectResolverConfigToContext(context.Background(), map[string]string{
+		ConfigFieldSSHIdleTimeout: "50ms",
+	})
+	params := map[string]string{
+		URLParam:  fmt.Sprintf("ssh://git@%s%s", server.addr, repoDir),
+		PathParam: "task.yaml",
+	}
+	resolver := &Resolver{}
+	if _, err := resolver.Resolve(ctx, params); err != nil {
+		t.Fatalf("unexpected error resolving over ssh: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for atomic.LoadInt32(&server.open) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected idle ssh connection to be closed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, err := resolver.Resolve(ctx, params); err != nil {
+		t.Fatalf("unexpected error resolving over ssh after reaping: %v", err)
+	}
+	if n := atomic.LoadInt32(&server.accepted); n != 2 {
+		t.Errorf("expected a new ssh connection after the idle one was reaped, received %d", n)
+	}
+}
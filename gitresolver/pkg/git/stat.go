@@ -0,0 +1,107 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// The kinds of tree entry that a FileStat can describe.
+const (
+	StatTypeFile      = "file"
+	StatTypeDirectory = "directory"
+	StatTypeSymlink   = "symlink"
+	StatTypeSubmodule = "submodule"
+)
+
+// FileStat is the json document returned in place of a file's content
+// when only its metadata is requested.
+type FileStat struct {
+	Path string `json:"path"`
+	Type string `json:"type"`
+
+	// Mode is the octal mode that git records for the entry, like
+	// "100644" for a regular file or "040000" for a directory.
+	Mode string `json:"mode"`
+
+	// Hash is the sha of the blob, tree or submodule commit the entry
+	// points to.
+	Hash string `json:"hash"`
+
+	// Size is the size in bytes of a file or symlink's blob. It's left
+	// out for directories and submodules.
+	Size *int64 `json:"size,omitempty"`
+}
+
+// statPath returns the metadata of the entry at path in the tree of
+// the given commit as a json-encoded resource. Blobs' sizes are read
+// from their headers, so their content isn't read.
+func statPath(repository *git.Repository, commit, path string) (*ResolvedGitResource, error) {
+	commitObj, err := repository.CommitObject(plumbing.NewHash(commit))
+	if err != nil {
+		return nil, fmt.Errorf("error reading commit %q: %v", commit, err)
+	}
+	tree, err := commitObj.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("error reading tree of commit %q: %v", commit, err)
+	}
+
+	stat := FileStat{Path: path}
+	mode, hash := filemode.Dir, tree.Hash
+	if name := strings.Trim(path, "/"); name != "" {
+		entry, err := tree.FindEntry(name)
+		if err != nil {
+			return nil, fmt.Errorf("error opening file %q: %w", path, object.ErrFileNotFound)
+		}
+		mode, hash = entry.Mode, entry.Hash
+	}
+	stat.Mode, stat.Hash = fmt.Sprintf("%06o", uint32(mode)), hash.String()
+	switch mode {
+	case filemode.Dir:
+		stat.Type = StatTypeDirectory
+	case filemode.Submodule:
+		stat.Type = StatTypeSubmodule
+	default:
+		stat.Type = StatTypeFile
+		if mode == filemode.Symlink {
+			stat.Type = StatTypeSymlink
+		}
+		obj, err := repository.Storer.EncodedObject(plumbing.BlobObject, hash)
+		if err != nil {
+			return nil, fmt.Errorf("error reading file %q: %v", path, err)
+		}
+		size := obj.Size()
+		stat.Size = &size
+	}
+
+	content, err := json.Marshal(stat)
+	if err != nil {
+		return nil, fmt.Errorf("error serializing stat of %q: %w", path, err)
+	}
+	return &ResolvedGitResource{
+		Commit:      commit,
+		Content:     content,
+		ContentType: JSONContentType,
+	}, nil
+}
@@ -0,0 +1,112 @@
+package git
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+func TestResolveStatOnly(t *testing.T) {
+	content := "kind: Task\nmetadata:\n  name: large\n"
+	repoDir, commits := createTestRepo(t, []testCommit{{
+		files: map[string]string{
+			"task.yaml":        content,
+			"tasks/build.yaml": "kind: Task",
+		},
+	}})
+	size := int64(len(content))
+
+	for _, tc := range []struct {
+		name     string
+		path     string
+		expected FileStat
+	}{{
+		name: "file",
+		path: "task.yaml",
+		expected: FileStat{
+			Path: "task.yaml",
+			Type: StatTypeFile,
+			Mode: "100644",
+			Hash: plumbing.ComputeHash(plumbing.BlobObject, []byte(content)).String(),
+			Size: &size,
+		},
+	}, {
+		name: "directory",
+		path: "tasks",
+		expected: FileStat{
+			Path: "tasks",
+			Type: StatTypeDirectory,
+			Mode: "040000",
+		},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			params := map[string]string{
+				URLParam:      repoDir,
+				PathParam:     tc.path,
+				StatOnlyParam: "true",
+			}
+			resolver := Resolver{}
+			if err := resolver.ValidateParams(context.Background(), params); err != nil {
+				t.Fatalf("unexpected error validating params: %v", err)
+			}
+			resource, err := resolver.Resolve(context.Background(), params)
+			if err != nil {
+				t.Fatalf("unexpected error resolving: %v", err)
+			}
+			if ct := resource.(*ResolvedGitResource).ContentType; ct != JSONContentType {
+				t.Errorf("expected content type %q received %q", JSONContentType, ct)
+			}
+			if commit := resource.Annotations()[AnnotationKeyCommitHash]; commit != commits[0] {
+				t.Errorf("expected commit %q received %q", commits[0], commit)
+			}
+			stat := FileStat{}
+			if err := json.Unmarshal(resource.Data(), &stat); err != nil {
+				t.Fatalf("error parsing stat: %v", err)
+			}
+			if tc.expected.Hash == "" {
+				if len(stat.Hash) != 40 {
+					t.Errorf("expected a tree hash, received %q", stat.Hash)
+				}
+				tc.expected.Hash = stat.Hash
+			}
+			if stat.Path != tc.expected.Path || stat.Type != tc.expected.Type || stat.Mode != tc.expected.Mode || stat.Hash != tc.expected.Hash {
+				t.Errorf("expected stat %+v received %+v", tc.expected, stat)
+			}
+			if (stat.Size == nil) != (tc.expected.Size == nil) || stat.Size != nil && *stat.Size != *tc.expected.Size {
+				t.Errorf("expected size %v received %v", tc.expected.Size, stat.Size)
+			}
+		})
+	}
+}
+
+func TestResolveStatOnlyMissing(t *testing.T) {
+	repoDir, _ := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "kind: Task"},
+	}})
+
+	resolver := Resolver{}
+	_, err := resolver.Resolve(context.Background(), map[string]string{
+		URLParam:      repoDir,
+		PathParam:     "missing.yaml",
+		StatOnlyParam: "true",
+	})
+	if !isFileMissing(err) || !strings.Contains(err.Error(), `"missing.yaml"`) {
+		t.Errorf("expected file not found error for missing.yaml, received %v", err)
+	}
+}
+
+func TestValidateParamsStatOnly(t *testing.T) {
+	resolver := Resolver{}
+	err := resolver.ValidateParams(context.Background(), map[string]string{
+		URLParam:      "https://github.com/tektoncd/catalog",
+		PathParam:     "task.yaml",
+		StatOnlyParam: "true",
+		GrepParam:     "kind",
+	})
+	if err == nil || !strings.Contains(err.Error(), `supplied both "statOnly" and "grep"`) {
+		t.Errorf("expected error supplying both statOnly and grep, received %v", err)
+	}
+}
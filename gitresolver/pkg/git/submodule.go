@@ -0,0 +1,184 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-git/go-billy/v5"
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+// defaultMaxSubmoduleDepth is how many submodules deep a path may
+// lead when max-submodule-depth isn't configured.
+const defaultMaxSubmoduleDepth = 5
+
+// followSubmodules returns true if paths that lead into a submodule are
+// read from it, going by the submodules param or, when that's unset,
+// the submodules field in the git-resolver-config configmap.
+func followSubmodules(conf, params map[string]string) bool {
+	if v, ok := params[SubmodulesParam]; ok {
+		follow, _ := strconv.ParseBool(v)
+		return follow
+	}
+	follow, _ := strconv.ParseBool(conf[ConfigFieldSubmodules])
+	return follow
+}
+
+// getMaxSubmoduleDepth returns how many submodules deep a path may lead,
+// as configured with the max-submodule-depth field in the
+// git-resolver-config configmap.
+func getMaxSubmoduleDepth(conf map[string]string) (int, error) {
+	depthString, ok := conf[ConfigFieldMaxSubmoduleDepth]
+	if !ok || depthString == "" {
+		return defaultMaxSubmoduleDepth, nil
+	}
+	depth, err := strconv.Atoi(depthString)
+	if err != nil || depth <= 0 {
+		return 0, fmt.Errorf("invalid %s %q: must be a positive integer", ConfigFieldMaxSubmoduleDepth, depthString)
+	}
+	return depth, nil
+}
+
+// submodulePath is where a path that may lead into submodules is read
+// from: the url, repository, filesystem and commit of the innermost
+// submodule it leads into, and the path within it.
+type submodulePath struct {
+	url        string
+	repository *git.Repository
+	filesystem billy.Filesystem
+	commit     string
+	path       string
+}
+
+// enterSubmodules follows path through the submodules of repository at
+// commit that it leads into, initializing and updating each one at the
+// commit its parent records, and returns where the rest of the path is
+// read from. A path that doesn't lead into a submodule is returned as
+// it is. auth is only sent to submodules on the same host as the repo
+// itself, and submodule urls are checked against the allowed urls
+// like any other. Paths nested more than maxDepth submodules deep, or
+// that lead back into a repo they've already been through, are
+// rejected.
+func enterSubmodules(ctx context.Context, conf map[string]string, target submodulePath, auth transport.AuthMethod, maxDepth int) (submodulePath, error) {
+	host := repoHost(target.url)
+	visited := map[string]bool{target.url: true}
+	for depth := 0; ; depth++ {
+		sub, rest, err := findSubmodule(target.repository, target.commit, target.path)
+		if err != nil || sub == nil {
+			return target, err
+		}
+		if depth == maxDepth {
+			return submodulePath{}, fmt.Errorf("path %q leads through more than %d submodules; see %s", target.path, maxDepth, ConfigFieldMaxSubmoduleDepth)
+		}
+		if target, err = updateSubmodule(ctx, conf, sub, host, auth, visited); err != nil {
+			return submodulePath{}, err
+		}
+		target.path = rest
+	}
+}
+
+// findSubmodule returns the submodule of repository at commit that path
+// leads into, if any, and the rest of the path beneath it. Paths that
+// don't exist are left for the read to report as missing.
+func findSubmodule(repository *git.Repository, commit, path string) (*git.Submodule, string, error) {
+	commitObj, err := repository.CommitObject(plumbing.NewHash(commit))
+	if err != nil {
+		return nil, "", fmt.Errorf("error reading commit %q: %w", commit, err)
+	}
+	tree, err := commitObj.Tree()
+	if err != nil {
+		return nil, "", fmt.Errorf("error reading tree of commit %q: %w", commit, err)
+	}
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	for i := range parts {
+		prefix := strings.Join(parts[:i+1], "/")
+		entry, err := tree.FindEntry(prefix)
+		if err != nil || entry.Mode == filemode.Regular || entry.Mode == filemode.Executable || entry.Mode == filemode.Symlink {
+			return nil, "", nil
+		}
+		if entry.Mode != filemode.Submodule {
+			continue
+		}
+		w, err := repository.Worktree()
+		if err != nil {
+			return nil, "", fmt.Errorf("worktree error: %v", err)
+		}
+		submodules, err := w.Submodules()
+		if err != nil {
+			return nil, "", fmt.Errorf("error reading submodules: %w", err)
+		}
+		for _, sub := range submodules {
+			if strings.Trim(sub.Config().Path, "/") == prefix {
+				return sub, strings.Join(parts[i+1:], "/"), nil
+			}
+		}
+		return nil, "", fmt.Errorf("submodule at %q isn't listed in .gitmodules", prefix)
+	}
+	return nil, "", nil
+}
+
+// updateSubmodule initializes sub and checks out the commit that its
+// parent records, returning where it's read from.
+func updateSubmodule(ctx context.Context, conf map[string]string, sub *git.Submodule, host string, auth transport.AuthMethod, visited map[string]bool) (submodulePath, error) {
+	name := sub.Config().Name
+	if err := sub.Init(); err != nil && !errors.Is(err, git.ErrSubmoduleAlreadyInitialized) {
+		return submodulePath{}, fmt.Errorf("error initializing submodule %q: %w", name, err)
+	}
+	repository, err := sub.Repository()
+	if err != nil {
+		return submodulePath{}, fmt.Errorf("error opening submodule %q: %w", name, err)
+	}
+	remote, err := repository.Remote(git.DefaultRemoteName)
+	if err != nil {
+		return submodulePath{}, fmt.Errorf("error reading url of submodule %q: %w", name, err)
+	}
+	url := remote.Config().URLs[0]
+	if err := checkAllowedURL(conf, url); err != nil {
+		return submodulePath{}, err
+	}
+	if visited[url] {
+		return submodulePath{}, fmt.Errorf("submodule %q leads back into %s", name, sanitizeURL(url))
+	}
+	visited[url] = true
+	if repoHost(url) != host {
+		auth = nil
+	}
+
+	_, span := startSpan(ctx, "git.submodule", attrHost.String(repoHost(url)), attrURL.String(sanitizeURL(url)))
+	err = sub.UpdateContext(ctx, &git.SubmoduleUpdateOptions{Auth: auth})
+	endSpan(span, err)
+	if err != nil {
+		return submodulePath{}, fmt.Errorf("error updating submodule %q: %w", name, err)
+	}
+	head, err := repository.Head()
+	if err != nil {
+		return submodulePath{}, fmt.Errorf("error reading head of submodule %q: %w", name, err)
+	}
+	w, err := repository.Worktree()
+	if err != nil {
+		return submodulePath{}, fmt.Errorf("worktree error: %v", err)
+	}
+	return submodulePath{url: url, repository: repository, filesystem: w.Filesystem, commit: head.Hash().String()}, nil
+}
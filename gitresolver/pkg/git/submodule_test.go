@@ -0,0 +1,194 @@
+package git
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tektoncd/resolution/pkg/resolver/framework"
+)
+
+// gitRepo is a repo created with the git binary, so that it can have
+// submodules.
+type gitRepo struct {
+	t   *testing.T
+	dir string
+}
+
+func newGitRepo(t *testing.T) *gitRepo {
+	t.Helper()
+	r := &gitRepo{t: t, dir: t.TempDir()}
+	r.run("init", "--quiet", "--initial-branch", "master")
+	return r
+}
+
+func (r *gitRepo) run(args ...string) string {
+	r.t.Helper()
+	args = append([]string{"-c", "protocol.file.allow=always", "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)
+	cmd := exec.Command(gitBinary, args...)
+	cmd.Dir = r.dir
+	cmd.Env = append(os.Environ(), nonInteractiveEnv()...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		r.t.Fatalf("git %v: %v: %s", args, err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// commit commits files and returns the new commit's hash.
+func (r *gitRepo) commit(files map[string]string) string {
+	r.t.Helper()
+	for name, content := range files {
+		path := filepath.Join(r.dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			r.t.Fatalf("error creating directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			r.t.Fatalf("error writing file: %v", err)
+		}
+	}
+	r.run("add", "--all")
+	r.run("commit", "--quiet", "--allow-empty", "-m", "commit")
+	return r.run("rev-parse", "HEAD")
+}
+
+// addSubmodule adds sub as a submodule at path and commits it.
+func (r *gitRepo) addSubmodule(sub *gitRepo, path string) string {
+	r.t.Helper()
+	r.run("submodule", "--quiet", "add", sub.dir, path)
+	return r.commit(nil)
+}
+
+func resolveSubmodulePath(t *testing.T, conf, params map[string]string) (framework.ResolvedResource, error) {
+	t.Helper()
+	resolver := Resolver{}
+	return resolver.Resolve(framework.InjectResolverConfigToContext(context.Background(), conf), params)
+}
+
+func TestResolveFileInSubmodule(t *testing.T) {
+	lib := newGitRepo(t)
+	lib.commit(map[string]string{"tasks/build.yaml": "old"})
+	lib.commit(map[string]string{"tasks/build.yaml": "build"})
+	parent := newGitRepo(t)
+	parent.commit(map[string]string{"pipeline.yaml": "pipeline"})
+	commit := parent.addSubmodule(lib, "vendor/lib")
+	// The parent records the commit it was added at, so later commits
+	// to the submodule aren't read.
+	lib.commit(map[string]string{"tasks/build.yaml": "newer"})
+
+	for _, tc := range []struct {
+		name   string
+		conf   map[string]string
+		params map[string]string
+	}{{
+		name:   "param",
+		params: map[string]string{SubmodulesParam: "true"},
+	}, {
+		name: "config",
+		conf: map[string]string{ConfigFieldSubmodules: "true"},
+	}, {
+		name:   "commit",
+		params: map[string]string{SubmodulesParam: "true", CommitParam: commit},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			params := map[string]string{URLParam: parent.dir, PathParam: "vendor/lib/tasks/build.yaml"}
+			for k, v := range tc.params {
+				params[k] = v
+			}
+			resource, err := resolveSubmodulePath(t, tc.conf, params)
+			if err != nil {
+				t.Fatalf("unexpected error resolving file in submodule: %v", err)
+			}
+			if string(resource.Data()) != "build" {
+				t.Errorf("expected the submodule's file at the recorded commit, received %q", string(resource.Data()))
+			}
+			if resource.Annotations()[AnnotationKeyCommitHash] != commit {
+				t.Errorf("expected the parent's commit %s to be recorded, received %q", commit, resource.Annotations()[AnnotationKeyCommitHash])
+			}
+		})
+	}
+
+	t.Run("parent files", func(t *testing.T) {
+		resource, err := resolveSubmodulePath(t, nil, map[string]string{URLParam: parent.dir, PathParam: "pipeline.yaml", SubmodulesParam: "true"})
+		if err != nil {
+			t.Fatalf("unexpected error resolving file: %v", err)
+		}
+		if string(resource.Data()) != "pipeline" {
+			t.Errorf("expected the parent's file, received %q", string(resource.Data()))
+		}
+	})
+
+	t.Run("not followed", func(t *testing.T) {
+		_, err := resolveSubmodulePath(t, map[string]string{ConfigFieldSubmodules: "true"}, map[string]string{URLParam: parent.dir, PathParam: "vendor/lib/tasks/build.yaml", SubmodulesParam: "false"})
+		if !isFileMissing(err) {
+			t.Errorf("expected the file to be missing without submodules, received %v", err)
+		}
+	})
+
+	t.Run("disallowed url", func(t *testing.T) {
+		remote := newGitRepo(t)
+		remote.addSubmodule(lib, "vendor/lib")
+		remote.run("config", "--file", ".gitmodules", "submodule.vendor/lib.url", "https://example.com/lib.git")
+		remote.commit(nil)
+		_, err := resolveSubmodulePath(t, map[string]string{ConfigFieldAllowedURLs: "file://"}, map[string]string{URLParam: remote.dir, PathParam: "vendor/lib/tasks/build.yaml", SubmodulesParam: "true"})
+		if err == nil || !strings.Contains(err.Error(), `host "example.com" isn't in allowed-urls`) {
+			t.Errorf("expected the submodule's url to be checked against %s, received %v", ConfigFieldAllowedURLs, err)
+		}
+	})
+}
+
+func TestResolveNestedSubmodules(t *testing.T) {
+	inner := newGitRepo(t)
+	inner.commit(map[string]string{"task.yaml": "inner"})
+	middle := newGitRepo(t)
+	middle.commit(map[string]string{"README.md": "middle"})
+	middle.addSubmodule(inner, "inner")
+	outer := newGitRepo(t)
+	outer.commit(map[string]string{"README.md": "outer"})
+	outer.addSubmodule(middle, "middle")
+	params := map[string]string{URLParam: outer.dir, PathParam: "middle/inner/task.yaml", SubmodulesParam: "true"}
+
+	resource, err := resolveSubmodulePath(t, nil, params)
+	if err != nil {
+		t.Fatalf("unexpected error resolving file in nested submodule: %v", err)
+	}
+	if string(resource.Data()) != "inner" {
+		t.Errorf("expected the nested submodule's file, received %q", string(resource.Data()))
+	}
+
+	_, err = resolveSubmodulePath(t, map[string]string{ConfigFieldMaxSubmoduleDepth: "1"}, params)
+	if err == nil || !strings.Contains(err.Error(), "leads through more than 1 submodules") {
+		t.Errorf("expected the path to exceed %s, received %v", ConfigFieldMaxSubmoduleDepth, err)
+	}
+}
+
+func TestResolveSubmoduleCycle(t *testing.T) {
+	first := newGitRepo(t)
+	first.commit(map[string]string{"task.yaml": "first"})
+	second := newGitRepo(t)
+	second.commit(map[string]string{"README.md": "second"})
+	second.addSubmodule(first, "first")
+	first.addSubmodule(second, "second")
+
+	_, err := resolveSubmodulePath(t, nil, map[string]string{URLParam: first.dir, PathParam: "second/first/task.yaml", SubmodulesParam: "true"})
+	if err == nil || !strings.Contains(err.Error(), "leads back into") {
+		t.Errorf("expected the submodule cycle to be rejected, received %v", err)
+	}
+}
+
+func TestValidateParamsSubmodules(t *testing.T) {
+	for _, params := range []map[string]string{
+		{SubmodulesParam: "yes"},
+		{SubmodulesParam: "true", BlameParam: "true"},
+		{SubmodulesParam: "true", StatOnlyParam: "true"},
+		{SubmodulesParam: "true", FormatParam: FormatTar},
+	} {
+		params[URLParam], params[PathParam] = "https://github.com/tektoncd/catalog", "task.yaml"
+		if err := (&Resolver{}).ValidateParams(context.Background(), params); err == nil {
+			t.Errorf("expected params %v to be invalid", params)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/tektoncd/resolution/pkg/resolver/framework"
+)
+
+// defaultMaxSymrefDepth is the most symbolic refs that are followed to
+// reach a hash when max-symref-depth isn't configured, matching git's
+// own limit.
+const defaultMaxSymrefDepth = 5
+
+// getMaxSymrefDepth returns the most symbolic refs that may be followed
+// to reach a hash, as configured with the max-symref-depth field in
+// the git-resolver-config configmap.
+func getMaxSymrefDepth(ctx context.Context) (int, error) {
+	conf := framework.GetResolverConfigFromContext(ctx)
+	depthString, ok := conf[ConfigFieldMaxSymrefDepth]
+	if !ok || depthString == "" {
+		return defaultMaxSymrefDepth, nil
+	}
+	depth, err := strconv.Atoi(depthString)
+	if err != nil || depth <= 0 {
+		return 0, fmt.Errorf("invalid %s %q: must be a positive integer", ConfigFieldMaxSymrefDepth, depthString)
+	}
+	return depth, nil
+}
+
+// resolveSymref follows the chain of symbolic refs starting at name,
+// looking each up with lookup, until it reaches a ref that points to a
+// hash. Malformed repos can chain symbolic refs endlessly or in a
+// cycle, so chains longer than maxDepth, or that visit a ref twice,
+// fail rather than being followed.
+func resolveSymref(name plumbing.ReferenceName, maxDepth int, lookup func(plumbing.ReferenceName) (*plumbing.Reference, error)) (*plumbing.Reference, error) {
+	start := name
+	seen := map[plumbing.ReferenceName]bool{}
+	for depth := 0; ; depth++ {
+		ref, err := lookup(name)
+		if err != nil {
+			return nil, err
+		}
+		if ref.Type() != plumbing.SymbolicReference {
+			return ref, nil
+		}
+		seen[name] = true
+		if seen[ref.Target()] {
+			return nil, fmt.Errorf("symbolic ref chain too deep: %q points back to %q", name, ref.Target())
+		}
+		if depth >= maxDepth {
+			return nil, fmt.Errorf("symbolic ref chain too deep: more than %d symbolic refs followed from %q", maxDepth, start)
+		}
+		name = ref.Target()
+	}
+}
+
+// resolveRepositoryRef returns the hash ref that name resolves to in
+// repository, following at most the configured number of symbolic refs.
+func resolveRepositoryRef(ctx context.Context, repository *git.Repository, name plumbing.ReferenceName) (*plumbing.Reference, error) {
+	maxDepth, err := getMaxSymrefDepth(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return resolveSymref(name, maxDepth, repository.Storer.Reference)
+}
@@ -0,0 +1,134 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/tektoncd/resolution/pkg/resolver/framework"
+)
+
+const symrefTestHash = "0123456789abcdef0123456789abcdef01234567"
+
+// symrefChain returns refs where HEAD points through length symbolic
+// refs to a branch that points to symrefTestHash.
+func symrefChain(length int) []*plumbing.Reference {
+	refs := []*plumbing.Reference{}
+	name := plumbing.HEAD
+	for i := 1; i < length; i++ {
+		next := plumbing.ReferenceName(fmt.Sprintf("refs/heads/link%d", i))
+		refs = append(refs, plumbing.NewSymbolicReference(name, next))
+		name = next
+	}
+	refs = append(refs,
+		plumbing.NewSymbolicReference(name, plumbing.Master),
+		plumbing.NewHashReference(plumbing.Master, plumbing.NewHash(symrefTestHash)),
+	)
+	return refs
+}
+
+func TestPickRefSymrefChain(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		refs        []*plumbing.Reference
+		maxDepth    int
+		expectedErr string
+	}{{
+		name:     "head to branch",
+		refs:     symrefChain(1),
+		maxDepth: defaultMaxSymrefDepth,
+	}, {
+		name:     "chain at the limit",
+		refs:     symrefChain(5),
+		maxDepth: defaultMaxSymrefDepth,
+	}, {
+		name:        "chain past the limit",
+		refs:        symrefChain(6),
+		maxDepth:    defaultMaxSymrefDepth,
+		expectedErr: "more than 5 symbolic refs followed from \"HEAD\"",
+	}, {
+		name:     "long chain with a raised limit",
+		refs:     symrefChain(20),
+		maxDepth: 20,
+	}, {
+		name: "cycle",
+		refs: []*plumbing.Reference{
+			plumbing.NewSymbolicReference(plumbing.HEAD, "refs/heads/a"),
+			plumbing.NewSymbolicReference("refs/heads/a", "refs/heads/b"),
+			plumbing.NewSymbolicReference("refs/heads/b", "refs/heads/a"),
+		},
+		maxDepth:    defaultMaxSymrefDepth,
+		expectedErr: "\"refs/heads/b\" points back to \"refs/heads/a\"",
+	}, {
+		name:        "self reference",
+		refs:        []*plumbing.Reference{plumbing.NewSymbolicReference(plumbing.HEAD, plumbing.HEAD)},
+		maxDepth:    defaultMaxSymrefDepth,
+		expectedErr: "\"HEAD\" points back to \"HEAD\"",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			ref, err := pickRef(tc.refs, nil, tc.maxDepth)
+			if tc.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), "symbolic ref chain too deep") || !strings.Contains(err.Error(), tc.expectedErr) {
+					t.Fatalf("expected error containing %q, received %v", tc.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error picking ref: %v", err)
+			}
+			if ref.Name() != plumbing.HEAD || ref.Hash().String() != symrefTestHash {
+				t.Errorf("expected HEAD at %q, received %v", symrefTestHash, ref)
+			}
+		})
+	}
+}
+
+func TestPickRefSymrefDangling(t *testing.T) {
+	refs := []*plumbing.Reference{plumbing.NewSymbolicReference(plumbing.HEAD, "refs/heads/missing")}
+	if _, err := pickRef(refs, nil, defaultMaxSymrefDepth); err == nil || !strings.Contains(err.Error(), plumbing.ErrReferenceNotFound.Error()) {
+		t.Errorf("expected reference not found error, received %v", err)
+	}
+}
+
+func TestResolveRepositoryRefSymrefChain(t *testing.T) {
+	repository, err := git.Init(memory.NewStorage(), nil)
+	if err != nil {
+		t.Fatalf("error initializing repository: %v", err)
+	}
+	for _, ref := range symrefChain(8) {
+		if err := repository.Storer.SetReference(ref); err != nil {
+			t.Fatalf("error setting ref %v: %v", ref, err)
+		}
+	}
+
+	_, err = resolveRepositoryRef(context.Background(), repository, plumbing.HEAD)
+	if err == nil || !strings.Contains(err.Error(), "symbolic ref chain too deep") {
+		t.Errorf("expected symbolic ref chain too deep error, received %v", err)
+	}
+
+	ctx := framework.InjectResolverConfigToContext(context.Background(), map[string]string{
+		ConfigFieldMaxSymrefDepth: "8",
+	})
+	ref, err := resolveRepositoryRef(ctx, repository, plumbing.HEAD)
+	if err != nil {
+		t.Fatalf("unexpected error resolving HEAD: %v", err)
+	}
+	if ref.Hash().String() != symrefTestHash {
+		t.Errorf("expected HEAD to resolve to %q, received %q", symrefTestHash, ref.Hash())
+	}
+}
+
+func TestGetMaxSymrefDepthInvalid(t *testing.T) {
+	for _, depth := range []string{"0", "-1", "deep"} {
+		ctx := framework.InjectResolverConfigToContext(context.Background(), map[string]string{
+			ConfigFieldMaxSymrefDepth: depth,
+		})
+		if _, err := getMaxSymrefDepth(ctx); err == nil || !strings.Contains(err.Error(), ConfigFieldMaxSymrefDepth) {
+			t.Errorf("expected invalid %s error for %q, received %v", ConfigFieldMaxSymrefDepth, depth, err)
+		}
+	}
+}
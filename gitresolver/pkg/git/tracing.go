@@ -0,0 +1,82 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"context"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName identifies the spans recorded by the git resolver.
+const tracerName = "github.com/tektoncd/resolution/gitresolver/pkg/git"
+
+// Attributes recorded on git resolver spans.
+const (
+	attrHost         = attribute.Key("git.host")
+	attrURL          = attribute.Key("git.url")
+	attrRef          = attribute.Key("git.ref")
+	attrCommit       = attribute.Key("git.commit")
+	attrPath         = attribute.Key("git.path")
+	attrBytes        = attribute.Key("git.bytes")
+	attrFetchedBytes = attribute.Key("git.fetched_bytes")
+	attrCacheHit     = attribute.Key("git.cache_hit")
+)
+
+// startSpan starts a span as a child of any span in ctx using the
+// global tracer provider, which records nothing unless one has been
+// configured.
+func startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// endSpan ends span, marking it as failed if err is non-nil.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// repoHost returns the host that the repo at url is fetched from, or an
+// empty string for local paths.
+func repoHost(url string) string {
+	ep, err := transport.NewEndpoint(url)
+	if err != nil || ep.Protocol == "file" {
+		return ""
+	}
+	return ep.Host
+}
+
+// sanitizeURL returns url without any credentials, query or fragment,
+// so that it's safe to record on a span.
+func sanitizeURL(url string) string {
+	ep, err := transport.NewEndpoint(url)
+	if err != nil {
+		return ""
+	}
+	ep.User, ep.Password = "", ""
+	ep.Path, _, _ = strings.Cut(ep.Path, "?")
+	ep.Path, _, _ = strings.Cut(ep.Path, "#")
+	return ep.String()
+}
@@ -0,0 +1,425 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/transport/client"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/tektoncd/resolution/pkg/resolver/framework"
+)
+
+// installTransports replaces go-git's default http, https and ssh
+// clients, and the client for git hosts' apis, with ones whose
+// behaviour can be configured per resolution. go-git only supports
+// registering transports globally so any request-scoped settings are
+// read from the context of each outgoing request.
+func installTransports() {
+	apiClient = newAPIClient()
+	transport := githttp.NewClient(&http.Client{
+		Transport:     &byteCountTransport{base: &htmlResponseTransport{base: &retryAfterTransport{base: newHTTPTransport()}}},
+		CheckRedirect: checkRedirect,
+	})
+	client.InstallProtocol("http", transport)
+	client.InstallProtocol("https", transport)
+	client.InstallProtocol("ssh", sshTransport{})
+}
+
+// proxyFunc picks the proxy for each outgoing request. It is a
+// variable so that tests can route requests through a stub proxy.
+var proxyFunc = http.ProxyFromEnvironment
+
+// rootCAs is the pool that https servers' certificates are verified
+// against, with nil meaning the system's pool. It is a variable so that
+// tests can trust the certificate of a stub server.
+var rootCAs *x509.CertPool
+
+// newHTTPTransport returns a copy of http.DefaultTransport that dials
+// connections using dialContext, presents the client certificate and
+// trusts the ca bundle from the request's context, if any, and
+// authenticates to proxies with the Proxy-Authorization value from the
+// request's context, if any.
+func newHTTPTransport() http.RoundTripper {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{RootCAs: rootCAs}
+	transport.DialContext = dialContext
+	transport.Proxy = requestProxy
+	transport.GetProxyConnectHeader = func(ctx context.Context, _ *url.URL, _ string) (http.Header, error) {
+		if value := proxyAuthorization(ctx); value != "" {
+			return http.Header{"Proxy-Authorization": []string{value}}, nil
+		}
+		return nil, nil
+	}
+	return &tlsTransport{base: transport}
+}
+
+// tlsTransport sends requests that have a client certificate or a ca
+// bundle in their context through a copy of its base transport that
+// presents the certificate and trusts the bundle. Each combination gets
+// its own copy so that connections made with one are never reused for
+// requests made with another, or with neither.
+type tlsTransport struct {
+	base *http.Transport
+
+	mu    sync.Mutex
+	byTLS map[[sha256.Size]byte]*http.Transport
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *tlsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport := t.base
+	cert, bundle := clientCertificate(req.Context()), caBundleFromContext(req.Context())
+	if cert != nil || bundle != nil {
+		transport = t.forTLS(cert, bundle)
+	}
+	return (&proxyAuthTransport{base: transport}).RoundTrip(req)
+}
+
+// forTLS returns the copy of the base transport that presents cert, if
+// it's set, and trusts bundle, if it's set, creating it the first time
+// they're used together.
+func (t *tlsTransport) forTLS(cert *tls.Certificate, bundle *caBundle) *http.Transport {
+	h := sha256.New()
+	if cert != nil {
+		h.Write(cert.Certificate[0])
+	}
+	h.Write([]byte{0})
+	if bundle != nil {
+		h.Write(bundle.digest[:])
+	}
+	var key [sha256.Size]byte
+	copy(key[:], h.Sum(nil))
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if transport, ok := t.byTLS[key]; ok {
+		return transport
+	}
+	transport := t.base.Clone()
+	if cert != nil {
+		transport.TLSClientConfig.Certificates = []tls.Certificate{*cert}
+	}
+	if bundle != nil {
+		transport.TLSClientConfig.RootCAs = bundle.pool
+	}
+	if t.byTLS == nil {
+		t.byTLS = map[[sha256.Size]byte]*http.Transport{}
+	}
+	t.byTLS[key] = transport
+	return transport
+}
+
+// proxyAuthTransport adds a Proxy-Authorization header to plain http
+// requests that are sent through a proxy. Https requests tunnel through
+// the proxy with CONNECT, which gets its header from the base
+// transport's GetProxyConnectHeader instead.
+type proxyAuthTransport struct {
+	base *http.Transport
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *proxyAuthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if value := proxyAuthorization(req.Context()); value != "" && req.URL.Scheme == "http" {
+		proxy, err := t.base.Proxy(req)
+		if err != nil {
+			return nil, err
+		}
+		if proxy != nil {
+			req = req.Clone(req.Context())
+			req.Header.Set("Proxy-Authorization", value)
+		}
+	}
+	return t.base.RoundTrip(req)
+}
+
+// checkRedirect stops following redirects once they loop back to a url
+// that was already requested or their number exceeds the max-redirects
+// from the resolver's config, so that misconfigured servers fail
+// quickly instead of running until the resolution times out.
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	for _, previous := range via {
+		if previous.URL.String() == req.URL.String() {
+			return fmt.Errorf("too many redirects: redirect loop back to %s", req.URL.Redacted())
+		}
+	}
+	if max := getMaxRedirects(req.Context()); len(via) > max {
+		return fmt.Errorf("too many redirects: stopped after %d", max)
+	}
+	return nil
+}
+
+// defaultMaxRedirects is the number of redirects followed when
+// max-redirects isn't configured, matching net/http's default.
+const defaultMaxRedirects = 10
+
+// getMaxRedirects returns the number of redirects a request to a git
+// server may follow, as configured with the max-redirects field in the
+// git-resolver-config configmap.
+func getMaxRedirects(ctx context.Context) int {
+	conf := framework.GetResolverConfigFromContext(ctx)
+	if maxString, ok := conf[ConfigFieldMaxRedirects]; ok {
+		max, err := strconv.Atoi(maxString)
+		if err == nil && max >= 0 {
+			return max
+		}
+	}
+	return defaultMaxRedirects
+}
+
+// htmlResponseTransport rejects html pages served in place of git data.
+// Git servers never return html from their smart http endpoints, so one
+// is almost always a login page that an auth redirect ended up at, and
+// failing here gives a clearer error than go-git's parser would.
+type htmlResponseTransport struct {
+	base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *htmlResponseTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return resp, err
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "text/html" {
+		return resp, nil
+	}
+	resp.Body.Close()
+	return nil, fmt.Errorf("%s returned an html page instead of git data, which usually means it redirected to a login page: check the repo's url and credentials", req.URL.Redacted())
+}
+
+// byteCountTransport adds the size of each response body that's read to
+// the counter in the request's context, if there is one, so that the
+// bytes a clone fetched can be recorded on its span.
+type byteCountTransport struct {
+	base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *byteCountTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if counter := fetchedBytesCounter(req.Context()); counter != nil && err == nil {
+		resp.Body = &countingReadCloser{ReadCloser: resp.Body, counter: counter}
+	}
+	return resp, err
+}
+
+// countingReadCloser adds the number of bytes read through it to
+// counter.
+type countingReadCloser struct {
+	io.ReadCloser
+	counter *int64
+}
+
+func (r *countingReadCloser) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	atomic.AddInt64(r.counter, int64(n))
+	return n, err
+}
+
+// defaultMaxRetryAfter is the longest a rate limited request waits
+// before it's retried when max-retry-after isn't configured.
+const defaultMaxRetryAfter = 30 * time.Second
+
+// retryAfterTransport retries requests that a server turns away with a
+// 429 or 503 response carrying a Retry-After header, waiting as long as
+// the header asks. Rate limited apis say exactly when to come back, so
+// this is more precise than backing off blindly.
+type retryAfterTransport struct {
+	base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper. A request is retried up to
+// rate-limit-retries times, each time waiting at most max-retry-after.
+// The response is returned as is once retries run out, if the wait
+// would pass the deadline of the request's context, or if the request
+// has a body that can't be sent again.
+func (t *retryAfterTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	retries, maxWait := getRateLimitRetries(ctx)
+	resp, err := t.base.RoundTrip(req)
+	for attempt := 0; attempt < retries && err == nil; attempt++ {
+		wait, ok := retryAfter(resp, time.Now())
+		if !ok {
+			break
+		}
+		if wait > maxWait {
+			wait = maxWait
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			break
+		}
+		retry := req.Clone(ctx)
+		if req.Body != nil && req.Body != http.NoBody {
+			if req.GetBody == nil {
+				break
+			}
+			if retry.Body, err = req.GetBody(); err != nil {
+				return nil, fmt.Errorf("error rewinding request body to retry it: %w", err)
+			}
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+		resp, err = t.base.RoundTrip(retry)
+	}
+	return resp, err
+}
+
+// retryAfter returns how long resp asks for a request to wait before
+// it's retried, given as seconds or an http date in its Retry-After
+// header. It returns false unless resp is a 429 or 503 with a valid
+// header.
+func retryAfter(resp *http.Response, now time.Time) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+		return 0, false
+	}
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	if wait := date.Sub(now); wait > 0 {
+		return wait, true
+	}
+	return 0, true
+}
+
+// getRateLimitRetries returns how many times a rate limited request is
+// retried and the longest it waits each time, as configured with the
+// rate-limit-retries and max-retry-after fields in the
+// git-resolver-config configmap. Invalid values disable retries.
+func getRateLimitRetries(ctx context.Context) (int, time.Duration) {
+	conf := framework.GetResolverConfigFromContext(ctx)
+	retries, err := strconv.Atoi(conf[ConfigFieldRateLimitRetries])
+	if err != nil || retries < 0 {
+		return 0, 0
+	}
+	maxWait := defaultMaxRetryAfter
+	if maxString, ok := conf[ConfigFieldMaxRetryAfter]; ok {
+		if maxWait, err = time.ParseDuration(maxString); err != nil || maxWait < 0 {
+			return 0, 0
+		}
+	}
+	return retries, maxWait
+}
+
+// proxyAuthKey is the context key for the Proxy-Authorization value of
+// a resolution.
+type proxyAuthKey struct{}
+
+// withProxyAuthorization returns a context whose outgoing requests
+// authenticate to proxies with the given Proxy-Authorization value.
+func withProxyAuthorization(ctx context.Context, value string) context.Context {
+	return context.WithValue(ctx, proxyAuthKey{}, value)
+}
+
+// proxyAuthorization returns the Proxy-Authorization value stored in
+// ctx, or an empty string.
+func proxyAuthorization(ctx context.Context) string {
+	value, _ := ctx.Value(proxyAuthKey{}).(string)
+	return value
+}
+
+// fetchedBytesKey is the context key for the counter of the bytes
+// fetched over http for a resolution.
+type fetchedBytesKey struct{}
+
+// withFetchedBytesCounter returns a context whose outgoing http
+// requests add the size of their responses to the returned counter.
+func withFetchedBytesCounter(ctx context.Context) (context.Context, *int64) {
+	counter := new(int64)
+	return context.WithValue(ctx, fetchedBytesKey{}, counter), counter
+}
+
+// fetchedBytesCounter returns the counter stored in ctx, or nil.
+func fetchedBytesCounter(ctx context.Context) *int64 {
+	counter, _ := ctx.Value(fetchedBytesKey{}).(*int64)
+	return counter
+}
+
+// clientCertificateKey is the context key for the client certificate
+// of a resolution.
+type clientCertificateKey struct{}
+
+// withClientCertificate returns a context whose outgoing https requests
+// present cert to servers that ask for a client certificate.
+func withClientCertificate(ctx context.Context, cert *tls.Certificate) context.Context {
+	return context.WithValue(ctx, clientCertificateKey{}, cert)
+}
+
+// clientCertificate returns the client certificate stored in ctx, or
+// nil.
+func clientCertificate(ctx context.Context) *tls.Certificate {
+	cert, _ := ctx.Value(clientCertificateKey{}).(*tls.Certificate)
+	return cert
+}
+
+// dialContext opens a connection to addr, giving up once the connect
+// timeout from the resolver's config has elapsed. The deadline of ctx
+// still applies when it is sooner.
+func dialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	dialer := &net.Dialer{
+		Timeout: getConnectTimeout(ctx),
+	}
+	return dialer.DialContext(ctx, network, addr)
+}
+
+// getConnectTimeout returns the maximum time establishing a connection
+// to a git server may take, as configured with the connect-timeout
+// field in the git-resolver-config configmap. Zero means only the
+// overall resolution timeout applies.
+func getConnectTimeout(ctx context.Context) time.Duration {
+	conf := framework.GetResolverConfigFromContext(ctx)
+	if timeoutString, ok := conf[ConfigFieldConnectTimeout]; ok {
+		timeout, err := time.ParseDuration(timeoutString)
+		if err == nil {
+			return timeout
+		}
+	}
+	return 0
+}
@@ -0,0 +1,404 @@
+package git
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tektoncd/resolution/pkg/resolver/framework"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"knative.dev/pkg/system"
+)
+
+// unroutableAddress is a non-routable IP address, so connections to it
+// are never accepted or refused and only time out.
+const unroutableAddress = "10.255.255.1"
+
+func TestGetConnectTimeout(t *testing.T) {
+	if timeout := getConnectTimeout(context.Background()); timeout != 0 {
+		t.Errorf("expected no connect timeout by default, received %s", timeout)
+	}
+	ctx := framework.InjectResolverConfigToContext(context.Background(), map[string]string{
+		ConfigFieldConnectTimeout: "250ms",
+	})
+	if timeout := getConnectTimeout(ctx); timeout != 250*time.Millisecond {
+		t.Errorf("expected connect timeout of 250ms, received %s", timeout)
+	}
+}
+
+func TestResolveConnectTimeout(t *testing.T) {
+	installTransports()
+	resolver := Resolver{}
+
+	ctx := framework.InjectResolverConfigToContext(context.Background(), map[string]string{
+		ConfigFieldConnectTimeout: "200ms",
+	})
+	// The overall deadline is much longer than the connect timeout so
+	// that a failure within it can only come from the dialer.
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	_, err := resolver.Resolve(ctx, map[string]string{
+		URLParam:  "http://" + unroutableAddress + "/repo.git",
+		PathParam: "task.yaml",
+	})
+	elapsed := time.Since(start)
+	if err == nil {
+		t.Fatalf("expected error resolving from unroutable address")
+	}
+	if elapsed > 5*time.Second {
+		t.Errorf("expected connect to fail fast, took %s", elapsed)
+	}
+}
+
+func TestResolveProxyAuth(t *testing.T) {
+	t.Setenv(system.NamespaceEnvKey, "tekton-remote-resolution")
+	newMetadataServer(t)
+	repoDir, _ := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "via proxy"},
+	}})
+	gitServer := newGitHTTPServer(t, repoDir, nil)
+	target, err := url.Parse(gitServer.URL)
+	if err != nil {
+		t.Fatalf("error parsing git server url: %v", err)
+	}
+
+	expectedProxyAuth := "Basic " + base64.StdEncoding.EncodeToString([]byte("proxyuser:proxypass"))
+	var proxied int32
+	forward := httputil.NewSingleHostReverseProxy(target)
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Proxy-Authorization"); got != expectedProxyAuth {
+			t.Errorf("expected Proxy-Authorization %q received %q", expectedProxyAuth, got)
+			http.Error(w, "proxy auth required", http.StatusProxyAuthRequired)
+			return
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer token-1" {
+			t.Errorf("expected repo Authorization %q received %q", "Bearer token-1", got)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		atomic.AddInt32(&proxied, 1)
+		forward.ServeHTTP(w, r)
+	}))
+	defer proxy.Close()
+	proxyURL, err := url.Parse(proxy.URL)
+	if err != nil {
+		t.Fatalf("error parsing proxy url: %v", err)
+	}
+	original := proxyFunc
+	proxyFunc = http.ProxyURL(proxyURL)
+	defer func() { proxyFunc = original }()
+	installTransports()
+
+	resolver := &Resolver{
+		kubeClientSet: fake.NewSimpleClientset(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "proxy-credentials", Namespace: "tekton-remote-resolution"},
+			Type:       corev1.SecretTypeBasicAuth,
+			Data: map[string][]byte{
+				corev1.BasicAuthUsernameKey: []byte("proxyuser"),
+				corev1.BasicAuthPasswordKey: []byte("proxypass"),
+			},
+		}),
+	}
+	ctx := framework.InjectResolverConfigToContext(context.Background(), map[string]string{
+		ConfigFieldAuthMode:        AuthModeWorkloadIdentity,
+		ConfigFieldProxyAuthSecret: "proxy-credentials",
+	})
+	resource, err := resolver.Resolve(ctx, map[string]string{
+		URLParam:  gitServer.URL + "/repo",
+		PathParam: "task.yaml",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error resolving through proxy: %v", err)
+	}
+	if string(resource.Data()) != "via proxy" {
+		t.Errorf("expected content %q received %q", "via proxy", string(resource.Data()))
+	}
+	if atomic.LoadInt32(&proxied) == 0 {
+		t.Errorf("expected requests to be sent through the proxy")
+	}
+}
+
+func TestProxyConnectHeader(t *testing.T) {
+	transport := newHTTPTransport().(*tlsTransport)
+	header, err := transport.base.GetProxyConnectHeader(context.Background(), nil, "example.com:443")
+	if err != nil || header != nil {
+		t.Errorf("expected no proxy connect header without proxy auth, received %v %v", header, err)
+	}
+	ctx := withProxyAuthorization(context.Background(), "Basic abc")
+	header, err = transport.base.GetProxyConnectHeader(ctx, nil, "example.com:443")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := header.Get("Proxy-Authorization"); got != "Basic abc" {
+		t.Errorf("expected CONNECT Proxy-Authorization %q received %q", "Basic abc", got)
+	}
+}
+
+func TestResolveRedirectLoop(t *testing.T) {
+	installTransports()
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		http.Redirect(w, r, r.URL.String(), http.StatusFound)
+	}))
+	defer server.Close()
+
+	resolver := Resolver{}
+	_, err := resolver.Resolve(context.Background(), map[string]string{
+		URLParam:  server.URL + "/repo",
+		PathParam: "task.yaml",
+	})
+	if err == nil || !strings.Contains(err.Error(), "too many redirects") {
+		t.Fatalf("expected too many redirects error, got %v", err)
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("expected the loop to be detected before repeating a request, received %d requests", n)
+	}
+}
+
+func TestResolveMaxRedirects(t *testing.T) {
+	installTransports()
+	repoDir, _ := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "kind: Task"},
+	}})
+	gitServer := newGitHTTPServer(t, repoDir, nil)
+	target, err := url.Parse(gitServer.URL)
+	if err != nil {
+		t.Fatalf("error parsing git server url: %v", err)
+	}
+	forward := httputil.NewSingleHostReverseProxy(target)
+	// Each hop of the ref advertisement redirects to the next until the
+	// git server is reached. Fetches are forwarded straight to it.
+	hops := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			forward.ServeHTTP(w, r)
+			return
+		}
+		hop, _ := strconv.Atoi(r.URL.Query().Get("hop"))
+		if hop >= 3 {
+			http.Redirect(w, r, gitServer.URL+r.URL.Path+"?service="+r.URL.Query().Get("service"), http.StatusFound)
+			return
+		}
+		next := *r.URL
+		next.RawQuery = url.Values{"hop": {strconv.Itoa(hop + 1)}, "service": {r.URL.Query().Get("service")}}.Encode()
+		http.Redirect(w, r, next.String(), http.StatusFound)
+	}))
+	defer hops.Close()
+
+	for _, tc := range []struct {
+		maxRedirects string
+		wantErr      bool
+	}{
+		{maxRedirects: "4", wantErr: false},
+		{maxRedirects: "3", wantErr: true},
+	} {
+		ctx := framework.InjectResolverConfigToContext(context.Background(), map[string]string{
+			ConfigFieldMaxRedirects: tc.maxRedirects,
+		})
+		resolver := Resolver{}
+		_, err := resolver.Resolve(ctx, map[string]string{
+			URLParam:  hops.URL + "/repo",
+			PathParam: "task.yaml",
+		})
+		if !tc.wantErr && err != nil {
+			t.Errorf("unexpected error resolving with %s %s: %v", ConfigFieldMaxRedirects, tc.maxRedirects, err)
+		}
+		if tc.wantErr && (err == nil || !strings.Contains(err.Error(), "too many redirects")) {
+			t.Errorf("expected too many redirects error with %s %s, got %v", ConfigFieldMaxRedirects, tc.maxRedirects, err)
+		}
+	}
+}
+
+func TestResolveRedirectToLoginPage(t *testing.T) {
+	installTransports()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			fmt.Fprint(w, "<html><body>Sign in</body></html>")
+			return
+		}
+		http.Redirect(w, r, "/login", http.StatusFound)
+	}))
+	defer server.Close()
+
+	resolver := Resolver{}
+	_, err := resolver.Resolve(context.Background(), map[string]string{
+		URLParam:  server.URL + "/repo",
+		PathParam: "task.yaml",
+	})
+	if err == nil || !strings.Contains(err.Error(), "login page") {
+		t.Fatalf("expected login page error, got %v", err)
+	}
+}
+
+// rateLimitOnce returns a wrapper for a git http server that answers
+// the first upload-pack request, which carries the fetch negotiation in
+// its body, with a 429 asking to be retried after retryAfter.
+func rateLimitOnce(retryAfter string, limited *int32) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodPost && atomic.CompareAndSwapInt32(limited, 0, 1) {
+				w.Header().Set("Retry-After", retryAfter)
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func TestResolveRetryAfter(t *testing.T) {
+	installTransports()
+	repoDir, _ := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "kind: Task"},
+	}})
+
+	for _, tc := range []struct {
+		name       string
+		conf       map[string]string
+		retryAfter string
+		minWait    time.Duration
+		maxWait    time.Duration
+		expectErr  bool
+	}{{
+		name:       "waits the indicated time",
+		conf:       map[string]string{ConfigFieldRateLimitRetries: "1"},
+		retryAfter: "1",
+		minWait:    time.Second,
+		maxWait:    10 * time.Second,
+	}, {
+		name:       "wait is capped",
+		conf:       map[string]string{ConfigFieldRateLimitRetries: "1", ConfigFieldMaxRetryAfter: "100ms"},
+		retryAfter: "3600",
+		minWait:    100 * time.Millisecond,
+		maxWait:    10 * time.Second,
+	}, {
+		name:       "retries disabled",
+		conf:       map[string]string{},
+		retryAfter: "1",
+		maxWait:    time.Second,
+		expectErr:  true,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			var limited int32
+			server := newGitHTTPServer(t, repoDir, rateLimitOnce(tc.retryAfter, &limited))
+			ctx := framework.InjectResolverConfigToContext(context.Background(), tc.conf)
+			resolver := Resolver{}
+
+			start := time.Now()
+			resource, err := resolver.Resolve(ctx, map[string]string{
+				URLParam:  server.URL + "/repo",
+				PathParam: "task.yaml",
+			})
+			elapsed := time.Since(start)
+			if atomic.LoadInt32(&limited) != 1 {
+				t.Fatalf("expected the fetch to be rate limited")
+			}
+			if tc.expectErr {
+				if err == nil {
+					t.Errorf("expected rate limited fetch to fail without retries")
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("unexpected error resolving: %v", err)
+				}
+				if string(resource.Data()) != "kind: Task" {
+					t.Errorf("expected content %q received %q", "kind: Task", resource.Data())
+				}
+			}
+			if elapsed < tc.minWait || elapsed > tc.maxWait {
+				t.Errorf("expected resolution to take between %s and %s, took %s", tc.minWait, tc.maxWait, elapsed)
+			}
+		})
+	}
+}
+
+func TestResolveRetryAfterPastDeadline(t *testing.T) {
+	installTransports()
+	repoDir, _ := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "kind: Task"},
+	}})
+	var limited int32
+	server := newGitHTTPServer(t, repoDir, rateLimitOnce("60", &limited))
+	ctx := framework.InjectResolverConfigToContext(context.Background(), map[string]string{
+		ConfigFieldRateLimitRetries: "1",
+		ConfigFieldMaxRetryAfter:    "1m",
+	})
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	resolver := Resolver{}
+	if _, err := resolver.Resolve(ctx, map[string]string{
+		URLParam:  server.URL + "/repo",
+		PathParam: "task.yaml",
+	}); err == nil {
+		t.Errorf("expected rate limited fetch to fail")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("expected a wait past the deadline to fail straight away, took %s", elapsed)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		name     string
+		status   int
+		header   string
+		expected time.Duration
+		ok       bool
+	}{{
+		name:     "seconds",
+		status:   http.StatusTooManyRequests,
+		header:   "120",
+		expected: 2 * time.Minute,
+		ok:       true,
+	}, {
+		name:     "http date",
+		status:   http.StatusServiceUnavailable,
+		header:   now.Add(30 * time.Second).Format(http.TimeFormat),
+		expected: 30 * time.Second,
+		ok:       true,
+	}, {
+		name:   "past http date",
+		status: http.StatusTooManyRequests,
+		header: now.Add(-time.Minute).Format(http.TimeFormat),
+		ok:     true,
+	}, {
+		name:   "no header",
+		status: http.StatusTooManyRequests,
+	}, {
+		name:   "invalid header",
+		status: http.StatusTooManyRequests,
+		header: "soon",
+	}, {
+		name:   "other status",
+		status: http.StatusInternalServerError,
+		header: "1",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: tc.status, Header: http.Header{}}
+			if tc.header != "" {
+				resp.Header.Set("Retry-After", tc.header)
+			}
+			wait, ok := retryAfter(resp, now)
+			if wait != tc.expected || ok != tc.ok {
+				t.Errorf("expected %s, %t received %s, %t", tc.expected, tc.ok, wait, ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+const (
+	// GitSuffixKeep clones repo urls exactly as they're given.
+	GitSuffixKeep = "keep"
+
+	// GitSuffixAdd appends ".git" to remote repo urls that don't
+	// already end with it before cloning.
+	GitSuffixAdd = "add"
+
+	// GitSuffixStrip removes a trailing ".git" from remote repo urls
+	// before cloning.
+	GitSuffixStrip = "strip"
+)
+
+// gitSuffix is the suffix that git hosting services conventionally
+// accept at the end of a repo url.
+const gitSuffix = ".git"
+
+// getGitSuffixMode returns how the ".git" suffix of repo urls is
+// handled, as configured with the git-url-suffix field in the
+// git-resolver-config configmap. Urls are kept as given by default.
+func getGitSuffixMode(conf map[string]string) (string, error) {
+	switch mode := conf[ConfigFieldGitURLSuffix]; mode {
+	case "", GitSuffixKeep:
+		return GitSuffixKeep, nil
+	case GitSuffixAdd, GitSuffixStrip:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid %s %q: must be one of %q, %q, %q", ConfigFieldGitURLSuffix, mode, GitSuffixKeep, GitSuffixAdd, GitSuffixStrip)
+	}
+}
+
+// cloneURL applies the given ".git" suffix handling to a remote repo
+// url. Local paths and file urls are left alone since they name a
+// directory on disk.
+func cloneURL(url, mode string) string {
+	if mode == GitSuffixKeep || !isRemoteURL(url) {
+		return url
+	}
+	trimmed := strings.TrimSuffix(strings.TrimRight(url, "/"), gitSuffix)
+	if mode == GitSuffixAdd {
+		return trimmed + gitSuffix
+	}
+	return trimmed
+}
+
+// canonicalRepoURL returns url without any trailing slash or ".git"
+// suffix, so that both spellings of a repo compare equal when deriving
+// things like cache keys from it.
+func canonicalRepoURL(url string) string {
+	if !isRemoteURL(url) {
+		return url
+	}
+	return strings.TrimSuffix(strings.TrimRight(url, "/"), gitSuffix)
+}
+
+// isRemoteURL returns true if url points at a git server rather than
+// a local path.
+func isRemoteURL(url string) bool {
+	ep, err := transport.NewEndpoint(url)
+	return err == nil && ep.Protocol != "file"
+}
@@ -0,0 +1,138 @@
+package git
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/tektoncd/resolution/pkg/resolver/framework"
+)
+
+func TestCloneURL(t *testing.T) {
+	for _, tc := range []struct {
+		url  string
+		mode string
+		want string
+	}{
+		{url: "https://example.com/org/repo", mode: GitSuffixKeep, want: "https://example.com/org/repo"},
+		{url: "https://example.com/org/repo", mode: GitSuffixAdd, want: "https://example.com/org/repo.git"},
+		{url: "https://example.com/org/repo.git", mode: GitSuffixAdd, want: "https://example.com/org/repo.git"},
+		{url: "https://example.com/org/repo/", mode: GitSuffixAdd, want: "https://example.com/org/repo.git"},
+		{url: "https://example.com/org/repo.git", mode: GitSuffixStrip, want: "https://example.com/org/repo"},
+		{url: "https://example.com/org/repo", mode: GitSuffixStrip, want: "https://example.com/org/repo"},
+		{url: "git@example.com:org/repo", mode: GitSuffixAdd, want: "git@example.com:org/repo.git"},
+		{url: "/tmp/repo", mode: GitSuffixAdd, want: "/tmp/repo"},
+		{url: "file:///tmp/repo.git", mode: GitSuffixStrip, want: "file:///tmp/repo.git"},
+	} {
+		if got := cloneURL(tc.url, tc.mode); got != tc.want {
+			t.Errorf("cloneURL(%q, %q) = %q, want %q", tc.url, tc.mode, got, tc.want)
+		}
+	}
+}
+
+func TestCanonicalRepoURL(t *testing.T) {
+	for _, url := range []string{
+		"https://example.com/org/repo",
+		"https://example.com/org/repo.git",
+		"https://example.com/org/repo/",
+	} {
+		if got, want := canonicalRepoURL(url), "https://example.com/org/repo"; got != want {
+			t.Errorf("canonicalRepoURL(%q) = %q, want %q", url, got, want)
+		}
+	}
+}
+
+func TestGetGitSuffixModeInvalid(t *testing.T) {
+	if _, err := getGitSuffixMode(map[string]string{ConfigFieldGitURLSuffix: "sometimes"}); err == nil {
+		t.Fatalf("expected error for invalid %s", ConfigFieldGitURLSuffix)
+	}
+}
+
+func TestResolveGitSuffix(t *testing.T) {
+	repoDir, commits := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "kind: Task"},
+	}})
+
+	for _, tc := range []struct {
+		name string
+		mode string
+		// accept reports whether the server serves a request path,
+		// standing in for servers that only accept one spelling.
+		accept func(path string) bool
+	}{{
+		name:   "add",
+		mode:   GitSuffixAdd,
+		accept: func(path string) bool { return strings.Contains(path, ".git/") },
+	}, {
+		name:   "strip",
+		mode:   GitSuffixStrip,
+		accept: func(path string) bool { return !strings.Contains(path, ".git/") },
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			server := newGitHTTPServer(t, repoDir, func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					if !tc.accept(r.URL.Path) {
+						http.NotFound(w, r)
+						return
+					}
+					// The backing repo is only linked as "repo".
+					r.URL.Path = strings.Replace(r.URL.Path, "/repo.git/", "/repo/", 1)
+					next.ServeHTTP(w, r)
+				})
+			})
+			ctx := framework.InjectResolverConfigToContext(context.Background(), map[string]string{
+				ConfigFieldGitURLSuffix: tc.mode,
+			})
+			for _, url := range []string{server.URL + "/repo", server.URL + "/repo.git"} {
+				resolver := Resolver{}
+				resource, err := resolver.Resolve(ctx, map[string]string{
+					URLParam:  url,
+					PathParam: "task.yaml",
+				})
+				if err != nil {
+					t.Fatalf("unexpected error resolving %s: %v", url, err)
+				}
+				resolved := resource.(*ResolvedGitResource)
+				if string(resolved.Data()) != "kind: Task" {
+					t.Errorf("unexpected content resolving %s: %q", url, resolved.Data())
+				}
+				if resolved.Commit != commits[0] {
+					t.Errorf("expected %s to resolve commit %s, got %s", url, commits[0], resolved.Commit)
+				}
+				if resolved.URL != url {
+					t.Errorf("expected resolved url %s, got %s", url, resolved.URL)
+				}
+			}
+		})
+	}
+}
+
+func TestResolveCacheSharedAcrossGitSuffix(t *testing.T) {
+	repoDir, _ := createTestRepo(t, []testCommit{{
+		files: map[string]string{"task.yaml": "kind: Task"},
+	}})
+	repoURL, fetches := newCountingGitHTTPServer(t, repoDir)
+
+	ctx := framework.InjectResolverConfigToContext(context.Background(), map[string]string{
+		ConfigFieldCacheSize:    "10",
+		ConfigFieldGitURLSuffix: GitSuffixStrip,
+	})
+	resolver := Resolver{}
+	for _, url := range []string{repoURL, repoURL + ".git"} {
+		resource, err := resolver.Resolve(ctx, map[string]string{
+			URLParam:  url,
+			PathParam: "task.yaml",
+		})
+		if err != nil {
+			t.Fatalf("unexpected error resolving %s: %v", url, err)
+		}
+		if got := resource.(*ResolvedGitResource).URL; got != url {
+			t.Errorf("expected resolved url %s, got %s", url, got)
+		}
+	}
+	if n := atomic.LoadInt32(fetches); n != 1 {
+		t.Errorf("expected both spellings of the url to share one fetch, got %d", n)
+	}
+}
@@ -78,6 +78,41 @@ type ResolutionRequestStatusFields struct {
 	// of the requested resource in-lined into the ResolutionRequest
 	// object.
 	Data string `json:"data"`
+
+	// Resolved is structured metadata describing where the resolved
+	// content came from, for resolvers that provide it.
+	// +optional
+	Resolved *ResolvedMetadata `json:"resolved,omitempty"`
+}
+
+// ResolvedMetadata describes the provenance of a resolved resource.
+type ResolvedMetadata struct {
+	// Repo is the location the resource was resolved from, such as
+	// a git repository url.
+	// +optional
+	Repo string `json:"repo,omitempty"`
+
+	// Ref is the name of the branch, tag or other reference that was
+	// requested, if any.
+	// +optional
+	Ref string `json:"ref,omitempty"`
+
+	// Commit is the immutable revision the resource was read from.
+	// +optional
+	Commit string `json:"commit,omitempty"`
+
+	// Path is the location of the resource within the repo.
+	// +optional
+	Path string `json:"path,omitempty"`
+
+	// Digest is the digest of the resolved content, e.g.
+	// "sha256:abc123...".
+	// +optional
+	Digest string `json:"digest,omitempty"`
+
+	// ResolvedAt is the time at which the resource was resolved.
+	// +optional
+	ResolvedAt *metav1.Time `json:"resolvedAt,omitempty"`
 }
 
 // GetStatus implements KRShaped.
@@ -113,7 +113,7 @@ func (in *ResolutionRequestSpec) DeepCopy() *ResolutionRequestSpec {
 func (in *ResolutionRequestStatus) DeepCopyInto(out *ResolutionRequestStatus) {
 	*out = *in
 	in.Status.DeepCopyInto(&out.Status)
-	out.ResolutionRequestStatusFields = in.ResolutionRequestStatusFields
+	in.ResolutionRequestStatusFields.DeepCopyInto(&out.ResolutionRequestStatusFields)
 	return
 }
 
@@ -130,6 +130,11 @@ func (in *ResolutionRequestStatus) DeepCopy() *ResolutionRequestStatus {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ResolutionRequestStatusFields) DeepCopyInto(out *ResolutionRequestStatusFields) {
 	*out = *in
+	if in.Resolved != nil {
+		in, out := &in.Resolved, &out.Resolved
+		*out = new(ResolvedMetadata)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -142,3 +147,23 @@ func (in *ResolutionRequestStatusFields) DeepCopy() *ResolutionRequestStatusFiel
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResolvedMetadata) DeepCopyInto(out *ResolvedMetadata) {
+	*out = *in
+	if in.ResolvedAt != nil {
+		in, out := &in.ResolvedAt, &out.ResolvedAt
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResolvedMetadata.
+func (in *ResolvedMetadata) DeepCopy() *ResolvedMetadata {
+	if in == nil {
+		return nil
+	}
+	out := new(ResolvedMetadata)
+	in.DeepCopyInto(out)
+	return out
+}
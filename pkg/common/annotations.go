@@ -20,4 +20,20 @@ const (
 	// AnnotationKeyContentType is the annotation key passed back
 	// with a resolved resource's content type.
 	AnnotationKeyContentType = "content-type"
+
+	// AnnotationKeyContentEncoding is the annotation key passed back
+	// with a resolved resource's data when it's been compressed, with
+	// the compression used, like "gzip".
+	AnnotationKeyContentEncoding = "content-encoding"
+
+	// AnnotationKeySpanContext is the annotation key on a
+	// ResolutionRequest holding the W3C trace context, as a JSON object
+	// like {"traceparent": "..."}, of the span that resolving it should
+	// be traced under.
+	AnnotationKeySpanContext = "resolution.tekton.dev/span-context"
+
+	// AnnotationKeyResolutionAttempts is the annotation key on a
+	// ResolutionRequest counting how many times resolving it has
+	// failed with a retryable error.
+	AnnotationKeyResolutionAttempts = "resolution.tekton.dev/resolution-attempts"
 )
@@ -48,3 +48,26 @@ func RequestNamespace(ctx context.Context) string {
 	}
 	return ""
 }
+
+// requestAnnotationsContextKey is the key stored in a context alongside
+// the annotations of a resolution request.
+type requestAnnotationsContextKey struct{}
+
+// InjectRequestAnnotations returns a new context with the annotations
+// of the resolution request being processed. Like the namespace, this
+// value may only be set once per request.
+func InjectRequestAnnotations(ctx context.Context, annotations map[string]string) context.Context {
+	if val := ctx.Value(requestAnnotationsContextKey{}); val != nil {
+		return ctx
+	}
+	return context.WithValue(ctx, requestAnnotationsContextKey{}, annotations)
+}
+
+// RequestAnnotations returns the annotations of the resolution request
+// currently being processed or nil if none were injected.
+func RequestAnnotations(ctx context.Context) map[string]string {
+	if val, ok := ctx.Value(requestAnnotationsContextKey{}).(map[string]string); ok {
+		return val
+	}
+	return nil
+}
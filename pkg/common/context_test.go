@@ -25,3 +25,20 @@ func TestRequestNamespace(t *testing.T) {
 		t.Fatalf("expected empty namespace returned if no value was previously injected")
 	}
 }
+
+func TestRequestAnnotations(t *testing.T) {
+	ctx := context.Background()
+	if RequestAnnotations(ctx) != nil {
+		t.Fatalf("expected nil annotations returned if none were previously injected")
+	}
+
+	ctx = InjectRequestAnnotations(ctx, map[string]string{"foo": "bar"})
+	if RequestAnnotations(ctx)["foo"] != "bar" {
+		t.Fatalf("expected annotations to be stored as part of context")
+	}
+
+	ctx = InjectRequestAnnotations(ctx, map[string]string{"foo": "baz"})
+	if RequestAnnotations(ctx)["foo"] != "bar" {
+		t.Fatalf("expected stored annotations to be immutable once set")
+	}
+}
@@ -50,6 +50,47 @@ func NewError(reason string, err error) *Error {
 	}
 }
 
+// RetryableError is implemented by errors that know whether the
+// problem they describe is transient, such as a remote being briefly
+// unavailable, so that resolving the request again may succeed.
+type RetryableError interface {
+	error
+	IsRetryable() bool
+}
+
+// IsRetryable returns true if err, or an error it wraps, is a
+// RetryableError that reports itself as retryable.
+func IsRetryable(err error) bool {
+	var retryable RetryableError
+	return errors.As(err, &retryable) && retryable.IsRetryable()
+}
+
+// retryableError marks the error it wraps as retryable.
+type retryableError struct {
+	Original error
+}
+
+var _ RetryableError = &retryableError{}
+
+func (e *retryableError) Error() string {
+	return e.Original.Error()
+}
+
+func (e *retryableError) Unwrap() error {
+	return e.Original
+}
+
+func (e *retryableError) IsRetryable() bool {
+	return true
+}
+
+// NewRetryableError returns err marked as retryable, for resolvers to
+// return when a failure is transient and resolving the request again
+// may succeed.
+func NewRetryableError(err error) error {
+	return &retryableError{Original: err}
+}
+
 var (
 	// ErrorRequestInProgress is a sentinel value to indicate that
 	// a resource request is still in progress.
@@ -107,6 +148,24 @@ func (e *ErrorGettingResource) Unwrap() error {
 	return e.Original
 }
 
+// ErrorRejectedResource is an error received when a resource was
+// resolved successfully but a validator registered with the resolver
+// refused to accept it.
+type ErrorRejectedResource struct {
+	ResolutionRequestKey string
+	Original             error
+}
+
+var _ error = &ErrorRejectedResource{}
+
+func (e *ErrorRejectedResource) Error() string {
+	return fmt.Sprintf("resolved resource for %q rejected: %v", e.ResolutionRequestKey, e.Original)
+}
+
+func (e *ErrorRejectedResource) Unwrap() error {
+	return e.Original
+}
+
 // ErrorUpdatingRequest is an error during any part of the update
 // process for a ResolutionRequest, e.g. when attempting to patch the
 // ResolutionRequest with resolved data.
@@ -127,14 +186,20 @@ func (e *ErrorUpdatingRequest) Unwrap() error {
 
 // ReasonError extracts the reason and underlying error
 // embedded in a given error or returns some sane defaults
-// if the error isn't a common.Error.
+// if the error isn't a common.Error. The reason of an Error
+// wrapped inside err, such as one returned by a resolver, is
+// used too, but err is then returned whole so that the context
+// it was wrapped with is kept.
 func ReasonError(err error) (string, error) {
 	reason := ReasonResolutionFailed
 	resolutionError := err
 
+	var wrapped *Error
 	if e, ok := err.(*Error); ok {
 		reason = e.Reason
 		resolutionError = e.Unwrap()
+	} else if errors.As(err, &wrapped) && wrapped.Reason != "" {
+		reason = wrapped.Reason
 	}
 
 	return reason, resolutionError
@@ -18,6 +18,7 @@ package common
 
 import (
 	"errors"
+	"fmt"
 	"testing"
 )
 
@@ -44,3 +45,75 @@ func TestResolutionErrorMessage(t *testing.T) {
 		t.Errorf("resolution error message expected to equal that of original error")
 	}
 }
+
+func TestReasonError(t *testing.T) {
+	original := errors.New("commit is not signed")
+	for _, tc := range []struct {
+		name            string
+		err             error
+		expectedReason  string
+		expectedMessage string
+	}{{
+		name:            "plain error",
+		err:             original,
+		expectedReason:  ReasonResolutionFailed,
+		expectedMessage: "commit is not signed",
+	}, {
+		name:            "resolution error",
+		err:             NewError("UnsignedCommit", original),
+		expectedReason:  "UnsignedCommit",
+		expectedMessage: "commit is not signed",
+	}, {
+		name: "wrapped resolution error",
+		err: &ErrorGettingResource{
+			ResolverName: "Git",
+			Key:          "default/request",
+			Original:     fmt.Errorf("resolving: %w", NewError("UnsignedCommit", original)),
+		},
+		expectedReason:  "UnsignedCommit",
+		expectedMessage: `error getting "Git" "default/request": resolving: commit is not signed`,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			reason, err := ReasonError(tc.err)
+			if reason != tc.expectedReason {
+				t.Errorf("expected reason %q received %q", tc.expectedReason, reason)
+			}
+			if err.Error() != tc.expectedMessage {
+				t.Errorf("expected message %q received %q", tc.expectedMessage, err.Error())
+			}
+		})
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	original := errors.New("connection reset by peer")
+	for _, tc := range []struct {
+		name     string
+		err      error
+		expected bool
+	}{{
+		name: "plain error",
+		err:  original,
+	}, {
+		name:     "retryable error",
+		err:      NewRetryableError(original),
+		expected: true,
+	}, {
+		name: "wrapped retryable error",
+		err: &ErrorGettingResource{
+			ResolverName: "Git",
+			Key:          "default/request",
+			Original:     fmt.Errorf("cloning: %w", NewRetryableError(original)),
+		},
+		expected: true,
+	}, {
+		name: "resolution error",
+		err:  NewError("UnsignedCommit", original),
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			if IsRetryable(tc.err) != tc.expected {
+				t.Errorf("expected IsRetryable to be %t for %v", tc.expected, tc.err)
+			}
+		})
+	}
+}
@@ -40,4 +40,17 @@ const (
 	// ReasonResolutionTimedOut indicates that a resolver did not
 	// manage to respond to a ResolutionRequest within a timeout.
 	ReasonResolutionTimedOut = "ResolutionTimedOut"
+
+	// ReasonNamespaceTerminating indicates that a ResolutionRequest
+	// could not be resolved because its namespace is being, or has
+	// been, deleted.
+	ReasonNamespaceTerminating = "NamespaceTerminating"
+
+	// ReasonResourceTooLarge indicates that a resolver returned more
+	// data than can be stored in a ResolutionRequest's status.
+	ReasonResourceTooLarge = "ResourceTooLarge"
+
+	// ReasonResolverPanicked indicates that a resolver panicked while
+	// validating or resolving a ResolutionRequest.
+	ReasonResolverPanicked = "ResolverPanicked"
 )
@@ -0,0 +1,94 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ArchiveContentType is the content type of the data written for a
+// ResolvedResourceWithFiles.
+const ArchiveContentType = "application/x-tar"
+
+// ResolvedFile is one of the named files of a
+// ResolvedResourceWithFiles.
+type ResolvedFile struct {
+	// Name is the file's path within the archive.
+	Name string
+
+	// Data is the file's content. It's ignored for symlinks.
+	Data []byte
+
+	// Mode is the file's permissions. Zero means 0644, or 0777 for
+	// a symlink.
+	Mode int64
+
+	// UID and GID own the file. They default to root.
+	UID, GID int
+
+	// Linkname, if set, makes the file a symlink to it.
+	Linkname string
+}
+
+// ArchiveFiles returns files as a tar archive. Entries are written in
+// name order with zero mtimes so that the same files always produce
+// the same bytes, letting a resolver compute digests of its data that
+// match what's written to the ResolutionRequest.
+func ArchiveFiles(files []ResolvedFile) ([]byte, error) {
+	sorted := append([]ResolvedFile{}, files...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	for _, file := range sorted {
+		header := &tar.Header{
+			Name:    file.Name,
+			Mode:    file.Mode,
+			ModTime: time.Unix(0, 0),
+			Uid:     file.UID,
+			Gid:     file.GID,
+			Format:  tar.FormatPAX,
+		}
+		if file.Linkname != "" {
+			header.Typeflag, header.Linkname = tar.TypeSymlink, file.Linkname
+			if header.Mode == 0 {
+				header.Mode = 0777
+			}
+		} else {
+			header.Typeflag, header.Size = tar.TypeReg, int64(len(file.Data))
+			if header.Mode == 0 {
+				header.Mode = 0644
+			}
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return nil, fmt.Errorf("error writing archive entry %q: %w", file.Name, err)
+		}
+		if file.Linkname == "" {
+			if _, err := tw.Write(file.Data); err != nil {
+				return nil, fmt.Errorf("error writing archive entry %q: %w", file.Name, err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("error writing archive: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,81 @@
+package framework
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+// readArchive returns the files in a tar archive in the order they
+// were written.
+func readArchive(t *testing.T, data []byte) []ResolvedFile {
+	t.Helper()
+	files := []ResolvedFile{}
+	tr := tar.NewReader(bytes.NewReader(data))
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return files
+		}
+		if err != nil {
+			t.Fatalf("error reading archive: %v", err)
+		}
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatalf("error reading archive entry %q: %v", header.Name, err)
+		}
+		file := ResolvedFile{
+			Name:     header.Name,
+			Mode:     header.Mode,
+			UID:      header.Uid,
+			GID:      header.Gid,
+			Linkname: header.Linkname,
+		}
+		if len(content) > 0 {
+			file.Data = content
+		}
+		files = append(files, file)
+	}
+}
+
+func TestArchiveFilesRoundTrip(t *testing.T) {
+	files := []ResolvedFile{
+		{Name: "tasks/b.yaml", Data: []byte("kind: Task\nname: b\n")},
+		{Name: "pipeline.yaml", Data: []byte("kind: Pipeline\n"), Mode: 0600, UID: 1000, GID: 1000},
+		{Name: "run.sh", Data: []byte("#!/bin/sh\n"), Mode: 0755},
+		{Name: "latest.yaml", Linkname: "pipeline.yaml"},
+	}
+	data, err := ArchiveFiles(files)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []ResolvedFile{
+		{Name: "latest.yaml", Mode: 0777, Linkname: "pipeline.yaml"},
+		{Name: "pipeline.yaml", Data: []byte("kind: Pipeline\n"), Mode: 0600, UID: 1000, GID: 1000},
+		{Name: "run.sh", Data: []byte("#!/bin/sh\n"), Mode: 0755},
+		{Name: "tasks/b.yaml", Data: []byte("kind: Task\nname: b\n"), Mode: 0644},
+	}
+	if got := readArchive(t, data); !reflect.DeepEqual(expected, got) {
+		t.Errorf("expected files %+v received %+v", expected, got)
+	}
+}
+
+func TestArchiveFilesIsDeterministic(t *testing.T) {
+	a := ResolvedFile{Name: "a.yaml", Data: []byte("a")}
+	b := ResolvedFile{Name: "b.yaml", Data: []byte("b")}
+	first, err := ArchiveFiles([]ResolvedFile{a, b})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second, err := ArchiveFiles([]ResolvedFile{b, a})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(first, second) {
+		t.Errorf("expected the same files in any order to produce the same archive")
+	}
+}
@@ -2,6 +2,7 @@ package framework
 
 import (
 	"context"
+	"sync"
 
 	corev1 "k8s.io/api/core/v1"
 	"knative.dev/pkg/configmap"
@@ -30,6 +31,12 @@ func DataFromConfigMap(config *corev1.ConfigMap) (map[string]string, error) {
 type ConfigStore struct {
 	resolverConfigName string
 	untyped            *configmap.UntypedStore
+
+	// validate, if set, checks each configuration that's stored.
+	validate func(map[string]string) error
+
+	mu        sync.RWMutex
+	configErr error
 }
 
 // GetResolverConfig returns a copy of the resolver's current
@@ -45,6 +52,34 @@ func (store *ConfigStore) GetResolverConfig() map[string]string {
 	return resolverConfig
 }
 
+// ConfigError returns the error reported when the resolver's current
+// configuration was validated, or nil if it's valid or the resolver
+// doesn't implement ConfigValidator.
+func (store *ConfigStore) ConfigError() error {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	return store.configErr
+}
+
+// checkConfig returns a callback for the untyped store that validates
+// the resolver's configuration each time it's stored, logging and
+// recording any error so that it's reported by ConfigError.
+func (store *ConfigStore) checkConfig(logger configmap.Logger) func(string, interface{}) {
+	return func(name string, value interface{}) {
+		if name != store.resolverConfigName || store.validate == nil {
+			return
+		}
+		conf, _ := value.(map[string]string)
+		err := store.validate(conf)
+		if err != nil {
+			logger.Errorf("invalid resolver config %q: %v", name, err)
+		}
+		store.mu.Lock()
+		defer store.mu.Unlock()
+		store.configErr = err
+	}
+}
+
 // ToContext returns a new context with the resolver's configuration
 // data stored in it.
 func (store *ConfigStore) ToContext(ctx context.Context) context.Context {
@@ -1,9 +1,14 @@
 package framework
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"knative.dev/pkg/configmap"
 	logtesting "knative.dev/pkg/logging/testing"
 )
@@ -60,6 +65,64 @@ func TestGetResolverConfig(t *testing.T) {
 	}
 }
 
+func TestConfigStoreValidatesConfig(t *testing.T) {
+	store := &ConfigStore{
+		resolverConfigName: "test",
+		validate: func(conf map[string]string) error {
+			_, err := time.ParseDuration(conf["timeout"])
+			return err
+		},
+	}
+	logger := logtesting.TestLogger(t)
+	store.untyped = configmap.NewUntypedStore(
+		"test-config",
+		logger,
+		configmap.Constructors{
+			"test": DataFromConfigMap,
+		},
+		store.checkConfig(logger),
+	)
+	r := &Reconciler{configStore: store}
+	handler := r.readinessHandler()
+
+	probe := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, ReadinessPath, nil))
+		return rec
+	}
+
+	store.untyped.OnConfigChanged(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "test"},
+		Data:       map[string]string{"timeout": "1 minute"},
+	})
+	if err := store.ConfigError(); err == nil {
+		t.Fatal("expected an error for a malformed timeout")
+	}
+	if rec := probe(); rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), "1 minute") {
+		t.Fatalf("expected probe to fail with the malformed value, got %d %q", rec.Code, rec.Body.String())
+	}
+
+	store.untyped.OnConfigChanged(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "test"},
+		Data:       map[string]string{"timeout": "1m"},
+	})
+	if err := store.ConfigError(); err != nil {
+		t.Fatalf("unexpected error once the timeout is fixed: %v", err)
+	}
+	if rec := probe(); rec.Code != http.StatusOK {
+		t.Fatalf("expected probe to pass, got %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestReadinessWithoutConfigStore(t *testing.T) {
+	r := &Reconciler{}
+	rec := httptest.NewRecorder()
+	r.readinessHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, ReadinessPath, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected probe to pass, got %d", rec.Code)
+	}
+}
+
 func mapsAreEqual(m1, m2 map[string]string) bool {
 	if m1 == nil || m2 == nil {
 		return m1 == nil && m2 == nil
@@ -42,6 +42,46 @@ import (
 // things like injecting a test clock.
 type ReconcilerModifier = func(reconciler *Reconciler)
 
+// WithValidators returns a ReconcilerModifier that registers validators
+// to run over every resource the resolver returns before it's written
+// to the ResolutionRequest.
+func WithValidators(validators ...ResolvedResourceValidator) ReconcilerModifier {
+	return func(r *Reconciler) {
+		r.validators = append(r.validators, validators...)
+	}
+}
+
+// WithMaxResolvedSize returns a ReconcilerModifier that sets the most
+// bytes of data a resolved resource may have before the request is
+// failed rather than written. A negative size removes the limit.
+func WithMaxResolvedSize(size int) ReconcilerModifier {
+	return func(r *Reconciler) {
+		r.maxResolvedSize = size
+	}
+}
+
+// WithMaxAttempts returns a ReconcilerModifier that sets the most
+// times a request is resolved, while the resolver keeps returning
+// retryable errors, before the request is failed. An attempts of one
+// fails a request on its first error.
+func WithMaxAttempts(attempts int) ReconcilerModifier {
+	return func(r *Reconciler) {
+		r.maxAttempts = attempts
+	}
+}
+
+// WithCompressionThreshold returns a ReconcilerModifier that gzips
+// resolved data larger than size bytes before it's written, recording
+// a content-encoding annotation of "gzip" so that consumers know to
+// decompress it. Data is only compressed if that makes it smaller, and
+// the max resolved size applies to what's written. Compression is off
+// unless a positive size is set.
+func WithCompressionThreshold(size int) ReconcilerModifier {
+	return func(r *Reconciler) {
+		r.compressionThreshold = size
+	}
+}
+
 // NewController returns a knative controller for a Tekton Resolver.
 // This sets up a lot of the boilerplate that individual resolvers
 // shouldn't need to be concerned with since it's common to all of them.
@@ -76,9 +116,18 @@ func NewController(ctx context.Context, resolver Resolver, modifiers ...Reconcil
 
 		applyModifiersAndDefaults(ctx, r, modifiers)
 
+		if r.readinessAddr != "" {
+			serveReadiness(ctx, r.readinessAddr, r.readinessHandler())
+		}
+
+		if err := registerMetricViews(r.metricLabels); err != nil {
+			logger.Errorf("error registering resolution metrics: %v", err)
+		}
+
 		impl := controller.NewContext(ctx, r, controller.ControllerOptions{
 			WorkQueueName: "TektonResolverFramework." + resolverName,
 			Logger:        logger,
+			Concurrency:   r.concurrency(),
 		})
 
 		rrInformer.Informer().AddEventHandler(cache.FilteringResourceEventHandler{
@@ -167,16 +216,21 @@ func watchConfigChanges(ctx context.Context, reconciler *Reconciler, cmw configm
 		if resolverConfigName == "" {
 			panic("resolver returned empty config name")
 		}
-		reconciler.configStore = &ConfigStore{
-			resolverConfigName: resolverConfigName,
-			untyped: configmap.NewUntypedStore(
-				"resolver-config",
-				logger,
-				configmap.Constructors{
-					resolverConfigName: DataFromConfigMap,
-				},
-			),
+		store := &ConfigStore{resolverConfigName: resolverConfigName}
+		if validator, ok := reconciler.resolver.(ConfigValidator); ok {
+			store.validate = func(conf map[string]string) error {
+				return validator.ValidateConfig(ctx, conf)
+			}
 		}
+		store.untyped = configmap.NewUntypedStore(
+			"resolver-config",
+			logger,
+			configmap.Constructors{
+				resolverConfigName: DataFromConfigMap,
+			},
+			store.checkConfig(logger),
+		)
+		reconciler.configStore = store
 		reconciler.configStore.untyped.WatchConfigs(cmw)
 	}
 }
@@ -19,6 +19,8 @@ package framework
 import (
 	"context"
 	"time"
+
+	"github.com/tektoncd/resolution/pkg/apis/resolution/v1alpha1"
 )
 
 // Resolver is the interface to implement for type-specific resource
@@ -67,6 +69,19 @@ type ConfigWatcher interface {
 	GetConfigName(context.Context) string
 }
 
+// ConfigValidator is an optional interface that a ConfigWatcher can
+// implement to check its configuration as soon as it's loaded, rather
+// than when a request first trips over a malformed value. It's called
+// at startup and after every change to the resolver's configmap. An
+// invalid configuration is logged and reported by the readiness probe
+// served with WithReadinessProbe until it's fixed.
+type ConfigValidator interface {
+	// ValidateConfig receives the resolver's configuration and
+	// should return an error describing any field that can't be
+	// parsed or is out of range.
+	ValidateConfig(context.Context, map[string]string) error
+}
+
 // TimedResolution is an optional interface that a resolver can
 // implement to override the default resolution request timeout.
 //
@@ -89,9 +104,50 @@ type TimedResolution interface {
 	GetResolutionTimeout(context.Context, time.Duration) time.Duration
 }
 
+// ResolvedResourceValidator is the interface to implement for checks
+// that run over resolved content before it is accepted, such as
+// policy-as-code or custom linters. Validators are registered with the
+// WithValidators modifier and are called, in order, after a successful
+// call to Resolve and before the data is written to the
+// ResolutionRequest.
+type ResolvedResourceValidator interface {
+	// ValidateResolvedResource receives the parameters of the
+	// resource request along with the resource that was resolved for
+	// it. Returning an error rejects the resource and fails the
+	// request with the error's message.
+	ValidateResolvedResource(context.Context, map[string]string, ResolvedResource) error
+}
+
 // ResolvedResource returns the data and annotations of a successful
 // resource fetch.
 type ResolvedResource interface {
 	Data() []byte
 	Annotations() map[string]string
 }
+
+// ResolvedResourceWithMetadata is an optional interface that a
+// ResolvedResource can implement to report structured metadata about
+// where its data came from. The metadata is written to the
+// ResolutionRequest's status.resolved field alongside the annotations,
+// giving consumers a typed place to read provenance from.
+type ResolvedResourceWithMetadata interface {
+	ResolvedResource
+
+	// Metadata returns the provenance of the resolved data. If its
+	// ResolvedAt time is unset then the time the data is written to
+	// the ResolutionRequest is used.
+	Metadata() *v1alpha1.ResolvedMetadata
+}
+
+// ResolvedResourceWithFiles is an optional interface that a
+// ResolvedResource can implement to return several named files, like
+// the contents of a directory, as one unit. The reconciler packages
+// them with ArchiveFiles and writes the archive, with an
+// ArchiveContentType content type annotation, in place of Data.
+type ResolvedResourceWithFiles interface {
+	ResolvedResource
+
+	// ResolvedFiles returns the files to archive. Returning nil
+	// writes Data as usual instead.
+	ResolvedFiles() []ResolvedFile
+}
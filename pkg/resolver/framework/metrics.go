@@ -0,0 +1,136 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/tektoncd/resolution/pkg/apis/resolution/v1alpha1"
+	resolutioncommon "github.com/tektoncd/resolution/pkg/common"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	"knative.dev/pkg/controller"
+)
+
+var (
+	resolutionCount = stats.Int64(
+		"resolution_request_count",
+		"Number of ResolutionRequests resolved",
+		stats.UnitDimensionless)
+
+	resolutionDuration = stats.Float64(
+		"resolution_request_duration_seconds",
+		"Time taken to resolve a ResolutionRequest",
+		stats.UnitSeconds)
+
+	resolverKey = tag.MustNewKey("resolver")
+	statusKey   = tag.MustNewKey("status")
+	reasonKey   = tag.MustNewKey("reason")
+)
+
+// Values of the status tag on resolution metrics.
+const (
+	statusSuccess  = "success"
+	statusFailed   = "failed"
+	statusTimeout  = "timeout"
+	statusRetrying = "retrying"
+)
+
+// WithMetricLabels returns a ReconcilerModifier that tags the
+// resolution metrics with the values of the given ResolutionRequest
+// labels. Only these labels are used, rather than every label on a
+// request, so that the number of time series stays bounded. Requests
+// without one of the labels are tagged with an empty value for it.
+func WithMetricLabels(labelKeys ...string) ReconcilerModifier {
+	return func(r *Reconciler) {
+		r.metricLabels = append(r.metricLabels, labelKeys...)
+	}
+}
+
+// metricViews returns the views over the resolution metrics, tagged by
+// resolver, outcome, failure reason and each of the given
+// ResolutionRequest labels.
+func metricViews(labelKeys []string) ([]*view.View, error) {
+	tagKeys := []tag.Key{resolverKey, statusKey, reasonKey}
+	for _, labelKey := range labelKeys {
+		key, err := tag.NewKey(labelKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid metric label %q: %w", labelKey, err)
+		}
+		tagKeys = append(tagKeys, key)
+	}
+	return []*view.View{{
+		Description: resolutionCount.Description(),
+		Measure:     resolutionCount,
+		Aggregation: view.Count(),
+		TagKeys:     tagKeys,
+	}, {
+		Description: resolutionDuration.Description(),
+		Measure:     resolutionDuration,
+		Aggregation: view.Distribution(0.1, 0.5, 1, 2, 5, 10, 20, 30, 45, 60),
+		TagKeys:     tagKeys,
+	}}, nil
+}
+
+// registerMetricViews registers the resolution metric views, replacing
+// any registered before with different labels.
+func registerMetricViews(labelKeys []string) error {
+	views, err := metricViews(labelKeys)
+	if err != nil {
+		return err
+	}
+	for _, v := range views {
+		if existing := view.Find(v.Measure.Name()); existing != nil {
+			view.Unregister(existing)
+		}
+	}
+	return view.Register(views...)
+}
+
+// recordResolution records the outcome and duration of resolving rr,
+// which started at start. Unsuccessful resolutions are tagged with the
+// reason they failed for.
+func (r *Reconciler) recordResolution(ctx context.Context, rr *v1alpha1.ResolutionRequest, start time.Time, err error) {
+	status := statusSuccess
+	mutators := []tag.Mutator{
+		tag.Upsert(resolverKey, r.resolver.GetName(ctx)),
+	}
+	if err != nil {
+		reason, _ := resolutioncommon.ReasonError(err)
+		switch {
+		case reason == resolutioncommon.ReasonResolutionTimedOut:
+			status = statusTimeout
+		case !controller.IsPermanentError(err) && resolutioncommon.IsRetryable(err):
+			status = statusRetrying
+		default:
+			status = statusFailed
+		}
+		mutators = append(mutators, tag.Upsert(reasonKey, reason))
+	}
+	mutators = append(mutators, tag.Upsert(statusKey, status))
+	for _, labelKey := range r.metricLabels {
+		if key, err := tag.NewKey(labelKey); err == nil {
+			mutators = append(mutators, tag.Upsert(key, rr.Labels[labelKey]))
+		}
+	}
+	_ = stats.RecordWithTags(ctx, mutators,
+		resolutionCount.M(1),
+		resolutionDuration.M(r.Clock.Now().Sub(start).Seconds()))
+}
@@ -0,0 +1,190 @@
+package framework
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	resolutioncommon "github.com/tektoncd/resolution/pkg/common"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+)
+
+// registerTestMetricViews registers the resolution metric views with
+// the given labels for the duration of the test.
+func registerTestMetricViews(t *testing.T, labelKeys ...string) {
+	t.Helper()
+	if err := registerMetricViews(labelKeys); err != nil {
+		t.Fatalf("error registering metric views: %v", err)
+	}
+	t.Cleanup(func() {
+		views, _ := metricViews(labelKeys)
+		view.Unregister(views...)
+	})
+}
+
+func TestReconcileMetricLabels(t *testing.T) {
+	registerTestMetricViews(t, "team")
+	rr := newTestRequest()
+	rr.Labels["team"] = "build"
+	rr.Labels["app"] = "catalog"
+	resolver := &fakeResolver{
+		resolve: func(context.Context, map[string]string) (ResolvedResource, error) {
+			return &fakeResource{data: []byte("kind: Task")}, nil
+		},
+	}
+	r, _ := newTestReconciler(t, resolver, rr, WithMetricLabels("team"))
+
+	if err := r.Reconcile(context.Background(), "foo/rr"); err != nil {
+		t.Fatalf("unexpected error reconciling: %v", err)
+	}
+
+	for _, name := range []string{resolutionCount.Name(), resolutionDuration.Name()} {
+		rows, err := view.RetrieveData(name)
+		if err != nil {
+			t.Fatalf("error retrieving %s: %v", name, err)
+		}
+		if len(rows) != 1 {
+			t.Fatalf("expected 1 row for %s, got %d", name, len(rows))
+		}
+		tags := map[string]string{}
+		for _, tg := range rows[0].Tags {
+			tags[tg.Key.Name()] = tg.Value
+		}
+		expected := map[string]string{"resolver": "Fake", "status": statusSuccess, "team": "build"}
+		for k, v := range expected {
+			if tags[k] != v {
+				t.Errorf("expected %s tag %s=%q, got %q", name, k, v, tags[k])
+			}
+		}
+		if _, ok := tags["app"]; ok {
+			t.Errorf("expected unlisted label app not to be a %s tag", name)
+		}
+		if len(tags) != len(expected) {
+			t.Errorf("unexpected %s tags %v", name, tags)
+		}
+	}
+}
+
+func TestReconcileMetricsRecordFailure(t *testing.T) {
+	registerTestMetricViews(t)
+	rr := newTestRequest()
+	resolver := &fakeResolver{
+		resolve: func(context.Context, map[string]string) (ResolvedResource, error) {
+			return nil, errors.New("repo not found")
+		},
+	}
+	r, _ := newTestReconciler(t, resolver, rr)
+
+	if err := r.Reconcile(context.Background(), "foo/rr"); err == nil {
+		t.Fatalf("expected error reconciling")
+	}
+
+	rows, err := view.RetrieveData(resolutionCount.Name())
+	if err != nil {
+		t.Fatalf("error retrieving %s: %v", resolutionCount.Name(), err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("expected 1 row, got %d", len(rows))
+	}
+	want := tag.Tag{Key: statusKey, Value: statusFailed}
+	found := false
+	for _, tg := range rows[0].Tags {
+		found = found || tg == want
+	}
+	if !found {
+		t.Errorf("expected status tag %q, got tags %v", statusFailed, rows[0].Tags)
+	}
+	if count := rows[0].Data.(*view.CountData).Value; count != 1 {
+		t.Errorf("expected a count of 1, got %d", count)
+	}
+}
+
+func TestReconcileMetricsRecordOutcome(t *testing.T) {
+	for _, tc := range []struct {
+		name           string
+		err            error
+		timeout        time.Duration
+		expectedStatus string
+		expectedReason string
+	}{{
+		name:           "success",
+		expectedStatus: statusSuccess,
+	}, {
+		name:           "failure",
+		err:            errors.New("repo not found"),
+		expectedStatus: statusFailed,
+		expectedReason: resolutioncommon.ReasonResolutionFailed,
+	}, {
+		name:           "failure with reason",
+		err:            resolutioncommon.NewError("UnsignedCommit", errors.New("commit is not signed")),
+		expectedStatus: statusFailed,
+		expectedReason: "UnsignedCommit",
+	}, {
+		name:           "timeout",
+		timeout:        20 * time.Millisecond,
+		expectedStatus: statusTimeout,
+		expectedReason: resolutioncommon.ReasonResolutionTimedOut,
+	}, {
+		name:           "retrying",
+		err:            resolutioncommon.NewRetryableError(errors.New("connection reset by peer")),
+		expectedStatus: statusRetrying,
+		expectedReason: resolutioncommon.ReasonResolutionFailed,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			registerTestMetricViews(t)
+			rr := newTestRequest()
+			resolver := &timedFakeResolver{
+				fakeResolver: fakeResolver{
+					resolve: func(ctx context.Context, _ map[string]string) (ResolvedResource, error) {
+						if tc.timeout > 0 {
+							<-ctx.Done()
+						}
+						if tc.err != nil {
+							return nil, tc.err
+						}
+						return &fakeResource{data: []byte("kind: Task")}, nil
+					},
+				},
+				timeout: defaultMaximumResolutionDuration,
+			}
+			if tc.timeout > 0 {
+				resolver.timeout = tc.timeout
+			}
+			r, _ := newTestReconciler(t, resolver, rr)
+
+			_ = r.Reconcile(context.Background(), "foo/rr")
+
+			for _, name := range []string{resolutionCount.Name(), resolutionDuration.Name()} {
+				rows, err := view.RetrieveData(name)
+				if err != nil {
+					t.Fatalf("error retrieving %s: %v", name, err)
+				}
+				if len(rows) != 1 {
+					t.Fatalf("expected 1 row for %s, got %d", name, len(rows))
+				}
+				tags := map[string]string{}
+				for _, tg := range rows[0].Tags {
+					tags[tg.Key.Name()] = tg.Value
+				}
+				if tags["status"] != tc.expectedStatus {
+					t.Errorf("expected %s status tag %q, got %q", name, tc.expectedStatus, tags["status"])
+				}
+				if reason, ok := tags["reason"]; reason != tc.expectedReason || ok != (tc.expectedReason != "") {
+					t.Errorf("expected %s reason tag %q, got tags %v", name, tc.expectedReason, tags)
+				}
+			}
+			rows, _ := view.RetrieveData(resolutionCount.Name())
+			if count := rows[0].Data.(*view.CountData).Value; count != 1 {
+				t.Errorf("expected a count of 1, got %d", count)
+			}
+		})
+	}
+}
+
+func TestMetricViewsInvalidLabel(t *testing.T) {
+	if _, err := metricViews([]string{""}); err == nil {
+		t.Errorf("expected error for an empty metric label")
+	}
+}
@@ -0,0 +1,110 @@
+package framework
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	resolutioncommon "github.com/tektoncd/resolution/pkg/common"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"knative.dev/pkg/apis"
+	"knative.dev/pkg/controller"
+)
+
+// withKubeClient returns a ReconcilerModifier that sets the kube client
+// a reconciler looks up namespaces with.
+func withKubeClient(client *kubefake.Clientset) ReconcilerModifier {
+	return func(r *Reconciler) {
+		r.kubeClientSet = client
+	}
+}
+
+func newTestNamespace(phase corev1.NamespacePhase) *corev1.Namespace {
+	return &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo"},
+		Status:     corev1.NamespaceStatus{Phase: phase},
+	}
+}
+
+func TestReconcileNamespaceTerminating(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		objects []*corev1.Namespace
+	}{{
+		name:    "terminating",
+		objects: []*corev1.Namespace{newTestNamespace(corev1.NamespaceTerminating)},
+	}, {
+		name: "deleted",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := newTestRequest()
+			resolves := 0
+			resolver := &fakeResolver{
+				resolve: func(context.Context, map[string]string) (ResolvedResource, error) {
+					resolves++
+					return nil, errors.New("secret not found")
+				},
+			}
+			kubeClient := kubefake.NewSimpleClientset()
+			for _, ns := range tc.objects {
+				if err := kubeClient.Tracker().Add(ns); err != nil {
+					t.Fatalf("error adding namespace: %v", err)
+				}
+			}
+			r, clientSet := newTestReconciler(t, resolver, rr, withKubeClient(kubeClient))
+
+			err := r.Reconcile(context.Background(), "foo/rr")
+			if !controller.IsPermanentError(err) {
+				t.Errorf("expected a permanent error so the request isn't retried, received %v", err)
+			}
+			if resolves != 0 {
+				t.Errorf("expected no resolution in a terminating namespace, resolved %d times", resolves)
+			}
+			cond := getTestRequest(t, clientSet, rr).Status.GetCondition(apis.ConditionSucceeded)
+			if cond == nil || !cond.IsFalse() || cond.Reason != resolutioncommon.ReasonNamespaceTerminating {
+				t.Errorf("expected request to fail with reason %q, received condition %v", resolutioncommon.ReasonNamespaceTerminating, cond)
+			}
+		})
+	}
+}
+
+func TestReconcileNamespaceTerminatesMidResolution(t *testing.T) {
+	rr := newTestRequest()
+	kubeClient := kubefake.NewSimpleClientset(newTestNamespace(corev1.NamespaceActive))
+	resolver := &fakeResolver{
+		resolve: func(ctx context.Context, _ map[string]string) (ResolvedResource, error) {
+			if _, err := kubeClient.CoreV1().Namespaces().Update(ctx, newTestNamespace(corev1.NamespaceTerminating), metav1.UpdateOptions{}); err != nil {
+				t.Errorf("error updating namespace: %v", err)
+			}
+			return nil, errors.New("secret not found")
+		},
+	}
+	r, clientSet := newTestReconciler(t, resolver, rr, withKubeClient(kubeClient))
+
+	if err := r.Reconcile(context.Background(), "foo/rr"); !controller.IsPermanentError(err) {
+		t.Errorf("expected a permanent error so the request isn't retried, received %v", err)
+	}
+	cond := getTestRequest(t, clientSet, rr).Status.GetCondition(apis.ConditionSucceeded)
+	if cond == nil || cond.Reason != resolutioncommon.ReasonNamespaceTerminating {
+		t.Errorf("expected request to fail with reason %q, received condition %v", resolutioncommon.ReasonNamespaceTerminating, cond)
+	}
+}
+
+func TestReconcileNamespaceActive(t *testing.T) {
+	rr := newTestRequest()
+	resolver := &fakeResolver{
+		resolve: func(context.Context, map[string]string) (ResolvedResource, error) {
+			return nil, errors.New("secret not found")
+		},
+	}
+	kubeClient := kubefake.NewSimpleClientset(newTestNamespace(corev1.NamespaceActive))
+	r, clientSet := newTestReconciler(t, resolver, rr, withKubeClient(kubeClient))
+
+	_ = r.Reconcile(context.Background(), "foo/rr")
+	cond := getTestRequest(t, clientSet, rr).Status.GetCondition(apis.ConditionSucceeded)
+	if cond == nil || cond.Reason != resolutioncommon.ReasonResolutionFailed {
+		t.Errorf("expected request to fail with reason %q, received condition %v", resolutioncommon.ReasonResolutionFailed, cond)
+	}
+}
@@ -0,0 +1,179 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+
+	"github.com/tektoncd/resolution/pkg/apis/resolution/v1alpha1"
+)
+
+// maxWaitingResolutions is the number of ResolutionRequests, beyond
+// those being resolved, that the controller takes off its work queue
+// to wait in the priority queue. Requests past this wait in the work
+// queue in the order they arrived.
+const maxWaitingResolutions = 100
+
+// WithPriorities returns a ReconcilerModifier that limits the resolver
+// to resolving the given number of ResolutionRequests at once, starting
+// waiting requests in order of priority so that urgent requests aren't
+// held up behind a backlog. A request's priority is the value that
+// priorities maps the value of its label to, with higher values going
+// first. Requests without the label, or with a value that isn't
+// listed, have priority 0. Requests of equal priority start in the
+// order they arrived.
+func WithPriorities(workers int, label string, priorities map[string]int) ReconcilerModifier {
+	return func(r *Reconciler) {
+		r.priorityLabel = label
+		r.priorities = priorities
+		r.queue = newPriorityQueue(workers)
+	}
+}
+
+// priority returns the priority of a ResolutionRequest according to
+// the reconciler's label mapping.
+func (r *Reconciler) priority(rr *v1alpha1.ResolutionRequest) int {
+	return r.priorities[rr.Labels[r.priorityLabel]]
+}
+
+// concurrency returns the number of workers the controller should
+// reconcile with, or 0 for the default. When resolutions are limited
+// by a priority queue, extra workers take requests off the work queue
+// so that they can wait in priority order.
+func (r *Reconciler) concurrency() int {
+	if r.queue == nil {
+		return 0
+	}
+	return r.queue.workers + maxWaitingResolutions
+}
+
+// priorityQueue limits the number of resolutions that run at once and
+// hands out free slots to the highest priority waiter first.
+type priorityQueue struct {
+	workers int
+
+	mu      sync.Mutex
+	running int
+	waiting waiters
+	arrived uint64
+}
+
+func newPriorityQueue(workers int) *priorityQueue {
+	if workers < 1 {
+		workers = 1
+	}
+	return &priorityQueue{workers: workers}
+}
+
+// acquire blocks until a resolution with the given priority may start,
+// returning ctx's error if it's done first. Each successful call must
+// be paired with a call to release.
+func (q *priorityQueue) acquire(ctx context.Context, priority int) error {
+	q.mu.Lock()
+	if q.running < q.workers && len(q.waiting) == 0 {
+		q.running++
+		q.mu.Unlock()
+		return nil
+	}
+	w := &waiter{priority: priority, arrived: q.arrived, ready: make(chan struct{})}
+	q.arrived++
+	heap.Push(&q.waiting, w)
+	q.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		if w.index < 0 {
+			// The slot was handed over as ctx finished, so it's
+			// passed on rather than lost.
+			q.releaseLocked()
+		} else {
+			heap.Remove(&q.waiting, w.index)
+		}
+		return ctx.Err()
+	}
+}
+
+// release frees a slot taken by acquire, starting the highest priority
+// waiter if there is one.
+func (q *priorityQueue) release() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.releaseLocked()
+}
+
+func (q *priorityQueue) releaseLocked() {
+	if len(q.waiting) == 0 {
+		q.running--
+		return
+	}
+	w := heap.Pop(&q.waiting).(*waiter)
+	close(w.ready)
+}
+
+// waiter is a resolution waiting in a priorityQueue.
+type waiter struct {
+	priority int
+	arrived  uint64
+	ready    chan struct{}
+
+	// index is the waiter's position in the heap, or -1 once it has
+	// been handed a slot.
+	index int
+}
+
+// waiters is a heap of waiters ordered by descending priority, then by
+// arrival.
+type waiters []*waiter
+
+var _ heap.Interface = &waiters{}
+
+func (w waiters) Len() int { return len(w) }
+
+func (w waiters) Less(i, j int) bool {
+	if w[i].priority != w[j].priority {
+		return w[i].priority > w[j].priority
+	}
+	return w[i].arrived < w[j].arrived
+}
+
+func (w waiters) Swap(i, j int) {
+	w[i], w[j] = w[j], w[i]
+	w[i].index = i
+	w[j].index = j
+}
+
+func (w *waiters) Push(x interface{}) {
+	item := x.(*waiter)
+	item.index = len(*w)
+	*w = append(*w, item)
+}
+
+func (w *waiters) Pop() interface{} {
+	old := *w
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	item.index = -1
+	*w = old[:n-1]
+	return item
+}
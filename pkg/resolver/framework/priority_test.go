@@ -0,0 +1,160 @@
+package framework
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tektoncd/resolution/pkg/apis/resolution/v1alpha1"
+	"github.com/tektoncd/resolution/pkg/client/clientset/versioned/fake"
+	rrv1alpha1 "github.com/tektoncd/resolution/pkg/client/listers/resolution/v1alpha1"
+	resolutioncommon "github.com/tektoncd/resolution/pkg/common"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/utils/clock"
+)
+
+const testPriorityLabel = "example.com/priority"
+
+// waitForWaiters blocks until n resolutions are waiting in q.
+func waitForWaiters(t *testing.T, q *priorityQueue, n int) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		q.mu.Lock()
+		waiting := len(q.waiting)
+		q.mu.Unlock()
+		if waiting == n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d waiting resolutions, found %d", n, waiting)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestReconcilePriorities(t *testing.T) {
+	names := []string{"blocker", "scheduled-1", "unlabelled", "interactive-1", "scheduled-2", "interactive-2"}
+	labels := map[string]string{
+		"scheduled-1":   "scheduled",
+		"scheduled-2":   "scheduled",
+		"interactive-1": "interactive",
+		"interactive-2": "interactive",
+	}
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	objects := []runtime.Object{}
+	for _, name := range names {
+		rr := newTestRequest()
+		rr.Name = name
+		rr.Spec.Parameters = map[string]string{"name": name}
+		if value, ok := labels[name]; ok {
+			rr.Labels[testPriorityLabel] = value
+		}
+		if err := indexer.Add(rr); err != nil {
+			t.Fatalf("error adding resolutionrequest to indexer: %v", err)
+		}
+		objects = append(objects, rr)
+	}
+
+	started, unblock := make(chan struct{}), make(chan struct{})
+	var mu sync.Mutex
+	var order []string
+	resolver := &fakeResolver{
+		resolve: func(_ context.Context, params map[string]string) (ResolvedResource, error) {
+			if params["name"] == "blocker" {
+				close(started)
+				<-unblock
+			}
+			mu.Lock()
+			order = append(order, params["name"])
+			mu.Unlock()
+			return &fakeResource{data: []byte("kind: Task")}, nil
+		},
+	}
+	r := &Reconciler{
+		Clock:                      clock.RealClock{},
+		resolver:                   resolver,
+		resolutionRequestLister:    rrv1alpha1.NewResolutionRequestLister(indexer),
+		resolutionRequestClientSet: fake.NewSimpleClientset(objects...),
+	}
+	WithPriorities(1, testPriorityLabel, map[string]int{
+		"interactive": 10,
+		"scheduled":   -1,
+	})(r)
+
+	var wg sync.WaitGroup
+	reconcile := func(name string) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := r.Reconcile(context.Background(), "foo/"+name); err != nil {
+				t.Errorf("unexpected error reconciling %q: %v", name, err)
+			}
+		}()
+	}
+	// The blocker takes the only worker, then each request is queued
+	// behind it in turn so that their arrival order is known.
+	reconcile(names[0])
+	<-started
+	for i, name := range names[1:] {
+		reconcile(name)
+		waitForWaiters(t, r.queue, i+1)
+	}
+	close(unblock)
+	wg.Wait()
+
+	expected := []string{"blocker", "interactive-1", "interactive-2", "unlabelled", "scheduled-1", "scheduled-2"}
+	if len(order) != len(expected) {
+		t.Fatalf("expected resolution order %v received %v", expected, order)
+	}
+	for i := range expected {
+		if order[i] != expected[i] {
+			t.Fatalf("expected resolution order %v received %v", expected, order)
+		}
+	}
+}
+
+func TestPriorityQueueAcquireCanceled(t *testing.T) {
+	q := newPriorityQueue(1)
+	if err := q.acquire(context.Background(), 0); err != nil {
+		t.Fatalf("unexpected error acquiring free slot: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error)
+	go func() { errs <- q.acquire(ctx, 5) }()
+	waitForWaiters(t, q, 1)
+	cancel()
+	if err := <-errs; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected canceled wait to return %v, received %v", context.Canceled, err)
+	}
+	waitForWaiters(t, q, 0)
+
+	q.release()
+	acquireCtx, acquireCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer acquireCancel()
+	if err := q.acquire(acquireCtx, 0); err != nil {
+		t.Errorf("expected slot to be free after release, received %v", err)
+	}
+}
+
+func TestReconcilerConcurrency(t *testing.T) {
+	r := &Reconciler{}
+	if c := r.concurrency(); c != 0 {
+		t.Errorf("expected default concurrency without priorities, received %d", c)
+	}
+	WithPriorities(3, testPriorityLabel, nil)(r)
+	if c := r.concurrency(); c != 3+maxWaitingResolutions {
+		t.Errorf("expected concurrency %d received %d", 3+maxWaitingResolutions, c)
+	}
+	rr := &v1alpha1.ResolutionRequest{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{
+		resolutioncommon.LabelKeyResolverType: "fake",
+	}}}
+	if p := r.priority(rr); p != 0 {
+		t.Errorf("expected unlabelled request to have priority 0, received %d", p)
+	}
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"knative.dev/pkg/logging"
+)
+
+// ReadinessPath is the path that the readiness probe is served on.
+const ReadinessPath = "/readiness"
+
+// WithReadinessProbe returns a ReconcilerModifier that serves a
+// readiness probe at ReadinessPath on addr, e.g. ":8080". The probe
+// fails with a 503 while the resolver's ConfigValidator rejects its
+// current configuration, so that a malformed configmap shows up in
+// the resolver's pod status instead of in failed requests.
+func WithReadinessProbe(addr string) ReconcilerModifier {
+	return func(r *Reconciler) {
+		r.readinessAddr = addr
+	}
+}
+
+// readinessHandler returns the http handler for the readiness probe,
+// reporting the error from the reconciler's config store if it has
+// one.
+func (r *Reconciler) readinessHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(ReadinessPath, func(w http.ResponseWriter, _ *http.Request) {
+		if r.configStore != nil {
+			if err := r.configStore.ConfigError(); err != nil {
+				http.Error(w, fmt.Sprintf("invalid resolver config: %v", err), http.StatusServiceUnavailable)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	return mux
+}
+
+// serveReadiness serves handler on addr until ctx is done.
+func serveReadiness(ctx context.Context, addr string, handler http.Handler) {
+	logger := logging.FromContext(ctx)
+	server := &http.Server{Addr: addr, Handler: handler}
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Errorf("error serving readiness probe on %s: %v", addr, err)
+		}
+	}()
+}
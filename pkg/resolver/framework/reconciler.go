@@ -17,17 +17,24 @@ limitations under the License.
 package framework
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"runtime/debug"
+	"strconv"
 	"time"
 
 	"github.com/tektoncd/resolution/pkg/apis/resolution/v1alpha1"
 	rrclient "github.com/tektoncd/resolution/pkg/client/clientset/versioned"
 	rrv1alpha1 "github.com/tektoncd/resolution/pkg/client/listers/resolution/v1alpha1"
 	resolutioncommon "github.com/tektoncd/resolution/pkg/common"
+	"go.opentelemetry.io/otel/attribute"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
@@ -55,24 +62,61 @@ type Reconciler struct {
 	resolutionRequestClientSet rrclient.Interface
 
 	configStore *ConfigStore
+
+	validators []ResolvedResourceValidator
+
+	// metricLabels are the ResolutionRequest labels that resolution
+	// metrics are tagged with.
+	metricLabels []string
+
+	// queue, if set, limits how many requests are resolved at once and
+	// starts waiting ones in the order of the priorities that their
+	// priorityLabel maps to.
+	queue         *priorityQueue
+	priorityLabel string
+	priorities    map[string]int
+
+	// maxResolvedSize is the most bytes of data a resolved resource
+	// may have. Zero means defaultMaxResolvedSize.
+	maxResolvedSize int
+
+	// maxAttempts is the most times a request is resolved before a
+	// retryable error fails it. Zero means defaultMaxAttempts.
+	maxAttempts int
+
+	// compressionThreshold, if positive, is the size in bytes above
+	// which resolved data is gzipped before it's written.
+	compressionThreshold int
+
+	// readinessAddr, if set, is the address that the readiness probe
+	// is served on.
+	readinessAddr string
 }
 
 var _ reconciler.LeaderAware = &Reconciler{}
 
-// defaultMaximumResolutionDuration is the maximum amount of time
-// resolution may take.
-
 // defaultMaximumResolutionDuration is the max time that a call to
 // Resolve() may take. It can be overridden by a resolver implementing
 // the framework.TimedResolution interface.
 const defaultMaximumResolutionDuration = time.Minute
 
+// defaultMaxResolvedSize is the most bytes of data that a resolved
+// resource may have unless overridden with WithMaxResolvedSize. Data is
+// base64 encoded into the ResolutionRequest's status, growing by a
+// third, and etcd rejects objects much over 1.5MB.
+const defaultMaxResolvedSize = 1024 * 1024
+
+// defaultMaxAttempts is the most times a request is resolved, while
+// the resolver keeps returning retryable errors, before it's failed
+// unless overridden with WithMaxAttempts.
+const defaultMaxAttempts = 5
+
 // Reconcile receives the string key of a ResolutionRequest object, looks
 // it up, checks it for common errors, and then delegates
 // resolver-specific functionality to the reconciler's embedded
 // type-specific resolver. Any errors that occur during validation or
 // resolution are handled by updating or failing the ResolutionRequest.
-func (r *Reconciler) Reconcile(ctx context.Context, key string) error {
+func (r *Reconciler) Reconcile(ctx context.Context, key string) (err error) {
 	namespace, name, err := cache.SplitMetaNamespaceKey(key)
 	if err != nil {
 		err = &resolutioncommon.ErrorInvalidResourceKey{Key: key, Original: err}
@@ -89,20 +133,54 @@ func (r *Reconciler) Reconcile(ctx context.Context, key string) error {
 		return nil
 	}
 
+	ctx, span := startReconcileSpan(ctx, key, r.resolver.GetName(ctx), rr)
+	defer func() { endSpan(span, err) }()
+
 	// Inject request-scoped information into the context, such as
-	// the namespace that the request originates from and the
-	// configuration from the configmap this resolver is watching.
+	// the namespace that the request originates from, its annotations
+	// and the configuration from the configmap this resolver is
+	// watching.
 	ctx = resolutioncommon.InjectRequestNamespace(ctx, namespace)
+	ctx = resolutioncommon.InjectRequestAnnotations(ctx, rr.Annotations)
 	if r.configStore != nil {
 		ctx = r.configStore.ToContext(ctx)
 	}
+	// Resolvers that enforce their own size limits need to know
+	// whether the data they return is compressed before it's checked
+	// against the reconciler's.
+	if r.compressionThreshold > 0 {
+		ctx = InjectCompressionThresholdToContext(ctx, r.compressionThreshold)
+	}
+
+	// Lookups of secrets and configmaps in a namespace that's being
+	// torn down fail in confusing ways, so there's no point trying.
+	if err := r.checkNamespace(ctx, namespace); err != nil {
+		return r.OnError(ctx, rr, err)
+	}
+
+	if r.queue != nil {
+		if err := r.queue.acquire(ctx, r.priority(rr)); err != nil {
+			return err
+		}
+		defer r.queue.release()
+	}
 
 	return r.resolve(ctx, key, rr)
 }
 
-func (r *Reconciler) resolve(ctx context.Context, key string, rr *v1alpha1.ResolutionRequest) error {
-	errChan := make(chan error)
-	resourceChan := make(chan ResolvedResource)
+func (r *Reconciler) resolve(ctx context.Context, key string, rr *v1alpha1.ResolutionRequest) (err error) {
+	start := r.Clock.Now()
+	ctx, span := startSpan(ctx, "resolve")
+	defer func() {
+		endSpan(span, err)
+		r.recordResolution(ctx, rr, start, err)
+	}()
+
+	// The channels are buffered so that the goroutine below can send
+	// its result and exit even after the resolution has timed out and
+	// nothing is left to receive it.
+	errChan := make(chan error, 1)
+	resourceChan := make(chan ResolvedResource, 1)
 
 	timeoutDuration := defaultMaximumResolutionDuration
 	if timed, ok := r.resolver.(TimedResolution); ok {
@@ -116,6 +194,18 @@ func (r *Reconciler) resolve(ctx context.Context, key string, rr *v1alpha1.Resol
 	defer cancelFn()
 
 	go func() {
+		// A panicking resolver fails only the request it was
+		// resolving rather than taking the controller down with it.
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				logging.FromContext(ctx).Errorf("resolver %s panicked resolving %q: %v\n%s", r.resolver.GetName(resolutionCtx), key, recovered, debug.Stack())
+				errChan <- &resolutioncommon.ErrorGettingResource{
+					ResolverName: r.resolver.GetName(resolutionCtx),
+					Key:          key,
+					Original:     resolutioncommon.NewError(resolutioncommon.ReasonResolverPanicked, fmt.Errorf("resolver panicked: %v", recovered)),
+				}
+			}
+		}()
 		validationError := r.resolver.ValidateParams(resolutionCtx, rr.Spec.Parameters)
 		if validationError != nil {
 			errChan <- &resolutioncommon.ErrorInvalidRequest{
@@ -133,6 +223,15 @@ func (r *Reconciler) resolve(ctx context.Context, key string, rr *v1alpha1.Resol
 			}
 			return
 		}
+		for _, validator := range r.validators {
+			if err := validator.ValidateResolvedResource(resolutionCtx, rr.Spec.Parameters, resource); err != nil {
+				errChan <- &resolutioncommon.ErrorRejectedResource{
+					ResolutionRequestKey: key,
+					Original:             err,
+				}
+				return
+			}
+		}
 		resourceChan <- resource
 	}()
 
@@ -143,6 +242,9 @@ func (r *Reconciler) resolve(ctx context.Context, key string, rr *v1alpha1.Resol
 		}
 	case <-resolutionCtx.Done():
 		if err := resolutionCtx.Err(); err != nil {
+			if errors.Is(err, context.DeadlineExceeded) {
+				err = resolutioncommon.NewError(resolutioncommon.ReasonResolutionTimedOut, fmt.Errorf("resolution took longer than %s: %w", timeoutDuration, err))
+			}
 			return r.OnError(ctx, rr, err)
 		}
 	case resource := <-resourceChan:
@@ -153,18 +255,99 @@ func (r *Reconciler) resolve(ctx context.Context, key string, rr *v1alpha1.Resol
 }
 
 // OnError is used to handle any situation where a ResolutionRequest has
-// reached a terminal situation that cannot be recovered from.
+// reached a terminal situation that cannot be recovered from. Errors
+// that are retryable instead leave the request in progress and are
+// returned as is so that it's requeued, until it has been attempted
+// the maximum number of times.
 func (r *Reconciler) OnError(ctx context.Context, rr *v1alpha1.ResolutionRequest, err error) error {
 	if rr == nil {
 		return controller.NewPermanentError(err)
 	}
 	if err != nil {
+		// A failure partway through resolution may be because the
+		// request's namespace started terminating, which is the
+		// clearer reason to give.
+		if nsErr := r.checkNamespace(ctx, rr.Namespace); nsErr != nil {
+			err = nsErr
+		}
+		// Credentials embedded in a url mustn't reach the request's
+		// status or the logs.
+		err = resolutioncommon.RedactError(err)
+		if resolutioncommon.IsRetryable(err) {
+			attempts, retry := r.recordAttempt(ctx, rr)
+			if retry {
+				logging.FromContext(ctx).Infof("attempt %d to resolve %s/%s failed, retrying: %v", attempts, rr.Namespace, rr.Name, err)
+				return err
+			}
+			err = fmt.Errorf("failed after %d attempts: %w", attempts, err)
+		}
 		_ = r.MarkFailed(ctx, rr, err)
 		return controller.NewPermanentError(err)
 	}
 	return nil
 }
 
+// recordAttempt counts a failed attempt at resolving a request in its
+// AnnotationKeyResolutionAttempts annotation and returns the number of
+// attempts so far and whether it should be resolved again. A request
+// whose count can't be updated is still retried, since the update is
+// likely failing for the same kind of transient reason.
+func (r *Reconciler) recordAttempt(ctx context.Context, rr *v1alpha1.ResolutionRequest) (int, bool) {
+	maxAttempts := r.maxAttempts
+	if maxAttempts == 0 {
+		maxAttempts = defaultMaxAttempts
+	}
+	key := fmt.Sprintf("%s/%s", rr.Namespace, rr.Name)
+	latestGeneration, err := r.resolutionRequestClientSet.ResolutionV1alpha1().ResolutionRequests(rr.Namespace).Get(ctx, rr.Name, metav1.GetOptions{})
+	if err != nil {
+		logging.FromContext(ctx).Warnf("error getting latest generation of resolutionrequest %q: %v", key, err)
+		return 0, true
+	}
+	attempts := 1
+	if previous, err := strconv.Atoi(latestGeneration.Annotations[resolutioncommon.AnnotationKeyResolutionAttempts]); err == nil && previous > 0 {
+		attempts = previous + 1
+	}
+	if attempts >= maxAttempts {
+		return attempts, false
+	}
+	patchBytes, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{
+				resolutioncommon.AnnotationKeyResolutionAttempts: strconv.Itoa(attempts),
+			},
+		},
+	})
+	if err == nil {
+		_, err = r.resolutionRequestClientSet.ResolutionV1alpha1().ResolutionRequests(rr.Namespace).Patch(ctx, rr.Name, types.MergePatchType, patchBytes, metav1.PatchOptions{})
+	}
+	if err != nil {
+		logging.FromContext(ctx).Warnf("error recording attempt on resolutionrequest %q: %v", key, err)
+	}
+	return attempts, true
+}
+
+// checkNamespace returns an error with the NamespaceTerminating reason
+// if the given namespace is being deleted or no longer exists. Any
+// other problem looking it up, such as the resolver lacking permission
+// to, is ignored so that resolution carries on as it would without the
+// check.
+func (r *Reconciler) checkNamespace(ctx context.Context, namespace string) error {
+	if r.kubeClientSet == nil {
+		return nil
+	}
+	ns, err := r.kubeClientSet.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		return resolutioncommon.NewError(resolutioncommon.ReasonNamespaceTerminating, fmt.Errorf("namespace %q has been deleted", namespace))
+	case err != nil:
+		logging.FromContext(ctx).Debugf("error checking status of namespace %q: %v", namespace, err)
+		return nil
+	case ns.Status.Phase == corev1.NamespaceTerminating || ns.DeletionTimestamp != nil:
+		return resolutioncommon.NewError(resolutioncommon.ReasonNamespaceTerminating, fmt.Errorf("namespace %q is being deleted", namespace))
+	}
+	return nil
+}
+
 // MarkFailed updates a ResolutionRequest as having failed. It returns
 // errors that occur during the update process or nil if the update
 // appeared to succeed.
@@ -192,16 +375,134 @@ func (r *Reconciler) MarkFailed(ctx context.Context, rr *v1alpha1.ResolutionRequ
 // a ResolutionRequest with its data and annotations once successfully
 // resolved.
 type statusDataPatch struct {
-	Annotations map[string]string `json:"annotations"`
-	Data        string            `json:"data"`
+	Annotations map[string]string          `json:"annotations"`
+	Data        string                     `json:"data"`
+	Resolved    *v1alpha1.ResolvedMetadata `json:"resolved,omitempty"`
+}
+
+// resolvedData returns the data and annotations to write for
+// resource, archiving the files of a ResolvedResourceWithFiles.
+func resolvedData(resource ResolvedResource) ([]byte, map[string]string, error) {
+	withFiles, ok := resource.(ResolvedResourceWithFiles)
+	if !ok {
+		return resource.Data(), resource.Annotations(), nil
+	}
+	files := withFiles.ResolvedFiles()
+	if files == nil {
+		return resource.Data(), resource.Annotations(), nil
+	}
+	data, err := ArchiveFiles(files)
+	if err != nil {
+		return nil, nil, err
+	}
+	annotations := map[string]string{}
+	for key, value := range resource.Annotations() {
+		annotations[key] = value
+	}
+	annotations[resolutioncommon.AnnotationKeyContentType] = ArchiveContentType
+	return data, annotations, nil
+}
+
+// compressionThresholdKey is the context key that the reconciler's
+// compression threshold is stored under.
+type compressionThresholdKey struct{}
+
+// InjectCompressionThresholdToContext returns a new context with the
+// size in bytes above which resolved data is gzipped stored in it.
+func InjectCompressionThresholdToContext(ctx context.Context, size int) context.Context {
+	return context.WithValue(ctx, compressionThresholdKey{}, size)
+}
+
+// GetCompressionThresholdFromContext returns the size in bytes above
+// which resolved data is gzipped before it's written, or 0 if it
+// isn't compressed.
+func GetCompressionThresholdFromContext(ctx context.Context) int {
+	size, _ := ctx.Value(compressionThresholdKey{}).(int)
+	return size
+}
+
+// CompressedSize returns the number of bytes that data above the
+// compression threshold is written as: its gzipped size, or its own
+// size if compressing doesn't make it any smaller. Resolvers with
+// their own size limits can use it to leave room for data that only
+// fits once it's compressed.
+func CompressedSize(data []byte) (int, error) {
+	compressed, err := gzipData(data)
+	if err != nil {
+		return 0, err
+	}
+	if len(compressed) >= len(data) {
+		return len(data), nil
+	}
+	return len(compressed), nil
+}
+
+// gzipData returns data gzipped.
+func gzipData(data []byte) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	zw := gzip.NewWriter(buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, fmt.Errorf("error compressing resolved data: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("error compressing resolved data: %w", err)
+	}
+	return buf.Bytes(), nil
 }
 
-func (r *Reconciler) writeResolvedData(ctx context.Context, rr *v1alpha1.ResolutionRequest, resource ResolvedResource) error {
-	encodedData := base64.StdEncoding.Strict().EncodeToString(resource.Data())
+// compressData returns data gzipped, along with annotations recording
+// its content encoding, or data and annotations as they are if
+// compressing doesn't make it any smaller.
+func compressData(data []byte, annotations map[string]string) ([]byte, map[string]string, error) {
+	compressed, err := gzipData(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(compressed) >= len(data) {
+		return data, annotations, nil
+	}
+	encoded := map[string]string{}
+	for key, value := range annotations {
+		encoded[key] = value
+	}
+	encoded[resolutioncommon.AnnotationKeyContentEncoding] = "gzip"
+	return compressed, encoded, nil
+}
+
+func (r *Reconciler) writeResolvedData(ctx context.Context, rr *v1alpha1.ResolutionRequest, resource ResolvedResource) (err error) {
+	data, annotations, err := resolvedData(resource)
+	if err == nil && r.compressionThreshold > 0 && len(data) > r.compressionThreshold {
+		data, annotations, err = compressData(data, annotations)
+	}
+	if err != nil {
+		return r.OnError(ctx, rr, err)
+	}
+	ctx, span := startSpan(ctx, "write", attribute.Int("resolution.bytes", len(data)))
+	defer func() { endSpan(span, err) }()
+
+	maxSize := r.maxResolvedSize
+	if maxSize == 0 {
+		maxSize = defaultMaxResolvedSize
+	}
+	if size := len(data); maxSize > 0 && size > maxSize {
+		return r.OnError(ctx, rr, resolutioncommon.NewError(resolutioncommon.ReasonResourceTooLarge, fmt.Errorf("resolved data is %d bytes, exceeds max size %d bytes", size, maxSize)))
+	}
+	encodedData := base64.StdEncoding.Strict().EncodeToString(data)
+	var resolved *v1alpha1.ResolvedMetadata
+	if withMetadata, ok := resource.(ResolvedResourceWithMetadata); ok {
+		if metadata := withMetadata.Metadata(); metadata != nil {
+			resolved = metadata.DeepCopy()
+			if resolved.ResolvedAt == nil {
+				now := metav1.NewTime(r.Clock.Now())
+				resolved.ResolvedAt = &now
+			}
+		}
+	}
 	patchBytes, err := json.Marshal(map[string]statusDataPatch{
 		"status": {
 			Data:        encodedData,
-			Annotations: resource.Annotations(),
+			Annotations: annotations,
+			Resolved:    resolved,
 		},
 	})
 	if err != nil {
@@ -0,0 +1,67 @@
+/*
+Copyright 2022 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/tektoncd/resolution/pkg/apis/resolution/v1alpha1"
+	resolutioncommon "github.com/tektoncd/resolution/pkg/common"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"knative.dev/pkg/logging"
+)
+
+// tracerName identifies the spans recorded by the framework.
+const tracerName = "github.com/tektoncd/resolution/pkg/resolver/framework"
+
+// startReconcileSpan starts the span that reconciling rr is traced
+// under. If rr carries a span context annotation the span joins that
+// trace, otherwise it's a child of any span already in ctx. Nothing is
+// recorded unless a global tracer provider has been configured.
+func startReconcileSpan(ctx context.Context, key, resolverName string, rr *v1alpha1.ResolutionRequest) (context.Context, trace.Span) {
+	if raw, ok := rr.Annotations[resolutioncommon.AnnotationKeySpanContext]; ok {
+		carrier := propagation.MapCarrier{}
+		if err := json.Unmarshal([]byte(raw), &carrier); err != nil {
+			logging.FromContext(ctx).Warnf("ignoring invalid %s annotation on resolutionrequest %q: %v", resolutioncommon.AnnotationKeySpanContext, key, err)
+		} else {
+			ctx = propagation.TraceContext{}.Extract(ctx, carrier)
+		}
+	}
+	return startSpan(ctx, "reconcile",
+		attribute.String("resolution.request", key),
+		attribute.String("resolution.resolver", resolverName),
+	)
+}
+
+// startSpan starts a span as a child of any span in ctx.
+func startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// endSpan ends span, marking it as failed if err is non-nil.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,120 @@
+package framework
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	resolutioncommon "github.com/tektoncd/resolution/pkg/common"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// newTestSpanExporter installs a global tracer provider that records
+// spans in memory for the duration of the test.
+func newTestSpanExporter(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+		_ = provider.Shutdown(context.Background())
+	})
+	return exporter
+}
+
+func TestReconcileSpanJoinsRequestTrace(t *testing.T) {
+	exporter := newTestSpanExporter(t)
+	rr := newTestRequest()
+	rr.Annotations = map[string]string{
+		resolutioncommon.AnnotationKeySpanContext: `{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}`,
+	}
+	var resolveSpan trace.SpanContext
+	resolver := &fakeResolver{
+		resolve: func(ctx context.Context, _ map[string]string) (ResolvedResource, error) {
+			resolveSpan = trace.SpanContextFromContext(ctx)
+			return &fakeResource{data: []byte("kind: Task")}, nil
+		},
+	}
+	r, _ := newTestReconciler(t, resolver, rr)
+
+	if err := r.Reconcile(context.Background(), "foo/rr"); err != nil {
+		t.Fatalf("unexpected error reconciling: %v", err)
+	}
+
+	spans := map[string]tracetest.SpanStub{}
+	for _, span := range exporter.GetSpans() {
+		spans[span.Name] = span
+	}
+	if len(spans) != 3 {
+		t.Fatalf("expected reconcile, resolve and write spans, got %v", spans)
+	}
+	span := spans["reconcile"]
+	if got := span.SpanContext.TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("expected span to join the request's trace, got trace %s", got)
+	}
+	if got := span.Parent.SpanID().String(); got != "00f067aa0ba902b7" {
+		t.Errorf("expected span to be a child of the request's span, got parent %s", got)
+	}
+	if spans["resolve"].Parent.SpanID() != span.SpanContext.SpanID() {
+		t.Errorf("expected resolve to be a child of reconcile")
+	}
+	if resolveSpan.SpanID() != spans["resolve"].SpanContext.SpanID() {
+		t.Errorf("expected Resolve to be called with the resolve span in its context")
+	}
+	if spans["write"].Parent.SpanID() != spans["resolve"].SpanContext.SpanID() {
+		t.Errorf("expected write to be a child of resolve")
+	}
+	wantAttrs := map[attribute.Key]string{
+		"resolution.request":  "foo/rr",
+		"resolution.resolver": "Fake",
+	}
+	for _, attr := range span.Attributes {
+		if want, ok := wantAttrs[attr.Key]; ok {
+			if attr.Value.AsString() != want {
+				t.Errorf("expected attribute %s=%q, got %q", attr.Key, want, attr.Value.AsString())
+			}
+			delete(wantAttrs, attr.Key)
+		}
+	}
+	if len(wantAttrs) != 0 {
+		t.Errorf("missing span attributes %v", wantAttrs)
+	}
+}
+
+func TestReconcileSpanRecordsError(t *testing.T) {
+	exporter := newTestSpanExporter(t)
+	rr := newTestRequest()
+	resolver := &fakeResolver{
+		resolve: func(context.Context, map[string]string) (ResolvedResource, error) {
+			return nil, errors.New("repo not found")
+		},
+	}
+	r, _ := newTestReconciler(t, resolver, rr)
+
+	if err := r.Reconcile(context.Background(), "foo/rr"); err == nil {
+		t.Fatalf("expected error reconciling")
+	}
+
+	spans := map[string]tracetest.SpanStub{}
+	for _, span := range exporter.GetSpans() {
+		spans[span.Name] = span
+	}
+	if len(spans) != 2 {
+		t.Fatalf("expected reconcile and resolve spans, got %v", spans)
+	}
+	for _, name := range []string{"reconcile", "resolve"} {
+		if spans[name].Status.Code != codes.Error {
+			t.Errorf("expected %s span to be marked as failed, got status %v", name, spans[name].Status)
+		}
+	}
+	if spans["reconcile"].Parent.IsValid() {
+		t.Errorf("expected a root span for a request without a span context")
+	}
+}